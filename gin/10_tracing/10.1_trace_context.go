@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// HeaderTraceparent W3C Trace Context 请求头
+// 格式: {version}-{trace-id}-{parent-id}-{trace-flags}
+// 示例: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+const HeaderTraceparent = "traceparent"
+
+// HeaderRequestID 请求ID请求头，网关、客户端日志通常用它关联一次调用
+const HeaderRequestID = "X-Request-ID"
+
+// ErrInvalidTraceparent traceparent 格式不正确
+var ErrInvalidTraceparent = errors.New("traceparent 格式不正确")
+
+// SpanContext 一个 span 在链路中的位置，跨进程传递的就是它
+type SpanContext struct {
+	TraceID string // 32 位小写十六进制，整条链路共享
+	SpanID  string // 16 位小写十六进制，当前 span
+	Sampled bool   // trace-flags 的采样位，未采样的 span 不导出
+	Remote  bool   // 是否从上游请求头解析而来
+}
+
+// IsValid trace-id 和 span-id 都存在时才是有效的上下文
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Traceparent 编码为 traceparent 头的值
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// ParseTraceparent 解析 traceparent 头
+//
+// 规则（W3C Trace Context Level 1）:
+//   - 版本 ff 非法；00 版本必须恰好 4 段
+//   - 更高的版本向前兼容：只解析前 4 段，后面必须以 "-" 分隔
+//   - trace-id / parent-id 必须是小写十六进制且不能全为 0
+func ParseTraceparent(value string) (SpanContext, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	version := value[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if version == "00" && len(value) != 55 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	traceID, spanID, flags := value[3:35], value[36:52], value[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) ||
+		isZero(traceID) || isZero(spanID) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	flagBits, _ := hex.DecodeString(flags)
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBits[0]&0x01 == 0x01,
+		Remote:  true,
+	}, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// newTraceID 生成 16 字节随机 trace-id
+func newTraceID() string {
+	return randomHex(16)
+}
+
+// newSpanID 生成 8 字节随机 span-id
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		_, _ = rand.Read(b)
+		if s := hex.EncodeToString(b); !isZero(s) {
+			return s
+		}
+	}
+}
+
+// validRequestID 只接受长度合理、由可见安全字符组成的请求ID
+// 请求ID会原样写进日志和响应头，不能信任客户端传入任意内容
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ========== 上下文传递 ==========
+
+type contextKey int
+
+const (
+	spanContextKey contextKey = iota
+	spanKey
+	requestIDKey
+	loggerKey
+)
+
+// ContextWithSpanContext 把（通常来自上游的）SpanContext 放入 context，
+// 之后 Start 创建的 span 会成为它的子 span
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// SpanContextFromContext 返回 context 中当前的 SpanContext
+// 优先取本进程内正在进行的 span，其次是上游传入的
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(spanContextKey).(SpanContext)
+	return sc
+}
+
+// RequestIDFromContext 返回 context 中的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Inject 把链路信息写入下游请求的请求头
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	tracing.Inject(ctx, req.Header)
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(HeaderTraceparent, sc.Traceparent())
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		header.Set(HeaderRequestID, id)
+	}
+}
@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SpanKind span 的类型
+type SpanKind string
+
+const (
+	SpanKindServer   SpanKind = "server"   // 处理一次入站请求
+	SpanKindClient   SpanKind = "client"   // 发起一次出站调用（HTTP、数据库）
+	SpanKindInternal SpanKind = "internal" // 进程内的一段逻辑
+)
+
+// Span 一段被记录的操作
+// 导出时的 JSON 字段参考 OpenTelemetry 的命名，便于以后迁移
+type Span struct {
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         SpanKind               `json:"kind"`
+	StartTime    time.Time              `json:"startTime"`
+	EndTime      time.Time              `json:"endTime"`
+	DurationMs   float64                `json:"durationMs"`
+	Status       string                 `json:"status"` // ok / error
+	Error        string                 `json:"error,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+
+	mu      sync.Mutex
+	sampled bool
+	ended   bool
+	tracer  *Tracer
+}
+
+// SpanContext 当前 span 的传播信息
+func (s *Span) SpanContext() SpanContext {
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Sampled: s.sampled}
+}
+
+// SetAttribute 设置属性，span 结束后的调用会被忽略
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetName 修改名称，用于开始时还不知道最终名称的情况（如约束路由），span 结束后的调用会被忽略
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Name = name
+	}
+}
+
+// RecordError 将 span 标记为失败
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.Status = "error"
+	s.Error = err.Error()
+}
+
+// End 结束 span 并导出，重复调用只生效一次
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt 以指定时间结束 span
+func (s *Span) EndAt(end time.Time) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = end
+	s.DurationMs = float64(end.Sub(s.StartTime).Microseconds()) / 1000
+	s.mu.Unlock()
+
+	if s.sampled && s.tracer != nil && s.tracer.exporter != nil {
+		if err := s.tracer.exporter.ExportSpan(s); err != nil {
+			log.Printf("[tracing] 导出 span 失败: %v", err)
+		}
+	}
+}
+
+// SpanFromContext 返回 context 中正在进行的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ========== Tracer ==========
+
+// Exporter span 导出器，ExportSpan 在 span 结束时同步调用
+type Exporter interface {
+	ExportSpan(span *Span) error
+}
+
+// Tracer 创建 span
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer 创建 Tracer，exporter 为 nil 时只在进程内传递链路信息，不导出
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start 创建一个子 span，并返回携带它的 context
+// context 中没有父 span 时开启一条新链路
+//
+//	ctx, span := tracer.Start(ctx, "计算价格")
+//	defer span.End()
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	return t.start(ctx, name, SpanKindInternal, time.Now())
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind, start time.Time) (context.Context, *Span) {
+	span := &Span{
+		SpanID:    newSpanID(),
+		Name:      name,
+		Kind:      kind,
+		StartTime: start,
+		Status:    "ok",
+		tracer:    t,
+	}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+		span.sampled = parent.Sampled
+	} else {
+		span.TraceID = newTraceID()
+		span.sampled = true
+	}
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// ========== JSON Lines 导出 ==========
+
+// JSONLinesExporter 每个 span 输出一行 JSON，可以直接用 jq 查询或导入日志系统
+//
+//	jq 'select(.traceId=="4bf92f35...")' spans.jsonl
+type JSONLinesExporter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewJSONLinesExporter 输出到任意 Writer
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{enc: json.NewEncoder(w)}
+}
+
+// OpenJSONLinesFile 以追加方式打开本地文件，不存在时创建
+func OpenJSONLinesFile(path string) (*JSONLinesExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesExporter{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+// ExportSpan 写入一行
+func (e *JSONLinesExporter) ExportSpan(span *Span) error {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(span)
+}
+
+// Close 关闭文件（NewJSONLinesExporter 创建的不会关闭传入的 Writer）
+func (e *JSONLinesExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
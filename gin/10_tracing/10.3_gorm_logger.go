@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger 实现 gorm logger.Interface
+//
+// 与 logger.Default 的区别:
+//   - 使用 context 中的请求日志器输出，SQL 日志自动带上 trace_id、request_id、user_id
+//   - Tracer 不为空时每条 SQL 记录一个 span，挂在当前请求的 span 下
+//
+// 业务代码需要把请求的 context 传给 GORM:
+//
+//	db.WithContext(tracing.Context(c)).First(&user, id)
+type GormLogger struct {
+	Tracer                    *Tracer
+	LogLevel                  logger.LogLevel
+	SlowThreshold             time.Duration // 超过该耗时记为慢查询（Warn）
+	IgnoreRecordNotFoundError bool          // 记录不存在不算错误
+	ParameterizedQueries      bool          // 日志中不输出参数值，避免手机号、密码等落入日志
+}
+
+// NewGormLogger 创建 GORM 日志器，默认只输出慢查询和错误
+func NewGormLogger(tracer *Tracer) *GormLogger {
+	return &GormLogger{
+		Tracer:                    tracer,
+		LogLevel:                  logger.Warn,
+		SlowThreshold:             200 * time.Millisecond,
+		IgnoreRecordNotFoundError: true,
+	}
+}
+
+// LogMode 返回指定日志级别的副本
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.LogLevel = level
+	return &copied
+}
+
+// Info 输出 GORM 内部的信息日志
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+// Warn 输出 GORM 内部的警告日志
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+// Error 输出 GORM 内部的错误日志
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, data...), "component", "gorm")
+	}
+}
+
+// Trace 每条 SQL 执行完后调用
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.LogLevel <= logger.Silent && l.Tracer == nil {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	notFound := errors.Is(err, gorm.ErrRecordNotFound) && l.IgnoreRecordNotFoundError
+
+	if l.Tracer != nil {
+		_, span := l.Tracer.start(ctx, "gorm.query", SpanKindClient, begin)
+		span.SetAttribute("db.statement", sql)
+		span.SetAttribute("db.rows_affected", rows)
+		if !notFound {
+			span.RecordError(err)
+		}
+		span.EndAt(begin.Add(elapsed))
+	}
+
+	attrs := []interface{}{
+		"component", "gorm",
+		"elapsed_ms", float64(elapsed.Microseconds()) / 1000,
+		"rows", rows,
+		"sql", sql,
+	}
+	log := FromContext(ctx)
+	switch {
+	case err != nil && !notFound && l.LogLevel >= logger.Error:
+		log.ErrorContext(ctx, "SQL 执行失败", append(attrs, "error", err)...)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.LogLevel >= logger.Warn:
+		log.WarnContext(ctx, "慢查询", append(attrs, "threshold", l.SlowThreshold.String())...)
+	case l.LogLevel >= logger.Info:
+		log.InfoContext(ctx, "SQL", attrs...)
+	}
+}
+
+// ParamsFilter 开启 ParameterizedQueries 时，GORM 生成日志用的 SQL 不再代入参数值
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
@@ -0,0 +1,297 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+// Options 链路追踪中间件配置
+type Options struct {
+	Tracer *Tracer      // 为 nil 时只传递链路信息，不导出 span
+	Logger *slog.Logger // 基础日志器，为 nil 时使用 slog.Default()
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{Tracer: NewTracer(nil), Logger: slog.Default()}
+}
+
+// stateKey 请求级状态在 gin.Context 中的键
+const stateKey = "tracing.state"
+
+type requestState struct {
+	base   *slog.Logger // 带 trace_id、request_id 的日志器
+	route  string       // 已经附加到日志器上的路由模板
+	userID string       // 已经附加到日志器上的用户ID
+}
+
+// Middleware 链路追踪中间件，应当注册在最前面
+//
+// 处理流程:
+//  1. 解析上游的 traceparent，有效时本次请求成为上游 span 的子 span，否则开启新链路
+//  2. 沿用上游的 X-Request-ID（校验格式），没有时生成 UUID
+//  3. 创建服务端 span，把 span、请求ID、请求日志器放入 c.Request.Context()
+//  4. 响应头返回 X-Request-ID 和本次 span 的 traceparent，方便客户端反馈问题
+//  5. 请求结束（包括 panic）时记录状态码、用户ID并导出 span
+//
+// 为兼容已有的处理函数，请求ID同时写入 c.Set("requestID")
+func Middleware(options Options) gin.HandlerFunc {
+	if options.Tracer == nil {
+		options.Tracer = NewTracer(nil)
+	}
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if parent, err := ParseTraceparent(c.GetHeader(HeaderTraceparent)); err == nil {
+			ctx = ContextWithSpanContext(ctx, parent)
+		}
+
+		requestID := c.GetHeader(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		route := constraint.Route(c)
+		ctx, span := options.Tracer.start(ctx, spanName(c.Request.Method, route), SpanKindServer, time.Now())
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("request.id", requestID)
+
+		baseLogger := options.Logger.With(
+			"trace_id", span.TraceID,
+			"span_id", span.SpanID,
+			"request_id", requestID,
+		)
+		ctx = context.WithValue(ctx, requestIDKey, requestID)
+		ctx = ContextWithLogger(ctx, baseLogger.With("route", route))
+		c.Request = c.Request.WithContext(ctx)
+		c.Set("requestID", requestID)
+		c.Set(stateKey, &requestState{base: baseLogger, route: route})
+
+		c.Header(HeaderRequestID, requestID)
+		c.Header(HeaderTraceparent, span.SpanContext().Traceparent())
+
+		defer func() {
+			if r := recover(); r != nil {
+				span.SetAttribute("http.status_code", http.StatusInternalServerError)
+				span.RecordError(fmt.Errorf("panic: %v", r))
+				span.End()
+				panic(r) // 交给 Recovery 中间件处理
+			}
+		}()
+
+		c.Next()
+
+		// 约束路由（/users/{id:int}）在处理函数执行时才确定，结束时更新名称
+		if final := constraint.Route(c); final != route {
+			span.SetName(spanName(c.Request.Method, final))
+			span.SetAttribute("http.route", final)
+		}
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if userID := userIDOf(c); userID != "" {
+			span.SetAttribute("user.id", userID)
+		}
+		if err := c.Errors.Last(); err != nil {
+			span.RecordError(err)
+		} else if status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("HTTP %d", status))
+		}
+		span.End()
+	}
+}
+
+// spanName 服务端 span 的名称
+// 未匹配路由时不使用原始路径命名，避免扫描请求产生大量不同的 span 名称
+func spanName(method, route string) string {
+	if route == "" {
+		return method
+	}
+	return method + " " + route
+}
+
+// userIDOf 读取鉴权中间件写入的用户ID（c.Set("userID", ...)）
+func userIDOf(c *gin.Context) string {
+	v, ok := c.Get("userID")
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// ContextWithLogger 把日志器放入 context
+func ContextWithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext 返回 context 中的请求日志器，没有时返回 slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Context 返回当前请求的 context，传给 GORM、下游调用使用
+//
+// 鉴权中间件在链路追踪之后才执行，用户ID是后来才知道的，约束路由的模板也要到处理函数才确定：
+// 每次调用时检查 c.Get("userID") 和 constraint.Route(c)，有变化就更新 context 中的日志器
+func Context(c *gin.Context) context.Context {
+	v, ok := c.Get(stateKey)
+	if !ok {
+		return c.Request.Context()
+	}
+	state := v.(*requestState)
+	route, userID := constraint.Route(c), userIDOf(c)
+	if userID == "" {
+		userID = state.userID
+	}
+	if route != state.route || userID != state.userID {
+		state.route, state.userID = route, userID
+		l := state.base.With("route", route)
+		if userID != "" {
+			l = l.With("user_id", userID)
+		}
+		c.Request = c.Request.WithContext(ContextWithLogger(c.Request.Context(), l))
+	}
+	return c.Request.Context()
+}
+
+// Logger 返回当前请求的日志器（带 trace_id、request_id、route、user_id）
+func Logger(c *gin.Context) *slog.Logger {
+	return FromContext(Context(c))
+}
+
+// traceUser 示例用户表
+type traceUser struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:50"`
+}
+
+func (traceUser) TableName() string {
+	return "t_user"
+}
+
+// TracingDemo 演示 traceparent / X-Request-ID 传递、请求日志器和 span 导出
+func TracingDemo() {
+	fmt.Println("=== 链路追踪与请求日志示例 ===")
+	fmt.Println()
+
+	// ========== 1. span 导出到本地 JSON Lines 文件 ==========
+	spanFile := filepath.Join(os.TempDir(), "go-learning-spans.jsonl")
+	_ = os.Remove(spanFile)
+	exporter, err := OpenJSONLinesFile(spanFile)
+	if err != nil {
+		fmt.Printf("打开 span 文件失败: %v\n", err)
+		return
+	}
+	defer exporter.Close()
+	tracer := NewTracer(exporter)
+
+	// 示例输出不打印时间，便于阅读
+	baseLogger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	// ========== 2. GORM 使用请求日志器 ==========
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+	_ = db.AutoMigrate(&traceUser{})
+	db.Create(&traceUser{ID: 1, Name: "张三"})
+	db.Logger = NewGormLogger(tracer).LogMode(logger.Info) // 建表完成后再开启，只记录请求中的 SQL
+
+	// ========== 3. 路由 ==========
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(Middleware(Options{Tracer: tracer, Logger: baseLogger}))
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", "42") // 模拟 JWT 鉴权中间件
+		c.Next()
+	})
+	router.GET("/users/:id", func(c *gin.Context) {
+		ctx := Context(c)
+		Logger(c).Info("查询用户", "id", c.Param("id"))
+
+		var user traceUser
+		if err := db.WithContext(ctx).First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"code": 1004, "message": "用户不存在"})
+			return
+		}
+
+		_, span := tracer.Start(ctx, "组装响应")
+		span.SetAttribute("user.name", user.Name)
+		span.End()
+
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": user})
+	})
+
+	send := func(desc string, header map[string]string) {
+		fmt.Println(desc)
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("  → %d %s: %s\n", w.Code, HeaderRequestID, w.Header().Get(HeaderRequestID))
+		fmt.Printf("        %s: %s\n", HeaderTraceparent, w.Header().Get(HeaderTraceparent))
+		fmt.Println()
+	}
+
+	send("1. 上游传入 traceparent 和 X-Request-ID（沿用）:", map[string]string{
+		HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		HeaderRequestID:   "gateway-req-1001",
+	})
+	send("2. 没有上游信息（开启新链路，生成请求ID）:", nil)
+	send("3. 格式错误的请求头（忽略）:", map[string]string{
+		HeaderTraceparent: "00-xyz-123-01",
+		HeaderRequestID:   "<script>",
+	})
+
+	// ========== 4. 查看导出的 span ==========
+	fmt.Printf("导出的 span（%s）:\n", spanFile)
+	f, err := os.Open(spanFile)
+	if err != nil {
+		fmt.Printf("读取 span 文件失败: %v\n", err)
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		fmt.Println("  " + scanner.Text())
+	}
+	fmt.Println()
+
+	fmt.Println("使用方式:")
+	fmt.Println("  router.Use(tracing.Middleware(options))     // 注册在最前面")
+	fmt.Println("  tracing.Logger(c).Info(...)                 // 请求日志器")
+	fmt.Println("  db.WithContext(tracing.Context(c))          // SQL 日志和 span 关联到请求")
+	fmt.Println("  tracing.Inject(ctx, req.Header)             // 调用下游时传递 traceparent / X-Request-ID")
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+// TestParseTraceparent 测试 traceparent 解析
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID)
+	assert.True(t, sc.Sampled)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.NoError(t, err)
+	assert.False(t, sc.Sampled)
+
+	// 更高版本只解析前 4 段
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01extra",
+	} {
+		_, err := ParseTraceparent(value)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, value)
+	}
+}
+
+type testUser struct {
+	ID   uint
+	Name string
+}
+
+func (testUser) TableName() string {
+	return "t_user"
+}
+
+// TestMiddleware 测试链路传递、请求日志器、GORM 日志和 span 导出
+func TestMiddleware(t *testing.T) {
+	var spans, logs bytes.Buffer
+	tracer := NewTracer(NewJSONLinesExporter(&spans))
+	base := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库，测试中只用一个连接
+	assert.NoError(t, db.AutoMigrate(&testUser{}))
+	db.Logger = NewGormLogger(tracer).LogMode(logger.Info)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(Options{Tracer: tracer, Logger: base}))
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", 42)
+		c.Next()
+	})
+	router.GET("/users/:id", func(c *gin.Context) {
+		Logger(c).Info("查询用户")
+		var user testUser
+		db.WithContext(Context(c)).Where("id = ?", c.Param("id")).Find(&user)
+		c.JSON(http.StatusOK, gin.H{"requestID": c.GetString("requestID")})
+	})
+
+	t.Run("沿用上游的链路和请求ID", func(t *testing.T) {
+		spans.Reset()
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set(HeaderRequestID, "req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "req-1", w.Header().Get(HeaderRequestID))
+		assert.Contains(t, w.Body.String(), `"requestID":"req-1"`)
+		sc, err := ParseTraceparent(w.Header().Get(HeaderTraceparent))
+		assert.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+		assert.NotEqual(t, "00f067aa0ba902b7", sc.SpanID)
+
+		// 导出顺序: SQL span 先结束，服务端 span 最后
+		lines := strings.Split(strings.TrimSpace(spans.String()), "\n")
+		assert.Len(t, lines, 2)
+		var query, server Span
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &query))
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &server))
+
+		assert.Equal(t, "GET /users/:id", server.Name)
+		assert.Equal(t, SpanKindServer, server.Kind)
+		assert.Equal(t, "00f067aa0ba902b7", server.ParentSpanID)
+		assert.Equal(t, sc.SpanID, server.SpanID)
+		assert.EqualValues(t, 200, server.Attributes["http.status_code"])
+		assert.Equal(t, "42", server.Attributes["user.id"])
+
+		assert.Equal(t, "gorm.query", query.Name)
+		assert.Equal(t, server.TraceID, query.TraceID)
+		assert.Equal(t, server.SpanID, query.ParentSpanID)
+		assert.Contains(t, query.Attributes["db.statement"], "SELECT")
+
+		// 处理函数和 GORM 的日志都带有相同的链路字段
+		logLines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		assert.Len(t, logLines, 2)
+		for _, line := range logLines {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["trace_id"])
+			assert.Equal(t, "req-1", entry["request_id"])
+			assert.Equal(t, "/users/:id", entry["route"])
+			assert.Equal(t, "42", entry["user_id"])
+		}
+		assert.Contains(t, logLines[1], `"component":"gorm"`)
+	})
+
+	t.Run("忽略格式错误的请求头", func(t *testing.T) {
+		spans.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set(HeaderTraceparent, "00-xyz-01")
+		req.Header.Set(HeaderRequestID, "bad id\r\n")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Len(t, w.Header().Get(HeaderRequestID), 36, "应当生成新的 UUID")
+		sc, err := ParseTraceparent(w.Header().Get(HeaderTraceparent))
+		assert.NoError(t, err)
+		assert.True(t, sc.Sampled)
+
+		lines := strings.Split(strings.TrimSpace(spans.String()), "\n")
+		var server Span
+		assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &server))
+		assert.Equal(t, sc.TraceID, server.TraceID)
+		assert.Empty(t, server.ParentSpanID, "应当开启新链路")
+	})
+
+	t.Run("panic 时导出错误 span", func(t *testing.T) {
+		spans.Reset()
+		r := gin.New()
+		r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}))
+		r.Use(Middleware(Options{Tracer: tracer, Logger: base}))
+		r.GET("/panic", func(*gin.Context) { panic("boom") })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		var span Span
+		assert.NoError(t, json.Unmarshal(spans.Bytes(), &span))
+		assert.Equal(t, "error", span.Status)
+		assert.Equal(t, "panic: boom", span.Error)
+	})
+
+	t.Run("约束路由使用声明的模板", func(t *testing.T) {
+		spans.Reset()
+		logs.Reset()
+		r := gin.New()
+		r.Use(Middleware(Options{Tracer: tracer, Logger: base}))
+		constraint.New(r, constraint.Options{}).GET("/orders/{id:int}", func(c *gin.Context) {
+			Logger(c).Info("查询订单")
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+		var span Span
+		assert.NoError(t, json.Unmarshal(spans.Bytes(), &span))
+		assert.Equal(t, "GET /orders/{id:int}", span.Name)
+		assert.Equal(t, "/orders/{id:int}", span.Attributes["http.route"])
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, "/orders/{id:int}", entry["route"])
+	})
+}
+
+// TestInject 测试向下游传递
+func TestInject(t *testing.T) {
+	tracer := NewTracer(nil)
+	ctx, span := tracer.Start(context.WithValue(context.Background(), requestIDKey, "req-1"), "调用下游")
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Equal(t, span.SpanContext().Traceparent(), header.Get(HeaderTraceparent))
+	assert.Equal(t, "req-1", header.Get(HeaderRequestID))
+
+	_, child := tracer.Start(ctx, "子操作")
+	assert.Equal(t, span.TraceID, child.TraceID)
+	assert.Equal(t, span.SpanID, child.ParentSpanID)
+}
@@ -0,0 +1,150 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Masked 脱敏后的占位值
+const Masked = "[REDACTED]"
+
+// Redaction 脱敏配置，名称均不区分大小写
+type Redaction struct {
+	Headers    []string // 请求头，如 Authorization、Cookie
+	Query      []string // 查询参数，如 token、sign
+	BodyFields []string // JSON / 表单请求体字段，任意层级都会匹配，如 password
+}
+
+// DefaultRedaction 默认脱敏规则
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+			"X-Api-Key", "X-Auth-Token",
+		},
+		Query: []string{
+			"token", "access_token", "refresh_token", "password", "sign", "signature",
+		},
+		BodyFields: []string{
+			"password", "old_password", "new_password", "oldPassword", "newPassword",
+			"token", "access_token", "refresh_token", "accessToken", "refreshToken",
+			"secret", "id_card", "idCard", "card_no", "cardNo",
+		},
+	}
+}
+
+// redactor 预处理后的脱敏规则
+type redactor struct {
+	headers map[string]bool
+	query   map[string]bool
+	fields  map[string]bool
+}
+
+func newRedactor(r Redaction) *redactor {
+	return &redactor{
+		headers: lowerSet(r.Headers),
+		query:   lowerSet(r.Query),
+		fields:  lowerSet(r.BodyFields),
+	}
+}
+
+func lowerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
+
+// header 输出脱敏后的请求头，多个值用 ", " 连接
+func (r *redactor) header(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if r.headers[strings.ToLower(name)] {
+			out[name] = Masked
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// values 脱敏查询参数或表单，保持参数名有序，便于日志比对
+func (r *redactor) values(raw string, sensitive map[string]bool) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		// 无法解析时不原样输出，避免敏感参数漏网
+		return Masked
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			if sensitive[strings.ToLower(k)] {
+				v = Masked
+			}
+			b.WriteString(url.QueryEscape(k) + "=" + url.QueryEscape(v))
+		}
+	}
+	return b.String()
+}
+
+// queryString 脱敏查询字符串
+func (r *redactor) queryString(raw string) string {
+	return r.values(raw, r.query)
+}
+
+// body 按请求体类型脱敏
+//   - JSON: 解析后递归替换敏感字段，返回结构化的值
+//   - 表单: 按字段脱敏
+//   - 其他类型不记录内容
+func (r *redactor) body(contentType string, data []byte) (interface{}, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, false
+		}
+		return r.redactJSON(v), true
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		return r.values(string(data), r.fields), true
+	}
+	return nil, false
+}
+
+func (r *redactor) redactJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if r.fields[strings.ToLower(k)] {
+				val[k] = Masked
+				continue
+			}
+			val[k] = r.redactJSON(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = r.redactJSON(item)
+		}
+		return val
+	}
+	return v
+}
@@ -0,0 +1,248 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	tracing "go-learning/gin/10_tracing"
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+// Options 访问日志配置
+type Options struct {
+	Logger        *slog.Logger  // 为 nil 时输出 JSON 到标准输出
+	SampleRate    float64       // 成功请求的采样率（0~1），错误请求和慢请求总是记录
+	SlowThreshold time.Duration // 超过该耗时的请求总是记录，0 表示不启用
+	LogHeaders    bool          // 是否记录（脱敏后的）请求头
+	LogBody       bool          // 是否记录（脱敏后的）JSON / 表单请求体
+	MaxBody       int64         // 记录请求体的上限，超过时不记录内容
+	SkipPaths     []string      // 不记录的路径，如健康检查
+	Redaction     Redaction     // 脱敏规则
+}
+
+// DefaultOptions 默认配置：成功请求全部记录，记录请求头，不记录请求体
+func DefaultOptions() Options {
+	return Options{
+		SampleRate:    1,
+		SlowThreshold: time.Second,
+		LogHeaders:    true,
+		MaxBody:       8 << 10, // 8KB
+		Redaction:     DefaultRedaction(),
+	}
+}
+
+// Middleware 访问日志中间件，每个请求输出一行结构化日志
+//
+// 字段: method、route（路由模板）、path、query、status、latency_ms、bytes、
+// client_ip、user_id，以及 tracing 中间件提供的 request_id、trace_id
+//
+// 级别: 5xx 为 ERROR，4xx 为 WARN，其余为 INFO
+// 采样: 只对 2xx/3xx 且不慢的请求按 SampleRate 采样，错误请求不会被采样丢弃
+//
+// 放在 tracing.Middleware 之后、鉴权中间件之前，这样鉴权失败的请求也会被记录
+func Middleware(options Options) gin.HandlerFunc {
+	return newMiddleware(options, rand.Float64)
+}
+
+func newMiddleware(options Options, random func() float64) gin.HandlerFunc {
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	if options.MaxBody <= 0 {
+		options.MaxBody = 8 << 10
+	}
+	redact := newRedactor(options.Redaction)
+	skip := make(map[string]bool, len(options.SkipPaths))
+	for _, p := range options.SkipPaths {
+		skip[p] = true
+	}
+
+	return func(c *gin.Context) {
+		if skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		// 请求体: 读取前 MaxBody+1 字节用于日志，再拼回去，不影响处理函数流式读取
+		var body []byte
+		if options.LogBody && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, options.MaxBody+1))
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body),
+				Closer: c.Request.Body,
+			}
+		}
+
+		c.Next()
+
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		failed := status >= http.StatusBadRequest || len(c.Errors) > 0
+		slow := options.SlowThreshold > 0 && latency > options.SlowThreshold
+		if !failed && !slow && random() >= options.SampleRate {
+			return
+		}
+
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", constraint.Route(c)),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+			slog.Int("bytes", size),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if q := redact.queryString(c.Request.URL.RawQuery); q != "" {
+			attrs = append(attrs, slog.String("query", q))
+		}
+		if v, ok := c.Get("userID"); ok && v != nil {
+			attrs = append(attrs, slog.String("user_id", fmt.Sprint(v)))
+		}
+		if id := c.GetString("requestID"); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if sc := tracing.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID))
+		}
+		if slow {
+			attrs = append(attrs, slog.Bool("slow", true))
+		}
+		if options.LogHeaders {
+			attrs = append(attrs, slog.Any("headers", redact.header(c.Request.Header)))
+		}
+		if options.LogBody && len(body) > 0 {
+			if int64(len(body)) > options.MaxBody {
+				attrs = append(attrs, slog.Bool("body_truncated", true))
+			} else if v, ok := redact.body(c.ContentType(), body); ok {
+				attrs = append(attrs, slog.Any("body", v))
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", strings.Join(c.Errors.Errors(), "; ")))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest, slow:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(context.WithoutCancel(c.Request.Context()), level, "access", attrs...)
+	}
+}
+
+// readCloser 把读过的部分拼回去，同时保留原始 Body 的 Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// AccessLogDemo 演示结构化访问日志
+func AccessLogDemo() {
+	fmt.Println("=== 结构化访问日志示例 ===")
+	fmt.Println()
+
+	// 示例输出不打印时间，便于阅读
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	options := DefaultOptions()
+	options.Logger = logger
+	options.LogBody = true
+	options.SampleRate = 0.2
+	options.SkipPaths = []string{"/healthz"}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(tracing.Middleware(tracing.DefaultOptions()))
+	router.Use(Middleware(options))
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.Set("userID", "42") // 模拟 JWT 鉴权中间件
+		}
+		c.Next()
+	})
+
+	router.POST("/auth/login", func(c *gin.Context) {
+		var req struct {
+			Username string `json:"username" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 1002, "message": "用户名或密码错误"})
+	})
+	router.GET("/orders/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"id": c.Param("id")}})
+	})
+	router.GET("/reports", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("报表服务超时"))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 2003, "message": "第三方服务错误"})
+	})
+	router.GET("/healthz", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	send := func(method, target, body string, header map[string]string) {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, target, reader)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	fmt.Println("1. 登录失败（请求体中的 password 被脱敏，4xx 总是记录）:")
+	send(http.MethodPost, "/auth/login", `{"username":"john","password":"secret123"}`,
+		map[string]string{"Content-Type": "application/json"})
+	fmt.Println()
+
+	fmt.Println("2. 服务端错误（Authorization 和 query 中的 token 被脱敏，5xx 总是记录）:")
+	send(http.MethodGet, "/reports?month=2024-01&token=abc", "",
+		map[string]string{"Authorization": "Bearer eyJhbGciOi..."})
+	fmt.Println()
+
+	fmt.Println("3. 20 个成功请求，采样率 0.2（大约记录 4 条）:")
+	for i := 1; i <= 20; i++ {
+		send(http.MethodGet, fmt.Sprintf("/orders/%d", i), "", nil)
+	}
+	fmt.Println()
+
+	fmt.Println("4. 健康检查（SkipPaths，不记录）:")
+	send(http.MethodGet, "/healthz", "", nil)
+	fmt.Println()
+
+	fmt.Println("配置说明:")
+	fmt.Println("  SampleRate     - 成功请求的采样率，错误和慢请求不受影响")
+	fmt.Println("  SlowThreshold  - 慢请求阈值，超过时以 WARN 记录")
+	fmt.Println("  Redaction      - 请求头、查询参数、请求体字段的脱敏名单")
+	fmt.Println("  LogBody        - 只记录 JSON / 表单请求体，超过 MaxBody 时只标记 body_truncated")
+}
@@ -0,0 +1,167 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	tracing "go-learning/gin/10_tracing"
+)
+
+type testServer struct {
+	router *gin.Engine
+	logs   bytes.Buffer
+	random float64
+}
+
+func newTestServer(options Options) *testServer {
+	gin.SetMode(gin.TestMode)
+	s := &testServer{router: gin.New()}
+	options.Logger = slog.New(slog.NewJSONHandler(&s.logs, nil))
+	s.router.Use(tracing.Middleware(tracing.DefaultOptions()))
+	s.router.Use(newMiddleware(options, func() float64 { return s.random }))
+	s.router.Use(func(c *gin.Context) {
+		c.Set("userID", 7)
+		c.Next()
+	})
+	s.router.POST("/login", func(c *gin.Context) {
+		// 处理函数仍能读到完整的请求体
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	s.router.GET("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	s.router.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(io.ErrUnexpectedEOF)
+		c.Status(http.StatusBadGateway)
+	})
+	s.router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	return s
+}
+
+func (s *testServer) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *testServer) entries(t *testing.T) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s.logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+// TestAccessLog 测试字段和脱敏
+func TestAccessLog(t *testing.T) {
+	options := DefaultOptions()
+	options.LogBody = true
+	s := newTestServer(options)
+
+	body := `{"username":"john","password":"p@ss","profile":{"idCard":"110101"},"items":[{"token":"t1"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/login?redirect=/home&Token=abc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set(tracing.HeaderRequestID, "req-1")
+	w := s.do(req)
+	assert.Equal(t, strconv.Itoa(len(body)), w.Body.String(), "处理函数读到完整的请求体")
+
+	entries := s.entries(t)
+	assert.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, "INFO", e["level"])
+	assert.Equal(t, "access", e["msg"])
+	assert.Equal(t, "POST", e["method"])
+	assert.Equal(t, "/login", e["route"])
+	assert.EqualValues(t, 200, e["status"])
+	assert.EqualValues(t, 2, e["bytes"])
+	assert.Equal(t, "7", e["user_id"])
+	assert.Equal(t, "req-1", e["request_id"])
+	assert.Len(t, e["trace_id"], 32)
+	assert.Equal(t, "Token=%5BREDACTED%5D&redirect=%2Fhome", e["query"])
+
+	headers := e["headers"].(map[string]interface{})
+	assert.Equal(t, Masked, headers["Authorization"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+
+	logged := e["body"].(map[string]interface{})
+	assert.Equal(t, "john", logged["username"])
+	assert.Equal(t, Masked, logged["password"])
+	assert.Equal(t, Masked, logged["profile"].(map[string]interface{})["idCard"])
+	assert.Equal(t, Masked, logged["items"].([]interface{})[0].(map[string]interface{})["token"])
+	assert.NotContains(t, s.logs.String(), "p@ss")
+	assert.NotContains(t, s.logs.String(), "Bearer secret")
+
+	t.Run("表单请求体", func(t *testing.T) {
+		s.logs.Reset()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("username=john&password=p%40ss"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		s.do(req)
+		assert.Equal(t, "password=%5BREDACTED%5D&username=john", s.entries(t)[0]["body"])
+	})
+
+	t.Run("超过上限不记录请求体", func(t *testing.T) {
+		s.logs.Reset()
+		big := `{"password":"` + strings.Repeat("x", 9<<10) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(big))
+		req.Header.Set("Content-Type", "application/json")
+		w := s.do(req)
+		assert.Equal(t, strconv.Itoa(len(big)), w.Body.String(), "处理函数读到完整的请求体")
+		e := s.entries(t)[0]
+		assert.Equal(t, true, e["body_truncated"])
+		assert.Nil(t, e["body"])
+	})
+}
+
+// TestSampling 测试采样：成功请求按采样率丢弃，错误和慢请求总是记录
+func TestSampling(t *testing.T) {
+	options := DefaultOptions()
+	options.SampleRate = 0.1
+	options.SlowThreshold = 10 * time.Millisecond
+	options.SkipPaths = []string{"/users/0"}
+	s := newTestServer(options)
+
+	s.random = 0.5 // 未命中采样
+	s.do(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Empty(t, s.logs.String())
+
+	s.random = 0.05 // 命中采样
+	s.do(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Len(t, s.entries(t), 1)
+
+	s.random = 0.5
+	s.logs.Reset()
+	s.do(httptest.NewRequest(http.MethodGet, "/fail", nil))
+	s.do(httptest.NewRequest(http.MethodGet, "/missing", nil))
+	s.do(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	s.do(httptest.NewRequest(http.MethodGet, "/users/0", nil))
+	entries := s.entries(t)
+	assert.Len(t, entries, 3)
+
+	assert.Equal(t, "ERROR", entries[0]["level"])
+	assert.Equal(t, "unexpected EOF", entries[0]["errors"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "", entries[1]["route"], "未匹配的路由没有模板")
+	assert.Equal(t, "WARN", entries[2]["level"])
+	assert.Equal(t, true, entries[2]["slow"])
+}
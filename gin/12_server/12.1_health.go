@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Check 就绪检查，返回 error 表示依赖不可用
+type Check func(ctx context.Context) error
+
+// Health 健康检查
+//
+//   - /healthz 存活探针: 进程能处理请求就返回 200，失败时 Kubernetes 会重启容器，
+//     因此不检查数据库等外部依赖，否则数据库抖动会导致所有实例被重启
+//   - /readyz  就绪探针: 执行所有就绪检查，任何一项失败返回 503，负载均衡摘除该实例；
+//     开始优雅关闭后也返回 503，让流量在关闭前切走
+type Health struct {
+	mu           sync.RWMutex
+	checks       map[string]Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHealth 创建健康检查，timeout 为每项检查的超时时间
+func NewHealth(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Health{checks: make(map[string]Check), timeout: timeout}
+}
+
+// AddReadinessCheck 添加就绪检查，同名检查会被替换
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// SetShuttingDown 标记正在关闭，之后 /readyz 返回 503
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// CheckResult 单项检查结果
+type CheckResult struct {
+	Status    string  `json:"status"` // ok / fail
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// Ready 并发执行所有就绪检查
+func (h *Health) Ready(ctx context.Context) (bool, map[string]CheckResult) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = h.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	ready := true
+	out := make(map[string]CheckResult, len(names))
+	for i, name := range names {
+		out[name] = results[i]
+		if results[i].Status != "ok" {
+			ready = false
+		}
+	}
+	return ready, out
+}
+
+// run 执行单项检查，超时或 panic 都视为失败
+func (h *Health) run(ctx context.Context, check Check) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// 检查函数不响应 ctx 时也不能卡住探针
+		err = ctx.Err()
+	}
+
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = fmt.Sprintf("检查超时（%s）", h.timeout)
+		}
+		return result
+	}
+	result.Status = "ok"
+	return result
+}
+
+// Register 注册 /healthz 和 /readyz
+func (h *Health) Register(r gin.IRoutes) {
+	r.GET("/healthz", h.handleLiveness)
+	r.HEAD("/healthz", h.handleLiveness)
+	r.GET("/readyz", h.handleReadiness)
+	r.HEAD("/readyz", h.handleReadiness)
+}
+
+func (h *Health) handleLiveness(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Health) handleReadiness(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if h.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+
+	ready, checks := h.Ready(c.Request.Context())
+	status, text := http.StatusOK, "ok"
+	if !ready {
+		status, text = http.StatusServiceUnavailable, "fail"
+	}
+	c.JSON(status, gin.H{"status": text, "checks": checks})
+}
+
+// ========== 常用检查 ==========
+
+// PingCheck 数据库连通性检查
+func PingCheck(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// HTTPCheck 依赖服务检查，GET 请求返回 5xx 或连接失败时视为不可用
+func HTTPCheck(client *http.Client, url string) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s 返回 %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
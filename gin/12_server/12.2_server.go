@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	gormexamples "go-learning/gorm"
+)
+
+// Config 服务配置
+type Config struct {
+	Addr              string        // 监听地址
+	ReadHeaderTimeout time.Duration // 读取请求头超时，防止慢速攻击（Slowloris）
+	ReadTimeout       time.Duration // 读取整个请求（含请求体）超时
+	WriteTimeout      time.Duration // 从读完请求头到写完响应的超时
+	IdleTimeout       time.Duration // Keep-Alive 空闲连接超时
+	MaxHeaderBytes    int           // 请求头大小上限
+	DrainDelay        time.Duration // 收到退出信号后，/readyz 先返回 503 等待负载均衡摘除的时间
+	ShutdownTimeout   time.Duration // 等待进行中的请求处理完的最长时间
+	CheckTimeout      time.Duration // 每项就绪检查的超时时间
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20, // 1MB
+		DrainDelay:        5 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+		CheckTimeout:      2 * time.Second,
+	}
+}
+
+// closer 关闭时需要释放的资源
+type closer struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Server 包装 http.Server，负责启动、健康检查和优雅关闭
+//
+// 关闭顺序:
+//  1. 收到 SIGINT / SIGTERM（或 Run 的 ctx 结束）
+//  2. /readyz 返回 503，等待 DrainDelay，让负载均衡停止转发新请求
+//  3. http.Server.Shutdown: 停止接受新连接，等待进行中的请求处理完（最长 ShutdownTimeout）
+//  4. 超时仍未处理完的连接强制关闭
+//  5. 按注册的相反顺序关闭资源（数据库连接池等），此时已没有请求在使用它们
+type Server struct {
+	config  Config
+	health  *Health
+	http    *http.Server
+	closers []closer
+}
+
+// New 创建服务，并在 engine 上注册 /healthz 和 /readyz
+func New(engine *gin.Engine, config Config) *Server {
+	health := NewHealth(config.CheckTimeout)
+	health.Register(engine)
+
+	return &Server{
+		config: config,
+		health: health,
+		http: &http.Server{
+			Addr:              config.Addr,
+			Handler:           engine,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			ReadTimeout:       config.ReadTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+		},
+	}
+}
+
+// Health 返回健康检查，用于添加就绪检查
+func (s *Server) Health() *Health {
+	return s.health
+}
+
+// OnShutdown 注册关闭时需要释放的资源，HTTP 请求全部结束后按注册的相反顺序执行
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.closers = append(s.closers, closer{name: name, fn: fn})
+}
+
+// AddDatabase 注册数据库：就绪检查 ping，关闭时关闭连接池
+func (s *Server) AddDatabase(name string, database *gormexamples.Database) {
+	s.health.AddReadinessCheck(name, PingCheck(database.DB))
+	s.OnShutdown(name, func(context.Context) error { return database.Close() })
+}
+
+// ListenAndServe 启动服务，收到 SIGINT / SIGTERM 后优雅关闭
+func (s *Server) ListenAndServe() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return s.Run(ctx)
+}
+
+// Run 监听 Config.Addr 并启动服务，ctx 结束时优雅关闭
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在指定的 listener 上启动服务，ctx 结束时优雅关闭
+// 返回 nil 表示正常关闭
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(ln)
+	}()
+	log.Printf("[server] 已启动，监听 %s", ln.Addr())
+
+	select {
+	case err := <-serveErr:
+		// 没有收到退出信号，服务自己退出了（如端口被占用），仍然释放资源
+		return errors.Join(err, s.close(context.Background()))
+	case <-ctx.Done():
+	}
+
+	log.Printf("[server] 收到退出信号，开始优雅关闭")
+	s.health.SetShuttingDown()
+	if s.config.DrainDelay > 0 {
+		time.Sleep(s.config.DrainDelay)
+	}
+
+	timeout := s.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("等待请求处理完超时: %w", err))
+		_ = s.http.Close()
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+
+	// 资源关闭使用独立的超时，前面等待请求时用完了时间也能正常释放
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), timeout)
+	defer cancelClose()
+	errs = append(errs, s.close(closeCtx))
+
+	log.Printf("[server] 已关闭")
+	return errors.Join(errs...)
+}
+
+// close 按注册的相反顺序释放资源
+func (s *Server) close(ctx context.Context) error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		c := s.closers[i]
+		if err := c.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("关闭 %s 失败: %w", c.name, err))
+			continue
+		}
+		log.Printf("[server] 已关闭 %s", c.name)
+	}
+	return errors.Join(errs...)
+}
+
+// ServerDemo 演示服务启动、健康检查和优雅关闭
+func ServerDemo() {
+	fmt.Println("=== 服务启动与优雅关闭示例 ===")
+	fmt.Println()
+
+	database, err := gormexamples.NewDatabase(&gormexamples.DatabaseConfig{Name: "demo"})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.GET("/reports", func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond) // 模拟耗时请求
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "报表生成完成"})
+	})
+
+	config := DefaultConfig()
+	config.Addr = "127.0.0.1:0"
+	config.DrainDelay = 100 * time.Millisecond // 示例中缩短，生产环境应大于负载均衡的探测间隔
+	srv := New(router, config)
+	srv.AddDatabase("database", database)
+	var paymentUp atomic.Bool
+	srv.Health().AddReadinessCheck("payment", func(context.Context) error {
+		if !paymentUp.Load() {
+			return errors.New("支付服务不可用")
+		}
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		fmt.Printf("监听失败: %v\n", err)
+		return
+	}
+	baseURL := "http://" + ln.Addr().String()
+
+	ctx, stop := context.WithCancel(context.Background()) // 代替 SIGTERM
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	get := func(path string) string {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			return "请求失败: " + err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Sprintf("%d %s", resp.StatusCode, body)
+	}
+
+	fmt.Println("1. 存活探针（不检查依赖）:")
+	fmt.Println("   GET /healthz →", get("/healthz"))
+	fmt.Println()
+	fmt.Println("2. 就绪探针（支付服务不可用）:")
+	fmt.Println("   GET /readyz →", get("/readyz"))
+	paymentUp.Store(true)
+	fmt.Println("   支付服务恢复后:")
+	fmt.Println("   GET /readyz →", get("/readyz"))
+	fmt.Println()
+
+	fmt.Println("3. 请求处理中收到退出信号:")
+	inflight := make(chan string)
+	go func() { inflight <- get("/reports") }()
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	time.Sleep(20 * time.Millisecond)
+	fmt.Println("   关闭期间 GET /readyz →", get("/readyz"))
+	fmt.Println("   进行中的请求 →", <-inflight)
+	if err := <-done; err != nil {
+		fmt.Printf("   关闭出错: %v\n", err)
+	}
+	fmt.Println("   关闭后 GET /healthz →", get("/healthz"))
+	fmt.Println()
+
+	fmt.Println("main 函数示例:")
+	fmt.Println("  srv := server.New(router, server.DefaultConfig())")
+	fmt.Println("  srv.AddDatabase(\"database\", database)        // 就绪检查 + 关闭连接池")
+	fmt.Println("  srv.Health().AddReadinessCheck(\"redis\", redisPing)")
+	fmt.Println("  if err := srv.ListenAndServe(); err != nil {  // 阻塞，直到 SIGINT / SIGTERM")
+	fmt.Println("      log.Fatal(err)")
+	fmt.Println("  }")
+	fmt.Println()
+	fmt.Println("超时配置说明:")
+	fmt.Println("  ReadHeaderTimeout  5s    - 防止客户端慢慢发送请求头占用连接")
+	fmt.Println("  ReadTimeout        15s   - 包含请求体，上传大文件的接口需要单独调大")
+	fmt.Println("  WriteTimeout       30s   - 处理 + 写响应的总时间，SSE 等长连接不适用")
+	fmt.Println("  IdleTimeout        120s  - Keep-Alive 空闲连接保留时间")
+	fmt.Println("  ShutdownTimeout    30s   - 应小于 Kubernetes 的 terminationGracePeriodSeconds")
+}
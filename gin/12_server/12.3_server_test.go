@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func readyz(h *Health) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h.Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+// TestReadiness 测试就绪检查
+func TestReadiness(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+
+	h := NewHealth(50 * time.Millisecond)
+	h.AddReadinessCheck("database", PingCheck(db))
+	code, body := readyz(h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body["status"])
+
+	// 失败、超时、panic 都返回 503，并给出每一项的结果
+	h.AddReadinessCheck("cache", func(context.Context) error { return errors.New("连接被拒绝") })
+	h.AddReadinessCheck("slow", func(context.Context) error {
+		time.Sleep(time.Second) // 不响应 ctx 的检查
+		return nil
+	})
+	h.AddReadinessCheck("broken", func(context.Context) error { panic("boom") })
+
+	start := time.Now()
+	code, body = readyz(h)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "检查超时不应阻塞探针")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	checks := body["checks"].(map[string]interface{})
+	assert.Equal(t, "ok", checks["database"].(map[string]interface{})["status"])
+	assert.Equal(t, "连接被拒绝", checks["cache"].(map[string]interface{})["error"])
+	assert.Contains(t, checks["slow"].(map[string]interface{})["error"], "检查超时")
+	assert.Equal(t, "panic: boom", checks["broken"].(map[string]interface{})["error"])
+
+	// 关闭后数据库检查失败
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	_, results := readyWith(t, "database", PingCheck(db))
+	assert.Equal(t, "fail", results["database"].Status)
+
+	h = NewHealth(time.Second)
+	h.SetShuttingDown()
+	code, body = readyz(h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", body["status"])
+}
+
+// readyWith 只包含一项检查的 Ready 结果
+func readyWith(t *testing.T, name string, check Check) (bool, map[string]CheckResult) {
+	t.Helper()
+	h := NewHealth(time.Second)
+	h.AddReadinessCheck(name, check)
+	return h.Ready(context.Background())
+}
+
+// TestGracefulShutdown 测试优雅关闭: 进行中的请求处理完，之后按相反顺序释放资源
+func TestGracefulShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	entered := make(chan struct{})
+	router.GET("/slow", func(c *gin.Context) {
+		close(entered)
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	config := DefaultConfig()
+	config.DrainDelay = 0
+	config.ShutdownTimeout = 2 * time.Second
+	srv := New(router, config)
+
+	var closed []string
+	srv.OnShutdown("database", func(context.Context) error {
+		closed = append(closed, "database")
+		return nil
+	})
+	srv.OnShutdown("cache", func(context.Context) error {
+		closed = append(closed, "cache")
+		return errors.New("已断开")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-entered
+	stop()
+
+	assert.Equal(t, "done", <-response, "进行中的请求应当处理完")
+	err = <-done
+	assert.ErrorContains(t, err, "关闭 cache 失败: 已断开")
+	assert.Equal(t, []string{"cache", "database"}, closed)
+
+	_, err = http.Get("http://" + ln.Addr().String() + "/healthz")
+	assert.Error(t, err, "关闭后不再接受连接")
+}
+
+// TestShutdownTimeout 测试超过 ShutdownTimeout 时强制关闭，资源仍然释放
+func TestShutdownTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router.GET("/stuck", func(c *gin.Context) {
+		close(entered)
+		<-release
+	})
+	defer close(release)
+
+	config := DefaultConfig()
+	config.DrainDelay = 0
+	config.ShutdownTimeout = 100 * time.Millisecond
+	srv := New(router, config)
+	released := false
+	srv.OnShutdown("database", func(context.Context) error {
+		released = true
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+	stop()
+
+	err = <-done
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, released)
+}
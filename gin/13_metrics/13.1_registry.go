@@ -0,0 +1,382 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType Prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets 默认的耗时分桶（秒），与 Prometheus 客户端一致
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// metricType 指标类型
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// family 同名的一组指标（不同标签值为不同的序列）
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64 // 仅直方图
+
+	mu     sync.RWMutex
+	series map[string]*series
+	fn     func() float64 // GaugeFunc，采集时调用
+}
+
+// series 一组标签值对应的序列
+type series struct {
+	labelValues []string
+
+	mu     sync.Mutex
+	value  float64  // 计数器 / 仪表盘
+	counts []uint64 // 直方图每个分桶的计数（非累计）
+	sum    float64
+	count  uint64
+}
+
+// get 返回标签值对应的序列，不存在时创建
+// 标签值个数与定义不一致属于编程错误，直接 panic
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s 需要 %d 个标签值，实际 %d 个", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	s = &series{labelValues: append([]string(nil), labelValues...)}
+	if f.typ == typeHistogram {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+	f.series[key] = s
+	return s
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	families   map[string]*family
+	collectors map[string]func()
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family), collectors: make(map[string]func())}
+}
+
+// register 注册指标
+// 同名且定义相同时返回已有的指标（中间件可以安全地多次创建）；定义冲突时 panic。
+// GaugeFunc 的回调无法比较，同名只能注册一次，需要多个来源时用带标签的 Gauge 加 OnCollect
+func (r *Registry) register(f *family) *family {
+	if !metricNameRE.MatchString(f.name) {
+		panic("metrics: 非法的指标名 " + f.name)
+	}
+	for _, l := range f.labels {
+		if !labelNameRE.MatchString(l) || strings.HasPrefix(l, "__") || l == "le" {
+			panic("metrics: 非法的标签名 " + l)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.families[f.name]; ok {
+		if existing.typ != f.typ || strings.Join(existing.labels, ",") != strings.Join(f.labels, ",") ||
+			existing.fn != nil || f.fn != nil {
+			panic("metrics: 指标 " + f.name + " 重复注册且定义不同")
+		}
+		return existing
+	}
+	f.series = make(map[string]*series)
+	r.families[f.name] = f
+	return f
+}
+
+// ========== Counter ==========
+
+// Counter 只增不减的计数器，如请求总数
+type Counter struct{ f *family }
+
+// NewCounter 注册计数器，名称约定以 _total 结尾
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, typ: typeCounter, labels: labels})}
+}
+
+// Inc 加 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 增加 v，v 不能为负数
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: 计数器不能减少")
+	}
+	s := c.f.get(labelValues)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+// ========== Gauge ==========
+
+// Gauge 可增可减的仪表盘，如进行中的请求数
+type Gauge struct{ f *family }
+
+// NewGauge 注册仪表盘
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(&family{name: name, help: help, typ: typeGauge, labels: labels})}
+}
+
+// Set 设置为 v
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	s := g.f.get(labelValues)
+	s.mu.Lock()
+	s.value = v
+	s.mu.Unlock()
+}
+
+// Add 增加 v（可以为负数）
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	s := g.f.get(labelValues)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+// Inc 加 1
+func (g *Gauge) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec 减 1
+func (g *Gauge) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// NewGaugeFunc 注册采集时才计算的仪表盘，如连接池状态
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&family{name: name, help: help, typ: typeGauge, fn: fn})
+}
+
+// OnCollect 注册每次输出前调用的回调，用于在采集时更新带标签的 Gauge
+//
+//	open := registry.NewGauge("pool_open_connections", "连接数", "db")
+//	registry.OnCollect("pool:"+name, func() { open.Set(float64(sqlDB.Stats().OpenConnections), name) })
+//
+// key 用于去重，重复注册同一个 key 返回 false 且不生效
+func (r *Registry) OnCollect(key string, fn func()) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[key]; ok {
+		return false
+	}
+	r.collectors[key] = fn
+	return true
+}
+
+// ========== Histogram ==========
+
+// Histogram 直方图，统计分布（如耗时），可以在 Prometheus 中计算分位数:
+//
+//	histogram_quantile(0.99, sum by (le, route) (rate(http_request_duration_seconds_bucket[5m])))
+type Histogram struct{ f *family }
+
+// NewHistogram 注册直方图，buckets 为各分桶的上界（升序），为空时使用 DefBuckets
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: 直方图分桶必须升序")
+	}
+	if math.IsInf(buckets[len(buckets)-1], +1) {
+		buckets = buckets[:len(buckets)-1] // +Inf 桶输出时自动添加
+	}
+	return &Histogram{r.register(&family{name: name, help: help, typ: typeHistogram, labels: labels, buckets: buckets})}
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.f.get(labelValues)
+	i := sort.SearchFloat64s(h.f.buckets, v) // 第一个 >= v 的分桶
+	s.mu.Lock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// ========== 输出 ==========
+
+// WriteTo 以 Prometheus 文本格式输出所有指标，按名称和标签值排序
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := make([]func(), 0, len(r.collectors))
+	for _, fn := range r.collectors {
+		collectors = append(collectors, fn)
+	}
+	r.mu.Unlock()
+	for _, fn := range collectors {
+		fn()
+	}
+
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, f := range families {
+		f.write(cw)
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (f *family) write(w *countingWriter) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	if f.fn != nil {
+		fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(f.fn()))
+		return
+	}
+
+	f.mu.RLock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	for _, s := range all {
+		s.mu.Lock()
+		switch f.typ {
+		case typeHistogram:
+			var cumulative uint64
+			for i, upper := range f.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", formatFloat(upper)), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelString(s.labelValues), formatFloat(s.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelString(s.labelValues), s.count)
+		default:
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelString(s.labelValues), formatFloat(s.value))
+		}
+		s.mu.Unlock()
+	}
+}
+
+// labelString 生成 {a="1",b="2"}，extra 为附加的一对标签（直方图的 le）
+func (f *family) labelString(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escapeLabel(values[i]) + `"`)
+	}
+	if len(extra) == 2 {
+		if len(f.labels) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[0] + `="` + extra[1] + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// Handler 暴露 /metrics 的处理函数
+//
+//	router.GET("/metrics", registry.Handler())
+func (r *Registry) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", ContentType)
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		_, _ = r.WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startKey 语句开始时间在 gorm.Statement 实例中的键
+const startKey = "metrics:start"
+
+// GormPlugin 通过 GORM 回调记录 SQL 指标
+//
+//	db.Use(metrics.NewGormPlugin(registry))
+//	replica.Use(metrics.NewGormPlugin(registry).Named("replica")) // 同一个注册表上的多个数据库用名称区分
+//
+// 指标:
+//   - gorm_queries_total{db,operation,table,status}  语句数，status 为 ok / error
+//   - gorm_query_duration_seconds{db,operation,table} 语句耗时
+//   - gorm_db_open_connections{db} / gorm_db_in_use_connections{db} / gorm_db_idle_connections{db} /
+//     gorm_db_wait_count{db} 连接池状态（采集时读取 sql.DBStats）
+type GormPlugin struct {
+	registry *Registry
+	name     string
+	queries  *Counter
+	duration *Histogram
+}
+
+// NewGormPlugin 创建 GORM 指标插件
+func NewGormPlugin(registry *Registry) *GormPlugin {
+	return &GormPlugin{
+		registry: registry,
+		name:     "default",
+		queries:  registry.NewCounter("gorm_queries_total", "SQL 语句执行次数", "db", "operation", "table", "status"),
+		duration: registry.NewHistogram("gorm_query_duration_seconds", "SQL 语句耗时（秒）",
+			[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}, "db", "operation", "table"),
+	}
+}
+
+// Named 设置所有指标的 db 标签，默认为 default；需要在 db.Use 之前调用
+func (p *GormPlugin) Named(name string) *GormPlugin {
+	p.name = name
+	return p
+}
+
+// Name 插件名
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize db.Use 时调用，注册连接池指标，并在每类操作前后注册回调
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	// 所有指标按 db 标签区分，名称在这里确定；连接池指标放在注册回调之前，名称重复时不会留下一半的回调
+	name := p.name
+	open := p.registry.NewGauge("gorm_db_open_connections", "连接池中的连接数", "db")
+	inUse := p.registry.NewGauge("gorm_db_in_use_connections", "正在使用的连接数", "db")
+	idle := p.registry.NewGauge("gorm_db_idle_connections", "空闲连接数", "db")
+	waits := p.registry.NewGauge("gorm_db_wait_count", "等待空闲连接的累计次数，持续增长说明连接池过小", "db")
+	collect := func() {
+		stats := sqlDB.Stats()
+		open.Set(float64(stats.OpenConnections), name)
+		inUse.Set(float64(stats.InUse), name)
+		idle.Set(float64(stats.Idle), name)
+		waits.Set(float64(stats.WaitCount), name)
+	}
+	if !p.registry.OnCollect("gorm:"+name, collect) {
+		return fmt.Errorf("metrics: 数据库 %q 已在注册表中，多个数据库请用 Named 区分", name)
+	}
+
+	cb := db.Callback()
+	operations := []struct {
+		name          string
+		before, after registerer
+	}{
+		{"create", cb.Create().Before("gorm:create"), cb.Create().After("gorm:create")},
+		{"query", cb.Query().Before("gorm:query"), cb.Query().After("gorm:query")},
+		{"update", cb.Update().Before("gorm:update"), cb.Update().After("gorm:update")},
+		{"delete", cb.Delete().Before("gorm:delete"), cb.Delete().After("gorm:delete")},
+		{"row", cb.Row().Before("gorm:row"), cb.Row().After("gorm:row")},
+		{"raw", cb.Raw().Before("gorm:raw"), cb.Raw().After("gorm:raw")},
+	}
+	for _, op := range operations {
+		if err := op.before.Register("metrics:before_"+op.name, p.before); err != nil {
+			return err
+		}
+		if err := op.after.Register("metrics:after_"+op.name, p.after(name, op.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *GormPlugin) after(name, operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(v.(time.Time)).Seconds()
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown" // 原生 SQL 等无法确定表名
+		}
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+		p.queries.Inc(name, operation, table, status)
+		p.duration.Observe(elapsed, name, operation, table)
+	}
+}
+
+// registerer GORM 回调处理器 Before / After 的返回值（类型未导出）
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+// Middleware HTTP 请求指标中间件
+//
+// 指标:
+//   - http_requests_total{method,route,status}           请求数
+//   - http_request_duration_seconds{method,route}        请求耗时
+//   - http_requests_in_flight                            进行中的请求数
+//
+// route 使用路由模板（/users/:id、约束路由的 /users/{id:int}）而不是实际路径，未匹配的请求统一记为 "unmatched"，
+// 否则每个不同的 ID、每次扫描都会产生新的序列，撑爆 Prometheus
+func Middleware(registry *Registry) gin.HandlerFunc {
+	requests := registry.NewCounter("http_requests_total", "HTTP 请求数", "method", "route", "status")
+	duration := registry.NewHistogram("http_request_duration_seconds", "HTTP 请求耗时（秒）", DefBuckets, "method", "route")
+	inFlight := registry.NewGauge("http_requests_in_flight", "进行中的 HTTP 请求数")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		route := constraint.Route(c)
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		requests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		duration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// metricsOrder 示例订单表
+type metricsOrder struct {
+	ID     uint    `gorm:"primaryKey"`
+	Amount float64 `gorm:"not null"`
+}
+
+func (metricsOrder) TableName() string {
+	return "t_order"
+}
+
+// MetricsDemo 演示 Prometheus 指标
+func MetricsDemo() {
+	fmt.Println("=== Prometheus 指标示例 ===")
+	fmt.Println()
+
+	registry := NewRegistry()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+	_ = db.AutoMigrate(&metricsOrder{})
+	if err := db.Use(NewGormPlugin(registry)); err != nil {
+		fmt.Printf("注册 GORM 插件失败: %v\n", err)
+		return
+	}
+
+	// 业务自定义指标
+	ordersCreated := registry.NewCounter("orders_created_total", "创建的订单数", "channel")
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(Middleware(registry))
+	router.GET("/metrics", registry.Handler())
+	router.POST("/orders", func(c *gin.Context) {
+		order := metricsOrder{Amount: 99.5}
+		if err := db.WithContext(c.Request.Context()).Create(&order).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 2001, "message": "数据库错误"})
+			return
+		}
+		ordersCreated.Inc(c.DefaultQuery("channel", "web"))
+		c.JSON(http.StatusCreated, gin.H{"code": 0, "data": order})
+	})
+	router.GET("/orders/:id", func(c *gin.Context) {
+		var order metricsOrder
+		if err := db.WithContext(c.Request.Context()).First(&order, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"code": 1004, "message": "订单不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": order})
+	})
+
+	send := func(method, target string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+	}
+	send(http.MethodPost, "/orders")
+	send(http.MethodPost, "/orders?channel=app")
+	send(http.MethodGet, "/orders/1")
+	send(http.MethodGet, "/orders/2")
+	send(http.MethodGet, "/orders/999")
+	send(http.MethodGet, "/wp-login.php")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	fmt.Printf("GET /metrics（Content-Type: %s），省略直方图分桶:\n", w.Header().Get("Content-Type"))
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); !strings.Contains(line, "_bucket{") {
+			fmt.Println("  " + line)
+		}
+	}
+	fmt.Println()
+
+	fmt.Println("Prometheus 配置:")
+	fmt.Println("  scrape_configs:")
+	fmt.Println("    - job_name: go-learning")
+	fmt.Println("      static_configs:")
+	fmt.Println("        - targets: [\"localhost:8080\"]")
+	fmt.Println()
+	fmt.Println("常用查询:")
+	fmt.Println("  sum by (route) (rate(http_requests_total[5m]))                                 - 每个接口的 QPS")
+	fmt.Println("  sum(rate(http_requests_total{status=~\"5..\"}[5m])) / sum(rate(http_requests_total[5m])) - 错误率")
+	fmt.Println("  histogram_quantile(0.99, sum by (le, route) (rate(http_request_duration_seconds_bucket[5m]))) - P99 耗时")
+	fmt.Println("  sum by (db, table) (rate(gorm_queries_total[5m]))                              - 每个库每张表的 SQL 频率")
+	fmt.Println()
+	fmt.Println("注意: /metrics 应只对内网开放，或放在单独的端口上")
+}
@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+func render(r *Registry) string {
+	var b strings.Builder
+	_, _ = r.WriteTo(&b)
+	return b.String()
+}
+
+// TestRegistry 测试文本格式输出
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "处理的任务数\n含失败", "queue", "result")
+	c.Inc("mail", "ok")
+	c.Add(2, "mail", "ok")
+	c.Inc(`a"b\c`, "error")
+
+	g := r.NewGauge("temperature", "温度")
+	g.Set(36.5)
+	g.Dec()
+
+	h := r.NewHistogram("latency_seconds", "耗时", []float64{0.1, 0.5, 1})
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		h.Observe(v)
+	}
+
+	r.NewGaugeFunc("pool_size", "连接池大小", func() float64 { return 8 })
+
+	want := `# HELP jobs_total 处理的任务数\n含失败
+# TYPE jobs_total counter
+jobs_total{queue="a\"b\\c",result="error"} 1
+jobs_total{queue="mail",result="ok"} 3
+# HELP latency_seconds 耗时
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="0.5"} 3
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 2.45
+latency_seconds_count 4
+# HELP pool_size 连接池大小
+# TYPE pool_size gauge
+pool_size 8
+# HELP temperature 温度
+# TYPE temperature gauge
+temperature 35.5
+`
+	assert.Equal(t, want, render(r))
+
+	// 相同定义重复注册返回同一个指标，定义不同时 panic
+	r.NewCounter("jobs_total", "处理的任务数", "queue", "result").Inc("mail", "ok")
+	assert.Contains(t, render(r), `jobs_total{queue="mail",result="ok"} 4`)
+	assert.Panics(t, func() { r.NewGauge("jobs_total", "") })
+	assert.Panics(t, func() { r.NewCounter("bad-name", "") })
+	assert.Panics(t, func() { r.NewCounter("x_total", "", "le") })
+	assert.Panics(t, func() { c.Inc("only-one") })
+	assert.Panics(t, func() { c.Add(-1, "mail", "ok") })
+}
+
+// TestConcurrentUpdates 并发更新同一个序列
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("hits_total", "", "path")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Inc("/")
+				_ = render(r)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Contains(t, render(r), `hits_total{path="/"} 5000`)
+}
+
+type testOrder struct {
+	ID uint
+}
+
+func (testOrder) TableName() string {
+	return "t_order"
+}
+
+// TestMiddlewareAndGorm 测试 HTTP 和 GORM 指标
+func TestMiddlewareAndGorm(t *testing.T) {
+	r := NewRegistry()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库，测试中只用一个连接
+	assert.NoError(t, db.AutoMigrate(&testOrder{}))
+	assert.NoError(t, db.Use(NewGormPlugin(r)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(r))
+	router.GET("/metrics", r.Handler())
+	router.GET("/orders/:id", func(c *gin.Context) {
+		var order testOrder
+		if err := db.First(&order, c.Param("id")).Error; err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.POST("/orders", func(c *gin.Context) {
+		db.Create(&testOrder{})
+		db.Exec("INSERT INTO t_missing VALUES (1)")
+		c.Status(http.StatusCreated)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/orders", nil),
+		httptest.NewRequest(http.MethodGet, "/orders/1", nil),
+		httptest.NewRequest(http.MethodGet, "/orders/2", nil),
+		httptest.NewRequest(http.MethodGet, "/orders/3", nil),
+		httptest.NewRequest(http.MethodGet, "/not-found", nil),
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/orders/:id",status="200"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/orders/:id",status="404"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="POST",route="/orders",status="201"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/orders/:id"} 3`)
+	assert.Contains(t, body, "http_requests_in_flight 1", "/metrics 请求本身正在进行")
+
+	assert.Contains(t, body, `gorm_queries_total{db="default",operation="create",table="t_order",status="ok"} 1`)
+	assert.Contains(t, body, `gorm_queries_total{db="default",operation="query",table="t_order",status="ok"} 3`, "记录不存在不算错误")
+	assert.Contains(t, body, `gorm_queries_total{db="default",operation="raw",table="unknown",status="error"} 1`)
+	assert.Contains(t, body, `gorm_query_duration_seconds_count{db="default",operation="query",table="t_order"} 3`)
+	assert.Contains(t, body, `gorm_db_open_connections{db="default"} 1`)
+}
+
+// TestConstraintRoute 约束路由使用声明的模板作为 route 标签，而不是 Gin 的 :arg0
+func TestConstraintRoute(t *testing.T) {
+	r := NewRegistry()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(r))
+	users := constraint.New(router, constraint.Options{})
+	users.GET("/users/{id:int}", func(c *gin.Context) { c.Status(http.StatusOK) })
+	users.GET("/users/{name:slug}", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/john", nil))
+
+	body := render(r)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/users/{id:int}",status="200"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/users/{name:slug}",status="200"} 1`)
+	assert.NotContains(t, body, ":arg0")
+}
+
+// TestGormPluginMultipleDB 测试多个数据库共用一个注册表
+func TestGormPluginMultipleDB(t *testing.T) {
+	open := func() *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		assert.NoError(t, err)
+		return db
+	}
+	r := NewRegistry()
+	primary, replica := open(), open()
+	sqlDB, _ := replica.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, replica.AutoMigrate(&testOrder{}))
+
+	assert.NoError(t, primary.Use(NewGormPlugin(r)))
+	assert.NoError(t, replica.Use(NewGormPlugin(r).Named("replica")))
+	assert.Error(t, open().Use(NewGormPlugin(r)), "名称重复时报错而不是覆盖")
+
+	replica.Create(&testOrder{})
+	primary.Exec("SELECT 1")
+	var buf strings.Builder
+	_, err := r.WriteTo(&buf)
+	assert.NoError(t, err)
+	body := buf.String()
+	assert.Contains(t, body, `gorm_db_open_connections{db="default"} 1`)
+	assert.Contains(t, body, `gorm_db_open_connections{db="replica"} 1`)
+	assert.Contains(t, body, `gorm_queries_total{db="replica",operation="create",table="t_order",status="ok"} 1`)
+	assert.Contains(t, body, `gorm_queries_total{db="default",operation="raw",table="unknown",status="ok"} 1`)
+	assert.NotContains(t, body, `gorm_queries_total{db="default",operation="create"`, "主库和从库的语句不合并")
+	assert.Equal(t, 1, strings.Count(body, "# TYPE gorm_db_open_connections gauge"))
+}
@@ -0,0 +1,150 @@
+package httpcache
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry 缓存的完整响应
+type Entry struct {
+	Status    int
+	Header    http.Header
+	Body      []byte
+	ETag      string
+	Path      string // 请求路径（/users/1），用于按路径失效
+	Route     string // 路由模板（/users/:id），用于按路由失效
+	StoredAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Config 缓存配置
+type Config struct {
+	MaxEntries int // 最大条目数，超过时淘汰最早过期的条目
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() Config {
+	return Config{MaxEntries: 10000}
+}
+
+// Cache 进程内的响应缓存
+//
+// 只适合单实例部署；多实例时失效操作只作用于当前实例，
+// 需要时可以通过消息队列广播 InvalidatePath / InvalidateRoute
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+	config  Config
+	now     func() time.Time
+}
+
+// New 创建缓存
+func New(config Config) *Cache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultConfig().MaxEntries
+	}
+	return &Cache{entries: make(map[string]*Entry), config: config, now: time.Now}
+}
+
+// get 读取未过期的条目
+func (c *Cache) get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.ExpiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// set 保存条目，满了时先清理过期条目，仍然满则淘汰最早过期的条目
+func (c *Cache) set(key string, e *Entry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e.StoredAt = now
+	e.ExpiresAt = now.Add(ttl)
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.config.MaxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, v := range c.entries {
+			if !now.Before(v.ExpiresAt) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || v.ExpiresAt.Before(oldest) {
+				oldestKey, oldest = k, v.ExpiresAt
+			}
+		}
+		if len(c.entries) >= c.config.MaxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = e
+}
+
+// InvalidatePath 删除某个路径下的所有缓存（不同查询参数、不同用户的变体）
+// 返回删除的条目数
+//
+//	router.PUT("/users/:id", func(c *gin.Context) {
+//	    // ... 更新用户
+//	    cache.InvalidatePath("/users/" + c.Param("id"))
+//	    cache.InvalidateRoute("/users") // 列表也失效
+//	})
+func (c *Cache) InvalidatePath(paths ...string) int {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return c.invalidate(func(e *Entry) bool { return set[e.Path] })
+}
+
+// InvalidateRoute 删除某个路由模板下的所有缓存，如 "/users/:id" 会删除所有用户详情
+// 约束路由使用声明时的模板，如 "/users/{id:int}"
+func (c *Cache) InvalidateRoute(routes ...string) int {
+	set := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		set[r] = true
+	}
+	return c.invalidate(func(e *Entry) bool { return set[e.Route] })
+}
+
+// InvalidatePrefix 删除路径以 prefix 开头的缓存，如 "/users/1/" 删除该用户的所有子资源
+func (c *Cache) InvalidatePrefix(prefix string) int {
+	return c.invalidate(func(e *Entry) bool { return strings.HasPrefix(e.Path, prefix) })
+}
+
+// Purge 清空缓存
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*Entry)
+}
+
+// Len 当前条目数
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) invalidate(match func(*Entry) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for k, e := range c.entries {
+		if match(e) {
+			delete(c.entries, k)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,377 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	constraint "go-learning/gin/7_route_constraint"
+	response "go-learning/gin/8_content_negotiation"
+	"go-learning/gin/internal/httpheader"
+)
+
+// Options 缓存中间件配置
+type Options struct {
+	TTL          time.Duration // 响应在服务端缓存中保存的时长
+	VaryByUser   bool          // 按 c.Get("userID") 区分缓存，需放在认证中间件之后
+	VaryHeaders  []string      // 参与缓存键的请求头，默认 Accept（同一资源的 JSON / XML 是不同的表示）
+	CacheControl string        // 处理函数没有设置 Cache-Control 时使用的值，为空时按 VaryByUser 生成
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		TTL:         time.Minute,
+		VaryHeaders: []string{"Accept"},
+	}
+}
+
+// Middleware 响应缓存中间件
+//
+//	cache := httpcache.New(httpcache.DefaultConfig())
+//	router.GET("/users/:id", cache.Middleware(httpcache.DefaultOptions()), getUser)
+//
+// 处理流程:
+//   - 只处理 GET / HEAD，其它方法直接放行
+//   - 缓存键 = 方法 + 路径 + 排序后的查询参数 + VaryHeaders 的值 (+ 用户ID)，响应的 Vary 与之对应
+//   - 命中且请求没有要求 no-cache 时直接返回缓存（X-Cache: HIT），不执行处理函数
+//   - 未命中时缓冲响应，计算 ETag；If-None-Match 匹配则返回 304，否则返回完整响应
+//   - 请求带 Cache-Control: no-cache / max-age=0 或 Pragma: no-cache 时跳过缓存重新计算（X-Cache: BYPASS），
+//     结果仍会写回缓存；请求带 no-store 时不写入缓存
+//
+// 只有 200 响应会写入缓存；响应设置了 Cache-Control: no-store / private 或 Set-Cookie 时不缓存。
+// 注意 response.Error 的业务错误也是 200，不希望缓存的错误响应应设置 Cache-Control: no-store
+func (c *Cache) Middleware(options Options) gin.HandlerFunc {
+	if options.TTL <= 0 {
+		options.TTL = DefaultOptions().TTL
+	}
+	return middleware(c, options)
+}
+
+// ETag 只计算 ETag、处理 If-None-Match 的中间件，不在服务端保存响应
+//
+// 处理函数仍然每次执行，节省的是响应体的传输（客户端收到 304 后使用本地缓存）
+func ETag() gin.HandlerFunc {
+	return middleware(nil, Options{})
+}
+
+func middleware(cache *Cache, options Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method != http.MethodGet && method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		reqDirectives := parseCacheControl(c.GetHeader("Cache-Control"))
+		noCache := reqDirectives["no-cache"] || maxAgeZero(c.GetHeader("Cache-Control")) ||
+			strings.EqualFold(c.GetHeader("Pragma"), "no-cache")
+
+		var key string
+		if cache != nil {
+			key = cacheKey(c, options)
+			if !noCache {
+				if entry, ok := cache.get(key); ok {
+					serveEntry(c, entry, cache.now())
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		// 记录处理函数执行前已有的响应头（X-Request-ID 等），只缓存处理函数新增的
+		before := c.Writer.Header().Clone()
+		original := c.Writer
+		buffer := &bufferWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffer
+		c.Next()
+		c.Writer = original
+
+		header := original.Header()
+		status := buffer.status
+		body := buffer.body.Bytes()
+
+		if status != http.StatusOK {
+			original.WriteHeader(status)
+			_, _ = original.Write(body)
+			return
+		}
+
+		etag := header.Get("ETag")
+		if etag == "" {
+			etag = computeETag(body)
+			header.Set("ETag", etag)
+		}
+		if header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", defaultCacheControl(options))
+		}
+		// 参与缓存键的请求头都要出现在 Vary 中，浏览器和 CDN 才不会把不同表示混用
+		for _, name := range options.VaryHeaders {
+			httpheader.AddVary(header, name)
+		}
+		if options.VaryByUser {
+			httpheader.AddVary(header, "Authorization")
+		}
+
+		if cache != nil {
+			respDirectives := parseCacheControl(header.Get("Cache-Control"))
+			store := !reqDirectives["no-store"] && !respDirectives["no-store"] &&
+				(!respDirectives["private"] || options.VaryByUser) && header.Get("Set-Cookie") == ""
+			if store {
+				cache.set(key, &Entry{
+					Status: status,
+					Header: addedHeaders(before, header),
+					Body:   bytes.Clone(body),
+					ETag:   etag,
+					Path:   c.Request.URL.Path,
+					Route:  constraint.Route(c),
+				}, options.TTL)
+			}
+			if noCache {
+				header.Set("X-Cache", "BYPASS")
+			} else {
+				header.Set("X-Cache", "MISS")
+			}
+		}
+
+		if matchETag(c.GetHeader("If-None-Match"), etag) {
+			notModified(c)
+			return
+		}
+		original.WriteHeader(status)
+		_, _ = original.Write(body)
+	}
+}
+
+// serveEntry 返回缓存的响应
+func serveEntry(c *gin.Context, entry *Entry, now time.Time) {
+	header := c.Writer.Header()
+	for k, v := range entry.Header {
+		header[k] = slices.Clone(v)
+	}
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(now.Sub(entry.StoredAt).Seconds())))
+
+	if matchETag(c.GetHeader("If-None-Match"), entry.ETag) {
+		notModified(c)
+		return
+	}
+	c.Writer.WriteHeader(entry.Status)
+	_, _ = c.Writer.Write(entry.Body)
+}
+
+// notModified 返回 304，不带响应体和描述响应体的头
+func notModified(c *gin.Context) {
+	header := c.Writer.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.Writer.WriteHeader(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
+
+// cacheKey 生成缓存键，查询参数排序后参与，?a=1&b=2 与 ?b=2&a=1 共用缓存
+func cacheKey(c *gin.Context, options Options) string {
+	var b strings.Builder
+	b.WriteString(c.Request.Method + " " + c.Request.URL.Path)
+	if query := c.Request.URL.Query(); len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	for _, name := range options.VaryHeaders {
+		b.WriteString("\n" + strings.ToLower(name) + ": " + c.GetHeader(name))
+	}
+	if options.VaryByUser {
+		userID, _ := c.Get("userID")
+		fmt.Fprintf(&b, "\nuser: %v", userID)
+	}
+	return b.String()
+}
+
+// computeETag 强 ETag：响应体 sha256 的前 16 字节
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag If-None-Match 使用弱比较：忽略 W/ 前缀，支持列表和 *
+func matchETag(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == target {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCacheControl 解析 Cache-Control 中出现的指令名（忽略参数值）
+func parseCacheControl(value string) map[string]bool {
+	directives := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = true
+		}
+	}
+	return directives
+}
+
+// maxAgeZero 请求是否带 max-age=0（浏览器强制刷新时发送）
+func maxAgeZero(value string) bool {
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, "max-age") && strings.Trim(arg, `"`) == "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCacheControl 默认要求客户端每次都带 ETag 验证，数据变更后能立即看到
+func defaultCacheControl(options Options) string {
+	if options.CacheControl != "" {
+		return options.CacheControl
+	}
+	if options.VaryByUser {
+		return "private, no-cache" // 用户相关的数据不能被 CDN / 代理缓存
+	}
+	return "no-cache"
+}
+
+// addedHeaders 处理函数新增或修改的响应头
+func addedHeaders(before, after http.Header) http.Header {
+	added := make(http.Header)
+	for k, v := range after {
+		if k == "X-Cache" || k == "Age" || slices.Equal(before[k], v) {
+			continue
+		}
+		added[k] = slices.Clone(v)
+	}
+	return added
+}
+
+// bufferWriter 缓冲响应，处理函数结束后再决定返回 200 还是 304
+type bufferWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferWriter) Written() bool {
+	return w.written
+}
+
+// Flush 缓冲期间忽略，需要流式输出的接口不应该使用缓存中间件
+func (w *bufferWriter) Flush() {}
+
+// HTTPCacheDemo 演示响应缓存、ETag 和缓存失效
+func HTTPCacheDemo() {
+	fmt.Println("=== 响应缓存与 ETag 示例 ===")
+	fmt.Println()
+
+	cache := New(DefaultConfig())
+	users := map[string]string{"1": "John Doe", "2": "Jane Roe"}
+	computed := 0
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/users/:id", cache.Middleware(DefaultOptions()), func(c *gin.Context) {
+		computed++ // 模拟耗时的查询和组装
+		name, ok := users[c.Param("id")]
+		if !ok {
+			response.ErrorWithStatus(c, http.StatusNotFound, 1004, "用户不存在")
+			return
+		}
+		response.Success(c, gin.H{"id": c.Param("id"), "name": name})
+	})
+	router.PUT("/users/:id", func(c *gin.Context) {
+		users[c.Param("id")] = c.Query("name")
+		// 写操作完成后使相关缓存失效
+		n := cache.InvalidatePath("/users/" + c.Param("id"))
+		response.Success(c, gin.H{"invalidated": n})
+	})
+	router.GET("/config", ETag(), func(c *gin.Context) {
+		response.Success(c, gin.H{"feature_x": true})
+	})
+
+	request := func(title, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  %s %s -> %d X-Cache=%q ETag=%s 处理函数执行次数=%d\n",
+			title, method, target, w.Code, w.Header().Get("X-Cache"), w.Header().Get("ETag"), computed)
+		if w.Body.Len() > 0 {
+			fmt.Printf("  %s\n", strings.ReplaceAll(strings.TrimSpace(w.Body.String()), "\n", "\n  "))
+		}
+		return w
+	}
+
+	first := request("1. 首次请求，执行处理函数并写入缓存", http.MethodGet, "/users/1", nil)
+	request("2. 再次请求，直接返回缓存", http.MethodGet, "/users/1", nil)
+	request("3. 携带 If-None-Match，返回 304 无响应体", http.MethodGet, "/users/1",
+		map[string]string{"If-None-Match": first.Header().Get("ETag")})
+	request("4. Accept 不同是另一份缓存", http.MethodGet, "/users/1", map[string]string{"Accept": "application/xml"})
+	request("5. Cache-Control: no-cache 跳过缓存重新计算", http.MethodGet, "/users/1",
+		map[string]string{"Cache-Control": "no-cache"})
+	request("6. 不存在的用户（404）不缓存", http.MethodGet, "/users/9", nil)
+	request("7. 更新用户，使缓存失效", http.MethodPut, "/users/1?name=Johnny", nil)
+	request("8. 旧 ETag 不再匹配，返回新内容", http.MethodGet, "/users/1",
+		map[string]string{"If-None-Match": first.Header().Get("ETag")})
+	etag := request("9. 只计算 ETag 的中间件", http.MethodGet, "/config", nil).Header().Get("ETag")
+	request("10. 只计算 ETag 的中间件同样支持 304", http.MethodGet, "/config", map[string]string{"If-None-Match": etag})
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 默认 Cache-Control: no-cache，客户端每次带 ETag 验证，数据变更后立即可见")
+	fmt.Println("  - 用户相关的数据使用 VaryByUser，缓存中间件放在认证中间件之后")
+	fmt.Println("  - 进程内缓存，多实例部署时失效需要广播到每个实例，或改用 Redis")
+}
@@ -0,0 +1,257 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	constraint "go-learning/gin/7_route_constraint"
+)
+
+func get(router http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// newRouter 返回路由和处理函数执行次数
+func newRouter(cache *Cache, options Options) (*gin.Engine, *int) {
+	calls := 0
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", c.GetHeader("X-Request-ID"))
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("userID", user)
+		}
+		c.Next()
+	})
+	router.GET("/items/:id", cache.Middleware(options), func(c *gin.Context) {
+		calls++
+		switch c.Param("id") {
+		case "missing":
+			c.JSON(http.StatusNotFound, gin.H{"code": 1004})
+		case "cookie":
+			c.SetCookie("session", "x", 0, "/", "", false, true)
+			c.String(http.StatusOK, "with cookie")
+		case "nostore":
+			c.Header("Cache-Control", "no-store")
+			c.String(http.StatusOK, "secret")
+		default:
+			c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "user": c.GetString("userID"), "q": c.Query("q")})
+		}
+	})
+	return router, &calls
+}
+
+// TestCache 测试缓存命中、条件请求和 no-cache
+func TestCache(t *testing.T) {
+	cache := New(DefaultConfig())
+	router, calls := newRouter(cache, DefaultOptions())
+
+	first := get(router, "/items/1", map[string]string{"X-Request-ID": "a"})
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	assert.Equal(t, "no-cache", first.Header().Get("Cache-Control"))
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, first.Header().Get("ETag"))
+	etag := first.Header().Get("ETag")
+
+	hit := get(router, "/items/1", map[string]string{"X-Request-ID": "b"})
+	assert.Equal(t, "HIT", hit.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), hit.Body.String())
+	assert.Equal(t, etag, hit.Header().Get("ETag"))
+	assert.Equal(t, "application/json; charset=utf-8", hit.Header().Get("Content-Type"))
+	assert.Equal(t, "b", hit.Header().Get("X-Request-ID"), "处理函数之前设置的头不缓存")
+	assert.Equal(t, "0", hit.Header().Get("Age"))
+	assert.Equal(t, 1, *calls)
+
+	t.Run("If-None-Match 返回 304", func(t *testing.T) {
+		for _, inm := range []string{etag, `"other", W/` + etag, "*"} {
+			w := get(router, "/items/1", map[string]string{"If-None-Match": inm})
+			assert.Equal(t, http.StatusNotModified, w.Code, inm)
+			assert.Empty(t, w.Body.String())
+			assert.Empty(t, w.Header().Get("Content-Type"))
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+		}
+		w := get(router, "/items/1", map[string]string{"If-None-Match": `"stale"`})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("查询参数排序后参与缓存键", func(t *testing.T) {
+		get(router, "/items/1?q=x&p=1", nil)
+		w := get(router, "/items/1?p=1&q=x", nil)
+		assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Accept 不同不共用缓存", func(t *testing.T) {
+		w := get(router, "/items/1", map[string]string{"Accept": "application/xml"})
+		assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("no-cache 跳过缓存并刷新", func(t *testing.T) {
+		for _, h := range []map[string]string{
+			{"Cache-Control": "no-cache"},
+			{"Cache-Control": "max-age=0"},
+			{"Pragma": "no-cache"},
+		} {
+			w := get(router, "/items/1", h)
+			assert.Equal(t, "BYPASS", w.Header().Get("X-Cache"))
+		}
+		assert.Equal(t, 6, *calls)
+		assert.Equal(t, "HIT", get(router, "/items/1", nil).Header().Get("X-Cache"))
+	})
+
+	t.Run("不缓存的响应", func(t *testing.T) {
+		before := *calls
+		for _, target := range []string{"/items/missing", "/items/cookie", "/items/nostore"} {
+			get(router, target, nil)
+			get(router, target, nil)
+		}
+		get(router, "/items/2", map[string]string{"Cache-Control": "no-store"})
+		get(router, "/items/2", map[string]string{"Cache-Control": "no-store"})
+		assert.Equal(t, before+8, *calls)
+
+		w := get(router, "/items/missing", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":1004}`, w.Body.String())
+	})
+}
+
+// TestVaryByUser 测试按用户区分缓存
+func TestVaryByUser(t *testing.T) {
+	cache := New(DefaultConfig())
+	options := DefaultOptions()
+	options.VaryByUser = true
+	router, calls := newRouter(cache, options)
+
+	alice := get(router, "/items/1", map[string]string{"X-User": "alice"})
+	bob := get(router, "/items/1", map[string]string{"X-User": "bob"})
+	assert.Equal(t, "MISS", bob.Header().Get("X-Cache"))
+	assert.Contains(t, alice.Body.String(), `"user":"alice"`)
+	assert.Contains(t, bob.Body.String(), `"user":"bob"`)
+	assert.Equal(t, "private, no-cache", alice.Header().Get("Cache-Control"))
+	assert.Equal(t, []string{"Accept", "Authorization"}, alice.Header().Values("Vary"))
+
+	hit := get(router, "/items/1", map[string]string{"X-User": "alice"})
+	assert.Equal(t, "HIT", hit.Header().Get("X-Cache"))
+	assert.Equal(t, alice.Body.String(), hit.Body.String())
+	assert.Equal(t, 2, *calls)
+}
+
+// TestVaryHeaders 参与缓存键的请求头出现在响应的 Vary 中
+func TestVaryHeaders(t *testing.T) {
+	t.Run("默认 Accept", func(t *testing.T) {
+		router, _ := newRouter(New(DefaultConfig()), DefaultOptions())
+
+		miss := get(router, "/items/1", nil)
+		assert.Equal(t, []string{"Accept"}, miss.Header().Values("Vary"))
+		hit := get(router, "/items/1", nil)
+		assert.Equal(t, "HIT", hit.Header().Get("X-Cache"))
+		assert.Equal(t, []string{"Accept"}, hit.Header().Values("Vary"))
+		notModified := get(router, "/items/1", map[string]string{"If-None-Match": miss.Header().Get("ETag")})
+		assert.Equal(t, http.StatusNotModified, notModified.Code)
+		assert.Equal(t, []string{"Accept"}, notModified.Header().Values("Vary"))
+	})
+
+	t.Run("自定义请求头且不重复", func(t *testing.T) {
+		options := DefaultOptions()
+		options.VaryHeaders = []string{"Accept", "Accept-Language", "accept"}
+		options.VaryByUser = true
+		router, _ := newRouter(New(DefaultConfig()), options)
+
+		w := get(router, "/items/1", map[string]string{"X-User": "alice"})
+		assert.Equal(t, []string{"Accept", "Accept-Language", "Authorization"}, w.Header().Values("Vary"))
+	})
+}
+
+// TestInvalidateConstraintRoute 约束路由按声明的模板失效
+func TestInvalidateConstraintRoute(t *testing.T) {
+	cache := New(Config{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	users := constraint.New(router, constraint.Options{}).Group("", cache.Middleware(DefaultOptions()))
+	users.GET("/users/{id:int}", func(c *gin.Context) { c.String(http.StatusOK, c.Param("id")) })
+
+	get(router, "/users/1", nil)
+	get(router, "/users/2", nil)
+	assert.Equal(t, 0, cache.InvalidateRoute("/users/:arg0"))
+	assert.Equal(t, 2, cache.InvalidateRoute("/users/{id:int}"))
+}
+
+// TestInvalidate 测试失效、过期和淘汰
+func TestInvalidate(t *testing.T) {
+	cache := New(Config{MaxEntries: 3})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	router, calls := newRouter(cache, DefaultOptions())
+
+	get(router, "/items/1", nil)
+	get(router, "/items/1?q=x", nil)
+	get(router, "/items/2", nil)
+	assert.Equal(t, 3, cache.Len())
+
+	assert.Equal(t, 2, cache.InvalidatePath("/items/1"), "同一路径的所有变体")
+	assert.Equal(t, "MISS", get(router, "/items/1", nil).Header().Get("X-Cache"))
+	assert.Equal(t, 2, cache.InvalidateRoute("/items/:id"))
+	assert.Equal(t, 0, cache.Len())
+
+	get(router, "/items/1", nil)
+	now = now.Add(30 * time.Second)
+	w := get(router, "/items/1", nil)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "30", w.Header().Get("Age"))
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, "MISS", get(router, "/items/1", nil).Header().Get("X-Cache"), "超过 TTL")
+
+	// 满了淘汰最早过期的条目
+	for _, target := range []string{"/items/2", "/items/3", "/items/4"} {
+		now = now.Add(time.Second)
+		get(router, target, nil)
+	}
+	assert.Equal(t, 3, cache.Len())
+	assert.Equal(t, "MISS", get(router, "/items/1", nil).Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", get(router, "/items/4", nil).Header().Get("X-Cache"))
+
+	assert.Equal(t, 3, cache.InvalidatePrefix("/items/"))
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, 10, *calls)
+}
+
+// TestETag 测试不保存响应的 ETag 中间件
+func TestETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	calls := 0
+	router.GET("/config", ETag(), func(c *gin.Context) {
+		calls++
+		c.String(http.StatusOK, "v1")
+	})
+	router.GET("/custom", ETag(), func(c *gin.Context) {
+		c.Header("ETag", `W/"v42"`)
+		c.Header("Cache-Control", "max-age=60")
+		c.String(http.StatusOK, "custom")
+	})
+
+	w := get(router, "/config", nil)
+	etag := w.Header().Get("ETag")
+	assert.Empty(t, w.Header().Get("X-Cache"))
+	assert.Equal(t, http.StatusNotModified, get(router, "/config", map[string]string{"If-None-Match": etag}).Code)
+	assert.Equal(t, 2, calls)
+
+	w = get(router, "/custom", map[string]string{"If-None-Match": `"v42"`})
+	assert.Equal(t, http.StatusNotModified, w.Code, "处理函数设置的 ETag 优先，弱比较")
+	assert.Equal(t, "max-age=60", w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,247 @@
+package timeout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// 错误码
+const (
+	codeParamError = 1001 // 参数错误
+	codeTimeout    = 2005 // 处理超时
+)
+
+// Options 超时中间件配置
+type Options struct {
+	Timeout    time.Duration // 处理时限，写入 c.Request.Context() 的截止时间
+	StatusCode int           // 超时返回的状态码：504（默认）或 503（希望客户端/网关按过载处理、稍后重试）
+	Message    string        // 超时的提示信息
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		Timeout:    10 * time.Second,
+		StatusCode: http.StatusGatewayTimeout,
+		Message:    "请求处理超时",
+	}
+}
+
+// Timeout 按路由设置处理时限，其余配置使用默认值
+//
+//	router.GET("/reports/:id", timeout.Timeout(30*time.Second), getReport)
+func Timeout(d time.Duration) gin.HandlerFunc {
+	options := DefaultOptions()
+	options.Timeout = d
+	return Middleware(options)
+}
+
+// Middleware 请求超时中间件
+//
+// 处理流程:
+//  1. 给 c.Request.Context() 加上截止时间，处理函数中 db.WithContext / http 请求等会随之取消
+//  2. 在新的 goroutine 中执行后续处理函数，响应先写入缓冲区
+//  3. 按时完成：把缓冲的响应头、状态码和响应体原样写出
+//  4. 超时：立即返回统一的 504 错误并 Flush，之后处理函数的写入全部丢弃（返回 http.ErrHandlerTimeout）
+//
+// 超时后中间件仍会等待处理函数返回再结束：gin.Context 在请求结束后会被放回池中复用，
+// 不等待的话处理函数可能读写已经属于其他请求的 Context。
+// 所以处理函数必须使用 c.Request.Context()，否则超时只能提前给出响应，无法释放占用的资源。
+//
+// 注意:
+//   - 截止时间只能缩短不能延长，全局 5s 时单个路由设置 30s 无效，需要用路由分组区分
+//   - 超时响应不经过内容协商，固定为 JSON
+//   - 需要流式输出的接口（SSE、大文件下载）不能使用，缓冲期间 Flush 无效
+//   - 处理函数中的 panic 会在中间件所在的 goroutine 中重新抛出，交给 Recovery 处理
+func Middleware(options Options) gin.HandlerFunc {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.StatusCode == 0 {
+		options.StatusCode = defaults.StatusCode
+	}
+	if options.Message == "" {
+		options.Message = defaults.Message
+	}
+	body, _ := json.Marshal(response.Response{Code: codeTimeout, Message: options.Message})
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), options.Timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		tw := &timeoutWriter{
+			ResponseWriter: original,
+			ctx:            ctx,
+			header:         original.Header().Clone(),
+			status:         http.StatusOK,
+		}
+		c.Writer = tw
+
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() {
+				if p := recover(); p != nil {
+					panicked = p
+				}
+				tw.finish()
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		if tw.markTimedOut() {
+			header := original.Header()
+			header.Set("Content-Type", "application/json; charset=utf-8")
+			header.Del("Content-Length")
+			original.WriteHeader(options.StatusCode)
+			_, _ = original.Write(body)
+			original.Flush()
+		}
+		// 超时或客户端断开（context.Canceled）后同样等待处理函数结束
+		<-done
+		c.Writer = original
+
+		if panicked != nil {
+			panic(panicked)
+		}
+		if !tw.timedOut {
+			tw.copyTo(original)
+		}
+	}
+}
+
+// timeoutWriter 缓冲处理函数的响应，超时后拒绝写入
+//
+// 处理函数和中间件在不同的 goroutine 中，所有字段都通过 mu 保护；
+// header 是独立的副本，超时响应和处理函数不会同时修改同一个 map
+//
+// 是否超时在 mu 保护下判定：截止时间之后才返回或写入的处理函数同样按超时处理，
+// 不会出现处理函数看到 ctx.Done() 后抢先写出响应、与超时响应竞争的情况
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	written  bool
+	body     bytes.Buffer
+	finished bool
+	timedOut bool
+}
+
+// finish 处理函数已返回
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+	w.checkDeadline()
+}
+
+// markTimedOut 判定是否超时：处理函数在截止时间前返回，或者是客户端断开，返回 false
+func (w *timeoutWriter) markTimedOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.finished {
+		w.checkDeadline()
+	}
+	return w.timedOut
+}
+
+// checkDeadline 已过截止时间则标记超时，调用方持有 mu
+func (w *timeoutWriter) checkDeadline() {
+	if errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+	}
+}
+
+// copyTo 处理函数按时完成，把缓冲的响应写到原始 ResponseWriter
+func (w *timeoutWriter) copyTo(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	header := dst.Header()
+	clear(header)
+	maps.Copy(header, w.header)
+	dst.WriteHeader(w.status)
+	if w.written {
+		dst.WriteHeaderNow()
+	}
+	if w.body.Len() > 0 {
+		_, _ = dst.Write(w.body.Bytes())
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.written && !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.checkDeadline()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush 缓冲期间忽略
+func (w *timeoutWriter) Flush() {}
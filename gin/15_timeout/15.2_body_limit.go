@@ -0,0 +1,75 @@
+package timeout
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// MaxBodySize 限制请求体大小的中间件，超过返回 413
+//
+//	router.POST("/comments", timeout.MaxBodySize(64<<10), createComment)
+//
+// 两道检查:
+//   - Content-Length 已经超过上限：不读取请求体，直接返回 413
+//   - 分块传输或 Content-Length 不可信：用 http.MaxBytesReader 包装请求体，读到上限后返回 *http.MaxBytesError，
+//     处理函数发现 IsBodyTooLarge(err) 时直接 return 即可，由中间件统一返回 413
+//
+// 上传接口（见 Upload 示例）有自己的大小限制，不要再叠加更小的全局限制
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			bodyTooLarge(c, limit)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit)}
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded.Load() && !c.Writer.Written() {
+			bodyTooLarge(c, limit)
+		}
+	}
+}
+
+// IsBodyTooLarge 错误是否由请求体超过 MaxBodySize 引起
+//
+//	if err := c.ShouldBindJSON(&req); err != nil {
+//	    if timeout.IsBodyTooLarge(err) {
+//	        return // 由中间件返回 413
+//	    }
+//	    response.Error(c, 1001, "参数校验失败: "+err.Error())
+//	    return
+//	}
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func bodyTooLarge(c *gin.Context, limit int64) {
+	c.Header("Connection", "close") // 剩余的请求体不再读取，连接不能复用
+	response.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, codeParamError,
+		fmt.Sprintf("请求体过大，上限 %d 字节", limit))
+	c.Abort()
+}
+
+// limitedBody 记录读取时是否超过上限
+type limitedBody struct {
+	io.ReadCloser
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && IsBodyTooLarge(err) {
+		b.exceeded.Store(true)
+	}
+	return n, err
+}
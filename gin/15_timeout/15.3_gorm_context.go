@@ -0,0 +1,188 @@
+package timeout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// ErrNoContext 查询没有使用可取消的 context
+var ErrNoContext = errors.New("timeout: 查询没有使用请求的 context，请使用 db.WithContext(c.Request.Context())")
+
+// ContextGuard 检查 SQL 是否使用了可取消的 context 的 GORM 插件
+//
+//	db.Use(&timeout.ContextGuard{Strict: true})
+//
+// 没有调用 db.WithContext 时 Statement.Context 为 context.Background()，
+// 超时中间件的截止时间传不到数据库，慢查询会一直执行下去。
+//   - Strict 为 false：打印告警和调用位置，查询照常执行（适合生产环境逐步排查）
+//   - Strict 为 true：查询返回 ErrNoContext，不会执行（适合开发和测试环境）
+//
+// 启动时的 AutoMigrate 等没有请求 context，应在这些操作完成后再注册插件
+type ContextGuard struct {
+	Strict bool
+}
+
+// Name 插件名
+func (g *ContextGuard) Name() string {
+	return "timeout:context_guard"
+}
+
+// Initialize db.Use 时调用，在每类操作之前检查 context
+func (g *ContextGuard) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for name, processor := range map[string]registerer{
+		"create": cb.Create().Before("gorm:create"),
+		"query":  cb.Query().Before("gorm:query"),
+		"update": cb.Update().Before("gorm:update"),
+		"delete": cb.Delete().Before("gorm:delete"),
+		"row":    cb.Row().Before("gorm:row"),
+		"raw":    cb.Raw().Before("gorm:raw"),
+	} {
+		if err := processor.Register("timeout:check_context_"+name, g.check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *ContextGuard) check(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx != nil && ctx.Done() != nil {
+		return
+	}
+	if g.Strict {
+		_ = db.AddError(ErrNoContext)
+		return
+	}
+	log.Printf("[timeout] %s: 查询没有使用请求的 context，超时后无法取消", caller())
+}
+
+// caller 跳过 GORM 内部的调用，返回业务代码中发起查询的位置
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // 跳过 runtime.Callers、caller、check
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// registerer GORM 回调处理器 Before 的返回值（类型未导出）
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// slowQuery 一直执行直到被中断的 SQL，模拟慢查询
+const slowQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c"
+
+// TimeoutDemo 演示请求超时、请求体大小限制和 GORM 查询取消
+func TimeoutDemo() {
+	fmt.Println("=== 请求超时与请求体大小限制示例 ===")
+	fmt.Println()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+	if err := db.Use(&ContextGuard{Strict: true}); err != nil {
+		fmt.Printf("注册插件失败: %v\n", err)
+		return
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	api := router.Group("/api", Timeout(200*time.Millisecond))
+	api.GET("/fast", func(c *gin.Context) {
+		response.Success(c, gin.H{"status": "ok"})
+	})
+	api.GET("/report", func(c *gin.Context) {
+		var n int64
+		start := time.Now()
+		err := db.WithContext(c.Request.Context()).Raw(slowQuery).Scan(&n).Error
+		fmt.Printf("  [handler] 慢查询在 %dms 后结束: %v\n", time.Since(start).Milliseconds(), err)
+		if c.Request.Context().Err() != nil {
+			return // 已超时，中间件已经返回 504
+		}
+		response.Success(c, gin.H{"count": n})
+	})
+	api.GET("/legacy", func(c *gin.Context) {
+		var n int64
+		err := db.Raw("SELECT 1").Scan(&n).Error // 忘记 WithContext
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusInternalServerError, 2001, err.Error())
+			return
+		}
+		response.Success(c, gin.H{"n": n})
+	})
+	router.POST("/comments", MaxBodySize(64), func(c *gin.Context) {
+		var req struct {
+			Content string `json:"content" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if IsBodyTooLarge(err) {
+				return // 由中间件返回 413
+			}
+			response.Error(c, codeParamError, "参数校验失败: "+err.Error())
+			return
+		}
+		response.Success(c, gin.H{"content": req.Content})
+	})
+
+	send := func(title string, req *http.Request) {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  %s %s -> %d（%dms）%s\n", title, req.Method, req.URL.Path, w.Code,
+			time.Since(start).Milliseconds(), strings.TrimSpace(w.Body.String()))
+	}
+
+	send("1. 按时完成", httptest.NewRequest(http.MethodGet, "/api/fast", nil))
+	send("2. 慢查询超时，截止时间传到 SQLite 中断查询", httptest.NewRequest(http.MethodGet, "/api/report", nil))
+	send("3. 忘记 WithContext，ContextGuard 拒绝执行", httptest.NewRequest(http.MethodGet, "/api/legacy", nil))
+
+	// 客户端主动断开：context.Canceled，不返回 504，查询同样被取消
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	send("4. 客户端断开", httptest.NewRequest(http.MethodGet, "/api/report", nil).WithContext(ctx))
+
+	send("5. 请求体大小正常", httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(`{"content":"hi"}`)))
+	send("6. Content-Length 超过上限，不读取请求体", httptest.NewRequest(http.MethodPost, "/comments",
+		strings.NewReader(`{"content":"`+strings.Repeat("x", 100)+`"}`)))
+	// 分块传输没有 Content-Length，读到上限时才发现
+	chunked := httptest.NewRequest(http.MethodPost, "/comments",
+		io.MultiReader(strings.NewReader(`{"content":"`), bytes.NewReader(bytes.Repeat([]byte("x"), 100)), strings.NewReader(`"}`)))
+	chunked.ContentLength = -1
+	send("7. 分块传输超过上限", chunked)
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 处理函数中的 GORM 调用都要 db.WithContext(c.Request.Context())，超时才能取消查询")
+	fmt.Println("  - 超时中间件会等待处理函数返回，不使用 context 的处理函数仍然占用资源")
+	fmt.Println("  - 截止时间只能缩短：全局超时较短时，耗时接口放在单独的路由分组中")
+}
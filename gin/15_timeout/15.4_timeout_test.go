@@ -0,0 +1,174 @@
+package timeout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestTimeout 测试超时响应、按时完成和 panic
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(func(c *gin.Context) {
+		c.Header("X-Request-ID", "req-1")
+		c.Next()
+	})
+
+	handlerDone := make(chan error, 1)
+	group := router.Group("/", Timeout(50*time.Millisecond))
+	group.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Handler", "yes")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	group.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Header("X-Handler", "late")
+		_, err := c.Writer.WriteString("too late")
+		handlerDone <- err
+	})
+	group.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	router.GET("/busy", Middleware(Options{Timeout: 20 * time.Millisecond, StatusCode: http.StatusServiceUnavailable, Message: "服务繁忙"}),
+		func(c *gin.Context) {
+			<-c.Request.Context().Done()
+		})
+
+	t.Run("按时完成", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"ok":true}`, w.Body.String())
+		assert.Equal(t, "yes", w.Header().Get("X-Handler"))
+		assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("超时返回 504，处理函数之后的写入被丢弃", func(t *testing.T) {
+		start := time.Now()
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.JSONEq(t, `{"code":2005,"data":null,"message":"请求处理超时"}`, w.Body.String())
+		assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"), "之前中间件设置的头保留")
+		assert.Empty(t, w.Header().Get("X-Handler"))
+		assert.ErrorIs(t, <-handlerDone, http.ErrHandlerTimeout, "中间件返回前处理函数已结束")
+	})
+
+	t.Run("503 与自定义信息", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/busy", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "服务繁忙")
+	})
+
+	t.Run("panic 交给 Recovery", func(t *testing.T) {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("客户端断开不返回 504", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+		assert.NotEqual(t, http.StatusGatewayTimeout, w.Code)
+		assert.Equal(t, "too late", w.Body.String())
+		assert.NoError(t, <-handlerDone)
+	})
+}
+
+// TestMaxBodySize 测试请求体大小限制
+func TestMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/echo", MaxBodySize(16), func(c *gin.Context) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if IsBodyTooLarge(err) {
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, req.Text)
+	})
+
+	w := serve(router, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"text":"hi"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hi", w.Body.String())
+
+	w = serve(router, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"text":"hello world"}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"code":1001,"data":null,"message":"请求体过大，上限 16 字节"}`, w.Body.String())
+	assert.Equal(t, "close", w.Header().Get("Connection"))
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"text":"hello world"}`))
+	req.ContentLength = -1 // 分块传输
+	w = serve(router, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = serve(router, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "其他错误由处理函数处理")
+}
+
+// TestContextGuard 测试 GORM 查询随请求超时取消
+func TestContextGuard(t *testing.T) {
+	t.Run("宽松模式只告警", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(out)
+		lenient, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		assert.NoError(t, err)
+		assert.NoError(t, lenient.Use(&ContextGuard{}))
+
+		var n int
+		assert.NoError(t, lenient.Raw("SELECT 1").Scan(&n).Error)
+		assert.Equal(t, 1, n)
+		assert.Contains(t, buf.String(), "15.4_timeout_test.go", "告警带调用位置")
+	})
+
+	strict, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	assert.NoError(t, strict.Use(&ContextGuard{Strict: true}))
+
+	var n int
+	assert.ErrorIs(t, strict.Raw("SELECT 1").Scan(&n).Error, ErrNoContext)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	queryErr := make(chan error, 1)
+	router.GET("/report", Timeout(50*time.Millisecond), func(c *gin.Context) {
+		var count int64
+		queryErr <- strict.WithContext(c.Request.Context()).Raw(slowQuery).Scan(&count).Error
+	})
+
+	start := time.Now()
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	err = <-queryErr
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "interrupt"), err)
+	assert.Less(t, time.Since(start), 2*time.Second, "查询被中断")
+}
@@ -0,0 +1,319 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSlowConsumer 订阅者的缓冲区已满，连接被断开；客户端带上 Last-Event-ID 重连即可补齐
+	ErrSlowConsumer = errors.New("realtime: 客户端消费过慢，已断开")
+	// ErrHubClosed Hub 已关闭（服务正在退出）
+	ErrHubClosed = errors.New("realtime: hub 已关闭")
+)
+
+// Event 推送给客户端的事件
+type Event struct {
+	ID    uint64          `json:"id"`    // 全局递增，客户端重连时作为 Last-Event-ID
+	Topic string          `json:"topic"` // 如 order:ORD001、user:42
+	Type  string          `json:"type"`  // 如 order.status_changed
+	Data  json.RawMessage `json:"data"`
+	Time  time.Time       `json:"time"`
+}
+
+// HubConfig Hub 配置
+type HubConfig struct {
+	HistorySize int           // 每个主题保留的最近事件数，用于断线重连补发
+	HistoryTTL  time.Duration // 历史事件保留时长，过期的事件和空闲的主题会被清理
+	BufferSize  int           // 每个订阅者的发送缓冲区，写满说明客户端太慢
+}
+
+// DefaultHubConfig 默认配置
+func DefaultHubConfig() HubConfig {
+	return HubConfig{HistorySize: 100, HistoryTTL: 10 * time.Minute, BufferSize: 64}
+}
+
+// sweepInterval 每发布多少个事件清理一次所有主题
+const sweepInterval = 1024
+
+// Hub 按主题分发事件
+//
+// 背压策略：Publish 从不阻塞，订阅者缓冲区满时直接断开该订阅者（ErrSlowConsumer），
+// 客户端重连后通过 Last-Event-ID 从历史中补齐。一个慢客户端不会拖慢发布方和其他客户端，
+// 也不会在服务端无限堆积消息。
+//
+// 进程内实现，多实例部署时需要通过 Redis Pub/Sub 等把事件广播到每个实例再 Publish
+type Hub struct {
+	mu      sync.Mutex
+	config  HubConfig
+	startID uint64 // 本进程第一个事件之前的 ID
+	lastID  uint64
+	topics  map[string]*topic
+	pruned  uint64 // 已删除主题中移出历史的最大事件 ID，新建的主题以它为起点
+	closed  bool
+	now     func() time.Time
+	dropped uint64 // 因过慢被断开的订阅者数
+}
+
+// topic 单个主题的订阅者和最近事件
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	history     []Event // 按 ID 升序，最多 HistorySize 条
+	evicted     uint64  // 已从历史中移除的最大事件 ID
+}
+
+// NewHub 创建 Hub
+func NewHub(config HubConfig) *Hub {
+	defaults := DefaultHubConfig()
+	if config.HistorySize <= 0 {
+		config.HistorySize = defaults.HistorySize
+	}
+	if config.HistoryTTL <= 0 {
+		config.HistoryTTL = defaults.HistoryTTL
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	// 事件 ID 以启动时间（微秒）为起点，服务重启后仍然大于之前发出的 ID，
+	// 客户端带着旧的 Last-Event-ID 重连时不会与新事件混淆
+	start := uint64(time.Now().UnixMicro())
+	return &Hub{
+		config:  config,
+		startID: start,
+		lastID:  start,
+		topics:  make(map[string]*topic),
+		now:     time.Now,
+	}
+}
+
+// Publish 发布事件，data 序列化为 JSON
+func (h *Hub) Publish(topicName, eventType string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return Event{}, ErrHubClosed
+	}
+
+	h.lastID++
+	event := Event{ID: h.lastID, Topic: topicName, Type: eventType, Data: raw, Time: h.now()}
+
+	t := h.topic(topicName)
+	t.history = append(t.history, event)
+	h.prune(t, event.Time)
+	if h.lastID%sweepInterval == 0 {
+		h.sweep(event.Time)
+	}
+
+	for sub := range t.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			h.dropped++
+			h.remove(sub, ErrSlowConsumer)
+		}
+	}
+	return event, nil
+}
+
+// Subscription 一个连接的订阅
+type Subscription struct {
+	hub    *Hub
+	topics []string
+	ch     chan Event
+	done   chan struct{}
+	err    error
+
+	// Replay 断线期间错过的事件（Last-Event-ID 之后），应在读取 Events 之前先发送
+	Replay []Event
+	// Truncated 错过的事件已经部分移出历史，无法完整补发，客户端应重新拉取全量数据
+	Truncated bool
+}
+
+// Subscribe 订阅一组主题
+//
+// lastEventID 为客户端收到的最后一个事件 ID（首次连接为 0），之后的历史事件放在 Replay 中。
+// 注册订阅和读取历史在同一把锁内完成，补发和实时事件之间不会遗漏也不会重复
+func (h *Hub) Subscribe(topics []string, lastEventID uint64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	// 去重，?topic=a&topic=a 不应重复补发
+	unique := make([]string, 0, len(topics))
+	seen := make(map[string]struct{}, len(topics))
+	for _, name := range topics {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			unique = append(unique, name)
+		}
+	}
+	topics = unique
+
+	sub := &Subscription{
+		hub:    h,
+		topics: topics,
+		ch:     make(chan Event, h.config.BufferSize),
+		done:   make(chan struct{}),
+	}
+	switch {
+	case lastEventID == 0:
+	case lastEventID < h.startID:
+		// 重启之前的事件，断线到重启之间的事件已经丢失
+		sub.Truncated = true
+	case lastEventID > h.lastID:
+		// 不是本进程发出的 ID（时钟回拨等），无法判断错过了什么
+		sub.Truncated = true
+		lastEventID = 0
+	}
+	for _, name := range topics {
+		t := h.topic(name)
+		t.subscribers[sub] = struct{}{}
+		if lastEventID == 0 {
+			continue
+		}
+		if t.evicted > lastEventID {
+			sub.Truncated = true
+		}
+		for _, e := range t.history {
+			if e.ID > lastEventID {
+				sub.Replay = append(sub.Replay, e)
+			}
+		}
+	}
+	// 多个主题的历史按 ID 合并排序
+	sort.Slice(sub.Replay, func(i, j int) bool { return sub.Replay[i].ID < sub.Replay[j].ID })
+	return sub, nil
+}
+
+// Events 实时事件
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Done 订阅结束（过慢被断开、Hub 关闭或调用了 Close）时关闭
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 订阅结束的原因，Close 主动结束时为 nil
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close 取消订阅，可重复调用
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s, nil)
+}
+
+// Subscribers 当前订阅者数（按连接计）
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs := make(map[*Subscription]struct{})
+	for _, t := range h.topics {
+		for sub := range t.subscribers {
+			subs[sub] = struct{}{}
+		}
+	}
+	return len(subs)
+}
+
+// Dropped 因消费过慢被断开的订阅者累计数
+func (h *Hub) Dropped() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dropped
+}
+
+// Close 关闭 Hub，结束所有订阅，用于优雅关闭时让长连接尽快返回
+//
+// http.Server.Shutdown 会等待 SSE 响应结束，应通过 srv.RegisterOnShutdown(hub.Close) 在关闭开始时调用，
+// 而不是等请求处理完之后再释放
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, t := range h.topics {
+		for sub := range t.subscribers {
+			h.remove(sub, ErrHubClosed)
+		}
+	}
+}
+
+// topic 获取或创建主题，调用方持有 mu
+//
+// 新建的主题可能之前存在过、历史已随主题一起删除，无法区分，因此保守地继承 Hub 的 pruned：
+// 带着更早的 Last-Event-ID 重连的客户端会被标记为 Truncated，而不是拿到空的补发
+func (h *Hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{subscribers: make(map[*Subscription]struct{}), evicted: h.pruned}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// dropIfIdle 删除没有订阅者也没有历史的主题，保留它的 evicted 水位，调用方持有 mu
+func (h *Hub) dropIfIdle(name string, t *topic) {
+	if len(t.subscribers) > 0 || len(t.history) > 0 {
+		return
+	}
+	h.pruned = max(h.pruned, t.evicted)
+	delete(h.topics, name)
+}
+
+// prune 移除超出条数或过期的历史事件，调用方持有 mu
+func (h *Hub) prune(t *topic, now time.Time) {
+	n := 0
+	for n < len(t.history) && (len(t.history)-n > h.config.HistorySize || now.Sub(t.history[n].Time) > h.config.HistoryTTL) {
+		t.evicted = t.history[n].ID
+		n++
+	}
+	if n > 0 {
+		t.history = append(t.history[:0:0], t.history[n:]...)
+	}
+}
+
+// sweep 清理所有主题的过期历史，删除没有订阅者也没有历史的主题，调用方持有 mu
+//
+// order:ORD001 这类主题数量随业务增长，不清理会一直占用内存
+func (h *Hub) sweep(now time.Time) {
+	for name, t := range h.topics {
+		h.prune(t, now)
+		h.dropIfIdle(name, t)
+	}
+}
+
+// remove 从所有主题中移除订阅者，调用方持有 mu
+func (h *Hub) remove(sub *Subscription, err error) {
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+	sub.err = err
+	close(sub.done)
+	for _, name := range sub.topics {
+		if t, ok := h.topics[name]; ok {
+			delete(t.subscribers, sub)
+			h.dropIfIdle(name, t)
+		}
+	}
+}
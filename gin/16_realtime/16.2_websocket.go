@@ -0,0 +1,397 @@
+package realtime
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go-learning/gin/internal/httpheader"
+)
+
+// 帧类型（RFC 6455 5.2）
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// 关闭码（RFC 6455 7.4.1）
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001 // 服务端关闭或客户端离开页面
+	CloseProtocolError   = 1002
+	CloseInvalidPayload  = 1007 // 文本帧不是合法 UTF-8
+	ClosePolicyViolation = 1008 // 如消费过慢被断开
+	CloseMessageTooBig   = 1009
+)
+
+// acceptGUID 计算 Sec-WebSocket-Accept 使用的固定 GUID
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	// ErrBadHandshake 不是合法的 WebSocket 握手请求
+	ErrBadHandshake = errors.New("websocket: 不是合法的握手请求")
+	// ErrOriginNotAllowed Origin 校验失败
+	ErrOriginNotAllowed = errors.New("websocket: Origin 不允许")
+
+	errProtocol = errors.New("websocket: 协议错误")
+	errTooBig   = errors.New("websocket: 消息过大")
+)
+
+// CloseError 对端发来的关闭帧
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: 连接已关闭 (%d) %s", e.Code, e.Text)
+}
+
+// Conn 最小的 RFC 6455 连接实现：文本/二进制消息、分片、Ping/Pong、关闭握手
+//
+// 并发约定（与 gorilla/websocket 相同）：同一时刻只能有一个 goroutine 读，
+// 写操作内部加锁，读 goroutine 自动回复 Pong 时也可以安全地与其他写操作并发
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	client   bool // 客户端发送的帧必须加掩码，服务端发送的帧不能加掩码
+	maxBytes int64
+
+	writeMu sync.Mutex
+	onPong  func()
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, br: br, client: client, maxBytes: 64 << 10}
+}
+
+// Upgrade 完成握手，把 HTTP 连接升级为 WebSocket
+//
+// checkOrigin 为 nil 时只允许没有 Origin（非浏览器客户端）或与 Host 相同的 Origin，
+// 防止其他网站的页面借用户的 Cookie 建立连接（跨站 WebSocket 劫持）
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(*http.Request) bool) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!httpheader.Contains(r.Header, "Connection", "upgrade") ||
+		!httpheader.Contains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, ErrBadHandshake
+	}
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, ErrOriginNotAllowed
+	}
+
+	// 先设置状态码，访问日志等中间件能记录到 101
+	w.WriteHeader(http.StatusSwitchingProtocols)
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return newConn(netConn, rw.Reader, false), nil
+}
+
+// Dial 建立客户端连接（只支持 ws://），用于测试和服务间调用
+// 握手失败时返回服务端的响应，便于读取 401 / 403 等错误
+func Dial(rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, nil, fmt.Errorf("websocket: 不支持的协议 %q", u.Scheme)
+	}
+	netConn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	u.Scheme = "http"
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: header.Clone()}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		netConn.Close()
+		return nil, resp, ErrBadHandshake
+	}
+	return newConn(netConn, br, true), resp, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	_, host, ok := strings.Cut(origin, "://")
+	return ok && strings.EqualFold(host, r.Host)
+}
+
+// SetReadLimit 单条消息的最大字节数，默认 64KB，超过时以 1009 关闭连接
+func (c *Conn) SetReadLimit(n int64) {
+	c.maxBytes = n
+}
+
+// SetPongHandler 收到 Pong 时调用（在读 goroutine 中），通常用来延长读超时
+func (c *Conn) SetPongHandler(fn func()) {
+	c.onPong = fn
+}
+
+// SetReadDeadline 设置读超时
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置写超时
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close 直接关闭底层连接（不发送关闭帧）
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ReadMessage 读取一条完整的文本或二进制消息
+//
+// 控制帧在内部处理：Ping 自动回复 Pong，Pong 调用 SetPongHandler 的回调，
+// 收到关闭帧时回复关闭帧并返回 *CloseError
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		opcode  int
+		message []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errTooBig):
+				_ = c.WriteClose(CloseMessageTooBig, "")
+			case errors.Is(err, errProtocol):
+				_ = c.WriteClose(CloseProtocolError, "")
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			if c.onPong != nil {
+				c.onPong()
+			}
+			continue
+		case OpClose:
+			closeErr := &CloseError{Code: 1005} // 1005: 对端没有给出关闭码
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			_ = c.WriteClose(CloseNormal, "")
+			return 0, nil, closeErr
+		case OpText, OpBinary:
+			if message != nil {
+				return 0, nil, c.fail(CloseProtocolError, "分片消息未结束又收到新消息")
+			}
+			opcode = op
+			message = payload
+		case OpContinuation:
+			if message == nil {
+				return 0, nil, c.fail(CloseProtocolError, "没有起始帧的分片")
+			}
+			if int64(len(message)+len(payload)) > c.maxBytes {
+				return 0, nil, c.fail(CloseMessageTooBig, "")
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "未知的帧类型")
+		}
+
+		if fin {
+			if opcode == OpText && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "")
+			}
+			return opcode, message, nil
+		}
+	}
+}
+
+// fail 发送关闭帧并返回协议错误
+func (c *Conn) fail(code int, text string) error {
+	_ = c.WriteClose(code, text)
+	return fmt.Errorf("%w: %d %s", errProtocol, code, text)
+}
+
+// readFrame 读取一帧并去掉掩码
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = int(head[0] & 0x0F)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: 未协商扩展却设置了 RSV 位", errProtocol)
+	}
+	masked := head[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, fmt.Errorf("%w: 掩码设置错误", errProtocol)
+	}
+
+	length := int64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= OpClose && (!fin || length > 125) {
+		return false, 0, nil, fmt.Errorf("%w: 控制帧不能分片且不能超过 125 字节", errProtocol)
+	}
+	if length < 0 || length > c.maxBytes {
+		return false, 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage 发送一条文本（OpText）或二进制（OpBinary）消息
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.writeFrame(opcode, data)
+}
+
+// WritePing 发送 Ping，对端应回复 Pong
+func (c *Conn) WritePing(data []byte) error {
+	return c.writeFrame(OpPing, data)
+}
+
+// WriteClose 发送关闭帧
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	return c.writeFrame(OpClose, payload)
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	header := make([]byte, 0, 14)
+	header = append(header, 0x80|byte(opcode)) // 不分片发送
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, maskBit|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, maskBit|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, maskBit|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	data := payload
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		header = append(header, mask[:]...)
+		data = make([]byte, len(payload))
+		for i := range payload {
+			data[i] = payload[i] ^ mask[i%4]
+		}
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := (&net.Buffers{header, data}).WriteTo(c.conn)
+	return err
+}
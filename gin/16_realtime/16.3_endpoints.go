@@ -0,0 +1,286 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// 错误码
+const (
+	codeParamError = 1001 // 参数错误
+	codeForbidden  = 1003 // 权限不足
+	codeInternal   = 2004 // 内部服务器错误
+)
+
+// maxTopics 单个连接最多订阅的主题数
+const maxTopics = 20
+
+// EventReset 断线期间的事件无法完整补发时发送，客户端收到后应重新拉取全量数据
+const EventReset = "reset"
+
+// Options SSE / WebSocket 端点配置
+type Options struct {
+	Heartbeat    time.Duration // 心跳间隔：SSE 发送注释行，WebSocket 发送 Ping
+	WriteTimeout time.Duration // 单次写入超时，网络层面写不出去的客户端直接断开
+	Retry        time.Duration // SSE 断线后浏览器的重连间隔（retry: 字段）
+
+	// Authorize 判断当前用户能否订阅主题，为 nil 时只允许订阅自己的 user:<userID>
+	Authorize func(c *gin.Context, topic string) bool
+	// CheckOrigin WebSocket 的 Origin 校验，为 nil 时要求与 Host 相同
+	CheckOrigin func(r *http.Request) bool
+}
+
+// DefaultOptions 默认配置
+//
+// 心跳间隔要小于负载均衡 / Nginx 的空闲超时（通常 60s），否则没有事件时连接会被中间设备断开
+func DefaultOptions() Options {
+	return Options{
+		Heartbeat:    15 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		Retry:        3 * time.Second,
+	}
+}
+
+// QueryToken 把 ?<name>= 中的令牌转为 Authorization: Bearer 请求头，放在 JWTAuth 之前
+//
+// 浏览器的 EventSource 和 WebSocket 都不能设置自定义请求头，只能通过查询参数传递令牌；
+// 访问日志默认会把 ?token= 脱敏（见 AccessLog 示例）
+func QueryToken(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query(name); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// SSE Server-Sent Events 端点
+//
+//	GET /events/sse?topic=order:ORD001&topic=user:42
+//	Last-Event-ID: 1730000000000005   （浏览器 EventSource 重连时自动携带）
+//
+// 每个事件:
+//
+//	id: 1730000000000006
+//	event: order.status_changed
+//	data: {"id":...,"topic":"order:ORD001","type":"order.status_changed","data":{...},"time":"..."}
+func SSE(hub *Hub, options Options) gin.HandlerFunc {
+	options = withDefaults(options)
+	return func(c *gin.Context) {
+		topics, ok := resolveTopics(c, options)
+		if !ok {
+			return
+		}
+		sub, err := hub.Subscribe(topics, lastEventID(c))
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusServiceUnavailable, codeInternal, "服务正在关闭，请稍后重连")
+			return
+		}
+		defer sub.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // 关闭 Nginx 的响应缓冲
+		c.Status(http.StatusOK)
+
+		rc := http.NewResponseController(c.Writer)
+		write := func(s string) error {
+			_ = rc.SetWriteDeadline(time.Now().Add(options.WriteTimeout)) // httptest 等不支持时忽略
+			if _, err := io.WriteString(c.Writer, s); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		writeEvent := func(e Event) error {
+			data, _ := json.Marshal(e)
+			if e.ID == 0 {
+				return write(fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, data))
+			}
+			return write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data))
+		}
+
+		if err := write(fmt.Sprintf("retry: %d\n\n", options.Retry.Milliseconds())); err != nil {
+			return
+		}
+		if err := replay(sub, writeEvent); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(options.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-sub.Done():
+				// 过慢被断开或服务关闭：直接结束响应，浏览器按 retry 间隔带 Last-Event-ID 重连
+				return
+			case e := <-sub.Events():
+				if err := writeEvent(e); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := write(": ping\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// WebSocket WebSocket 端点
+//
+//	GET /events/ws?topic=order:ORD001&lastEventId=1730000000000005
+//
+// 每条文本消息是一个 Event 的 JSON；客户端自行记录最后收到的 id，重连时通过 lastEventId 传回。
+// 服务端定期发送 Ping，超过两个心跳周期没有收到任何数据（包括 Pong）视为连接已断开
+func WebSocket(hub *Hub, options Options) gin.HandlerFunc {
+	options = withDefaults(options)
+	return func(c *gin.Context) {
+		topics, ok := resolveTopics(c, options)
+		if !ok {
+			return
+		}
+		// 先订阅再升级，失败时还能返回普通的 HTTP 错误
+		sub, err := hub.Subscribe(topics, lastEventID(c))
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusServiceUnavailable, codeInternal, "服务正在关闭，请稍后重连")
+			return
+		}
+		defer sub.Close()
+
+		conn, err := Upgrade(c.Writer, c.Request, options.CheckOrigin)
+		if err != nil {
+			if errors.Is(err, ErrOriginNotAllowed) {
+				response.ErrorWithStatus(c, http.StatusForbidden, codeForbidden, "Origin 不允许")
+			} else {
+				response.ErrorWithStatus(c, http.StatusBadRequest, codeParamError, "需要 WebSocket 握手")
+			}
+			return
+		}
+		defer conn.Close()
+
+		idle := 2 * options.Heartbeat
+		_ = conn.SetReadDeadline(time.Now().Add(idle))
+		conn.SetPongHandler(func() { _ = conn.SetReadDeadline(time.Now().Add(idle)) })
+		readErr := make(chan error, 1)
+		go func() {
+			for {
+				// 客户端发来的消息目前不处理，读取是为了响应 Ping / 关闭帧并发现断线
+				if _, _, err := conn.ReadMessage(); err != nil {
+					readErr <- err
+					return
+				}
+				_ = conn.SetReadDeadline(time.Now().Add(idle))
+			}
+		}()
+
+		send := func(e Event) error {
+			data, _ := json.Marshal(e)
+			_ = conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+			return conn.WriteMessage(OpText, data)
+		}
+		if err := replay(sub, send); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(options.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-readErr:
+				return
+			case <-sub.Done():
+				_ = conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+				if errors.Is(sub.Err(), ErrSlowConsumer) {
+					_ = conn.WriteClose(ClosePolicyViolation, "too slow")
+				} else {
+					_ = conn.WriteClose(CloseGoingAway, "")
+				}
+				return
+			case e := <-sub.Events():
+				if err := send(e); err != nil {
+					return
+				}
+			case <-ticker.C:
+				_ = conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+				if err := conn.WritePing(nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// replay 先发送重置事件（如需要），再补发错过的事件
+func replay(sub *Subscription, send func(Event) error) error {
+	if sub.Truncated {
+		if err := send(Event{Type: EventReset, Data: json.RawMessage("{}")}); err != nil {
+			return err
+		}
+	}
+	for _, e := range sub.Replay {
+		if err := send(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func withDefaults(options Options) Options {
+	defaults := DefaultOptions()
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = defaults.Heartbeat
+	}
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = defaults.WriteTimeout
+	}
+	if options.Retry <= 0 {
+		options.Retry = defaults.Retry
+	}
+	if options.Authorize == nil {
+		options.Authorize = func(c *gin.Context, topic string) bool {
+			userID := c.GetString("userID")
+			return userID != "" && topic == "user:"+userID
+		}
+	}
+	return options
+}
+
+// resolveTopics 读取并校验 ?topic=，失败时已写入错误响应
+func resolveTopics(c *gin.Context, options Options) ([]string, bool) {
+	topics := c.QueryArray("topic")
+	if len(topics) == 0 || len(topics) > maxTopics {
+		response.ErrorWithStatus(c, http.StatusBadRequest, codeParamError,
+			fmt.Sprintf("需要 1~%d 个 topic 参数", maxTopics))
+		return nil, false
+	}
+	for _, topic := range topics {
+		if !options.Authorize(c, topic) {
+			response.ErrorWithStatus(c, http.StatusForbidden, codeForbidden, "无权订阅 "+topic)
+			return nil, false
+		}
+	}
+	return topics, true
+}
+
+// lastEventID 优先使用 Last-Event-ID 请求头（EventSource 重连时自动携带），其次 ?lastEventId=
+func lastEventID(c *gin.Context) uint64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	id, _ := strconv.ParseUint(value, 10, 64)
+	return id
+}
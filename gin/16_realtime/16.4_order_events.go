@@ -0,0 +1,351 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	middleware "go-learning/gin/2_middleware"
+	gormexamples "go-learning/gorm"
+)
+
+// EventOrderStatusChanged 订单状态变更事件类型
+const EventOrderStatusChanged = "order.status_changed"
+
+// OrderStatusChanged 订单状态变更事件的数据
+type OrderStatusChanged struct {
+	OrderID   int       `json:"orderID"`
+	OrderNo   string    `json:"orderNo"`
+	OldStatus *string   `json:"oldStatus,omitempty"`
+	NewStatus string    `json:"newStatus"`
+	Action    *string   `json:"action,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// OrderLogPublisher 写入 t_order_log 后推送订单状态变更的 GORM 插件
+//
+//	db.Use(realtime.NewOrderLogPublisher(hub))
+//
+// 事件同时发布到 order:<订单号> 和 user:<下单用户ID> 两个主题。
+// 回调注册在 GORM 提交默认事务之后，插入失败或回滚的日志不会被推送。
+//
+// 在 db.Transaction 等外部事务中创建的日志，回调执行时事务还没有提交，插件跳过推送并打印告警，
+// 需要在提交成功后调用 Publish 手动推送
+type OrderLogPublisher struct {
+	hub *Hub
+}
+
+// NewOrderLogPublisher 创建插件
+func NewOrderLogPublisher(hub *Hub) *OrderLogPublisher {
+	return &OrderLogPublisher{hub: hub}
+}
+
+// Name 插件名
+func (p *OrderLogPublisher) Name() string {
+	return "realtime:order_log_publisher"
+}
+
+// Initialize db.Use 时调用
+func (p *OrderLogPublisher) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:commit_or_rollback_transaction").
+		Register("realtime:publish_order_log", p.afterCreate)
+}
+
+func (p *OrderLogPublisher) afterCreate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil ||
+		db.Statement.Schema.Table != (gormexamples.OrderLog{}).TableName() {
+		return
+	}
+	logs := orderLogs(db)
+	if len(logs) == 0 {
+		return
+	}
+	// 默认事务提交后 ConnPool 会恢复为连接池，仍是事务说明处在外部事务中
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		log.Printf("[realtime] 订单 %s 的日志在外部事务中创建，未自动推送，请在提交后调用 OrderLogPublisher.Publish", logs[0].OrderNo)
+		return
+	}
+	conn := db.Session(&gorm.Session{NewDB: true, Context: db.Statement.Context})
+	if err := p.Publish(conn, logs...); err != nil {
+		log.Printf("[realtime] 推送订单状态变更失败: %v", err)
+	}
+}
+
+// Publish 推送订单日志对应的状态变更事件，用于外部事务提交之后
+func (p *OrderLogPublisher) Publish(db *gorm.DB, logs ...gormexamples.OrderLog) error {
+	userIDs := make(map[int]int)
+	for _, l := range logs {
+		userID, ok := userIDs[l.OrderID]
+		if !ok {
+			var ids []int
+			err := db.Table((gormexamples.OrderWithRelations{}).TableName()).
+				Where("id = ?", l.OrderID).Pluck("user_id", &ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) > 0 {
+				userID = ids[0]
+			}
+			userIDs[l.OrderID] = userID
+		}
+
+		data := OrderStatusChanged{
+			OrderID:   l.OrderID,
+			OrderNo:   l.OrderNo,
+			OldStatus: l.OldStatus,
+			NewStatus: l.NewStatus,
+			Action:    l.Action,
+			ChangedAt: l.CreatedAt,
+		}
+		if _, err := p.hub.Publish("order:"+l.OrderNo, EventOrderStatusChanged, data); err != nil {
+			return err
+		}
+		if userID != 0 {
+			if _, err := p.hub.Publish("user:"+strconv.Itoa(userID), EventOrderStatusChanged, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// orderLogs 从 Statement 中取出刚插入的订单日志，支持单条和批量创建
+//
+// 按列名读取字段，不要求 Dest 一定是 gormexamples.OrderLog
+func orderLogs(db *gorm.DB) []gormexamples.OrderLog {
+	stmt := db.Statement
+	rv := reflect.Indirect(stmt.ReflectValue)
+	var values []reflect.Value
+	switch rv.Kind() {
+	case reflect.Struct:
+		values = append(values, rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				values = append(values, elem)
+			}
+		}
+	default:
+		return nil // 使用 map 创建时拿不到自增 ID 等字段，不推送
+	}
+
+	get := func(rv reflect.Value, column string) any {
+		field := stmt.Schema.LookUpField(column)
+		if field == nil {
+			return nil
+		}
+		value, _ := field.ValueOf(stmt.Context, rv)
+		return value
+	}
+	logs := make([]gormexamples.OrderLog, 0, len(values))
+	for _, v := range values {
+		var l gormexamples.OrderLog
+		l.OrderID, _ = get(v, "order_id").(int)
+		l.OrderNo, _ = get(v, "order_no").(string)
+		l.OldStatus, _ = get(v, "old_status").(*string)
+		l.NewStatus, _ = get(v, "new_status").(string)
+		l.Action, _ = get(v, "action").(*string)
+		l.CreatedAt, _ = get(v, "created_at").(time.Time)
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+// RealtimeDemo 演示 SSE / WebSocket 推送订单状态
+func RealtimeDemo() {
+	fmt.Println("=== 实时推送（SSE / WebSocket）示例 ===")
+	fmt.Println()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库
+	if err := db.AutoMigrate(&gormexamples.OrderWithRelations{}, &gormexamples.OrderLog{}); err != nil {
+		fmt.Printf("迁移失败: %v\n", err)
+		return
+	}
+	order := gormexamples.OrderWithRelations{OrderNo: "ORD001", UserID: 42, OfferingID: 1, TotalPrice: 99, Status: "pending"}
+	db.Create(&order)
+
+	hub := NewHub(HubConfig{BufferSize: 4})
+	defer hub.Close()
+	publisher := NewOrderLogPublisher(hub)
+	if err := db.Use(publisher); err != nil {
+		fmt.Printf("注册插件失败: %v\n", err)
+		return
+	}
+
+	changeStatus := func(tx *gorm.DB, status string) gormexamples.OrderLog {
+		var current gormexamples.OrderWithRelations
+		tx.First(&current, order.ID)
+		oldStatus := current.Status
+		tx.Model(&current).Update("status", status)
+		entry := gormexamples.OrderLog{
+			OrderID: order.ID, OrderNo: order.OrderNo,
+			OldStatus: &oldStatus, NewStatus: status,
+		}
+		tx.Create(&entry)
+		return entry
+	}
+
+	options := DefaultOptions()
+	options.Heartbeat = 200 * time.Millisecond
+	// 允许订阅自己的用户主题和自己的订单
+	options.Authorize = func(c *gin.Context, topic string) bool {
+		userID := c.GetString("userID")
+		if topic == "user:"+userID {
+			return true
+		}
+		orderNo, ok := strings.CutPrefix(topic, "order:")
+		if !ok {
+			return false
+		}
+		var count int64
+		db.WithContext(c.Request.Context()).Model(&gormexamples.OrderWithRelations{}).
+			Where("order_no = ? AND user_id = ?", orderNo, userID).Count(&count)
+		return count > 0
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	events := router.Group("/api/events", QueryToken("token"), middleware.JWTAuth())
+	events.GET("/sse", SSE(hub, options))
+	events.GET("/ws", WebSocket(hub, options))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token, _ := middleware.GenerateToken("42", []string{"user"})
+	otherToken, _ := middleware.GenerateToken("7", []string{"user"})
+
+	fmt.Println("1. 鉴权：未登录 401，订阅别人的订单 403")
+	for _, query := range []string{"topic=order:ORD001", "topic=order:ORD001&token=" + otherToken} {
+		resp, err := http.Get(server.URL + "/api/events/sse?" + query)
+		if err != nil {
+			fmt.Printf("   请求失败: %v\n", err)
+			return
+		}
+		resp.Body.Close()
+		fmt.Printf("   -> %d\n", resp.StatusCode)
+	}
+	fmt.Println()
+
+	fmt.Println("2. SSE：订阅 order:ORD001，写入订单日志后收到推送")
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events/sse?topic=order:ORD001", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("   请求失败: %v\n", err)
+		cancel()
+		return
+	}
+	fmt.Printf("   Content-Type: %s\n", resp.Header.Get("Content-Type"))
+	lines := make(chan string, 32)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	changeStatus(db, "paid")
+	var lastID string
+	for line := range lines {
+		if line == "" {
+			continue
+		}
+		fmt.Printf("   %s\n", line)
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			lastID = id
+		}
+		if strings.HasPrefix(line, "data: ") {
+			break
+		}
+	}
+	// 等到下一次心跳
+	for line := range lines {
+		if line != "" {
+			fmt.Printf("   %s（心跳）\n", line)
+			break
+		}
+	}
+	cancel()
+	resp.Body.Close()
+	fmt.Println()
+
+	fmt.Println("3. 断线期间状态继续变化，带 Last-Event-ID 重连补发")
+	changeStatus(db, "shipped")
+	ws, _, err := Dial(strings.Replace(server.URL, "http", "ws", 1)+
+		"/api/events/ws?topic=order:ORD001&lastEventId="+lastID+"&token="+token, nil)
+	if err != nil {
+		fmt.Printf("   WebSocket 连接失败: %v\n", err)
+		return
+	}
+	readEvent := func() (Event, error) {
+		var e Event
+		_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return e, err
+		}
+		err = json.Unmarshal(data, &e)
+		return e, err
+	}
+	printEvent := func(e Event) {
+		var data OrderStatusChanged
+		_ = json.Unmarshal(e.Data, &data)
+		fmt.Printf("   [ws] id=%d topic=%s %s -> %s\n", e.ID, e.Topic, *data.OldStatus, data.NewStatus)
+	}
+	if e, err := readEvent(); err == nil {
+		printEvent(e)
+	}
+	fmt.Println()
+
+	fmt.Println("4. 外部事务中的日志在提交后手动推送")
+	var pending gormexamples.OrderLog
+	err = db.Transaction(func(tx *gorm.DB) error {
+		pending = changeStatus(tx, "completed")
+		return nil
+	})
+	if err == nil {
+		_ = publisher.Publish(db, pending)
+	}
+	if e, err := readEvent(); err == nil {
+		printEvent(e)
+	}
+	fmt.Println()
+
+	_ = ws.WriteClose(CloseNormal, "")
+	ws.Close()
+
+	fmt.Println("5. 背压：订阅者不读取，缓冲区写满后被断开，发布方不受影响")
+	slow, _ := hub.Subscribe([]string{"user:42"}, 0)
+	for i := 0; i < 8; i++ {
+		_, _ = hub.Publish("user:42", "demo.tick", gin.H{"n": i})
+	}
+	<-slow.Done()
+	fmt.Printf("   订阅结束: %v（WebSocket 端点随后发送 %d 关闭帧）\n", slow.Err(), ClosePolicyViolation)
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 浏览器的 EventSource / WebSocket 不能设置请求头，令牌通过 ?token= 传递")
+	fmt.Println("  - 心跳间隔小于代理的空闲超时，Nginx 需要关闭 proxy_buffering")
+	fmt.Println("  - 慢客户端直接断开并依赖 Last-Event-ID 补发，收到 reset 事件时重新拉取全量数据")
+	fmt.Println("  - 多实例部署时各实例的 Hub 独立，需要借助 Redis Pub/Sub 等广播事件")
+}
@@ -0,0 +1,407 @@
+package realtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	middleware "go-learning/gin/2_middleware"
+	gormexamples "go-learning/gorm"
+)
+
+// TestHub 测试发布、补发、截断和慢消费者
+func TestHub(t *testing.T) {
+	t.Run("实时事件与补发", func(t *testing.T) {
+		hub := NewHub(DefaultHubConfig())
+		first, err := hub.Publish("order:A", "created", gin.H{"n": 1})
+		require.NoError(t, err)
+		hub.Publish("order:B", "created", gin.H{"n": 2})
+		hub.Publish("order:A", "paid", gin.H{"n": 3})
+
+		sub, err := hub.Subscribe([]string{"order:A", "order:B"}, first.ID)
+		require.NoError(t, err)
+		defer sub.Close()
+		assert.False(t, sub.Truncated)
+		require.Len(t, sub.Replay, 2)
+		assert.Equal(t, "order:B", sub.Replay[0].Topic, "多个主题按 ID 合并")
+		assert.Equal(t, "paid", sub.Replay[1].Type)
+
+		live, _ := hub.Publish("order:B", "shipped", nil)
+		assert.Equal(t, live, <-sub.Events())
+		assert.Equal(t, 1, hub.Subscribers())
+
+		fresh, _ := hub.Subscribe([]string{"order:A"}, 0)
+		assert.Empty(t, fresh.Replay, "首次连接不补发")
+		fresh.Close()
+		fresh.Close()
+		assert.Equal(t, 1, hub.Subscribers())
+	})
+
+	t.Run("历史不足时标记截断", func(t *testing.T) {
+		hub := NewHub(HubConfig{HistorySize: 2, HistoryTTL: time.Minute})
+		now := time.Now()
+		hub.now = func() time.Time { return now }
+		var ids []uint64
+		for i := 0; i < 4; i++ {
+			e, _ := hub.Publish("order:A", "tick", i)
+			ids = append(ids, e.ID)
+		}
+
+		sub, _ := hub.Subscribe([]string{"order:A"}, ids[0])
+		assert.True(t, sub.Truncated, "ids[1] 已被移出历史")
+		assert.Len(t, sub.Replay, 2)
+		sub.Close()
+
+		sub, _ = hub.Subscribe([]string{"order:A"}, ids[1])
+		assert.False(t, sub.Truncated)
+		assert.Len(t, sub.Replay, 2)
+		sub.Close()
+
+		sub, _ = hub.Subscribe([]string{"order:A"}, 1)
+		assert.True(t, sub.Truncated, "重启之前的 ID")
+		sub.Close()
+
+		sub, _ = hub.Subscribe([]string{"order:A"}, ids[3]+100)
+		assert.True(t, sub.Truncated, "未知的 ID")
+		assert.Empty(t, sub.Replay, "无法判断错过了哪些事件，不补发")
+		sub.Close()
+
+		now = now.Add(2 * time.Minute)
+		hub.Publish("order:A", "tick", 4)
+		sub, _ = hub.Subscribe([]string{"order:A"}, ids[2])
+		assert.True(t, sub.Truncated, "过期的历史被清理")
+		assert.Len(t, sub.Replay, 1)
+		sub.Close()
+	})
+
+	t.Run("空闲主题被删除后仍能判断截断", func(t *testing.T) {
+		hub := NewHub(HubConfig{HistoryTTL: time.Minute})
+		now := time.Now()
+		hub.now = func() time.Time { return now }
+		old, _ := hub.Publish("order:A", "created", nil)
+		last, _ := hub.Publish("order:A", "paid", nil)
+
+		// order:A 的历史过期，清理时主题随之删除
+		now = now.Add(2 * time.Minute)
+		hub.mu.Lock()
+		hub.sweep(now)
+		_, exists := hub.topics["order:A"]
+		hub.mu.Unlock()
+		require.False(t, exists)
+
+		sub, _ := hub.Subscribe([]string{"order:A"}, old.ID)
+		assert.True(t, sub.Truncated, "错过的 paid 已随主题删除")
+		assert.Empty(t, sub.Replay)
+		sub.Close()
+
+		sub, _ = hub.Subscribe([]string{"order:A"}, last.ID)
+		assert.False(t, sub.Truncated, "没有错过任何事件")
+		sub.Close()
+	})
+
+	t.Run("重复的主题只订阅一次", func(t *testing.T) {
+		hub := NewHub(DefaultHubConfig())
+		first, _ := hub.Publish("order:A", "created", nil)
+		hub.Publish("order:A", "paid", nil)
+
+		sub, err := hub.Subscribe([]string{"order:A", "order:A"}, first.ID)
+		require.NoError(t, err)
+		defer sub.Close()
+		assert.Len(t, sub.Replay, 1)
+		assert.Equal(t, []string{"order:A"}, sub.topics)
+	})
+
+	t.Run("慢消费者被断开，不影响其他订阅者", func(t *testing.T) {
+		hub := NewHub(HubConfig{BufferSize: 2})
+		slow, _ := hub.Subscribe([]string{"user:1"}, 0)
+		fast, _ := hub.Subscribe([]string{"user:1"}, 0)
+		defer fast.Close()
+		for i := 0; i < 3; i++ {
+			hub.Publish("user:1", "tick", i)
+			<-fast.Events()
+		}
+		<-slow.Done()
+		assert.ErrorIs(t, slow.Err(), ErrSlowConsumer)
+		assert.Equal(t, uint64(1), hub.Dropped())
+		assert.Equal(t, 1, hub.Subscribers())
+	})
+
+	t.Run("关闭", func(t *testing.T) {
+		hub := NewHub(DefaultHubConfig())
+		sub, _ := hub.Subscribe([]string{"user:1"}, 0)
+		hub.Close()
+		<-sub.Done()
+		assert.ErrorIs(t, sub.Err(), ErrHubClosed)
+		_, err := hub.Publish("user:1", "tick", nil)
+		assert.ErrorIs(t, err, ErrHubClosed)
+		_, err = hub.Subscribe([]string{"user:1"}, 0)
+		assert.ErrorIs(t, err, ErrHubClosed)
+	})
+}
+
+func newServer(t *testing.T, hub *Hub) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	options := Options{Heartbeat: 50 * time.Millisecond}
+	events := router.Group("/events", QueryToken("token"), middleware.JWTAuth())
+	events.GET("/sse", SSE(hub, options))
+	events.GET("/ws", WebSocket(hub, options))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func bearer(t *testing.T, userID string) string {
+	token, err := middleware.GenerateToken(userID, []string{"user"})
+	require.NoError(t, err)
+	return token
+}
+
+// TestSSE 测试 SSE 端点
+func TestSSE(t *testing.T) {
+	hub := NewHub(DefaultHubConfig())
+	server := newServer(t, hub)
+	token := bearer(t, "42")
+
+	get := func(query string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events/sse?"+query, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("鉴权与参数校验", func(t *testing.T) {
+		for query, status := range map[string]int{
+			"topic=user:42":                        http.StatusUnauthorized,
+			"token=" + token:                       http.StatusBadRequest,
+			"topic=user:7&token=" + token:          http.StatusForbidden,
+			"topic=user:42&topic=x&token=" + token: http.StatusForbidden,
+		} {
+			resp := get(query, nil)
+			resp.Body.Close()
+			assert.Equal(t, status, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("推送、心跳与 Last-Event-ID", func(t *testing.T) {
+		missed, _ := hub.Publish("user:42", "order.status_changed", gin.H{"status": "paid"})
+		resp := get("topic=user:42", http.Header{
+			"Authorization": {"Bearer " + token},
+			"Last-Event-Id": {strconv.FormatUint(missed.ID-1, 10)},
+		})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "no", resp.Header.Get("X-Accel-Buffering"))
+
+		scanner := bufio.NewScanner(resp.Body)
+		next := func() string {
+			for scanner.Scan() {
+				if scanner.Text() != "" {
+					return scanner.Text()
+				}
+			}
+			return ""
+		}
+		assert.Equal(t, "retry: 3000", next())
+		assert.Equal(t, "id: "+strconv.FormatUint(missed.ID, 10), next(), "补发断线期间的事件")
+		assert.Equal(t, "event: order.status_changed", next())
+		assert.Contains(t, next(), `"data":{"status":"paid"}`)
+
+		live, _ := hub.Publish("user:42", "order.status_changed", gin.H{"status": "shipped"})
+		assert.Equal(t, "id: "+strconv.FormatUint(live.ID, 10), next())
+		next()
+		next()
+		assert.Equal(t, ": ping", next())
+	})
+
+	t.Run("过慢或关闭时结束响应", func(t *testing.T) {
+		resp := get("topic=user:42&token="+token, nil)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		hub.Close()
+		done := make(chan struct{})
+		go func() {
+			var buf bytes.Buffer
+			_, _ = buf.ReadFrom(resp.Body)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Hub 关闭后连接没有结束")
+		}
+	})
+}
+
+// TestWebSocket 测试 WebSocket 端点
+func TestWebSocket(t *testing.T) {
+	hub := NewHub(DefaultHubConfig())
+	server := newServer(t, hub)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws"
+	token := bearer(t, "42")
+
+	t.Run("握手失败返回 HTTP 错误", func(t *testing.T) {
+		_, resp, err := Dial(wsURL+"?topic=user:42", nil)
+		assert.ErrorIs(t, err, ErrBadHandshake)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		_, resp, err = Dial(wsURL+"?topic=user:42&token="+token, http.Header{"Origin": {"http://evil.example"}})
+		assert.ErrorIs(t, err, ErrBadHandshake)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp, err = http.Get(server.URL + "/events/ws?topic=user:42&token=" + token)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "不是 WebSocket 握手")
+		assert.Equal(t, 0, hub.Subscribers())
+	})
+
+	t.Run("推送、心跳与断线补发", func(t *testing.T) {
+		old, _ := hub.Publish("user:42", "tick", 0)
+		missed, _ := hub.Publish("user:42", "tick", 1)
+		conn, resp, err := Dial(wsURL+"?topic=user:42&lastEventId="+strconv.FormatUint(old.ID, 10),
+			http.Header{"Authorization": {"Bearer " + token}})
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+		pongs := make(chan struct{}, 1)
+		read := func() Event {
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			opcode, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, OpText, opcode)
+			var e Event
+			require.NoError(t, json.Unmarshal(data, &e))
+			return e
+		}
+		assert.Equal(t, missed.ID, read().ID)
+
+		live, _ := hub.Publish("user:42", "tick", 2)
+		e := read()
+		assert.Equal(t, live.ID, e.ID)
+		assert.JSONEq(t, "2", string(e.Data))
+
+		// 服务端的 Ping 由 ReadMessage 自动回复；客户端的 Ping 服务端回复 Pong
+		conn.SetPongHandler(func() { pongs <- struct{}{} })
+		require.NoError(t, conn.WritePing([]byte("hi")))
+		go func() { _, _, _ = conn.ReadMessage() }()
+		select {
+		case <-pongs:
+		case <-time.After(2 * time.Second):
+			t.Fatal("没有收到 Pong")
+		}
+	})
+
+	t.Run("历史不足时先发送 reset", func(t *testing.T) {
+		conn, _, err := Dial(wsURL+"?topic=user:42&lastEventId=1&token="+token, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		var e Event
+		require.NoError(t, json.Unmarshal(data, &e))
+		assert.Equal(t, EventReset, e.Type)
+	})
+
+	t.Run("Hub 关闭时发送关闭帧", func(t *testing.T) {
+		conn, _, err := Dial(wsURL+"?topic=user:42&token="+token, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Eventually(t, func() bool { return hub.Subscribers() > 0 }, time.Second, 10*time.Millisecond)
+		hub.Close()
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, _, err = conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		var closeErr *CloseError
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, CloseGoingAway, closeErr.Code)
+	})
+}
+
+// TestOrderLogPublisher 测试写入订单日志后推送
+func TestOrderLogPublisher(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库，测试中只用一个连接
+	require.NoError(t, db.AutoMigrate(&gormexamples.OrderWithRelations{}, &gormexamples.OrderLog{}))
+	order := gormexamples.OrderWithRelations{OrderNo: "ORD001", UserID: 42, OfferingID: 1, TotalPrice: 10}
+	require.NoError(t, db.Create(&order).Error)
+
+	hub := NewHub(DefaultHubConfig())
+	publisher := NewOrderLogPublisher(hub)
+	require.NoError(t, db.Use(publisher))
+	byOrder, _ := hub.Subscribe([]string{"order:ORD001"}, 0)
+	byUser, _ := hub.Subscribe([]string{"user:42"}, 0)
+
+	oldStatus := "pending"
+	require.NoError(t, db.WithContext(context.Background()).Create(&gormexamples.OrderLog{
+		OrderID: order.ID, OrderNo: order.OrderNo, OldStatus: &oldStatus, NewStatus: "paid",
+	}).Error)
+	e := <-byOrder.Events()
+	assert.Equal(t, EventOrderStatusChanged, e.Type)
+	var data OrderStatusChanged
+	require.NoError(t, json.Unmarshal(e.Data, &data))
+	assert.Equal(t, "paid", data.NewStatus)
+	assert.Equal(t, "pending", *data.OldStatus)
+	assert.Equal(t, e.Data, (<-byUser.Events()).Data)
+
+	t.Run("批量创建", func(t *testing.T) {
+		logs := []*gormexamples.OrderLog{
+			{OrderID: order.ID, OrderNo: order.OrderNo, NewStatus: "shipped"},
+			{OrderID: order.ID, OrderNo: order.OrderNo, NewStatus: "completed"},
+		}
+		require.NoError(t, db.Create(&logs).Error)
+		assert.Contains(t, string((<-byOrder.Events()).Data), "shipped")
+		assert.Contains(t, string((<-byOrder.Events()).Data), "completed")
+		<-byUser.Events()
+		<-byUser.Events()
+	})
+
+	t.Run("外部事务中不自动推送", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(out)
+
+		entry := gormexamples.OrderLog{OrderID: order.ID, OrderNo: order.OrderNo, NewStatus: "refunded"}
+		require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&entry).Error
+		}))
+		assert.Contains(t, buf.String(), "外部事务")
+		assert.Empty(t, byOrder.Events())
+
+		require.NoError(t, publisher.Publish(db, entry))
+		assert.Contains(t, string((<-byOrder.Events()).Data), "refunded")
+	})
+
+	t.Run("插入失败不推送", func(t *testing.T) {
+		err := db.Create(&gormexamples.OrderLog{ID: 1, OrderID: order.ID, OrderNo: order.OrderNo, NewStatus: "paid"}).Error
+		assert.Error(t, err, "主键冲突")
+		assert.Empty(t, byOrder.Events())
+	})
+}
@@ -0,0 +1,222 @@
+package csrf
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+	"go-learning/gin/internal/httpheader"
+)
+
+// codeForbidden 权限不足
+const codeForbidden = 1003
+
+// gin.Context 中的键
+const (
+	contextKey = "csrf.token"   // 当前请求的原始令牌
+	optionsKey = "csrf.options" // 中间件配置，供 Rotate 设置 Cookie
+)
+
+// Options CSRF 中间件配置
+type Options struct {
+	// Secret 非空时对 Cookie 签名，防止子域写入伪造的 Cookie；多实例部署时所有实例使用同一个密钥
+	Secret []byte
+
+	CookieName string
+	Path       string
+	Domain     string
+	MaxAge     time.Duration
+	Secure     bool          // 仅 HTTPS 发送；SameSite=None 时强制为 true
+	SameSite   http.SameSite // Lax 已能挡住大部分跨站 POST，Strict 下从外站链接进入时不带 Cookie
+
+	HeaderName string // AJAX 请求通过请求头提交令牌
+	FieldName  string // 表单通过隐藏字段提交令牌
+
+	// TrustedOrigins 允许跨域提交的来源，如 https://admin.example.com，与 Host 相同的来源总是允许
+	TrustedOrigins []string
+	// ExemptBearer 带 Authorization: Bearer 的请求不校验：浏览器不会自动附带该请求头，不存在 CSRF
+	ExemptBearer bool
+	// Skip 返回 true 的请求不校验，如第三方回调（应使用签名等其他方式鉴权）
+	Skip func(c *gin.Context) bool
+	// ErrorHandler 校验失败时的响应，为 nil 时返回 403 JSON
+	ErrorHandler func(c *gin.Context, err error)
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		CookieName:   "_csrf",
+		Path:         "/",
+		MaxAge:       12 * time.Hour,
+		SameSite:     http.SameSiteLaxMode,
+		HeaderName:   "X-CSRF-Token",
+		FieldName:    "_csrf",
+		ExemptBearer: true,
+	}
+}
+
+// Middleware CSRF 防护中间件（双重提交 Cookie）
+//
+// 每个浏览器持有一个随机令牌，保存在 HttpOnly Cookie 中；页面通过 Token / TemplateField
+// 把令牌（每次渲染都重新掩码）写入表单或 meta 标签，提交时放在表单字段或请求头中。
+// 非安全方法（POST、PUT、PATCH、DELETE 等）的请求需要满足:
+//  1. Origin（没有时为 Referer）与 Host 相同或在 TrustedOrigins 中
+//  2. 提交的令牌与 Cookie 中的令牌一致
+//
+// 跨站页面可以让浏览器带上 Cookie，但读不到 Cookie 和页面内容，因此无法提交正确的令牌
+func Middleware(options Options) gin.HandlerFunc {
+	defaults := DefaultOptions()
+	if options.CookieName == "" {
+		options.CookieName = defaults.CookieName
+	}
+	if options.Path == "" {
+		options.Path = defaults.Path
+	}
+	if options.MaxAge <= 0 {
+		options.MaxAge = defaults.MaxAge
+	}
+	if options.SameSite == 0 {
+		options.SameSite = defaults.SameSite
+	}
+	if options.SameSite == http.SameSiteNoneMode {
+		options.Secure = true // 浏览器会拒绝没有 Secure 的 SameSite=None Cookie
+	}
+	if options.HeaderName == "" {
+		options.HeaderName = defaults.HeaderName
+	}
+	if options.FieldName == "" {
+		options.FieldName = defaults.FieldName
+	}
+	if options.ErrorHandler == nil {
+		options.ErrorHandler = func(c *gin.Context, err error) {
+			response.ErrorWithStatus(c, http.StatusForbidden, codeForbidden, "CSRF 校验失败，请刷新页面后重试")
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Set(optionsKey, &options)
+
+		var token []byte
+		if cookie, err := c.Cookie(options.CookieName); err == nil {
+			token = decodeCookie(cookie, options.Secret)
+		}
+		if token == nil {
+			// 首次访问或 Cookie 无效：签发新令牌；这种请求如果是非安全方法，下面的校验一定失败
+			token = newToken()
+			setCookie(c, &options, token)
+		}
+		c.Set(contextKey, token)
+
+		if safeMethod(c.Request.Method) || (options.Skip != nil && options.Skip(c)) ||
+			(options.ExemptBearer && isBearer(c.GetHeader("Authorization"))) {
+			c.Next()
+			return
+		}
+
+		if err := verify(c, &options, token); err != nil {
+			_ = c.Error(err)
+			options.ErrorHandler(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// verify 校验来源和令牌
+func verify(c *gin.Context, options *Options, token []byte) error {
+	if !trustedSource(c.Request, options.TrustedOrigins) {
+		return ErrOriginMismatch
+	}
+	submitted := c.GetHeader(options.HeaderName)
+	if submitted == "" {
+		submitted = c.PostForm(options.FieldName)
+	}
+	if submitted == "" {
+		return ErrTokenMissing
+	}
+	if !equal(unmask(submitted), token) {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+// trustedSource Origin 优先，没有时检查 Referer；两者都没有时（部分老旧客户端）只依赖令牌
+func trustedSource(r *http.Request, trusted []string) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return true
+		}
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return false
+		}
+		source = u.Scheme + "://" + u.Host
+	}
+	if source == "null" {
+		return false // 沙箱 iframe、data: 页面等
+	}
+	for _, origin := range trusted {
+		if strings.EqualFold(source, origin) {
+			return true
+		}
+	}
+	_, host, ok := strings.Cut(source, "://")
+	return ok && strings.EqualFold(host, r.Host)
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isBearer(authorization string) bool {
+	scheme, _, ok := strings.Cut(authorization, " ")
+	return ok && strings.EqualFold(scheme, "Bearer")
+}
+
+func setCookie(c *gin.Context, options *Options, token []byte) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     options.CookieName,
+		Value:    encodeCookie(token, options.Secret),
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   int(options.MaxAge.Seconds()),
+		Secure:   options.Secure,
+		HttpOnly: true, // 页面脚本从 meta 标签或表单中读取令牌，不需要读 Cookie
+		SameSite: options.SameSite,
+	})
+}
+
+// Token 返回当前请求的令牌（每次调用都重新掩码），用于表单、meta 标签或 JSON 响应
+//
+// 没有经过 Middleware 时返回空字符串。页面内容因用户而异，同时设置 Vary: Cookie，
+// 避免被共享缓存返回给其他用户
+func Token(c *gin.Context) string {
+	token, ok := c.Get(contextKey)
+	if !ok {
+		return ""
+	}
+	httpheader.AddVary(c.Writer.Header(), "Cookie")
+	return mask(token.([]byte))
+}
+
+// Rotate 更换令牌，登录、退出或权限变化后调用，防止登录前泄露的令牌继续有效
+func Rotate(c *gin.Context) {
+	value, ok := c.Get(optionsKey)
+	if !ok {
+		return
+	}
+	token := newToken()
+	setCookie(c, value.(*Options), token)
+	c.Set(contextKey, token)
+}
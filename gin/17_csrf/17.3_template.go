@@ -0,0 +1,155 @@
+package csrf
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	middleware "go-learning/gin/2_middleware"
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// TemplateField 表单中的隐藏字段
+//
+//	<form method="POST">{{ .csrfField }} ...</form>
+func TemplateField(c *gin.Context) template.HTML {
+	return hiddenField(c, Token(c))
+}
+
+func hiddenField(c *gin.Context, token string) template.HTML {
+	name := DefaultOptions().FieldName
+	if value, ok := c.Get(optionsKey); ok {
+		name = value.(*Options).FieldName
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(name), token))
+}
+
+// H 在模板数据中加入 csrfToken 和 csrfField，配合 LoadHTMLGlob / SetHTMLTemplate 加载的模板使用
+//
+//	c.HTML(http.StatusOK, "register.tmpl", csrf.H(c, gin.H{"title": "注册"}))
+//
+// 模板中:
+//
+//	<meta name="csrf-token" content="{{ .csrfToken }}">   AJAX 从这里读取，放在 X-CSRF-Token 请求头中
+//	<form method="POST">{{ .csrfField }} ...</form>
+func H(c *gin.Context, data gin.H) gin.H {
+	if data == nil {
+		data = gin.H{}
+	}
+	token := Token(c)
+	data["csrfToken"] = token
+	data["csrfField"] = hiddenField(c, token)
+	return data
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// CSRFDemo 演示表单的 CSRF 防护
+func CSRFDemo() {
+	fmt.Println("=== CSRF 防护示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	// 真实项目中通常是 router.LoadHTMLGlob("templates/*")，这里使用嵌入的模板，不依赖工作目录
+	router.SetHTMLTemplate(template.Must(template.ParseFS(templateFS, "templates/*.tmpl")))
+
+	options := DefaultOptions()
+	options.Secret = []byte("demo-csrf-secret")
+	router.Use(Middleware(options))
+
+	type UserForm struct {
+		Name     string `form:"name" binding:"required"`
+		Email    string `form:"email" binding:"required,email"`
+		Password string `form:"password" binding:"required,min=6"`
+	}
+	router.GET("/register", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "register.tmpl", H(c, gin.H{"title": "注册"}))
+	})
+	router.POST("/register-form", func(c *gin.Context) {
+		var form UserForm
+		if err := c.ShouldBind(&form); err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, 1001, err.Error())
+			return
+		}
+		response.Success(c, gin.H{"name": form.Name})
+	})
+	router.POST("/login", func(c *gin.Context) {
+		Rotate(c) // 登录后更换令牌
+		response.Success(c, gin.H{"csrfToken": Token(c)})
+	})
+	// 移动端等使用 Bearer 令牌的接口
+	router.POST("/api/orders", middleware.JWTAuth(), func(c *gin.Context) {
+		response.Success(c, gin.H{"userID": c.GetString("userID")})
+	})
+
+	send := func(title string, req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		body := strings.TrimSpace(w.Body.String())
+		if strings.HasPrefix(body, "<!DOCTYPE") {
+			body = fmt.Sprintf("HTML %d 字节", len(body))
+		}
+		fmt.Printf("%s\n  %s %s -> %d %s\n", title, req.Method, req.URL.Path, w.Code, body)
+		return w
+	}
+	form := url.Values{"name": {"张三"}, "email": {"zhangsan@example.com"}, "password": {"123456"}}
+	post := func(values url.Values, cookie string, header map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/register-form", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", "http://example.com")
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	w := send("1. 渲染表单，签发 Cookie，隐藏字段中带令牌", httptest.NewRequest(http.MethodGet, "/register", nil))
+	setCookie := w.Header().Get("Set-Cookie")
+	cookie, _, _ := strings.Cut(setCookie, ";")
+	token := regexp.MustCompile(`name="_csrf" value="([^"]+)"`).FindStringSubmatch(w.Body.String())[1]
+	fmt.Printf("  Set-Cookie: %s...\n", setCookie[:min(len(setCookie), 40)])
+	fmt.Printf("  隐藏字段: _csrf=%s...\n", token[:16])
+	fmt.Println()
+
+	send("2. 跨站页面伪造的表单（浏览器自动带 Cookie，但拿不到令牌）", post(form, cookie, nil))
+
+	withToken := url.Values{"_csrf": {token}}
+	for k, v := range form {
+		withToken[k] = v
+	}
+	send("3. 本站表单提交", post(withToken, cookie, nil))
+	send("4. 令牌正确但来自其他站点", post(withToken, cookie, map[string]string{"Origin": "https://evil.example"}))
+	send("5. AJAX 通过请求头提交令牌", post(form, cookie, map[string]string{"X-CSRF-Token": token}))
+	send("6. 没有 Cookie（Cookie 过期或被篡改）", post(withToken, "", nil))
+
+	bearer, _ := middleware.GenerateToken("42", []string{"user"})
+	api := httptest.NewRequest(http.MethodPost, "/api/orders", nil)
+	api.Header.Set("Authorization", "Bearer "+bearer)
+	send("7. Bearer 令牌的接口不需要 CSRF 令牌", api)
+
+	login := post(url.Values{"_csrf": {token}}, cookie, nil)
+	login.URL.Path = "/login"
+	w = send("8. 登录后更换令牌", login)
+	newCookie, _, _ := strings.Cut(w.Header().Get("Set-Cookie"), ";")
+	send("9. 登录前的令牌失效", post(withToken, newCookie, nil))
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - SameSite=Lax 挡住跨站 POST，令牌校验兼容不支持 SameSite 的浏览器和同站子域的攻击")
+	fmt.Println("  - 每次渲染的令牌都重新掩码，防止 BREACH；Cookie 用 Secret 签名，防止子域写入")
+	fmt.Println("  - 只对 POST / PUT / PATCH / DELETE 校验，GET 请求不能有副作用")
+	fmt.Println("  - 嵌入令牌的页面设置了 Vary: Cookie，不会被共享缓存返回给其他用户")
+}
@@ -0,0 +1,200 @@
+package csrf
+
+import (
+	"bytes"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouter(options Options) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(options))
+	router.GET("/form", func(c *gin.Context) {
+		c.String(http.StatusOK, Token(c))
+	})
+	router.POST("/submit", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok:"+c.PostForm("name"))
+	})
+	router.POST("/login", func(c *gin.Context) {
+		Rotate(c)
+		c.String(http.StatusOK, Token(c))
+	})
+	return router
+}
+
+// session 访问表单页，返回 Cookie 和页面中的令牌
+func session(t *testing.T, router http.Handler) (cookie, token string) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookie, _, _ = strings.Cut(w.Header().Get("Set-Cookie"), ";")
+	return cookie, w.Body.String()
+}
+
+func submit(router http.Handler, cookie string, form url.Values, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestMiddleware 测试令牌校验
+func TestMiddleware(t *testing.T) {
+	options := DefaultOptions()
+	options.Secret = []byte("secret")
+	options.TrustedOrigins = []string{"https://admin.example.com"}
+	router := newRouter(options)
+	cookie, token := session(t, router)
+
+	t.Run("Cookie 属性", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+		set := w.Header().Get("Set-Cookie")
+		assert.Contains(t, set, "HttpOnly")
+		assert.Contains(t, set, "SameSite=Lax")
+		assert.Contains(t, set, "Path=/")
+		assert.Equal(t, "Cookie", w.Header().Get("Vary"))
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/form", nil)
+		req.Header.Set("Cookie", cookie)
+		router.ServeHTTP(w, req)
+		assert.Empty(t, w.Header().Get("Set-Cookie"), "已有有效 Cookie 时不重复签发")
+		assert.NotEqual(t, token, w.Body.String(), "每次渲染的令牌不同")
+		assert.Equal(t, http.StatusOK, submit(router, cookie, url.Values{"_csrf": {w.Body.String()}}, nil).Code)
+	})
+
+	form := url.Values{"name": {"john"}, "_csrf": {token}}
+	cases := []struct {
+		name   string
+		cookie string
+		form   url.Values
+		header map[string]string
+		status int
+	}{
+		{"表单字段", cookie, form, nil, http.StatusOK},
+		{"请求头", cookie, url.Values{"name": {"john"}}, map[string]string{"X-CSRF-Token": token}, http.StatusOK},
+		{"同源 Origin", cookie, form, map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{"可信来源", cookie, form, map[string]string{"Origin": "https://admin.example.com"}, http.StatusOK},
+		{"同源 Referer", cookie, form, map[string]string{"Referer": "http://example.com/register"}, http.StatusOK},
+		{"缺少令牌", cookie, url.Values{"name": {"john"}}, nil, http.StatusForbidden},
+		{"令牌错误", cookie, url.Values{"_csrf": {mask(newToken())}}, nil, http.StatusForbidden},
+		{"令牌格式错误", cookie, url.Values{"_csrf": {"abc"}}, nil, http.StatusForbidden},
+		{"没有 Cookie", "", form, nil, http.StatusForbidden},
+		{"Cookie 签名错误", "_csrf=" + strings.Split(strings.TrimPrefix(cookie, "_csrf="), ".")[0] + ".AAAA", form, nil, http.StatusForbidden},
+		{"跨站 Origin", cookie, form, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"null Origin", cookie, form, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"跨站 Referer", cookie, form, map[string]string{"Referer": "https://evil.example/x"}, http.StatusForbidden},
+		{"Bearer 免校验", "", url.Values{"name": {"john"}}, map[string]string{"Authorization": "Bearer abc"}, http.StatusOK},
+		{"Basic 不免校验", "", url.Values{"name": {"john"}}, map[string]string{"Authorization": "Basic abc"}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := submit(router, tc.cookie, tc.form, tc.header)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.status == http.StatusForbidden {
+				assert.JSONEq(t, `{"code":1003,"data":null,"message":"CSRF 校验失败，请刷新页面后重试"}`, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("multipart 表单", func(t *testing.T) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("_csrf", token)
+		_ = mw.WriteField("name", "john")
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/submit", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Cookie", cookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "ok:john", w.Body.String())
+	})
+
+	t.Run("Rotate 后旧令牌失效", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Cookie", cookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		newCookie, _, _ := strings.Cut(w.Header().Get("Set-Cookie"), ";")
+		assert.NotEqual(t, cookie, newCookie)
+
+		assert.Equal(t, http.StatusForbidden, submit(router, newCookie, form, nil).Code)
+		assert.Equal(t, http.StatusOK, submit(router, newCookie, url.Values{"_csrf": {w.Body.String()}}, nil).Code)
+	})
+}
+
+// TestOptions 测试配置项
+func TestOptions(t *testing.T) {
+	t.Run("SameSite=None 强制 Secure", func(t *testing.T) {
+		router := newRouter(Options{SameSite: http.SameSiteNoneMode})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+		set := w.Header().Get("Set-Cookie")
+		assert.Contains(t, set, "SameSite=None")
+		assert.Contains(t, set, "Secure")
+	})
+
+	t.Run("未签名 Cookie、Skip 与自定义错误", func(t *testing.T) {
+		var got error
+		router := newRouter(Options{
+			CookieName: "xsrf",
+			Skip:       func(c *gin.Context) bool { return c.GetHeader("X-Webhook-Signature") != "" },
+			ErrorHandler: func(c *gin.Context, err error) {
+				got = err
+				c.String(http.StatusBadRequest, "bad")
+			},
+		})
+		cookie, token := session(t, router)
+		assert.True(t, strings.HasPrefix(cookie, "xsrf="))
+		assert.NotContains(t, cookie, ".", "未设置 Secret 时不签名")
+		assert.Equal(t, http.StatusOK, submit(router, cookie, url.Values{"_csrf": {token}}, nil).Code)
+		assert.Equal(t, http.StatusOK, submit(router, "", nil, map[string]string{"X-Webhook-Signature": "sig"}).Code)
+
+		w := submit(router, cookie, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.ErrorIs(t, got, ErrTokenMissing)
+		submit(router, cookie, url.Values{"_csrf": {token}}, map[string]string{"Origin": "https://evil.example"})
+		assert.ErrorIs(t, got, ErrOriginMismatch)
+	})
+}
+
+// TestTemplate 测试模板辅助函数
+func TestTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(Options{FieldName: "csrf_token"}))
+	var data gin.H
+	router.GET("/", func(c *gin.Context) {
+		data = H(c, gin.H{"title": "注册"})
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "注册", data["title"])
+	token := data["csrfToken"].(string)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, `<input type="hidden" name="csrf_token" value="`+token+`">`, string(data["csrfField"].(template.HTML)))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Empty(t, Token(c), "没有经过中间件")
+}
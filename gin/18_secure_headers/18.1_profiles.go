@@ -0,0 +1,159 @@
+package secure
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile 预置的安全响应头配置
+type Profile string
+
+const (
+	// ProfileAPI JSON 接口：不会被当作页面渲染，CSP 禁止加载任何资源
+	ProfileAPI Profile = "api"
+	// ProfileWeb 服务端渲染的页面：只允许本站资源，内联脚本和样式需要 nonce
+	ProfileWeb Profile = "web"
+	// ProfileStrict 管理后台等敏感页面：禁止被嵌入，strict-dynamic，跨源隔离
+	ProfileStrict Profile = "strict"
+)
+
+// nonceToken CSP 中的占位符，每个请求替换为随机 nonce
+const nonceToken = "{nonce}"
+
+// Options 安全响应头配置，字段为空时不设置对应的响应头
+type Options struct {
+	// HSTS 只在 HTTPS 请求上发送（HTTP 响应中的 HSTS 会被浏览器忽略）
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool // 提交到浏览器内置列表后很难撤销，确认所有子域都支持 HTTPS 再开启
+	// TrustForwardedProto 部署在反向代理之后时，根据 X-Forwarded-Proto 判断是否为 HTTPS
+	TrustForwardedProto bool
+
+	ContentTypeNosniff        bool   // X-Content-Type-Options: nosniff
+	FrameOptions              string // X-Frame-Options: DENY / SAMEORIGIN（旧浏览器，新浏览器看 CSP frame-ancestors）
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginResourcePolicy string
+
+	// CSP Content-Security-Policy，其中的 {nonce} 每个请求替换为新的随机值
+	CSP string
+	// CSPReportOnly 只报告不拦截，用于上线新策略前观察会拦截哪些资源
+	CSPReportOnly bool
+	// CSPReportURI 违规报告的接收地址，如 /csp-report（见 ReportHandler）
+	CSPReportURI string
+}
+
+// ProfileOptions 返回预置配置，可在此基础上修改个别字段
+func ProfileOptions(p Profile) (Options, error) {
+	switch p {
+	case ProfileAPI:
+		return Options{
+			HSTSMaxAge:         365 * 24 * time.Hour,
+			ContentTypeNosniff: true,
+			FrameOptions:       "DENY",
+			ReferrerPolicy:     "no-referrer",
+			// 接口响应即使被浏览器直接打开，也不能执行脚本或加载资源
+			CSP: "default-src 'none'; frame-ancestors 'none'",
+		}, nil
+	case ProfileWeb:
+		return Options{
+			HSTSMaxAge:         180 * 24 * time.Hour,
+			ContentTypeNosniff: true,
+			FrameOptions:       "SAMEORIGIN",
+			ReferrerPolicy:     "strict-origin-when-cross-origin",
+			CSP: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; " +
+				"img-src 'self' data:; object-src 'none'; base-uri 'self'; frame-ancestors 'self'; form-action 'self'",
+		}, nil
+	case ProfileStrict:
+		return Options{
+			HSTSMaxAge:                2 * 365 * 24 * time.Hour,
+			HSTSIncludeSubdomains:     true,
+			ContentTypeNosniff:        true,
+			FrameOptions:              "DENY",
+			ReferrerPolicy:            "no-referrer",
+			PermissionsPolicy:         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+			CrossOriginOpenerPolicy:   "same-origin",
+			CrossOriginResourcePolicy: "same-origin",
+			// strict-dynamic：带 nonce 的脚本动态加载的脚本同样可信，不再需要维护域名白名单
+			CSP: "default-src 'none'; script-src 'nonce-{nonce}' 'strict-dynamic'; style-src 'self' 'nonce-{nonce}'; " +
+				"img-src 'self'; font-src 'self'; connect-src 'self'; object-src 'none'; base-uri 'none'; " +
+				"frame-ancestors 'none'; form-action 'self'; upgrade-insecure-requests",
+		}, nil
+	}
+	return Options{}, fmt.Errorf("secure: 未知的配置 %q，可选 api / web / strict", p)
+}
+
+// MustProfile 与 ProfileOptions 相同，未知配置时 panic，用于启动时的固定配置
+func MustProfile(p Profile) Options {
+	options, err := ProfileOptions(p)
+	if err != nil {
+		panic(err)
+	}
+	return options
+}
+
+// usesNonce CSP 中是否需要 nonce
+func (o *Options) usesNonce() bool {
+	return strings.Contains(o.CSP, nonceToken)
+}
+
+// apply 按配置设置（或移除）响应头，路由级覆盖时会再次调用
+func (o *Options) apply(header http.Header, r *http.Request, nonce string) {
+	set := func(name, value string) {
+		if value == "" {
+			header.Del(name)
+		} else {
+			header.Set(name, value)
+		}
+	}
+
+	hsts := ""
+	if o.HSTSMaxAge > 0 && isHTTPS(r, o.TrustForwardedProto) {
+		hsts = "max-age=" + strconv.FormatInt(int64(o.HSTSMaxAge.Seconds()), 10)
+		if o.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if o.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	set("Strict-Transport-Security", hsts)
+
+	nosniff := ""
+	if o.ContentTypeNosniff {
+		nosniff = "nosniff"
+	}
+	set("X-Content-Type-Options", nosniff)
+	set("X-Frame-Options", o.FrameOptions)
+	set("Referrer-Policy", o.ReferrerPolicy)
+	set("Permissions-Policy", o.PermissionsPolicy)
+	set("Cross-Origin-Opener-Policy", o.CrossOriginOpenerPolicy)
+	set("Cross-Origin-Resource-Policy", o.CrossOriginResourcePolicy)
+
+	csp := strings.ReplaceAll(o.CSP, nonceToken, nonce)
+	endpoints := ""
+	if csp != "" && o.CSPReportURI != "" {
+		// report-uri 兼容旧浏览器，report-to 为 Reporting API
+		csp += "; report-uri " + o.CSPReportURI + "; report-to csp-endpoint"
+		endpoints = `csp-endpoint="` + o.CSPReportURI + `"`
+	}
+	set("Reporting-Endpoints", endpoints)
+	if o.CSPReportOnly {
+		header.Del("Content-Security-Policy")
+		set("Content-Security-Policy-Report-Only", csp)
+	} else {
+		header.Del("Content-Security-Policy-Report-Only")
+		set("Content-Security-Policy", csp)
+	}
+}
+
+func isHTTPS(r *http.Request, trustForwardedProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,92 @@
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gin.Context 中的键
+const (
+	optionsKey = "secure.options" // 当前请求生效的配置（副本）
+	nonceKey   = "secure.nonce"   // 当前请求的 CSP nonce
+)
+
+// Middleware 安全响应头中间件
+//
+//	router.Use(secure.Middleware(secure.MustProfile(secure.ProfileAPI)))
+//
+// 响应头在处理函数执行之前写入，处理函数也可以直接修改；个别路由需要不同的策略时使用 Override
+func Middleware(options Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := options // 每个请求一份副本，Override 不影响其他请求
+		c.Set(optionsKey, &current)
+		current.apply(c.Writer.Header(), c.Request, ensureNonce(c, &current))
+		c.Next()
+	}
+}
+
+// Override 路由级覆盖，在 Middleware 之后注册
+//
+//	// 允许被合作方嵌入的页面
+//	router.GET("/widget", secure.Override(func(o *secure.Options) {
+//		o.FrameOptions = ""
+//		o.CSP = strings.Replace(o.CSP, "frame-ancestors 'self'", "frame-ancestors https://partner.example.com", 1)
+//	}), handler)
+func Override(fn func(o *Options)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := &Options{}
+		if value, ok := c.Get(optionsKey); ok {
+			current = value.(*Options)
+		} else {
+			c.Set(optionsKey, current)
+		}
+		fn(current)
+		current.apply(c.Writer.Header(), c.Request, ensureNonce(c, current))
+		c.Next()
+	}
+}
+
+// WithProfile 路由级切换为另一个预置配置，如 API 服务中个别返回 HTML 的页面
+func WithProfile(p Profile) gin.HandlerFunc {
+	options := MustProfile(p)
+	return Override(func(o *Options) {
+		profile := options
+		// 部署相关的配置沿用全局设置
+		profile.TrustForwardedProto = o.TrustForwardedProto
+		profile.CSPReportOnly = o.CSPReportOnly
+		profile.CSPReportURI = o.CSPReportURI
+		*o = profile
+	})
+}
+
+// Nonce 当前请求的 CSP nonce，CSP 中没有 {nonce} 时为空
+//
+//	<script nonce="{{ .cspNonce }}">...</script>
+func Nonce(c *gin.Context) string {
+	return c.GetString(nonceKey)
+}
+
+// H 在模板数据中加入 cspNonce，配合 LoadHTMLGlob / SetHTMLTemplate 加载的模板使用
+//
+//	c.HTML(http.StatusOK, "index.tmpl", secure.H(c, gin.H{"title": "首页"}))
+func H(c *gin.Context, data gin.H) gin.H {
+	if data == nil {
+		data = gin.H{}
+	}
+	data["cspNonce"] = Nonce(c)
+	return data
+}
+
+// ensureNonce 策略需要 nonce 时生成（每个请求只生成一次）
+func ensureNonce(c *gin.Context, options *Options) string {
+	if nonce := c.GetString(nonceKey); nonce != "" || !options.usesNonce() {
+		return nonce
+	}
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	nonce := base64.StdEncoding.EncodeToString(buf)
+	c.Set(nonceKey, nonce)
+	return nonce
+}
@@ -0,0 +1,246 @@
+package secure
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// maxReportSize 单个违规报告请求的最大字节数
+const maxReportSize = 64 << 10
+
+// Violation CSP 违规报告（两种上报格式统一后的字段）
+type Violation struct {
+	DocumentURL string `json:"documentURL"`
+	BlockedURL  string `json:"blockedURL"`
+	Directive   string `json:"directive"`   // 被违反的指令，如 script-src-elem
+	Disposition string `json:"disposition"` // enforce 或 report
+	SourceFile  string `json:"sourceFile,omitempty"`
+	LineNumber  int    `json:"lineNumber,omitempty"`
+	Sample      string `json:"sample,omitempty"` // 被拦截代码的前 40 个字符
+}
+
+// legacyReport report-uri 的上报格式（Content-Type: application/csp-report）
+type legacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ScriptSample       string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport Reporting API 的上报格式（Content-Type: application/reports+json），一次可能有多条
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Sample             string `json:"sample"`
+	} `json:"body"`
+}
+
+// ReportHandler 接收浏览器上报的 CSP 违规并记录日志，logger 为 nil 时输出 JSON 到标准输出
+//
+//	router.POST("/csp-report", secure.ReportHandler(nil))
+//
+// 浏览器上报时不带 CSRF 令牌，该路由不能放在 CSRF 中间件之后（或通过 Skip 排除）。
+// 报告内容来自客户端，只用于排查，不能作为安全判断的依据
+func ReportHandler(logger *slog.Logger) gin.HandlerFunc {
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return func(c *gin.Context) {
+		violations, err := parseReports(c.ContentType(), http.MaxBytesReader(c.Writer, c.Request.Body, maxReportSize))
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, 1001, "无法解析 CSP 报告")
+			return
+		}
+		for _, v := range violations {
+			logger.LogAttrs(c.Request.Context(), slog.LevelWarn, "csp violation",
+				slog.String("document_url", v.DocumentURL),
+				slog.String("blocked_url", v.BlockedURL),
+				slog.String("directive", v.Directive),
+				slog.String("disposition", v.Disposition),
+				slog.String("source_file", v.SourceFile),
+				slog.Int("line", v.LineNumber),
+				slog.String("sample", v.Sample),
+				slog.String("user_agent", c.Request.UserAgent()),
+			)
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// parseReports 解析两种上报格式
+func parseReports(contentType string, body io.Reader) ([]Violation, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "application/reports+json" {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(data, &reports); err != nil {
+			return nil, err
+		}
+		var violations []Violation
+		for _, r := range reports {
+			if r.Type != "csp-violation" {
+				continue // 同一个端点可能收到其他类型的报告
+			}
+			violations = append(violations, Violation{
+				DocumentURL: r.Body.DocumentURL,
+				BlockedURL:  r.Body.BlockedURL,
+				Directive:   r.Body.EffectiveDirective,
+				Disposition: r.Body.Disposition,
+				SourceFile:  r.Body.SourceFile,
+				LineNumber:  r.Body.LineNumber,
+				Sample:      r.Body.Sample,
+			})
+		}
+		return violations, nil
+	}
+
+	var report legacyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	r := report.Report
+	if r.DocumentURI == "" {
+		return nil, fmt.Errorf("secure: 缺少 csp-report")
+	}
+	directive := r.EffectiveDirective
+	if directive == "" {
+		directive = r.ViolatedDirective
+	}
+	return []Violation{{
+		DocumentURL: r.DocumentURI,
+		BlockedURL:  r.BlockedURI,
+		Directive:   directive,
+		Disposition: r.Disposition,
+		SourceFile:  r.SourceFile,
+		LineNumber:  r.LineNumber,
+		Sample:      r.ScriptSample,
+	}}, nil
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// SecureHeadersDemo 演示安全响应头、CSP nonce 和违规报告
+func SecureHeadersDemo() {
+	fmt.Println("=== 安全响应头示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	// 真实项目中通常是 router.LoadHTMLGlob("templates/*")，这里使用嵌入的模板，不依赖工作目录
+	router.SetHTMLTemplate(template.Must(template.ParseFS(templateFS, "templates/*.tmpl")))
+
+	web := MustProfile(ProfileWeb)
+	web.TrustForwardedProto = true // 部署在 Nginx 之后，由 Nginx 终止 TLS
+	web.CSPReportURI = "/csp-report"
+	router.Use(Middleware(web))
+
+	router.GET("/", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "index.tmpl", H(c, gin.H{"title": "首页"}))
+	})
+	router.GET("/api/orders", WithProfile(ProfileAPI), func(c *gin.Context) {
+		response.Success(c, []gin.H{{"orderNo": "ORD001"}})
+	})
+	router.GET("/admin", WithProfile(ProfileStrict), func(c *gin.Context) {
+		c.HTML(http.StatusOK, "index.tmpl", H(c, gin.H{"title": "管理后台"}))
+	})
+	// 新策略先以 report-only 上线，观察一段时间没有误报再切换为拦截
+	router.GET("/beta", Override(func(o *Options) {
+		o.CSP = strings.Replace(o.CSP, "img-src 'self' data:", "img-src 'self'", 1)
+		o.CSPReportOnly = true
+	}), func(c *gin.Context) {
+		c.HTML(http.StatusOK, "index.tmpl", H(c, gin.H{"title": "新版首页"}))
+	})
+	// 允许合作方嵌入的页面
+	router.GET("/widget", Override(func(o *Options) {
+		o.FrameOptions = ""
+		o.CSP = strings.Replace(o.CSP, "frame-ancestors 'self'", "frame-ancestors 'self' https://partner.example.com", 1)
+	}), func(c *gin.Context) {
+		c.String(http.StatusOK, "widget")
+	})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	router.POST("/csp-report", ReportHandler(logger))
+
+	show := func(title, path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  GET %s -> %d\n", title, path, w.Code)
+		for _, name := range []string{
+			"Strict-Transport-Security", "Content-Security-Policy", "Content-Security-Policy-Report-Only",
+			"X-Content-Type-Options", "X-Frame-Options", "Referrer-Policy", "Permissions-Policy",
+			"Cross-Origin-Opener-Policy", "Cross-Origin-Resource-Policy",
+		} {
+			if value := w.Header().Get(name); value != "" {
+				fmt.Printf("    %s: %s\n", name, value)
+			}
+		}
+		if strings.Contains(w.Body.String(), "nonce=") {
+			line := w.Body.String()[strings.Index(w.Body.String(), "<script nonce="):]
+			fmt.Printf("    页面: %s\n", line[:strings.Index(line, ">")+1])
+		}
+		fmt.Println()
+	}
+
+	show("1. web 配置：内联脚本需要 nonce", "/")
+	show("2. 接口路由切换为 api 配置", "/api/orders")
+	show("3. 管理后台切换为 strict 配置", "/admin")
+	show("4. report-only：只报告不拦截", "/beta")
+	show("5. 路由级覆盖：允许合作方嵌入", "/widget")
+
+	fmt.Println("6. 浏览器上报违规")
+	for _, report := range []struct{ contentType, body string }{
+		{"application/csp-report", `{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"inline",` +
+			`"violated-directive":"script-src-elem","disposition":"enforce","line-number":11,"script-sample":"console.log(\"没有 nonce"}}`},
+		{"application/reports+json", `[{"type":"csp-violation","body":{"documentURL":"https://example.com/beta",` +
+			`"blockedURL":"data","effectiveDirective":"img-src","disposition":"report"}}]`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(report.body))
+		req.Header.Set("Content-Type", report.contentType)
+		w := httptest.NewRecorder()
+		fmt.Print("  ")
+		router.ServeHTTP(w, req)
+		fmt.Printf("  POST /csp-report (%s) -> %d\n", report.contentType, w.Code)
+	}
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - HSTS 只在 HTTPS 响应中发送，反向代理终止 TLS 时需要信任 X-Forwarded-Proto")
+	fmt.Println("  - 每个请求生成新的 nonce，模板中 <script nonce=\"{{ .cspNonce }}\">")
+	fmt.Println("  - nonce 页面不能被共享缓存，否则所有用户拿到同一个 nonce")
+	fmt.Println("  - 新策略先 report-only 上线，根据 /csp-report 的日志调整后再拦截")
+}
@@ -0,0 +1,166 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(router http.Handler, path string, https bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if https {
+		req.TLS = &tls.ConnectionState{}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestProfiles 测试预置配置
+func TestProfiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, p := range []Profile{ProfileAPI, ProfileWeb, ProfileStrict} {
+		t.Run(string(p), func(t *testing.T) {
+			router := gin.New()
+			router.Use(Middleware(MustProfile(p)))
+			router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, Nonce(c)) })
+
+			w := get(router, "/", true)
+			header := w.Header()
+			assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+			assert.NotEmpty(t, header.Get("X-Frame-Options"))
+			assert.NotEmpty(t, header.Get("Referrer-Policy"))
+			assert.True(t, strings.HasPrefix(header.Get("Strict-Transport-Security"), "max-age="))
+			csp := header.Get("Content-Security-Policy")
+			assert.NotContains(t, csp, nonceToken, "占位符已替换")
+			if p == ProfileAPI {
+				assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", csp)
+				assert.Empty(t, w.Body.String(), "不需要 nonce")
+			} else {
+				nonce := w.Body.String()
+				assert.Len(t, nonce, 24)
+				assert.Contains(t, csp, "'nonce-"+nonce+"'")
+				assert.NotEqual(t, nonce, get(router, "/", true).Body.String(), "每个请求的 nonce 不同")
+			}
+
+			assert.Empty(t, get(router, "/", false).Header().Get("Strict-Transport-Security"), "HTTP 响应不发送 HSTS")
+		})
+	}
+
+	strict := MustProfile(ProfileStrict)
+	assert.Equal(t, "same-origin", strict.CrossOriginOpenerPolicy)
+	assert.Contains(t, strict.CSP, "'strict-dynamic'")
+
+	_, err := ProfileOptions("unknown")
+	assert.Error(t, err)
+	assert.Panics(t, func() { MustProfile("unknown") })
+}
+
+// TestOptions 测试 HSTS、代理与路由级覆盖
+func TestOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("HSTS 与 X-Forwarded-Proto", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(Options{HSTSMaxAge: time.Hour, HSTSIncludeSubdomains: true, HSTSPreload: true, TrustForwardedProto: true}))
+		router.GET("/", func(c *gin.Context) {})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "max-age=3600; includeSubDomains; preload", w.Header().Get("Strict-Transport-Security"))
+		assert.Empty(t, w.Header().Get("Content-Security-Policy"), "未配置的响应头不设置")
+		assert.Empty(t, w.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("不信任 X-Forwarded-Proto", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(Options{HSTSMaxAge: time.Hour}))
+		router.GET("/", func(c *gin.Context) {})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+	})
+
+	router := gin.New()
+	web := MustProfile(ProfileWeb)
+	web.CSPReportURI = "/csp-report"
+	router.Use(Middleware(web))
+	router.GET("/page", func(c *gin.Context) {})
+	router.GET("/api", WithProfile(ProfileAPI), func(c *gin.Context) { c.String(http.StatusOK, Nonce(c)) })
+	router.GET("/report-only", Override(func(o *Options) { o.CSPReportOnly = true }), func(c *gin.Context) {})
+	router.GET("/embed", Override(func(o *Options) { o.FrameOptions = "" }), func(c *gin.Context) {})
+
+	t.Run("切换配置", func(t *testing.T) {
+		w := get(router, "/api", true)
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Equal(t, "default-src 'none'; frame-ancestors 'none'; report-uri /csp-report; report-to csp-endpoint",
+			w.Header().Get("Content-Security-Policy"), "沿用全局的报告地址")
+		assert.Equal(t, `csp-endpoint="/csp-report"`, w.Header().Get("Reporting-Endpoints"))
+	})
+
+	t.Run("report-only", func(t *testing.T) {
+		w := get(router, "/report-only", true)
+		assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+		assert.Contains(t, w.Header().Get("Content-Security-Policy-Report-Only"), "default-src 'self'")
+	})
+
+	t.Run("覆盖只影响当前路由", func(t *testing.T) {
+		assert.Empty(t, get(router, "/embed", true).Header().Get("X-Frame-Options"))
+		assert.Equal(t, "SAMEORIGIN", get(router, "/page", true).Header().Get("X-Frame-Options"))
+	})
+
+	t.Run("H", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		Override(func(o *Options) { o.CSP = "script-src 'nonce-{nonce}'" })(c)
+		data := H(c, gin.H{"title": "首页"})
+		assert.Equal(t, "首页", data["title"])
+		assert.NotEmpty(t, data["cspNonce"])
+		assert.Equal(t, "script-src 'nonce-"+data["cspNonce"].(string)+"'", c.Writer.Header().Get("Content-Security-Policy"))
+	})
+}
+
+// TestReportHandler 测试违规报告接收
+func TestReportHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	router := gin.New()
+	router.POST("/csp-report", ReportHandler(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	post := func(contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, post("application/csp-report",
+		`{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"https://evil.example/x.js","violated-directive":"script-src"}}`))
+	assert.Contains(t, buf.String(), `"blocked_url":"https://evil.example/x.js"`)
+	assert.Contains(t, buf.String(), `"directive":"script-src"`)
+
+	buf.Reset()
+	assert.Equal(t, http.StatusNoContent, post("application/reports+json",
+		`[{"type":"deprecation","body":{}},{"type":"csp-violation","body":{"documentURL":"https://example.com/","blockedURL":"inline","effectiveDirective":"script-src-elem","disposition":"report"}}]`))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1, "只记录 CSP 违规")
+	assert.Contains(t, lines[0], `"disposition":"report"`)
+
+	assert.Equal(t, http.StatusBadRequest, post("application/csp-report", `{}`))
+	assert.Equal(t, http.StatusBadRequest, post("application/reports+json", `not json`))
+	assert.Equal(t, http.StatusBadRequest, post("application/csp-report", `{"csp-report":{"document-uri":"`+strings.Repeat("x", maxReportSize)+`"}}`))
+}
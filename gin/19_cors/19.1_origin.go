@@ -0,0 +1,75 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrWildcardCredentials 允许任意来源的同时允许携带凭证
+//
+// 浏览器规定带凭证的响应不能使用 Access-Control-Allow-Origin: *；
+// 把请求的 Origin 原样返回来绕过这个限制，等于允许任意网站以用户身份调用接口
+var ErrWildcardCredentials = errors.New("cors: AllowOrigins 为 * 时不能开启 AllowCredentials")
+
+// originPattern 一条来源规则
+//
+//	https://app.example.com      精确匹配
+//	https://*.example.com        任意层级的子域（不含 example.com 本身）
+//	http://localhost:*           任意端口，用于本地开发
+type originPattern struct {
+	scheme    string
+	host      string // 精确匹配时为完整主机名；子域通配时为 .example.com
+	port      string // 空表示默认端口，* 表示任意端口
+	subdomain bool
+}
+
+// parsePattern 解析并校验来源规则
+func parsePattern(raw string) (originPattern, error) {
+	if raw == "null" {
+		// 沙箱 iframe、file:// 页面的 Origin 都是 null，任何网站都能构造
+		return originPattern{}, fmt.Errorf("cors: 不能允许 null 来源")
+	}
+	scheme, rest, ok := strings.Cut(strings.ToLower(raw), "://")
+	if !ok || (scheme != "http" && scheme != "https") || rest == "" || strings.ContainsAny(rest, "/?#") {
+		return originPattern{}, fmt.Errorf("cors: 来源 %q 格式应为 scheme://host[:port]", raw)
+	}
+	p := originPattern{scheme: scheme}
+	p.host, p.port = splitHostPort(rest)
+	if strings.HasPrefix(p.host, "*.") {
+		p.subdomain = true
+		p.host = p.host[1:]
+		// *.com、*.localhost 之类的规则范围太大
+		if strings.Count(p.host, ".") < 2 {
+			return originPattern{}, fmt.Errorf("cors: 通配规则 %q 范围过大，至少需要两级域名，如 https://*.example.com", raw)
+		}
+	}
+	if strings.Contains(p.host, "*") || p.host == "" {
+		return originPattern{}, fmt.Errorf("cors: 来源 %q 只支持 *.域名 和 :* 两种通配", raw)
+	}
+	return p, nil
+}
+
+// match 判断 Origin 是否符合规则
+func (p originPattern) match(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme != p.scheme || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	host, port := splitHostPort(u.Host)
+	if p.port != "*" && port != p.port {
+		return false
+	}
+	if p.subdomain {
+		return strings.HasSuffix(host, p.host) && len(host) > len(p.host)
+	}
+	return host == p.host
+}
+
+func splitHostPort(hostport string) (host, port string) {
+	if i := strings.LastIndex(hostport, ":"); i >= 0 && !strings.Contains(hostport[i:], "]") {
+		return hostport[:i], hostport[i+1:]
+	}
+	return hostport, ""
+}
@@ -0,0 +1,286 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+	"go-learning/gin/internal/httpheader"
+)
+
+// codeForbidden 权限不足
+const codeForbidden = 1003
+
+// Options CORS 配置
+type Options struct {
+	// AllowOrigins 允许的来源：精确来源、https://*.example.com 子域通配、http://localhost:* 端口通配，
+	// 或单独一个 * 表示任意来源（不能与 AllowCredentials 同时使用）
+	AllowOrigins []string
+	// AllowOriginFunc 规则无法表达时的补充判断，如从数据库读取租户域名
+	AllowOriginFunc func(origin string) bool
+
+	AllowMethods  []string
+	AllowHeaders  []string // 预检时请求的头不在列表中（也不是 CORS 安全列表中的头）则拒绝
+	ExposeHeaders []string // 允许前端 JS 读取的响应头
+
+	// AllowCredentials 允许携带 Cookie / HTTP 认证，前端需要 fetch(url, {credentials: "include"})
+	AllowCredentials bool
+	// MaxAge 预检结果的缓存时间，Chrome 最多 2 小时，Firefox 最多 24 小时
+	MaxAge time.Duration
+	// TrustForwardedProto 部署在反向代理之后时，根据 X-Forwarded-Proto 判断请求的协议（用于同源判断）
+	TrustForwardedProto bool
+}
+
+// DefaultOptions 默认配置，AllowOrigins 需要按环境填写
+func DefaultOptions() Options {
+	return Options{
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead},
+		AllowHeaders:  []string{"Content-Type", "Authorization", "X-Request-ID", "Idempotency-Key", "If-None-Match", "If-Match"},
+		ExposeHeaders: []string{"X-Request-ID", "ETag", "Retry-After", "Location"},
+		MaxAge:        10 * time.Minute,
+	}
+}
+
+// safelistedHeaders 浏览器不会为这些请求头发起预检（CORS 安全列表）
+//
+// Content-Type 只有表单和 text/plain 三种取值在安全列表中，出现在预检里说明是 application/json 等，
+// 是否允许由 AllowHeaders 决定，因此不放在这里
+var safelistedHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Range"}
+
+// New 创建 CORS 中间件，配置不合法时返回错误
+//
+// 行为:
+//   - 没有 Origin 或 Origin 与请求同源（协议和主机都相同）：不是跨域请求，直接放行
+//   - 预检请求（OPTIONS + Access-Control-Request-Method）：来源、方法、请求头都允许时返回 204，否则 403，
+//     都不会进入后续处理函数（JWT 等中间件不会因为预检没带 Authorization 而返回 401）
+//   - 其他跨域请求：来源允许时加上 CORS 响应头，不允许时直接返回 403，不执行处理函数
+//   - 响应随 Origin 变化，总是加上 Vary: Origin，避免缓存把 A 站的响应返回给 B 站
+func New(options Options) (gin.HandlerFunc, error) {
+	allowAll := false
+	var patterns []originPattern
+	for _, origin := range options.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		p, err := parsePattern(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	if allowAll && options.AllowCredentials {
+		return nil, ErrWildcardCredentials
+	}
+	if !allowAll && len(patterns) == 0 && options.AllowOriginFunc == nil {
+		return nil, fmt.Errorf("cors: 没有配置允许的来源")
+	}
+
+	defaults := DefaultOptions()
+	if len(options.AllowMethods) == 0 {
+		options.AllowMethods = defaults.AllowMethods
+	}
+	methods := strings.Join(options.AllowMethods, ", ")
+	expose := strings.Join(options.ExposeHeaders, ", ")
+	maxAge := ""
+	if options.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(options.MaxAge.Seconds()), 10)
+	}
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		for _, p := range patterns {
+			if p.match(origin) {
+				return true
+			}
+		}
+		return options.AllowOriginFunc != nil && options.AllowOriginFunc(origin)
+	}
+	setOrigin := func(header http.Header, origin string) {
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+			return
+		}
+		header.Set("Access-Control-Allow-Origin", origin)
+		if options.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if !allowAll {
+			httpheader.AddVary(header, "Origin")
+		}
+		origin := c.GetHeader("Origin")
+		if origin == "" || sameOrigin(origin, c.Request, options.TrustForwardedProto) {
+			c.Next()
+			return
+		}
+
+		requestMethod := c.GetHeader("Access-Control-Request-Method")
+		if c.Request.Method == http.MethodOptions && requestMethod != "" {
+			httpheader.AddVary(header, "Access-Control-Request-Method")
+			httpheader.AddVary(header, "Access-Control-Request-Headers")
+			requested := splitHeaderList(c.GetHeader("Access-Control-Request-Headers"))
+			if !allowed(origin) || !contains(options.AllowMethods, requestMethod) || !headersAllowed(requested, options.AllowHeaders) {
+				// 不返回 CORS 头，浏览器会在控制台报告具体原因
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			setOrigin(header, origin)
+			header.Set("Access-Control-Allow-Methods", methods)
+			if len(requested) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if !allowed(origin) {
+			response.ErrorWithStatus(c, http.StatusForbidden, codeForbidden, "不允许来自 "+origin+" 的跨域请求")
+			c.Abort()
+			return
+		}
+		setOrigin(header, origin)
+		if expose != "" {
+			header.Set("Access-Control-Expose-Headers", expose)
+		}
+		c.Next()
+	}, nil
+}
+
+// Middleware 与 New 相同，配置不合法时 panic，用于启动时的固定配置
+func Middleware(options Options) gin.HandlerFunc {
+	handler, err := New(options)
+	if err != nil {
+		panic(err)
+	}
+	return handler
+}
+
+// sameOrigin Origin 的协议和主机与请求相同（浏览器对同源的 POST 也会带 Origin）
+// http://example.com 与 https://example.com 不同源，协议的判断与 18_secure_headers 的 isHTTPS 一致
+func sameOrigin(origin string, r *http.Request, trustForwardedProto bool) bool {
+	scheme, originHost, ok := strings.Cut(origin, "://")
+	if !ok || !strings.EqualFold(originHost, r.Host) {
+		return false
+	}
+	return strings.EqualFold(scheme, requestScheme(r, trustForwardedProto))
+}
+
+// requestScheme 请求的协议
+func requestScheme(r *http.Request, trustForwardedProto bool) string {
+	if r.TLS != nil || (trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")) {
+		return "https"
+	}
+	return "http"
+}
+
+func splitHeaderList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, http.CanonicalHeaderKey(part))
+		}
+	}
+	return list
+}
+
+func headersAllowed(requested, allowed []string) bool {
+	for _, h := range requested {
+		if !contains(allowed, h) && !contains(safelistedHeaders, h) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSDemo 演示来源白名单、预检和凭证
+func CORSDemo() {
+	fmt.Println("=== CORS 跨域示例 ===")
+	fmt.Println()
+
+	if _, err := New(Options{AllowOrigins: []string{"*"}, AllowCredentials: true}); err != nil {
+		fmt.Printf("0. 错误配置在启动时拒绝: %v\n\n", err)
+	}
+
+	options := DefaultOptions()
+	options.AllowOrigins = []string{"https://app.example.com", "https://*.example.com", "http://localhost:*"}
+	options.AllowCredentials = true
+	options.MaxAge = 2 * time.Hour
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(Middleware(options))
+	router.GET("/api/orders", func(c *gin.Context) {
+		c.Header("X-Request-ID", "req-1")
+		response.Success(c, []gin.H{{"orderNo": "ORD001"}})
+	})
+	router.PUT("/api/orders/:no", func(c *gin.Context) {
+		response.Success(c, gin.H{"orderNo": c.Param("no")})
+	})
+
+	send := func(title, method, origin string, header map[string]string) {
+		req := httptest.NewRequest(method, "http://api.example.com/api/orders", nil)
+		if method == http.MethodPut {
+			req = httptest.NewRequest(method, "http://api.example.com/api/orders/ORD001", nil)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  %s Origin: %s -> %d\n", title, method, origin, w.Code)
+		for _, name := range []string{
+			"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Methods",
+			"Access-Control-Allow-Headers", "Access-Control-Max-Age", "Access-Control-Expose-Headers",
+		} {
+			if value := w.Header().Get(name); value != "" {
+				fmt.Printf("    %s: %s\n", name, value)
+			}
+		}
+		fmt.Printf("    Vary: %s\n", strings.Join(w.Header().Values("Vary"), ", "))
+	}
+
+	preflight := map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "content-type, authorization"}
+	send("1. 预检请求", http.MethodOptions, "https://app.example.com", preflight)
+	send("2. 预检通过后的实际请求", http.MethodPut, "https://app.example.com", nil)
+	send("3. 子域通配", http.MethodGet, "https://admin.example.com", nil)
+	send("4. 本地开发任意端口", http.MethodGet, "http://localhost:5173", nil)
+	send("5. 不在白名单中的来源", http.MethodGet, "https://evil.example.net", nil)
+	send("6. 形似但不是子域", http.MethodGet, "https://evilexample.com", nil)
+	send("7. 预检请求了不允许的请求头", http.MethodOptions, "https://app.example.com",
+		map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "x-debug"})
+	send("8. 同源请求（不是跨域）", http.MethodGet, "http://api.example.com", nil)
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 带凭证时 Access-Control-Allow-Origin 必须是具体来源，* 与凭证不能同时使用")
+	fmt.Println("  - 响应随 Origin 变化，必须带 Vary: Origin，否则 CDN 可能把 A 站的响应返回给 B 站")
+	fmt.Println("  - 预检在鉴权中间件之前返回，Access-Control-Max-Age 减少预检次数")
+	fmt.Println("  - CORS 只限制浏览器读取响应，不能代替鉴权和 CSRF 防护")
+}
@@ -0,0 +1,203 @@
+package cors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouter(t *testing.T, options Options) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handler, err := New(options)
+	require.NoError(t, err)
+	router := gin.New()
+	router.Use(handler)
+	router.Any("/api/orders", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return router
+}
+
+func send(router http.Handler, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://api.example.com/api/orders", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestPattern 测试来源规则的解析与匹配
+func TestPattern(t *testing.T) {
+	for _, raw := range []string{"null", "https://*.com", "*.example.com", "ftp://example.com", "https://example.com/path", "https://a*.example.com", "https://"} {
+		_, err := parsePattern(raw)
+		assert.Error(t, err, raw)
+	}
+
+	_, err := New(Options{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	assert.ErrorIs(t, err, ErrWildcardCredentials)
+	_, err = New(Options{})
+	assert.Error(t, err, "没有配置来源")
+	assert.Panics(t, func() { Middleware(Options{AllowOrigins: []string{"null"}}) })
+
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "https://APP.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://app.example.com", "https://app.example.com:8443", false},
+		{"https://*.example.com", "https://admin.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"https://*.example.com", "https://example.com.evil.net", false},
+		{"http://localhost:*", "http://localhost:5173", true},
+		{"http://localhost:*", "http://localhost", true},
+		{"http://localhost:3000", "http://localhost:3001", false},
+	}
+	for _, tt := range tests {
+		p, err := parsePattern(tt.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, p.match(tt.origin), "%s ~ %s", tt.pattern, tt.origin)
+	}
+}
+
+// TestPreflight 测试预检请求
+func TestPreflight(t *testing.T) {
+	options := DefaultOptions()
+	options.AllowOrigins = []string{"https://app.example.com"}
+	options.AllowCredentials = true
+	options.MaxAge = 2 * time.Hour
+	router := newRouter(t, options)
+
+	w := send(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "content-type, authorization",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String(), "预检不进入处理函数")
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PUT")
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "7200", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	for name, header := range map[string]map[string]string{
+		"不允许的方法":  {"Access-Control-Request-Method": "TRACE"},
+		"不允许的请求头": {"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "x-debug"},
+	} {
+		w := send(router, http.MethodOptions, "https://app.example.com", header)
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), name)
+	}
+
+	w = send(router, http.MethodOptions, "https://evil.example.net", map[string]string{"Access-Control-Request-Method": "GET"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = send(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "accept-language",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code, "安全列表中的请求头总是允许")
+
+	options.AllowHeaders = []string{"Authorization"}
+	w = send(newRouter(t, options), http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "content-type",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, "预检中的 Content-Type 由 AllowHeaders 决定")
+}
+
+// TestActualRequest 测试实际的跨域请求
+func TestActualRequest(t *testing.T) {
+	options := DefaultOptions()
+	options.AllowOrigins = []string{"https://*.example.com"}
+	options.AllowOriginFunc = func(origin string) bool { return origin == "https://tenant.example.org" }
+	router := newRouter(t, options)
+
+	w := send(router, http.MethodGet, "https://admin.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID")
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	assert.Equal(t, http.StatusOK, send(router, http.MethodGet, "https://tenant.example.org", nil).Code, "AllowOriginFunc")
+
+	w = send(router, http.MethodPost, "https://evil.example.net", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body struct {
+		Code int `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeForbidden, body.Code)
+	assert.NotContains(t, w.Body.String(), "ok", "不执行处理函数")
+
+	t.Run("同源与非浏览器请求", func(t *testing.T) {
+		for _, origin := range []string{"", "http://api.example.com"} {
+			w := send(router, http.MethodPost, origin, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "Origin", w.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("协议不同不是同源", func(t *testing.T) {
+		options := DefaultOptions()
+		options.AllowOrigins = []string{"https://app.example.com"}
+		router := newRouter(t, options)
+		assert.Equal(t, http.StatusForbidden, send(router, http.MethodPost, "https://api.example.com", nil).Code,
+			"https 页面请求 http 接口是跨域请求")
+
+		assert.Equal(t, http.StatusForbidden, send(router, http.MethodPost, "https://api.example.com",
+			map[string]string{"X-Forwarded-Proto": "https"}).Code, "默认不信任 X-Forwarded-Proto")
+		options.TrustForwardedProto = true
+		router = newRouter(t, options)
+		assert.Equal(t, http.StatusOK, send(router, http.MethodPost, "https://api.example.com",
+			map[string]string{"X-Forwarded-Proto": "https"}).Code, "代理终止 TLS 后的同源请求")
+	})
+
+	t.Run("Vary 不重复", func(t *testing.T) {
+		handler, err := New(options)
+		require.NoError(t, err)
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Header("Vary", "origin") }, handler)
+		r.GET("/api/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := send(r, http.MethodGet, "https://admin.example.com", nil)
+		assert.Equal(t, []string{"origin"}, w.Header().Values("Vary"))
+	})
+}
+
+// TestAllowAll 测试允许任意来源
+func TestAllowAll(t *testing.T) {
+	options := DefaultOptions()
+	options.AllowOrigins = []string{"*"}
+	router := newRouter(t, options)
+
+	w := send(router, http.MethodGet, "https://anyone.example", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, w.Header().Values("Vary"), "响应不随 Origin 变化")
+
+	w = send(router, http.MethodOptions, "https://anyone.example", map[string]string{"Access-Control-Request-Method": "DELETE"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.True(t, strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), "DELETE"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+}
@@ -115,6 +115,8 @@ func BasicRoutesDemo() {
 	fmt.Println()
 	fmt.Println("注意: 此示例仅展示路由配置，实际运行需要启动服务器")
 	fmt.Println("      可以使用 router.Run(\":8080\") 启动服务器")
+	fmt.Println("      生产环境使用 server.New(router, server.DefaultConfig()).ListenAndServe()，")
+	fmt.Println("      带超时配置、健康检查和优雅关闭（见 ServerDemo）")
 	fmt.Println()
 	fmt.Println("测试示例:")
 	fmt.Println("  curl \"http://localhost:8080/welcome?firstname=John&lastname=Doe\"")
@@ -353,4 +355,5 @@ func RESTfulRoutesDemo() {
 	fmt.Println("  2. JSON 绑定: 使用 c.ShouldBindJSON() 自动解析和验证")
 	fmt.Println("  3. 状态码: 遵循 RESTful 规范使用正确的 HTTP 状态码")
 	fmt.Println("  4. HTTP 方法: GET(查询), POST(创建), PUT(更新), DELETE(删除)")
+	fmt.Println("  5. 部分更新: PATCH 使用 application/merge-patch+json 或 application/json-patch+json（见 go run . Patch）")
 }
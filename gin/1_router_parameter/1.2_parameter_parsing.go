@@ -576,6 +576,17 @@ func FormBindingDemo() {
 	fmt.Println("  curl -X POST http://localhost:8080/submit \\")
 	fmt.Println("    -d \"name=John&email=john@example.com&age=25\"")
 	fmt.Println()
+	fmt.Println("CSRF 防护:")
+	fmt.Println("  浏览器提交的表单会自动带上 Cookie，需要配合 CSRF 令牌防止跨站伪造请求")
+	fmt.Println("  router.Use(csrf.Middleware(csrf.DefaultOptions()))")
+	fmt.Println("  c.HTML(200, \"register.tmpl\", csrf.H(c, gin.H{...}))  // 模板中 {{ .csrfField }}")
+	fmt.Println("  详见 CSRF 示例")
+	fmt.Println()
+	fmt.Println("HTML 页面中的表单:")
+	fmt.Println("  校验失败时返回 JSON 对浏览器不友好，应重新渲染页面，回填已填写的值并在字段旁显示错误")
+	fmt.Println("  form, ok := views.Bind(c, &req)  // 错误信息由 binding 和 label 标签生成")
+	fmt.Println("  成功后 303 重定向，提示消息通过 views.AddFlash 带到下一个页面（见 go run . HTMLForms）")
+	fmt.Println()
 	fmt.Println("关键概念总结:")
 	fmt.Println("  1. 路径参数: /users/:id → c.Param(\"id\") - 资源标识，必需")
 	fmt.Println("  2. 查询参数: /users?page=1 → c.Query(\"page\") - 过滤条件，可选")
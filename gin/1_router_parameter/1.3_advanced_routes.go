@@ -3,15 +3,17 @@ package gin
 import (
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
-)
 
-// RouteGroupDemo 演示路由分组配置
-func RouteGroupDemo() {
-	fmt.Println("=== Gin 路由分组示例 ===")
-	fmt.Println()
+	routes "go-learning/gin/22_routes"
+	assets "go-learning/gin/6_static_assets"
+	constraint "go-learning/gin/7_route_constraint"
+)
 
+// RouteGroupRouter 创建 RouteGroupDemo 的路由，也用于 go run . routes:list RouteGroup 查看路由表
+func RouteGroupRouter() *gin.Engine {
 	router := gin.Default()
 
 	// API版本分组
@@ -90,6 +92,16 @@ func RouteGroupDemo() {
 		})
 	}
 
+	return router
+}
+
+// RouteGroupDemo 演示路由分组配置
+func RouteGroupDemo() {
+	fmt.Println("=== Gin 路由分组示例 ===")
+	fmt.Println()
+
+	RouteGroupRouter()
+
 	fmt.Println("路由分组配置完成:")
 	fmt.Println("  /api/v1/users      - GET    - 获取用户列表")
 	fmt.Println("  /api/v1/users      - POST   - 创建用户")
@@ -108,24 +120,27 @@ func RouteGroupDemo() {
 }
 
 // RegexRouteDemo 演示正则表达式路由
+// Gin 本身不支持 :id([0-9]+) 这种写法（括号会被当成参数名的一部分），
+// 这里通过 constraint 包在路由上声明参数类型
 func RegexRouteDemo() {
 	fmt.Println("=== Gin 正则表达式路由示例 ===")
 	fmt.Println()
 
 	router := gin.Default()
+	r := constraint.New(router, constraint.Options{OnFailure: constraint.NotFound})
 
 	// 1. 数字ID路由 - 只匹配数字
-	router.GET("/users/:id([0-9]+)", func(c *gin.Context) {
-		id := c.Param("id")
+	r.GET("/users/{id:int}", func(c *gin.Context) {
+		id := constraint.ParamInt(c, "id")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "数字ID路由",
 			"id":      id,
 		})
 	})
 
-	// 2. UUID路由 - 匹配UUID格式
-	router.GET("/users/:uuid([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})", func(c *gin.Context) {
-		uuid := c.Param("uuid")
+	// 2. UUID路由 - 匹配UUID格式（与数字ID路由位于同一位置，按注册顺序尝试）
+	r.GET("/users/{uuid:uuid}", func(c *gin.Context) {
+		uuid := constraint.ParamUUID(c, "uuid")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "UUID路由",
 			"uuid":    uuid,
@@ -133,8 +148,8 @@ func RegexRouteDemo() {
 	})
 
 	// 3. 字母数字组合路由
-	router.GET("/posts/:slug([a-z0-9-]+)", func(c *gin.Context) {
-		slug := c.Param("slug")
+	r.GET("/posts/{slug:regex([a-z0-9-]+)}", func(c *gin.Context) {
+		slug := constraint.ParamString(c, "slug")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Slug路由",
 			"slug":    slug,
@@ -143,23 +158,20 @@ func RegexRouteDemo() {
 
 	fmt.Println("正则表达式路由示例:")
 	fmt.Println("  GET /users/123     → 匹配数字ID")
-	fmt.Println("  GET /users/abc    → 不匹配（非数字）")
+	fmt.Println("  GET /users/abc    → 不匹配（非数字），返回 404")
 	fmt.Println("  GET /users/550e8400-e29b-41d4-a716-446655440000 → 匹配UUID")
 	fmt.Println("  GET /posts/my-post-123 → 匹配字母数字组合")
 	fmt.Println()
-	fmt.Println("正则表达式说明:")
-	fmt.Println("  :id([0-9]+)                    - 只匹配数字")
-	fmt.Println("  :uuid([0-9a-f]{8}-...)         - 匹配UUID格式")
-	fmt.Println("  :slug([a-z0-9-]+)              - 匹配小写字母、数字和连字符")
+	fmt.Println("参数约束说明:")
+	fmt.Println("  {id:int}                       - 只匹配数字")
+	fmt.Println("  {uuid:uuid}                    - 匹配UUID格式")
+	fmt.Println("  {slug:regex([a-z0-9-]+)}       - 匹配小写字母、数字和连字符")
 	fmt.Println()
-	fmt.Println("注意: 正则表达式必须用括号包裹，放在参数名后面")
+	fmt.Println("注意: Gin 原生路由不支持正则，更多用法参考 RouteConstraint 示例")
 }
 
-// MiddlewareRouteDemo 演示路由中间件
-func MiddlewareRouteDemo() {
-	fmt.Println("=== Gin 路由中间件示例 ===")
-	fmt.Println()
-
+// MiddlewareRouteRouter 创建 MiddlewareRouteDemo 的路由，也用于 go run . routes:list MiddlewareRoute 查看路由表
+func MiddlewareRouteRouter() *gin.Engine {
 	router := gin.Default()
 
 	// 自定义中间件 - 记录请求日志
@@ -214,6 +226,16 @@ func MiddlewareRouteDemo() {
 		})
 	}
 
+	return router
+}
+
+// MiddlewareRouteDemo 演示路由中间件
+func MiddlewareRouteDemo() {
+	fmt.Println("=== Gin 路由中间件示例 ===")
+	fmt.Println()
+
+	router := MiddlewareRouteRouter()
+
 	fmt.Println("中间件配置完成:")
 	fmt.Println("  全局中间件: 所有请求都会记录日志")
 	fmt.Println("  公开路由: /public/info (无需认证)")
@@ -223,6 +245,11 @@ func MiddlewareRouteDemo() {
 	fmt.Println("  router.Use(middleware)      - 全局中间件")
 	fmt.Println("  group.Use(middleware)       - 路由组中间件")
 	fmt.Println("  router.GET(path, m1, m2, handler) - 单个路由中间件")
+	fmt.Println("  router.Use(chain.Unless(chain.PathPrefix(\"/public\"), auth)...) - 不建分组也能让公开接口跳过认证")
+	fmt.Println("  （见 go run . MiddlewareChain）")
+	fmt.Println()
+	fmt.Println("各路由实际的中间件链（routes.WriteTable）:")
+	_ = routes.WriteTable(os.Stdout, routes.Inspect(router))
 	fmt.Println()
 	fmt.Println("中间件函数签名:")
 	fmt.Println("  func(c *gin.Context) {")
@@ -233,40 +260,41 @@ func MiddlewareRouteDemo() {
 }
 
 // StaticFilesDemo 演示静态文件服务
+// 资源通过 embed.FS 打包进二进制，不依赖运行时的工作目录
 func StaticFilesDemo() {
 	fmt.Println("=== Gin 静态文件服务示例 ===")
 	fmt.Println()
 
 	router := gin.Default()
 
-	// 1. 静态文件目录
-	router.Static("/static", "./static")
-
-	// 2. 静态文件服务（带虚拟路径前缀）
-	router.StaticFS("/assets", gin.Dir("./assets", false))
+	// 1. 静态文件目录（嵌入的 web 目录）
+	router.StaticFS("/static", http.FS(assets.Web()))
 
-	// 3. 单个静态文件
-	router.StaticFile("/favicon.ico", "./favicon.ico")
-	router.StaticFile("/robots.txt", "./robots.txt")
+	// 2. 单个静态文件
+	router.StaticFileFS("/favicon.svg", "favicon.svg", http.FS(assets.Web()))
+	router.StaticFileFS("/robots.txt", "robots.txt", http.FS(assets.Web()))
 
-	// 4. HTML模板渲染
-	router.LoadHTMLGlob("templates/*")
+	// 3. HTML模板渲染（嵌入的模板）
+	router.SetHTMLTemplate(assets.Templates())
 	router.GET("/", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "index.html", gin.H{
+		c.HTML(http.StatusOK, "index.tmpl", gin.H{
 			"title": "Gin Web应用",
 		})
 	})
 
 	fmt.Println("静态文件配置完成:")
-	fmt.Println("  /static/*          → ./static/ 目录下的文件")
-	fmt.Println("  /assets/*          → ./assets/ 目录下的文件")
-	fmt.Println("  /favicon.ico       → ./favicon.ico")
-	fmt.Println("  /robots.txt        → ./robots.txt")
+	fmt.Println("  /static/*          → 嵌入的 web/ 目录")
+	fmt.Println("  /favicon.svg       → web/favicon.svg")
+	fmt.Println("  /robots.txt        → web/robots.txt")
+	fmt.Println("  /                  → templates/index.tmpl")
 	fmt.Println()
 	fmt.Println("方法说明:")
-	fmt.Println("  router.Static(relativePath, root) - 静态文件目录")
-	fmt.Println("  router.StaticFS(relativePath, fs) - 使用文件系统")
-	fmt.Println("  router.StaticFile(relativePath, filepath) - 单个文件")
-	fmt.Println("  router.LoadHTMLGlob(pattern) - 加载HTML模板")
+	fmt.Println("  router.Static(relativePath, root) - 静态文件目录（依赖工作目录）")
+	fmt.Println("  router.StaticFS(relativePath, fs) - 使用文件系统（可传入 http.FS(embed.FS)）")
+	fmt.Println("  router.StaticFileFS(relativePath, filepath, fs) - 单个文件")
+	fmt.Println("  router.SetHTMLTemplate(tmpl) - 使用 template.ParseFS 解析的嵌入模板")
+	fmt.Println()
+	fmt.Println("ETag、预压缩、Range 和 SPA 回退请参考 StaticAssets 示例")
+	fmt.Println("多个页面共享布局和局部模板请参考 HTMLForms 示例（每个页面单独解析，避免 content 互相覆盖）")
 }
 
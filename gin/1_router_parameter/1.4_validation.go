@@ -3,11 +3,12 @@ package gin
 import (
 	"fmt"
 	"net/http"
-	"regexp"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+
+	validation "go-learning/gin/3_validator"
+	idempotency "go-learning/gin/9_idempotency"
 )
 
 // CustomValidationDemo 演示自定义验证规则
@@ -18,21 +19,11 @@ func CustomValidationDemo() {
 	router := gin.Default()
 
 	// 注册自定义验证器
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		// 注册手机号验证
-		v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
-			phone := fl.Field().String()
-			matched, _ := regexp.MatchString(`^1[3-9]\d{9}$`, phone)
-			return matched
-		})
-
-		// 注册密码强度验证（至少包含字母和数字）
-		v.RegisterValidation("strong_password", func(fl validator.FieldLevel) bool {
-			password := fl.Field().String()
-			hasLetter, _ := regexp.MatchString(`[a-zA-Z]`, password)
-			hasDigit, _ := regexp.MatchString(`[0-9]`, password)
-			return hasLetter && hasDigit && len(password) >= 8
-		})
+	// phone、strong_password 等规则统一由 validation 包提供（正则预编译，只编译一次）
+	// 详见 gin/3_validator
+	if err := validation.Register(); err != nil {
+		fmt.Printf("注册验证规则失败: %v\n", err)
+		return
 	}
 
 	// 使用自定义验证
@@ -43,7 +34,8 @@ func CustomValidationDemo() {
 		Password string `json:"password" binding:"required,strong_password"`
 	}
 
-	router.POST("/register", func(c *gin.Context) {
+	// 注册接口支持 Idempotency-Key，客户端重试不会重复注册
+	router.POST("/register", idempotency.Middleware(idempotency.NewMemoryStore(), idempotency.DefaultOptions()), func(c *gin.Context) {
 		var req RegisterRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -66,6 +58,7 @@ func CustomValidationDemo() {
 	fmt.Println()
 	fmt.Println("使用示例:")
 	fmt.Println("  POST /register")
+	fmt.Println("  Idempotency-Key: 7c9e6679-7425-40de-944b-e07fc1f90ae7  （可选，重试时保持不变）")
 	fmt.Println("  {")
 	fmt.Println("    \"username\": \"john\",")
 	fmt.Println("    \"phone\": \"13800138000\",")
@@ -78,6 +71,10 @@ func CustomValidationDemo() {
 	fmt.Println("    // 验证逻辑")
 	fmt.Println("    return true/false")
 	fmt.Println("  })")
+	fmt.Println()
+	fmt.Println("  注意: 正则应在包级别用 regexp.MustCompile 预编译，")
+	fmt.Println("        不要在验证函数里调用 regexp.MatchString（每次都会重新编译）")
+	fmt.Println("  更多规则（身份证、信用代码、银行卡等）见 ValidatorPack 示例")
 }
 
 // ValidationErrorHandlingDemo 演示参数验证错误处理标准流程
@@ -169,9 +166,11 @@ func formatFieldError(e validator.FieldError) string {
 		return fmt.Sprintf("参数 %s 必须小于等于 %s", field, e.Param())
 	case "len":
 		return fmt.Sprintf("参数 %s 长度必须为 %s", field, e.Param())
-	case "phone":
-		return fmt.Sprintf("参数 %s 必须是有效的手机号", field)
 	default:
+		// phone、idcard 等自定义规则的文案由 validation 包提供
+		if msg, ok := validation.Message(e); ok {
+			return msg
+		}
 		return fmt.Sprintf("参数 %s 校验失败：%s", field, tag)
 	}
 }
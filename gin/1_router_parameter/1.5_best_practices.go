@@ -6,6 +6,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	httpcache "go-learning/gin/14_http_cache"
+	secure "go-learning/gin/18_secure_headers"
+	response "go-learning/gin/8_content_negotiation"
+	idempotency "go-learning/gin/9_idempotency"
 )
 
 // UnifiedResponseDemo 演示 RESTful API 标准化响应格式
@@ -15,6 +20,8 @@ func UnifiedResponseDemo() {
 	fmt.Println()
 
 	router := gin.Default()
+	// JSON 接口统一加上安全响应头（nosniff、禁止嵌入、CSP default-src 'none'、HTTPS 下的 HSTS）
+	router.Use(secure.Middleware(secure.MustProfile(secure.ProfileAPI)))
 
 	// ========== 标准化响应结构 ==========
 	// 统一的响应格式，便于前端统一处理
@@ -22,41 +29,21 @@ func UnifiedResponseDemo() {
 	//   - code: 业务状态码，0 表示成功，非0表示失败
 	//   - data: 成功时返回的数据
 	//   - message: 错误时的提示信息
-	type Response struct {
-		Code    int         `json:"code"`    // 业务状态码：0=成功，非0=失败
-		Data    interface{} `json:"data"`    // 成功时返回的数据
-		Message string      `json:"message"` // 错误时的提示信息
-	}
+	// 结构定义见 response.Response
 
 	// ========== 响应辅助函数 ==========
-	// Success: 成功响应
-	// 参数:
-	//   - c: Gin 上下文
-	//   - data: 要返回的数据（可以是任意类型）
-	// 返回格式: {"code": 0, "data": {...}}
-	Success := func(c *gin.Context, data interface{}) {
-		c.JSON(http.StatusOK, Response{
-			Code: 0,
-			Data: data,
-		})
-	}
-
-	// Error: 错误响应
-	// 参数:
-	//   - c: Gin 上下文
-	//   - code: 业务错误码（非0）
-	//   - msg: 错误提示信息
-	// 返回格式: {"code": 1001, "message": "错误信息"}
-	Error := func(c *gin.Context, code int, msg string) {
-		c.JSON(http.StatusOK, Response{
-			Code:    code,
-			Message: msg,
-		})
-	}
+	// Success: 成功响应，返回格式: {"code": 0, "data": {...}}
+	// Error: 错误响应，返回格式: {"code": 1001, "message": "错误信息"}
+	// 编码格式根据 Accept 请求头或 ?format= 参数协商（JSON / XML / YAML / MessagePack / Protobuf），
+	// 所有格式使用同一个 {code, data, message} 信封
+	Success := response.Success
+	Error := response.Error
 
 	// ========== 使用示例 ==========
 	// 成功响应示例
-	router.GET("/users/:id", func(c *gin.Context) {
+	// 查询类接口加上响应缓存：重复请求直接返回缓存，客户端携带 If-None-Match 时返回 304
+	cache := httpcache.New(httpcache.DefaultConfig())
+	router.GET("/users/:id", cache.Middleware(httpcache.DefaultOptions()), func(c *gin.Context) {
 		id := c.Param("id")
 		// 模拟查询用户信息
 		user := gin.H{
@@ -68,7 +55,8 @@ func UnifiedResponseDemo() {
 	})
 
 	// 错误响应示例
-	router.POST("/users", func(c *gin.Context) {
+	// 创建类接口加上幂等中间件：移动端超时重试时携带相同的 Idempotency-Key，不会重复创建
+	router.POST("/users", idempotency.Middleware(idempotency.NewMemoryStore(), idempotency.DefaultOptions()), func(c *gin.Context) {
 		var user struct {
 			Name  string `json:"name" binding:"required"`
 			Email string `json:"email" binding:"required,email"`
@@ -86,7 +74,8 @@ func UnifiedResponseDemo() {
 			return
 		}
 
-		// 成功创建
+		// 成功创建，写操作之后使该资源的缓存失效
+		cache.InvalidatePath("/users/1")
 		Success(c, gin.H{
 			"id":    1,
 			"name":  user.Name,
@@ -103,6 +92,10 @@ func UnifiedResponseDemo() {
 	fmt.Println("      Message string      `json:\"message\"`")
 	fmt.Println("  }")
 	fmt.Println()
+	fmt.Println("响应格式协商:")
+	fmt.Println("  Accept: application/xml / application/yaml / application/msgpack / application/x-protobuf")
+	fmt.Println("  或 ?format=xml，不支持的格式返回 406")
+	fmt.Println()
 	fmt.Println("成功响应示例:")
 	fmt.Println("  GET /users/123")
 	fmt.Println("  响应: {\"code\": 0, \"data\": {\"id\": \"123\", \"name\": \"John Doe\"}}")
@@ -111,6 +104,17 @@ func UnifiedResponseDemo() {
 	fmt.Println("  POST /users (参数错误)")
 	fmt.Println("  响应: {\"code\": 1001, \"message\": \"参数校验失败\"}")
 	fmt.Println()
+	fmt.Println("重试安全:")
+	fmt.Println("  POST /users 携带 Idempotency-Key 请求头，相同的键重试会重放首次响应，不会重复创建")
+	fmt.Println()
+	fmt.Println("响应缓存:")
+	fmt.Println("  GET /users/:id 响应带 ETag，重复请求 X-Cache: HIT，携带 If-None-Match 返回 304")
+	fmt.Println("  写接口调用 cache.InvalidatePath 使缓存失效，详见 HTTPCache 示例")
+	fmt.Println()
+	fmt.Println("安全响应头:")
+	fmt.Println("  secure.Middleware(secure.MustProfile(secure.ProfileAPI))，返回 HTML 的页面使用 web / strict 配置")
+	fmt.Println("  详见 SecureHeaders 示例")
+	fmt.Println()
 	fmt.Println("========== 错误代码规范 ==========")
 	fmt.Println()
 	fmt.Println("业务错误码规范:")
@@ -271,6 +275,7 @@ func RateLimitDemo() {
 	fmt.Println("  使用 Redis 实现分布式限流")
 	fmt.Println("  使用 go-redis/redis 或 goredis 库")
 	fmt.Println("  支持不同用户/IP的差异化限流策略")
+	fmt.Println("  按客户端限流挡不住整体流量突增，还需要限制服务并发（见 go run . LoadShedding）")
 }
 
 // VersionControlDemo 演示路由版本控制方案
@@ -469,6 +474,8 @@ func SwaggerDocumentationDemo() {
 	fmt.Println("  2002  - 缓存错误")
 	fmt.Println("  2003  - 第三方服务错误")
 	fmt.Println("  2004  - 内部服务器错误")
+	fmt.Println("  2005  - 处理超时")
+	fmt.Println("  2006  - 服务繁忙（过载保护）")
 	fmt.Println()
 	fmt.Println("业务逻辑错误 (3xxx):")
 	fmt.Println("  3001  - 业务规则违反")
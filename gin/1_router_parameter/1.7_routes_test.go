@@ -0,0 +1,18 @@
+package gin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-learning/gin/21_contract/contracttest"
+)
+
+// TestRouteContracts 用 testdata 中的契约用例覆盖 RouteGroupDemo、MiddlewareRouteDemo 的全部接口
+func TestRouteContracts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := os.DirFS("testdata")
+	contracttest.RunFS(t, RouteGroupRouter(), cases, "route_group.yaml")
+	contracttest.RunFS(t, MiddlewareRouteRouter(), cases, "middleware_route.yaml")
+}
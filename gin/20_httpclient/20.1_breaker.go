@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开，请求没有发出
+var ErrCircuitOpen = errors.New("httpclient: 熔断器已打开，暂停调用")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 关闭：正常放行
+	StateOpen                  // 打开：直接失败，不再请求下游
+	StateHalfOpen              // 半开：放行少量探测请求，成功则关闭，失败则重新打开
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOptions 熔断器配置，每个下游主机一个熔断器
+type BreakerOptions struct {
+	FailureThreshold int           // 连续失败多少次后打开，<= 0 表示不熔断
+	OpenTimeout      time.Duration // 打开后经过多久进入半开
+	HalfOpenProbes   int           // 半开时最多同时放行的探测请求数，全部成功后关闭
+}
+
+// DefaultBreakerOptions 默认熔断配置
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// outcome 一次调用的结果
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // 调用方取消等与下游无关的结果，不计入统计
+)
+
+// breaker 连续失败计数的熔断器
+type breaker struct {
+	options  BreakerOptions
+	now      func() time.Time
+	onChange func(from, to State)
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // 每次状态变化加 1，旧状态下发出的请求结果不再影响新状态
+	failures   int
+	openedAt   time.Time
+	probing    int // 半开时进行中的探测请求
+	successes  int // 半开时成功的探测请求
+}
+
+func newBreaker(options BreakerOptions, onChange func(from, to State)) *breaker {
+	return &breaker{options: options, now: time.Now, onChange: onChange}
+}
+
+// State 返回当前状态（打开超时后返回半开）
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.options.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// allow 判断能否发出请求，能的话返回记录结果的 done，必须调用且只调用一次
+func (b *breaker) allow() (done func(outcome), err error) {
+	if b.options.FailureThreshold <= 0 {
+		return func(outcome) {}, nil
+	}
+
+	b.mu.Lock()
+	var notify func()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.options.OpenTimeout {
+		notify = b.setState(StateHalfOpen)
+	}
+	switch b.state {
+	case StateOpen:
+		err = ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probing >= b.options.HalfOpenProbes {
+			err = ErrCircuitOpen
+		} else {
+			b.probing++
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	run(notify)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func(result outcome) {
+		once.Do(func() { run(b.record(generation, result)) })
+	}, nil
+}
+
+// record 记录结果，返回需要在锁外执行的状态变化通知
+func (b *breaker) record(generation uint64, result outcome) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return nil
+	}
+
+	switch b.state {
+	case StateClosed:
+		switch result {
+		case outcomeSuccess:
+			b.failures = 0
+		case outcomeFailure:
+			b.failures++
+			if b.failures >= b.options.FailureThreshold {
+				return b.setState(StateOpen)
+			}
+		}
+	case StateHalfOpen:
+		b.probing--
+		switch result {
+		case outcomeSuccess:
+			b.successes++
+			if b.successes >= b.options.HalfOpenProbes {
+				return b.setState(StateClosed)
+			}
+		case outcomeFailure:
+			return b.setState(StateOpen)
+		}
+	}
+	return nil
+}
+
+// setState 切换状态并重置计数，调用时必须持有锁
+func (b *breaker) setState(to State) func() {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.probing, b.successes = 0, 0, 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.onChange == nil {
+		return nil
+	}
+	return func() { b.onChange(from, to) }
+}
+
+func run(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
@@ -0,0 +1,284 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	tracing "go-learning/gin/10_tracing"
+	idempotency "go-learning/gin/9_idempotency"
+)
+
+// Attempt 一次请求尝试的结果，传给指标钩子
+type Attempt struct {
+	Host       string
+	Method     string
+	Attempt    int // 从 1 开始
+	StatusCode int // 没有收到响应时为 0
+	Err        error
+	Duration   time.Duration
+}
+
+// Hooks 观测钩子，用于记录指标和日志，不能阻塞
+type Hooks struct {
+	OnAttempt     func(a Attempt)                      // 每次尝试结束后（包括被熔断拒绝的请求，此时 Err 为 ErrCircuitOpen）
+	OnRetry       func(a Attempt, delay time.Duration) // 决定重试时，delay 为等待时间
+	OnStateChange func(host string, from, to State)    // 熔断器状态变化时
+}
+
+// Options 客户端配置
+type Options struct {
+	Timeout      time.Duration            // 单次尝试的超时时间（包括读取响应体）
+	HostTimeouts map[string]time.Duration // 按主机（host:port）覆盖超时，如慢的报表服务
+
+	MaxRetries  int           // 最多重试次数，0 表示不重试
+	BaseDelay   time.Duration // 第一次重试的退避上限，之后每次翻倍
+	MaxDelay    time.Duration // 退避上限；Retry-After 超过它时不再重试，直接返回响应
+	RetryStatus []int         // 需要重试的状态码
+
+	Breaker   BreakerOptions
+	Transport http.RoundTripper // 为空时使用 http.DefaultTransport
+	Hooks     Hooks
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		Timeout:     5 * time.Second,
+		MaxRetries:  2,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		RetryStatus: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		Breaker:     DefaultBreakerOptions(),
+	}
+}
+
+// Client 调用第三方服务的 HTTP 客户端：按主机超时、指数退避重试、按主机熔断
+//
+// 只有幂等请求会重试：GET、HEAD、OPTIONS、PUT、DELETE，或者带 Idempotency-Key 的请求。
+// POST 等非幂等请求失败时可能已经在下游生效，重试会造成重复下单、重复扣款。
+//
+// 请求 context 中的链路信息会写入 traceparent / X-Request-ID 请求头。
+type Client struct {
+	options Options
+	http    *http.Client
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New 创建客户端，未设置的字段使用默认值
+func New(options Options) *Client {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.BaseDelay <= 0 {
+		options.BaseDelay = defaults.BaseDelay
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = defaults.MaxDelay
+	}
+	if options.RetryStatus == nil {
+		options.RetryStatus = defaults.RetryStatus
+	}
+	if options.Breaker.OpenTimeout <= 0 {
+		options.Breaker.OpenTimeout = defaults.Breaker.OpenTimeout
+	}
+	if options.Breaker.HalfOpenProbes <= 0 {
+		options.Breaker.HalfOpenProbes = defaults.Breaker.HalfOpenProbes
+	}
+	transport := options.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Client{
+		options:  options,
+		http:     &http.Client{Transport: transport},
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// State 返回主机的熔断器状态
+func (c *Client) State(host string) State {
+	return c.breaker(host).State()
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		var onChange func(from, to State)
+		if hook := c.options.Hooks.OnStateChange; hook != nil {
+			onChange = func(from, to State) { hook(host, from, to) }
+		}
+		b = newBreaker(c.options.Breaker, onChange)
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// Do 发送请求，失败时按配置重试
+//
+// 返回的错误:
+//   - ErrCircuitOpen: 熔断器打开，请求没有发出
+//   - context.DeadlineExceeded: 单次尝试超时，或请求 context 到期
+//   - 其他网络错误
+//
+// 重试用尽后返回最后一次的响应（如 503），由调用方决定如何处理；响应体需要调用方关闭
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	ctx := req.Context()
+	tracing.Inject(ctx, req.Header)
+	retryable := idempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	b := c.breaker(host)
+
+	for attempt := 1; ; attempt++ {
+		a := Attempt{Host: host, Method: req.Method, Attempt: attempt}
+		done, err := b.allow()
+		if err != nil {
+			a.Err = err
+			c.observe(a)
+			return nil, fmt.Errorf("httpclient: %s %s: %w", req.Method, host, err)
+		}
+
+		start := time.Now()
+		resp, err := c.send(req, attempt)
+		a.Duration = time.Since(start)
+		a.Err = err
+		if resp != nil {
+			a.StatusCode = resp.StatusCode
+		}
+		c.observe(a)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			done(outcomeIgnored) // 调用方取消，与下游的健康状况无关
+		case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+			done(outcomeFailure)
+		default:
+			done(outcomeSuccess)
+		}
+
+		if ctx.Err() != nil || !retryable || attempt > c.options.MaxRetries || !c.shouldRetry(resp, err) {
+			if err != nil {
+				return nil, fmt.Errorf("httpclient: %s %s（第 %d 次尝试）: %w", req.Method, host, attempt, err)
+			}
+			return resp, nil
+		}
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > c.options.MaxDelay {
+					return resp, nil // 下游要求等待的时间太长，交给调用方处理
+				}
+				delay = max(delay, after)
+			}
+			// 读完响应体才能复用连接
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if hook := c.options.Hooks.OnRetry; hook != nil {
+			hook(a, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("httpclient: %s %s: %w", req.Method, host, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// send 发送一次尝试，超时覆盖到响应体读取完成
+func (c *Client) send(req *http.Request, attempt int) (*http.Response, error) {
+	timeout := c.options.Timeout
+	if d, ok := c.options.HostTimeouts[req.URL.Host]; ok {
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := c.http.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true // 连接失败、超时等
+	}
+	return slices.Contains(c.options.RetryStatus, resp.StatusCode)
+}
+
+// backoff 带完全抖动的指数退避：[0, min(MaxDelay, BaseDelay*2^(attempt-1))) 内随机，
+// 避免大量客户端在同一时刻重试，把刚恢复的下游再次压垮
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.options.MaxDelay
+	if shift := attempt - 1; shift < 30 {
+		ceiling = min(ceiling, c.options.BaseDelay<<shift)
+	}
+	return rand.N(ceiling) + 1
+}
+
+func (c *Client) observe(a Attempt) {
+	if hook := c.options.Hooks.OnAttempt; hook != nil {
+		hook(a)
+	}
+}
+
+// idempotent 判断请求能否安全重试
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(idempotency.HeaderKey) != ""
+}
+
+// retryAfter 解析 Retry-After（秒数或 HTTP 日期）
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// cancelBody 关闭响应体时取消单次尝试的 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,276 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	metrics "go-learning/gin/13_metrics"
+	response "go-learning/gin/8_content_negotiation"
+	idempotency "go-learning/gin/9_idempotency"
+)
+
+// 错误码
+const (
+	codeThirdParty = 2003 // 第三方服务错误
+	codeTimeout    = 2005 // 处理超时
+)
+
+// StatusError 下游返回了非 2xx 响应
+type StatusError struct {
+	StatusCode int
+	Body       string // 响应体的前 512 字节，便于排查
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpclient: 下游返回 %d: %s", e.StatusCode, e.Body)
+}
+
+// GetJSON 发送 GET 请求并把 2xx 响应解析到 v
+func (c *Client) GetJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// WriteError 把调用下游的错误转换为统一响应，不暴露下游的地址和错误细节
+//
+//   - 熔断打开: 503 + 2003，客户端可以稍后重试
+//   - 超时: 504 + 2005
+//   - 其他（网络错误、下游 5xx、响应无法解析）: 502 + 2003
+func WriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		response.ErrorWithStatus(c, http.StatusServiceUnavailable, codeThirdParty, "第三方服务暂不可用，请稍后重试")
+	case errors.Is(err, context.DeadlineExceeded):
+		response.ErrorWithStatus(c, http.StatusGatewayTimeout, codeTimeout, "第三方服务响应超时")
+	default:
+		response.ErrorWithStatus(c, http.StatusBadGateway, codeThirdParty, "第三方服务错误")
+	}
+}
+
+// MetricsHooks 把调用情况记录为 Prometheus 指标
+//
+// 指标:
+//   - http_client_requests_total{host,method,result}       每次尝试，result 为状态码、error 或 circuit_open
+//   - http_client_request_duration_seconds{host}           每次尝试的耗时
+//   - http_client_retries_total{host}                      重试次数
+//   - http_client_circuit_state{host}                      熔断器状态：0 关闭，1 打开，2 半开
+//
+// host 来自配置的下游地址，数量有限；不要把用户输入拼进主机名，否则会产生无限多的序列
+func MetricsHooks(registry *metrics.Registry) Hooks {
+	requests := registry.NewCounter("http_client_requests_total", "调用下游的请求数（每次尝试）", "host", "method", "result")
+	duration := registry.NewHistogram("http_client_request_duration_seconds", "调用下游的耗时（秒）", metrics.DefBuckets, "host")
+	retries := registry.NewCounter("http_client_retries_total", "调用下游的重试次数", "host")
+	state := registry.NewGauge("http_client_circuit_state", "熔断器状态：0 关闭，1 打开，2 半开", "host")
+
+	return Hooks{
+		OnAttempt: func(a Attempt) {
+			result := strconv.Itoa(a.StatusCode)
+			switch {
+			case errors.Is(a.Err, ErrCircuitOpen):
+				result = "circuit_open"
+			case a.Err != nil:
+				result = "error"
+			}
+			requests.Inc(a.Host, a.Method, result)
+			if a.Duration > 0 {
+				duration.Observe(a.Duration.Seconds(), a.Host)
+			}
+		},
+		OnRetry: func(a Attempt, _ time.Duration) {
+			retries.Inc(a.Host)
+		},
+		OnStateChange: func(host string, _, to State) {
+			state.Set(float64(to), host)
+		},
+	}
+}
+
+// HTTPClientDemo 演示重试、退避、超时和熔断
+func HTTPClientDemo() {
+	fmt.Println("=== 第三方调用：重试、退避与熔断示例 ===")
+	fmt.Println()
+
+	// 模拟四个下游，熔断器按主机隔离，互不影响
+	var nodeCalls atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rate-limited" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		// 区块链节点：前两次返回 503，之后恢复
+		if nodeCalls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"number":1003,"hash":"0x3"}`)
+	}))
+	defer node.Close()
+	var orderCalls atomic.Int32
+	orders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if orderCalls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer orders.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	var healthy atomic.Bool
+	unstable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer unstable.Close()
+	names := map[string]string{
+		node.Listener.Addr().String():     "node",
+		orders.Listener.Addr().String():   "orders",
+		slow.Listener.Addr().String():     "report",
+		unstable.Listener.Addr().String(): "payment",
+	}
+
+	registry := metrics.NewRegistry()
+	hooks := MetricsHooks(registry)
+	onAttempt := hooks.OnAttempt
+	hooks.OnAttempt = func(a Attempt) {
+		onAttempt(a)
+		result := strconv.Itoa(a.StatusCode)
+		if a.Err != nil {
+			result = "错误: " + strings.ReplaceAll(a.Err.Error(), a.Host, names[a.Host])
+		}
+		fmt.Printf("    第 %d 次尝试 %s %s -> %s\n", a.Attempt, a.Method, names[a.Host], result)
+	}
+	onStateChange := hooks.OnStateChange
+	hooks.OnStateChange = func(host string, from, to State) {
+		onStateChange(host, from, to)
+		fmt.Printf("    熔断器 %s: %s -> %s\n", names[host], from, to)
+	}
+
+	options := DefaultOptions()
+	options.BaseDelay = 20 * time.Millisecond
+	options.HostTimeouts = map[string]time.Duration{slow.Listener.Addr().String(): 100 * time.Millisecond}
+	options.Breaker = BreakerOptions{FailureThreshold: 3, OpenTimeout: 200 * time.Millisecond, HalfOpenProbes: 1}
+	options.Hooks = hooks
+	client := New(options)
+	ctx := context.Background()
+
+	fmt.Println("1. GET 遇到 503，退避后重试成功")
+	var block struct {
+		Number int64  `json:"number"`
+		Hash   string `json:"hash"`
+	}
+	err := client.GetJSON(ctx, node.URL+"/blocks/latest", &block)
+	fmt.Printf("  区块 #%d, 错误: %v\n\n", block.Number, err)
+
+	post := func(key string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, orders.URL+"/orders", strings.NewReader(`{"offeringId":1}`))
+		if key != "" {
+			req.Header.Set(idempotency.HeaderKey, key)
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	fmt.Println("2. POST 遇到 503 不重试（下游可能已经创建了订单）")
+	post("")
+	fmt.Println("  带 Idempotency-Key 的 POST 可以重试（请求体通过 GetBody 重新读取）")
+	post("order-20261018-001")
+	fmt.Println()
+
+	fmt.Println("3. Retry-After 超过 MaxDelay，直接返回给调用方")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, node.URL+"/rate-limited", nil)
+	if resp, err := client.Do(req); err == nil {
+		fmt.Printf("  %d Retry-After: %s\n\n", resp.StatusCode, resp.Header.Get("Retry-After"))
+		resp.Body.Close()
+	}
+
+	// 接口中调用下游，错误统一转换为 2003/2005
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/api/proxy", func(c *gin.Context) {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, c.Query("target"), nil)
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, 1001, "参数错误")
+			return
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			WriteError(c, &StatusError{StatusCode: resp.StatusCode})
+			return
+		}
+		response.Success(c, nil)
+	})
+	call := func(target string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/proxy?target="+target, nil))
+		fmt.Printf("  接口响应: %d %s\n", w.Code, w.Body.String())
+	}
+
+	fmt.Println("4. 报表服务单次尝试超时 100ms（按主机配置）")
+	call(slow.URL)
+	fmt.Println()
+
+	fmt.Println("5. 支付服务连续失败后熔断，请求不再发往下游")
+	for range 4 {
+		call(unstable.URL)
+	}
+	fmt.Println()
+
+	fmt.Println("6. 等待 OpenTimeout 后半开，探测成功则关闭")
+	time.Sleep(options.Breaker.OpenTimeout)
+	healthy.Store(true)
+	call(unstable.URL)
+	fmt.Println()
+
+	fmt.Println("7. 指标（节选）")
+	var buf strings.Builder
+	_, _ = registry.WriteTo(&buf)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "http_client_retries_total") || strings.HasPrefix(line, "http_client_circuit_state") {
+			for addr, name := range names {
+				line = strings.Replace(line, addr, name, 1)
+			}
+			fmt.Println("  " + line)
+		}
+	}
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 只重试幂等请求；POST 需要带 Idempotency-Key，并且下游按该键去重")
+	fmt.Println("  - 退避带随机抖动，并遵守 Retry-After")
+	fmt.Println("  - 熔断按主机隔离，一个下游故障不影响其他下游")
+	fmt.Println("  - 对外只返回 2003/2005，不暴露下游地址和错误细节")
+}
@@ -0,0 +1,298 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracing "go-learning/gin/10_tracing"
+	metrics "go-learning/gin/13_metrics"
+	idempotency "go-learning/gin/9_idempotency"
+)
+
+// failing 前 failures 次返回 status，之后返回 200，记录调用次数和请求体
+func failing(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32, *[]string) {
+	t.Helper()
+	var calls atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls, &bodies
+}
+
+func testOptions() Options {
+	options := DefaultOptions()
+	options.BaseDelay = time.Millisecond
+	options.MaxDelay = 10 * time.Millisecond
+	return options
+}
+
+// TestBreaker 测试熔断器状态变化
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	var changes []string
+	b := newBreaker(BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenProbes: 1}, func(from, to State) {
+		changes = append(changes, from.String()+"->"+to.String())
+	})
+	b.now = func() time.Time { return now }
+
+	fail := func() {
+		done, err := b.allow()
+		require.NoError(t, err)
+		done(outcomeFailure)
+	}
+
+	done, err := b.allow()
+	require.NoError(t, err)
+	fail()
+	fail()
+	assert.Equal(t, StateOpen, b.State())
+	done(outcomeSuccess)
+	assert.Equal(t, StateOpen, b.State(), "打开之前发出的请求不影响新状态")
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	probe, err := b.allow()
+	require.NoError(t, err)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "半开时只放行 HalfOpenProbes 个探测")
+	probe(outcomeFailure)
+	assert.Equal(t, StateOpen, b.State(), "探测失败重新打开")
+
+	now = now.Add(time.Minute)
+	probe, err = b.allow()
+	require.NoError(t, err)
+	probe(outcomeIgnored)
+	probe, err = b.allow()
+	require.NoError(t, err, "被忽略的探测释放名额")
+	probe(outcomeSuccess)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}, changes)
+
+	disabled := newBreaker(BreakerOptions{}, nil)
+	for range 10 {
+		done, err := disabled.allow()
+		require.NoError(t, err)
+		done(outcomeFailure)
+	}
+	assert.Equal(t, StateClosed, disabled.State())
+}
+
+// TestRetry 测试重试策略
+func TestRetry(t *testing.T) {
+	client := New(testOptions())
+	ctx := context.Background()
+
+	t.Run("GET 重试成功", func(t *testing.T) {
+		server, calls, _ := failing(t, 2, http.StatusServiceUnavailable)
+		var body struct{ OK bool }
+		require.NoError(t, client.GetJSON(ctx, server.URL, &body))
+		assert.True(t, body.OK)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("重试用尽返回最后的响应", func(t *testing.T) {
+		server, calls, _ := failing(t, 10, http.StatusBadGateway)
+		err := client.GetJSON(ctx, server.URL, &struct{}{})
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		assert.Equal(t, int32(3), calls.Load(), "1 次请求 + 2 次重试")
+	})
+
+	t.Run("500 不重试", func(t *testing.T) {
+		server, calls, _ := failing(t, 1, http.StatusInternalServerError)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("POST 只在带 Idempotency-Key 时重试", func(t *testing.T) {
+		server, calls, bodies := failing(t, 2, http.StatusServiceUnavailable)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(`{"n":1}`))
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+
+		req, _ = http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(`{"n":2}`))
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{`{"n":1}`, `{"n":2}`, `{"n":2}`}, *bodies, "重试时重新发送请求体")
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		var calls atomic.Int32
+		var seconds atomic.Value
+		seconds.Store("0")
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", seconds.Load().(string))
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		calls.Store(0)
+		seconds.Store("60")
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "等待时间超过 MaxDelay，不重试")
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("退避", func(t *testing.T) {
+		c := New(Options{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+		for attempt := 1; attempt <= 40; attempt++ {
+			delay := c.backoff(attempt)
+			assert.Positive(t, delay)
+			assert.LessOrEqual(t, delay, min(time.Second, 100*time.Millisecond<<min(attempt-1, 10)))
+		}
+	})
+}
+
+// TestTimeoutAndBreaker 测试按主机超时、熔断与调用方取消
+func TestTimeoutAndBreaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	host := server.Listener.Addr().String()
+
+	var changes atomic.Int32
+	options := testOptions()
+	options.Timeout = time.Minute
+	options.HostTimeouts = map[string]time.Duration{host: 20 * time.Millisecond}
+	options.Breaker = BreakerOptions{FailureThreshold: 3, OpenTimeout: time.Minute}
+	options.Hooks.OnStateChange = func(string, State, State) { changes.Add(1) }
+	client := New(options)
+
+	t.Run("调用方取消不计入失败", func(t *testing.T) {
+		for range 5 {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			_, err := client.Do(req)
+			cancel()
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		assert.Equal(t, StateClosed, client.State(host))
+	})
+
+	calls.Store(0)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(3), calls.Load(), "每次超时都会重试")
+	assert.Equal(t, StateOpen, client.State(host))
+	assert.Equal(t, int32(1), changes.Load())
+
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), calls.Load(), "熔断后请求不再发出")
+
+	other, _, _ := failing(t, 0, 0)
+	req, _ = http.NewRequest(http.MethodGet, other.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err, "其他主机不受影响")
+	resp.Body.Close()
+}
+
+// TestObservability 测试链路传递、指标和错误响应
+func TestObservability(t *testing.T) {
+	var traceparent, requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.HeaderTraceparent)
+		requestID = r.Header.Get(tracing.HeaderRequestID)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	options := testOptions()
+	options.MaxRetries = 1
+	options.Hooks = MetricsHooks(registry)
+	client := New(options)
+
+	sc := tracing.SpanContext{TraceID: strings.Repeat("a", 32), SpanID: strings.Repeat("b", 16), Sampled: true}
+	ctx := tracing.ContextWithSpanContext(context.Background(), sc)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, sc.Traceparent(), traceparent)
+	assert.Empty(t, requestID)
+
+	var buf strings.Builder
+	_, err = registry.WriteTo(&buf)
+	require.NoError(t, err)
+	host := server.Listener.Addr().String()
+	assert.Contains(t, buf.String(), `http_client_requests_total{host="`+host+`",method="GET",result="503"} 2`)
+	assert.Contains(t, buf.String(), `http_client_retries_total{host="`+host+`"} 1`)
+
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err    error
+		status int
+		code   int
+	}{
+		{ErrCircuitOpen, http.StatusServiceUnavailable, codeThirdParty},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, codeTimeout},
+		{&StatusError{StatusCode: http.StatusInternalServerError}, http.StatusBadGateway, codeThirdParty},
+		{errors.New("connection refused"), http.StatusBadGateway, codeThirdParty},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		WriteError(c, tt.err)
+		assert.Equal(t, tt.status, w.Code, tt.err.Error())
+		var body struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, tt.code, body.Code)
+		assert.NotContains(t, body.Message, "connection refused", "不暴露下游错误细节")
+	}
+}
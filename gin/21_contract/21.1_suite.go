@@ -0,0 +1,153 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Suite 一组按顺序执行的接口用例，前面步骤捕获的变量可以在后面的步骤中使用
+//
+//	name: JWT 鉴权
+//	vars:
+//	  password: admin123
+//	steps:
+//	  - name: 登录
+//	    request:
+//	      method: POST
+//	      path: /api/login
+//	      body: {username: admin, password: "${password}"}
+//	    expect:
+//	      status: 200
+//	      json:
+//	        code: 0
+//	        data.token: "@notEmpty"
+//	    capture:
+//	      token: data.token
+//	  - name: 查看资料
+//	    request:
+//	      method: GET
+//	      path: /api/profile
+//	      headers: {Authorization: "Bearer ${token}"}
+//	    expect:
+//	      status: 200
+//	      json: {data.userID: user123}
+type Suite struct {
+	Name  string            `json:"name"`
+	Vars  map[string]string `json:"vars"`
+	Steps []Step            `json:"steps"`
+}
+
+// Step 一次请求及其断言
+type Step struct {
+	Name    string            `json:"name"`
+	Request Request           `json:"request"`
+	Expect  Expect            `json:"expect"`
+	Capture map[string]string `json:"capture"` // 变量名 -> JSON 路径，或 header:名称 取响应头
+}
+
+// Request 请求，path、headers 和 body 中的字符串支持 ${变量}
+type Request struct {
+	Method  string            `json:"method"` // 默认 GET
+	Path    string            `json:"path"`   // 可以带查询参数
+	Headers map[string]string `json:"headers"`
+	// Body 字符串原样发送；对象或数组编码为 JSON，未设置 Content-Type 时使用 application/json
+	Body any `json:"body"`
+}
+
+// Expect 断言，期望值为字符串时支持 ${变量} 和以下匹配器:
+//
+//	@exists          字段存在（值可以为 null）
+//	@absent          字段不存在
+//	@notEmpty        存在且不是 null、空字符串、空数组、空对象
+//	@len:N           数组、对象或字符串的长度为 N
+//	@regex:表达式    字符串（数字会先转为字符串）匹配正则
+//	@contains:文本   字符串包含文本
+//
+// 其他值按 JSON 语义比较：1 与 1.0 相等，对象与数组需要完全一致
+type Expect struct {
+	Status       int               `json:"status"`
+	Headers      map[string]string `json:"headers"`
+	JSON         map[string]any    `json:"json"` // JSON 路径 -> 期望值，路径写法见 Lookup
+	BodyContains string            `json:"bodyContains"`
+}
+
+// Parse 解析用例，name 的扩展名为 .json 时按 JSON 解析，否则按 YAML 解析
+//
+// 未知字段视为错误，避免 expect 写成 expected 之类的拼写错误让断言静默失效
+func Parse(name string, data []byte) (Suite, error) {
+	if ext := strings.ToLower(filepath.Ext(name)); ext != ".json" {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return Suite{}, fmt.Errorf("contract: 解析 %s 失败: %w", name, err)
+		}
+		data = converted
+	}
+
+	var suite Suite
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&suite); err != nil {
+		return Suite{}, fmt.Errorf("contract: 解析 %s 失败: %w", name, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	for i, step := range suite.Steps {
+		if step.Request.Path == "" {
+			return Suite{}, fmt.Errorf("contract: %s 第 %d 步缺少 request.path", name, i+1)
+		}
+		if step.Name == "" {
+			suite.Steps[i].Name = fmt.Sprintf("%d %s %s", i+1, step.Request.method(), step.Request.Path)
+		}
+	}
+	return suite, nil
+}
+
+// Load 读取并解析用例文件
+func Load(path string) (Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Suite{}, err
+	}
+	return Parse(path, data)
+}
+
+// LoadFS 读取 fsys 中匹配 pattern 的所有用例文件，按文件名排序
+//
+//	suites, err := contract.LoadFS(os.DirFS("testdata"), "*.yaml")
+func LoadFS(fsys fs.FS, pattern string) ([]Suite, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("contract: 没有匹配 %s 的用例文件", pattern)
+	}
+	suites := make([]Suite, 0, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		suite, err := Parse(name, data)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+func (r Request) method() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
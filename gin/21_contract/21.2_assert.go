@@ -0,0 +1,221 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Lookup 按路径在解码后的 JSON 中取值
+//
+//	$ 或空字符串        整个响应体
+//	data.token          对象字段，可以带 $. 前缀
+//	data.items[0].id    数组下标，负数从末尾开始
+//	data["x-key"]       字段名包含 . 或 [ 时使用引号
+func Lookup(doc any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := doc
+	for path != "" {
+		var key string
+		index, isIndex := 0, false
+		switch {
+		case strings.HasPrefix(path, `["`):
+			end := strings.Index(path, `"]`)
+			if end < 0 {
+				return nil, false
+			}
+			key, path = path[2:end], path[end+2:]
+		case path[0] == '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, false
+			}
+			n, err := strconv.Atoi(path[1:end])
+			if err != nil {
+				return nil, false
+			}
+			index, isIndex, path = n, true, path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			key, path = path[:end], path[end:]
+		}
+		path = strings.TrimPrefix(path, ".")
+
+		if isIndex {
+			list, ok := current.([]any)
+			if index < 0 {
+				index += len(list)
+			}
+			if !ok || index < 0 || index >= len(list) {
+				return nil, false
+			}
+			current = list[index]
+			continue
+		}
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// match 比较实际值与期望值，不匹配时返回原因
+func match(expected, actual any, found bool) string {
+	if s, ok := expected.(string); ok && strings.HasPrefix(s, "@") {
+		name, arg, _ := strings.Cut(s[1:], ":")
+		switch name {
+		case "exists":
+			if !found {
+				return "字段不存在"
+			}
+			return ""
+		case "absent":
+			if found {
+				return "字段应当不存在，实际为 " + format(actual)
+			}
+			return ""
+		}
+		if !found {
+			return "字段不存在"
+		}
+		switch name {
+		case "notEmpty":
+			if length(actual) == 0 {
+				return "值为空: " + format(actual)
+			}
+			return ""
+		case "len":
+			want, err := strconv.Atoi(arg)
+			if err != nil {
+				return "无效的匹配器 " + s
+			}
+			if got := length(actual); got != want {
+				return fmt.Sprintf("长度为 %d，期望 %d", got, want)
+			}
+			return ""
+		case "regex":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return "无效的正则 " + arg + ": " + err.Error()
+			}
+			if !re.MatchString(text(actual)) {
+				return fmt.Sprintf("%s 不匹配 %s", format(actual), arg)
+			}
+			return ""
+		case "contains":
+			if !strings.Contains(text(actual), arg) {
+				return fmt.Sprintf("%s 不包含 %q", format(actual), arg)
+			}
+			return ""
+		}
+		return "未知的匹配器 " + s
+	}
+
+	if !found {
+		return "字段不存在，期望 " + format(expected)
+	}
+	if !reflect.DeepEqual(normalize(expected), normalize(actual)) {
+		return fmt.Sprintf("实际为 %s，期望 %s", format(actual), format(expected))
+	}
+	return ""
+}
+
+// normalize 经过一次 JSON 编解码，让 int 与 float64、不同的 map 类型可以直接比较
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// length 空值的长度为 0；数字、布尔值视为非空
+func length(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len([]rune(v))
+	case []any:
+		return len(v)
+	case map[string]any:
+		return len(v)
+	}
+	return 1
+}
+
+// text 把标量转为字符串，用于正则匹配和捕获变量
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return format(v)
+}
+
+func format(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+var varPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// substitute 替换字符串中的 ${变量}，未定义的变量返回错误
+func substitute(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[2 : len(m)-1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("未定义的变量 %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// substituteAll 递归替换对象、数组中所有字符串的 ${变量}
+func substituteAll(v any, vars map[string]string) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return substitute(v, vars)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			replaced, err := substituteAll(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = replaced
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			replaced, err := substituteAll(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = replaced
+		}
+		return out, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,354 @@
+package contract
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// Result 一个步骤的执行结果
+type Result struct {
+	Step     string
+	Method   string
+	Path     string // 替换变量后的路径
+	Status   int
+	Failures []string // 为空表示通过
+	Skipped  bool     // 前面的步骤失败，没有执行
+}
+
+// Passed 步骤是否通过
+func (r Result) Passed() bool {
+	return !r.Skipped && len(r.Failures) == 0
+}
+
+// Execute 对 handler（通常是 *gin.Engine）按顺序执行用例，某一步失败后跳过剩余步骤
+//
+// 请求直接交给 handler.ServeHTTP，不经过网络；每次执行使用独立的变量，可以并发执行不同的用例
+func Execute(handler http.Handler, suite Suite) []Result {
+	vars := maps.Clone(suite.Vars)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	results := make([]Result, 0, len(suite.Steps))
+	failed := false
+	for _, step := range suite.Steps {
+		if failed {
+			results = append(results, Result{Step: step.Name, Method: step.Request.method(), Path: step.Request.Path, Skipped: true})
+			continue
+		}
+		result := runStep(handler, step, vars)
+		failed = !result.Passed()
+		results = append(results, result)
+	}
+	return results
+}
+
+// runStep 执行一个步骤，通过后把捕获的变量写入 vars
+func runStep(handler http.Handler, step Step, vars map[string]string) Result {
+	result := Result{Step: step.Name, Method: step.Request.method(), Path: step.Request.Path}
+	fail := func(format string, args ...any) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	req, err := buildRequest(step.Request, vars)
+	if err != nil {
+		fail("构造请求失败: %v", err)
+		return result
+	}
+	result.Path = req.URL.RequestURI()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	result.Status = w.Code
+
+	expect := step.Expect
+	if expect.Status != 0 && w.Code != expect.Status {
+		fail("状态码为 %d，期望 %d，响应: %s", w.Code, expect.Status, snippet(w.Body.String()))
+	}
+	for _, name := range slices.Sorted(maps.Keys(expect.Headers)) {
+		values := w.Header().Values(name)
+		if reason := matchValue(expect.Headers[name], strings.Join(values, ", "), len(values) > 0, vars); reason != "" {
+			fail("响应头 %s: %s", name, reason)
+		}
+	}
+	if expect.BodyContains != "" {
+		want, err := substitute(expect.BodyContains, vars)
+		if err != nil {
+			fail("bodyContains: %v", err)
+		} else if !strings.Contains(w.Body.String(), want) {
+			fail("响应体不包含 %q，响应: %s", want, snippet(w.Body.String()))
+		}
+	}
+
+	var doc any
+	needJSON := len(expect.JSON) > 0 || slices.ContainsFunc(slices.Collect(maps.Values(step.Capture)), func(path string) bool {
+		return !strings.HasPrefix(path, "header:")
+	})
+	if needJSON {
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			fail("响应不是 JSON: %s", snippet(w.Body.String()))
+			return result
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(expect.JSON)) {
+		actual, found := Lookup(doc, path)
+		if reason := matchValue(expect.JSON[path], actual, found, vars); reason != "" {
+			fail("%s: %s", path, reason)
+		}
+	}
+	if len(result.Failures) > 0 {
+		return result
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(step.Capture)) {
+		path := step.Capture[name]
+		if header, ok := strings.CutPrefix(path, "header:"); ok {
+			if value := w.Header().Get(header); value != "" {
+				vars[name] = value
+				continue
+			}
+			fail("捕获 %s: 响应头 %s 不存在", name, header)
+			continue
+		}
+		value, found := Lookup(doc, path)
+		if !found {
+			fail("捕获 %s: %s 不存在", name, path)
+			continue
+		}
+		vars[name] = text(value)
+	}
+	return result
+}
+
+// matchValue 替换期望值中的变量后比较
+//
+// 期望值恰好是一个 ${变量} 时按字符串比较，变量都是字符串，而捕获的 ID 在响应中通常是数字
+func matchValue(expected, actual any, found bool, vars map[string]string) string {
+	if s, ok := expected.(string); ok {
+		whole := varPattern.FindStringIndex(s)
+		replaced, err := substitute(s, vars)
+		if err != nil {
+			return err.Error()
+		}
+		if whole != nil && whole[0] == 0 && whole[1] == len(s) && found {
+			if text(actual) != replaced {
+				return fmt.Sprintf("实际为 %s，期望 %s", format(actual), replaced)
+			}
+			return ""
+		}
+		expected = replaced
+	} else {
+		replaced, err := substituteAll(expected, vars)
+		if err != nil {
+			return err.Error()
+		}
+		expected = replaced
+	}
+	return match(expected, actual, found)
+}
+
+func buildRequest(r Request, vars map[string]string) (*http.Request, error) {
+	path, err := substitute(r.Path, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := r.Body.(type) {
+	case nil:
+	case string:
+		s, err := substitute(b, vars)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(s)
+	default:
+		replaced, err := substituteAll(b, vars)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(replaced)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req := httptest.NewRequest(r.method(), path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range r.Headers {
+		if value, err = substitute(value, vars); err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// snippet 截断过长的响应体，失败信息中只需要开头部分
+func snippet(s string) string {
+	const limit = 300
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "...（共 " + strconv.Itoa(len(s)) + " 字节）"
+}
+
+//go:embed cases
+var caseFS embed.FS
+
+// newOrderRouter 示例订单接口：登录后创建、查询订单
+func newOrderRouter() *gin.Engine {
+	const token = "token-admin"
+	var (
+		mu     sync.Mutex
+		orders = map[int]gin.H{}
+		nextID = 1000
+	)
+
+	router := gin.New()
+	router.POST("/api/login", func(c *gin.Context) {
+		var login struct {
+			Username string `json:"username" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&login); err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, 1001, "参数校验失败")
+			return
+		}
+		if login.Username != "admin" || login.Password != "admin123" {
+			response.ErrorWithStatus(c, http.StatusUnauthorized, 1002, "用户名或密码错误")
+			return
+		}
+		response.Success(c, gin.H{"token": token, "expiresIn": 7200})
+	})
+
+	api := router.Group("/api", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer "+token {
+			response.ErrorWithStatus(c, http.StatusUnauthorized, 1002, "未登录")
+			c.Abort()
+		}
+	})
+	api.POST("/orders", func(c *gin.Context) {
+		var req struct {
+			OfferingID int     `json:"offeringId" binding:"required"`
+			Quantity   int     `json:"quantity" binding:"required,min=1"`
+			Price      float64 `json:"price" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, 1001, "参数校验失败")
+			return
+		}
+		mu.Lock()
+		nextID++
+		order := gin.H{
+			"id":         nextID,
+			"orderNo":    fmt.Sprintf("ORD%s%04d", time.Now().Format("20060102"), nextID),
+			"offeringId": req.OfferingID,
+			"totalPrice": float64(req.Quantity) * req.Price,
+			"status":     "pending",
+			"items":      []gin.H{{"offeringId": req.OfferingID, "quantity": req.Quantity}},
+		}
+		orders[nextID] = order
+		mu.Unlock()
+		c.Header("Location", fmt.Sprintf("/api/orders/%d", nextID))
+		c.JSON(http.StatusCreated, response.Response{Code: 0, Message: "创建成功", Data: order})
+	})
+	api.GET("/orders/:id", func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("id"))
+		mu.Lock()
+		order, ok := orders[id]
+		mu.Unlock()
+		if !ok {
+			response.ErrorWithStatus(c, http.StatusNotFound, 1004, "订单不存在")
+			return
+		}
+		response.Success(c, order)
+	})
+	return router
+}
+
+// ContractDemo 演示用 YAML 描述接口用例并执行
+func ContractDemo() {
+	fmt.Println("=== 声明式接口契约测试示例 ===")
+	fmt.Println()
+
+	data, _ := caseFS.ReadFile("cases/orders.yaml")
+	fmt.Println("用例文件 cases/orders.yaml（节选）:")
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[:min(24, len(lines))] {
+		fmt.Println("  " + line)
+	}
+	fmt.Println("  ...")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := newOrderRouter()
+	suites, err := LoadFS(caseFS, "cases/*.yaml")
+	if err != nil {
+		fmt.Println("加载用例失败:", err)
+		return
+	}
+	report := func(suite Suite) {
+		fmt.Printf("用例: %s\n", suite.Name)
+		for _, r := range Execute(router, suite) {
+			switch {
+			case r.Skipped:
+				fmt.Printf("  SKIP %s\n", r.Step)
+			case r.Passed():
+				fmt.Printf("  PASS %s (%s %s -> %d)\n", r.Step, r.Method, r.Path, r.Status)
+			default:
+				fmt.Printf("  FAIL %s (%s %s -> %d)\n", r.Step, r.Method, r.Path, r.Status)
+				for _, f := range r.Failures {
+					fmt.Printf("       %s\n", f)
+				}
+			}
+		}
+		fmt.Println()
+	}
+	for _, suite := range suites {
+		report(suite)
+	}
+
+	// 接口改动后契约不再满足时的输出
+	broken, _ := Parse("broken.yaml", []byte(`
+name: 期望与实现不一致
+steps:
+  - name: 登录
+    request: {method: POST, path: /api/login, body: {username: admin, password: admin123}}
+    expect:
+      status: 200
+      json: {data.accessToken: "@notEmpty", data.expiresIn: 3600}
+  - name: 依赖登录结果的步骤
+    request: {path: /api/orders/1}
+`))
+	report(broken)
+
+	fmt.Println("在测试中使用（contracttest 子包，每个步骤是一个子测试）:")
+	fmt.Println("  func TestOrderAPI(t *testing.T) {")
+	fmt.Println("      contracttest.RunFS(t, newRouter(), os.DirFS(\"testdata\"), \"*.yaml\")")
+	fmt.Println("  }")
+	fmt.Println()
+	fmt.Println("要点:")
+	fmt.Println("  - 请求直接交给 router.ServeHTTP，不需要启动服务")
+	fmt.Println("  - capture 把令牌、订单 ID 等保存为变量，后面的步骤用 ${变量} 引用")
+	fmt.Println("  - 某一步失败后跳过剩余步骤，避免一连串无意义的失败")
+	fmt.Println("  - 用例文件中的未知字段视为错误，拼错的断言不会静默通过")
+}
@@ -0,0 +1,155 @@
+package contract
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLookup 测试 JSON 路径
+func TestLookup(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{"code":0,"data":{"items":[{"id":1},{"id":2}],"x.y":"dot","empty":null}}`), &doc))
+
+	tests := []struct {
+		path  string
+		want  any
+		found bool
+	}{
+		{"code", 0.0, true},
+		{"$.code", 0.0, true},
+		{"data.items[1].id", 2.0, true},
+		{"data.items[-1].id", 2.0, true},
+		{`data["x.y"]`, "dot", true},
+		{"data.empty", nil, true},
+		{"data.items[2]", nil, false},
+		{"data.missing", nil, false},
+		{"code.x", nil, false},
+		{"data.items[x]", nil, false},
+	}
+	for _, tt := range tests {
+		got, found := Lookup(doc, tt.path)
+		assert.Equal(t, tt.found, found, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+	got, found := Lookup(doc, "$")
+	assert.True(t, found)
+	assert.Equal(t, doc, got)
+}
+
+// TestMatch 测试匹配器
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expected any
+		actual   any
+		found    bool
+		ok       bool
+	}{
+		{1, 1.0, true, true},
+		{"1", 1.0, true, false},
+		{map[string]any{"a": []any{1, "x"}}, map[string]any{"a": []any{1.0, "x"}}, true, true},
+		{"@exists", nil, true, true},
+		{"@exists", nil, false, false},
+		{"@absent", nil, false, true},
+		{"@absent", "x", true, false},
+		{"@notEmpty", "", true, false},
+		{"@notEmpty", []any{}, true, false},
+		{"@notEmpty", 0.0, true, true},
+		{"@len:2", []any{1.0, 2.0}, true, true},
+		{"@len:2", "中文", true, true},
+		{"@len:x", "ab", true, false},
+		{"@regex:^ORD\\d+$", "ORD001", true, true},
+		{"@regex:^\\d+$", 42.0, true, true},
+		{"@regex:(", "x", true, false},
+		{"@contains:登录", "请先登录", true, true},
+		{"@unknown", "x", true, false},
+		{"x", nil, false, false},
+	}
+	for _, tt := range tests {
+		reason := match(tt.expected, tt.actual, tt.found)
+		assert.Equal(t, tt.ok, reason == "", "%v ~ %v: %s", tt.expected, tt.actual, reason)
+	}
+
+	vars := map[string]string{"id": "1001"}
+	assert.Empty(t, matchValue("${id}", 1001.0, true, vars), "单独的变量按字符串比较")
+	assert.NotEmpty(t, matchValue("${id}", 1002.0, true, vars))
+	assert.NotEmpty(t, matchValue("${id}", nil, false, vars))
+	assert.Empty(t, matchValue("/orders/${id}", "/orders/1001", true, vars))
+	assert.Contains(t, matchValue("${missing}", "x", true, vars), "missing")
+}
+
+// TestParse 测试用例文件解析
+func TestParse(t *testing.T) {
+	suite, err := Parse("login.json", []byte(`{"steps":[{"request":{"method":"post","path":"/login"},"expect":{"status":200}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "login", suite.Name, "默认使用文件名")
+	assert.Equal(t, "1 POST /login", suite.Steps[0].Name)
+
+	suite, err = Parse("a.yaml", []byte("name: 示例\nsteps:\n  - request: {path: /}\n    expect: {json: {data.items: \"@len:0\"}}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "示例", suite.Name)
+	assert.Equal(t, "@len:0", suite.Steps[0].Expect.JSON["data.items"])
+
+	_, err = Parse("typo.yaml", []byte("steps:\n  - request: {path: /}\n    expected: {status: 200}\n"))
+	assert.ErrorContains(t, err, "expected", "未知字段")
+	_, err = Parse("nopath.yaml", []byte("steps:\n  - request: {method: GET}\n"))
+	assert.ErrorContains(t, err, "request.path")
+	_, err = Parse("bad.json", []byte(`{`))
+	assert.Error(t, err)
+
+	_, err = LoadFS(caseFS, "cases/*.json")
+	assert.Error(t, err, "没有匹配的文件")
+}
+
+// TestRun 测试执行用例
+func TestRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := newOrderRouter()
+	suites, err := LoadFS(caseFS, "cases/*.yaml")
+	require.NoError(t, err)
+	for _, suite := range suites {
+		for _, result := range Execute(router, suite) {
+			assert.True(t, result.Passed(), "%s: %v", result.Step, result.Failures)
+		}
+	}
+
+	suite, err := Parse("fail.yaml", []byte(`
+steps:
+  - name: 登录
+    request: {method: POST, path: /api/login, body: {username: admin, password: admin123}}
+    capture: {token: data.token, missing: header:X-Missing}
+  - name: 未定义的变量
+    request: {path: "/api/orders/${orderId}"}
+  - name: 跳过
+    request: {path: /api/orders/1}
+`))
+	require.NoError(t, err)
+	results := Execute(router, suite)
+	require.Len(t, results, 3)
+	assert.False(t, results[0].Passed())
+	assert.Equal(t, http.StatusOK, results[0].Status)
+	assert.Equal(t, []string{"捕获 missing: 响应头 X-Missing 不存在"}, results[0].Failures)
+	assert.True(t, results[1].Skipped)
+	assert.True(t, results[2].Skipped)
+
+	suite.Steps[0].Capture = map[string]string{"token": "data.token"}
+	results = Execute(router, suite)
+	assert.True(t, results[0].Passed())
+	assert.Equal(t, []string{"构造请求失败: 未定义的变量 orderId"}, results[1].Failures)
+
+	suite, err = Parse("raw.yaml", []byte(`
+steps:
+  - request:
+      method: POST
+      path: /api/login
+      headers: {Content-Type: application/json}
+      body: '{"username": "admin"'
+    expect: {status: 400, bodyContains: "1001"}
+`))
+	require.NoError(t, err)
+	assert.True(t, Execute(router, suite)[0].Passed(), "字符串请求体原样发送")
+}
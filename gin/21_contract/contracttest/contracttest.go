@@ -0,0 +1,50 @@
+// Package contracttest 在 go test 中执行接口契约用例
+//
+// 与 contract 包分开，contract 包不导入 testing，被示例运行器引用时不会把 testing 链接进主程序
+// （与 net/http/httptest、testing/fstest 的拆分方式相同）
+package contracttest
+
+import (
+	"io/fs"
+	"net/http"
+	"testing"
+
+	contract "go-learning/gin/21_contract"
+)
+
+// Run 在测试中执行用例，每个步骤是一个子测试
+//
+//	func TestOrderAPI(t *testing.T) {
+//		suite, err := contract.Load("testdata/orders.yaml")
+//		require.NoError(t, err)
+//		contracttest.Run(t, newRouter(), suite)
+//	}
+func Run(t *testing.T, handler http.Handler, suite contract.Suite) {
+	t.Helper()
+	t.Run(suite.Name, func(t *testing.T) {
+		for _, result := range contract.Execute(handler, suite) {
+			t.Run(result.Step, func(t *testing.T) {
+				if result.Skipped {
+					t.Skip("前面的步骤失败，跳过")
+				}
+				for _, failure := range result.Failures {
+					t.Errorf("%s %s: %s", result.Method, result.Path, failure)
+				}
+			})
+		}
+	})
+}
+
+// RunFS 执行 fsys 中匹配 pattern 的所有用例文件
+//
+//	contracttest.RunFS(t, router, os.DirFS("testdata"), "*.yaml")
+func RunFS(t *testing.T, handler http.Handler, fsys fs.FS, pattern string) {
+	t.Helper()
+	suites, err := contract.LoadFS(fsys, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, suite := range suites {
+		Run(t, handler, suite)
+	}
+}
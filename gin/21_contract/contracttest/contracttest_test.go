@@ -0,0 +1,30 @@
+package contracttest
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRunFS 每个用例文件、每个步骤都作为子测试执行
+func TestRunFS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+	fsys := fstest.MapFS{
+		"ping.yaml": {Data: []byte(`
+name: ping
+steps:
+  - name: 健康检查
+    request: {path: /ping}
+    expect:
+      status: 200
+      json: {message: pong}
+`)},
+	}
+	RunFS(t, router, fsys, "*.yaml")
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route 一条路由及其完整的处理链
+type Route struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Handler    string   `json:"handler"`    // 最后一个处理函数
+	Middleware []string `json:"middleware"` // 按执行顺序排列：全局 Use -> 分组 Use -> 路由上的中间件
+}
+
+// Inspect 列出 engine 的所有路由及中间件链，按路径、方法排序
+//
+// gin.Engine.Routes() 只提供最后一个处理函数，中间件链保存在路由树节点中（未导出），
+// 这里通过反射读取函数地址再解析函数名，不会调用任何处理函数。
+// 依赖 gin 的内部结构（go.mod 中的 v1.11.0），结构变化导致读取失败时 Middleware 为空，
+// 其他字段不受影响，TestInspect 会发现这种情况。
+//
+// 函数名是编译器生成的名称：中间件构造函数返回的闭包显示为 middleware.JWTAuth.func1，
+// 直接写在函数内的匿名函数显示为所在函数名加 .funcN
+func Inspect(engine *gin.Engine) []Route {
+	chains := handlerChains(engine)
+	infos := engine.Routes()
+	routes := make([]Route, 0, len(infos))
+	for _, info := range infos {
+		route := Route{Method: info.Method, Path: info.Path, Handler: shortName(info.Handler)}
+		if pcs := chains[info.Method+" "+info.Path]; len(pcs) > 0 {
+			route.Middleware = make([]string, 0, len(pcs)-1)
+			for _, pc := range pcs[:len(pcs)-1] {
+				route.Middleware = append(route.Middleware, funcName(pc))
+			}
+		}
+		routes = append(routes, route)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return methodOrder(routes[i].Method) < methodOrder(routes[j].Method)
+	})
+	return routes
+}
+
+// WriteTable 以表格形式输出路由，中间件链用 -> 连接
+func WriteTable(w io.Writer, routes []Route) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tMIDDLEWARE")
+	for _, r := range routes {
+		middleware := strings.Join(r.Middleware, " -> ")
+		if middleware == "" {
+			middleware = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Method, r.Path, r.Handler, middleware)
+	}
+	return tw.Flush()
+}
+
+// handlerChains 遍历 engine.trees，返回 "方法 路径" -> 处理链中各函数的地址
+func handlerChains(engine *gin.Engine) map[string][]uintptr {
+	chains := make(map[string][]uintptr)
+	trees := reflect.ValueOf(engine).Elem().FieldByName("trees")
+	if !trees.IsValid() || trees.Kind() != reflect.Slice {
+		return chains
+	}
+	for i := range trees.Len() {
+		tree := trees.Index(i)
+		method, root := tree.FieldByName("method"), tree.FieldByName("root")
+		if method.Kind() != reflect.String || root.Kind() != reflect.Pointer {
+			return chains
+		}
+		walk(root, method.String(), chains)
+	}
+	return chains
+}
+
+func walk(n reflect.Value, method string, chains map[string][]uintptr) {
+	if n.IsNil() {
+		return
+	}
+	n = n.Elem()
+	handlers, fullPath, children := n.FieldByName("handlers"), n.FieldByName("fullPath"), n.FieldByName("children")
+	if handlers.Kind() != reflect.Slice || fullPath.Kind() != reflect.String || children.Kind() != reflect.Slice {
+		return
+	}
+	if handlers.Len() > 0 {
+		pcs := make([]uintptr, handlers.Len())
+		for i := range pcs {
+			pcs[i] = handlers.Index(i).Pointer() // 只取地址，不需要（也无法）调用未导出字段中的函数
+		}
+		chains[method+" "+fullPath.String()] = pcs
+	}
+	for i := range children.Len() {
+		walk(children.Index(i), method, chains)
+	}
+}
+
+func funcName(pc uintptr) string {
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		return shortName(fn.Name())
+	}
+	return fmt.Sprintf("0x%x", pc)
+}
+
+// shortName 去掉包路径：go-learning/gin/2_middleware.JWTAuth.func1 -> 2_middleware.JWTAuth.func1，
+// github.com/gin-gonic/gin.LoggerWithConfig.func1 -> gin.LoggerWithConfig.func1
+func shortName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func methodOrder(method string) int {
+	for i, m := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"} {
+		if m == method {
+			return i
+		}
+	}
+	return 100
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	middleware "go-learning/gin/2_middleware"
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// Path 路由列表接口的默认路径
+const Path = "/debug/routes"
+
+// Handler 返回 engine 当前的路由表
+//
+// 查询参数:
+//   - method=GET       只看某个方法
+//   - prefix=/api/v1   只看某个前缀下的路由
+//   - format=table     输出文本表格（也可以用 Accept: text/plain），便于 curl 查看
+//
+// 每次请求时重新读取，之后注册的路由也会出现。
+// 路由表暴露了内部接口和中间件，只能给管理员使用，通常通过 Register 注册
+func Handler(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := strings.ToUpper(c.Query("method"))
+		prefix := c.Query("prefix")
+		all := Inspect(engine)
+		routes := make([]Route, 0, len(all))
+		for _, r := range all {
+			if (method == "" || r.Method == method) && strings.HasPrefix(r.Path, prefix) {
+				routes = append(routes, r)
+			}
+		}
+
+		if c.Query("format") == "table" || c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain) == gin.MIMEPlain {
+			var buf bytes.Buffer
+			_ = WriteTable(&buf, routes)
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+			return
+		}
+		response.Success(c, routes)
+	}
+}
+
+// Register 注册 GET /debug/routes，guards 为鉴权中间件，不能为空
+//
+//	routes.Register(router, middleware.JWTAuth(), middleware.RequireRole("admin"))
+func Register(engine *gin.Engine, guards ...gin.HandlerFunc) {
+	if len(guards) == 0 {
+		panic("routes: " + Path + " 会暴露全部接口，必须提供鉴权中间件")
+	}
+	engine.GET(Path, append(slices.Clip(guards), Handler(engine))...)
+}
+
+// RoutesDemo 演示查看路由和中间件链
+func RoutesDemo() {
+	fmt.Println("=== 路由与中间件链查看示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	// 在 Use 之前注册的路由不会经过 Recovery，路由表中可以看出来
+	router.GET("/health", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.Use(gin.Recovery())
+
+	api := router.Group("/api", middleware.JWTAuth())
+	api.GET("/profile", func(c *gin.Context) { response.Success(c, nil) })
+	admin := api.Group("/admin", middleware.RequireRole("admin"))
+	admin.DELETE("/users/:id", func(c *gin.Context) { response.Success(c, nil) })
+	Register(router, middleware.JWTAuth(), middleware.RequireRole("admin"))
+
+	get := func(title, target, token string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  GET %s -> %d\n", title, target, w.Code)
+		body := strings.TrimRight(w.Body.String(), "\n")
+		fmt.Println("  " + strings.ReplaceAll(body, "\n", "\n  "))
+		fmt.Println()
+	}
+
+	userToken, _ := middleware.GenerateToken("user456", []string{"user"})
+	adminToken, _ := middleware.GenerateToken("user123", []string{"admin"})
+	get("1. 未登录", Path, "")
+	get("2. 普通用户", Path, userToken)
+	get("3. 管理员查看表格", Path+"?format=table", adminToken)
+	get("4. 按方法和前缀过滤（JSON）", Path+"?method=DELETE&prefix=/api", adminToken)
+
+	fmt.Println("不启动服务查看示例路由:")
+	fmt.Println("  go run . routes:list                 # 列出可以查看的示例路由")
+	fmt.Println("  go run . routes:list MiddlewareRoute # 打印 MiddlewareRouteDemo 的路由表")
+	fmt.Println()
+	fmt.Println("要点:")
+	fmt.Println("  - 中间件链按执行顺序：全局 Use -> 分组 Use -> 路由上的中间件，最后是处理函数")
+	fmt.Println("  - 在 Use 之前注册的路由不会带上该中间件，路由表可以发现这类顺序问题")
+	fmt.Println("  - 路由表暴露内部接口，只能给管理员访问，生产环境也可以只在内网开放")
+}
@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func global(c *gin.Context) { c.Next() }
+func group(c *gin.Context)  { c.Next() }
+func route(c *gin.Context)  { c.Next() }
+func handle(c *gin.Context) { c.Status(http.StatusOK) }
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/early", handle) // 在 Use 之前注册
+	router.Use(global)
+	api := router.Group("/api", group)
+	api.GET("/users/:id", route, handle)
+	api.POST("/users", handle)
+	api.GET("/users", handle)
+	return router
+}
+
+// TestInspect 测试读取中间件链
+func TestInspect(t *testing.T) {
+	got := Inspect(newTestRouter())
+	require.Len(t, got, 4)
+
+	const pkg = "22_routes."
+	assert.Equal(t, []Route{
+		{Method: "GET", Path: "/api/users", Handler: pkg + "handle", Middleware: []string{pkg + "global", pkg + "group"}},
+		{Method: "POST", Path: "/api/users", Handler: pkg + "handle", Middleware: []string{pkg + "global", pkg + "group"}},
+		{Method: "GET", Path: "/api/users/:id", Handler: pkg + "handle", Middleware: []string{pkg + "global", pkg + "group", pkg + "route"}},
+		{Method: "GET", Path: "/early", Handler: pkg + "handle", Middleware: []string{}},
+	}, got, "gin 内部结构变化时中间件链会读取失败")
+
+	var buf strings.Builder
+	require.NoError(t, WriteTable(&buf, got))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], "METHOD"))
+	assert.Contains(t, lines[3], pkg+"global -> "+pkg+"group -> "+pkg+"route")
+	assert.True(t, strings.HasSuffix(lines[4], " -"), "没有中间件")
+}
+
+// TestHandler 测试路由列表接口
+func TestHandler(t *testing.T) {
+	router := newTestRouter()
+	assert.Panics(t, func() { Register(router) }, "必须提供鉴权中间件")
+
+	deny := func(c *gin.Context) {
+		if c.GetHeader("X-Admin") != "1" {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+	guards := make([]gin.HandlerFunc, 1, 2)
+	guards[0] = deny
+	Register(router, guards...)
+	assert.Nil(t, guards[:2][1], "不写入调用方切片的剩余容量")
+	router.DELETE("/late", handle) // 注册接口之后添加的路由也会出现
+
+	get := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	admin := map[string]string{"X-Admin": "1"}
+
+	assert.Equal(t, http.StatusForbidden, get(Path, nil).Code)
+
+	w := get(Path+"?method=get&prefix=/api", admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data []Route `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, "/api/users", body.Data[0].Path)
+	assert.Equal(t, "/api/users/:id", body.Data[1].Path)
+
+	w = get(Path, admin)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	paths := make([]string, 0, len(body.Data))
+	for _, r := range body.Data {
+		paths = append(paths, r.Method+" "+r.Path)
+	}
+	assert.Contains(t, paths, "GET "+Path)
+	assert.Contains(t, paths, "DELETE /late")
+
+	w = get(Path+"?prefix=/early", map[string]string{"X-Admin": "1", "Accept": "text/plain"})
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "/early")
+	assert.NotContains(t, w.Body.String(), "/api")
+	w = get(Path+"?format=table", admin)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "METHOD"))
+}
@@ -0,0 +1,168 @@
+package timing
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey 记录器在 gin.Context 中的键
+const contextKey = "serverTiming"
+
+// Span 一段计时，如一个中间件或一次数据库查询
+type Span struct {
+	Name     string        `json:"name"`
+	Depth    int           `json:"depth"`    // 嵌套层级，最外层为 0
+	Start    time.Duration `json:"start"`    // 相对请求开始的偏移
+	Duration time.Duration `json:"duration"` // 包含嵌套 span 的耗时
+	Self     time.Duration `json:"self"`     // 去掉嵌套 span 后自身的耗时
+	Done     bool          `json:"done"`     // 是否已结束，未结束时 Duration 为截至当前的耗时
+
+	children time.Duration // 已结束的直接子 span 的耗时之和
+}
+
+// recorder 一个请求的计时记录
+//
+// 中间件通过 c.Next() 层层嵌套：logger 的耗时包含 cors、jwt 和处理函数，
+// 所以用栈记录进行中的 span，子 span 结束时把耗时累加到父 span，自身耗时 = 总耗时 - 子 span 耗时
+type recorder struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	start time.Time
+	spans []*Span // 按开始顺序
+	stack []*Span // 进行中的 span
+}
+
+func newRecorder(now func() time.Time) *recorder {
+	return &recorder{now: now, start: now()}
+}
+
+// begin 开始一个 span，返回结束函数（多次调用只生效一次）
+func (r *recorder) begin(name string) func() {
+	r.mu.Lock()
+	span := &Span{Name: name, Depth: len(r.stack), Start: r.now().Sub(r.start)}
+	r.spans = append(r.spans, span)
+	r.stack = append(r.stack, span)
+	r.mu.Unlock()
+
+	var once sync.Once
+	return func() { once.Do(func() { r.end(span) }) }
+}
+
+func (r *recorder) end(span *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span.Duration = r.now().Sub(r.start) - span.Start
+	span.Self = span.Duration - span.children
+	span.Done = true
+
+	// 通常是栈顶；处理函数里启动的 goroutine 晚结束时可能不是
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		if r.stack[i] == span {
+			r.stack = append(r.stack[:i], r.stack[i+1:]...)
+			if i > 0 {
+				r.stack[i-1].children += span.Duration
+			}
+			break
+		}
+	}
+}
+
+// snapshot 返回当前所有 span 的副本和请求已用时间
+//
+// 进行中的 span 按截至当前计算：它的自身耗时还要减去栈中下一层（进行中的子 span）已用的时间
+func (r *recorder) snapshot() ([]Span, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elapsed := r.now().Sub(r.start)
+	open := make(map[*Span]time.Duration, len(r.stack))
+	for i, span := range r.stack {
+		running := elapsed - span.Start
+		var child time.Duration
+		if i+1 < len(r.stack) {
+			child = elapsed - r.stack[i+1].Start
+		}
+		open[span] = running - span.children - child
+	}
+
+	spans := make([]Span, 0, len(r.spans))
+	for _, span := range r.spans {
+		s := *span
+		if self, ok := open[span]; ok {
+			s.Duration = elapsed - span.Start
+			s.Self = self
+		}
+		spans = append(spans, s)
+	}
+	return spans, elapsed
+}
+
+func fromContext(c *gin.Context) *recorder {
+	if v, ok := c.Get(contextKey); ok {
+		if r, ok := v.(*recorder); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// Track 在处理函数中记录一段自定义耗时，返回结束函数
+//
+//	done := timing.Track(c, "db")
+//	rows, err := db.QueryContext(ctx, ...)
+//	done()
+//
+// 未注册 Middleware 时返回空函数
+func Track(c *gin.Context, name string) func() {
+	r := fromContext(c)
+	if r == nil {
+		return func() {}
+	}
+	return r.begin(name)
+}
+
+// Spans 返回当前请求已记录的 span，未注册 Middleware 时为 nil
+func Spans(c *gin.Context) []Span {
+	r := fromContext(c)
+	if r == nil {
+		return nil
+	}
+	spans, _ := r.snapshot()
+	return spans
+}
+
+// headerValue 生成 Server-Timing 头，每个 span 的 dur 为自身耗时（毫秒），最后是 total
+//
+//	logger;dur=0.02, cors;dur=0.01, jwt;dur=0.35, handler;dur=12.5, total;dur=12.9
+func headerValue(spans []Span, total time.Duration) string {
+	var b strings.Builder
+	for _, span := range spans {
+		b.WriteString(token(span.Name))
+		b.WriteString(";dur=")
+		b.WriteString(millis(span.Self))
+		b.WriteString(", ")
+	}
+	b.WriteString("total;dur=")
+	b.WriteString(millis(total))
+	return b.String()
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 2, 64)
+}
+
+// token 把名称转成 Server-Timing 允许的 token，其他字符替换为 _
+func token(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
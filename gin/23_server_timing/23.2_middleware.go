@@ -0,0 +1,143 @@
+package timing
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	tracing "go-learning/gin/10_tracing"
+)
+
+// HeaderName 响应头名称，浏览器开发者工具的 Network -> Timing 面板会展示
+const HeaderName = "Server-Timing"
+
+// Options Server-Timing 中间件配置
+type Options struct {
+	SlowThreshold time.Duration             // 超过该耗时输出瀑布图日志，0 表示不输出
+	Logger        *slog.Logger              // 为 nil 时输出 JSON 到标准输出
+	Expose        func(c *gin.Context) bool // 是否返回 Server-Timing 头，nil 表示总是返回
+}
+
+// DefaultOptions 默认配置：总是返回响应头，超过 500ms 记录瀑布图
+func DefaultOptions() Options {
+	return Options{SlowThreshold: 500 * time.Millisecond}
+}
+
+// Middleware 为请求创建计时记录，在响应头写出前加上 Server-Timing
+//
+// 必须注册在最前面（tracing.Middleware 之后即可），之后用 Wrap 包装的中间件和
+// Track 记录的耗时才会被统计:
+//
+//	router.Use(timing.Middleware(timing.DefaultOptions()))
+//	router.Use(timing.Wrap("logger", logger), timing.Wrap("jwt", middleware.JWTAuth()))
+//	router.GET("/api/data", timing.Wrap("handler", getData))
+//
+// 响应头在第一次写出时生成，此后的耗时（如 logger 在 c.Next() 之后的部分、流式输出）
+// 只会出现在慢请求日志里；只调用 c.Status() 不写响应体时，响应头在整条链结束后才生成。
+// Server-Timing 会暴露内部结构，对外服务可以用 Expose 只对内网或管理员返回
+func Middleware(options Options) gin.HandlerFunc {
+	return newMiddleware(options, time.Now)
+}
+
+func newMiddleware(options Options, now func() time.Time) gin.HandlerFunc {
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+
+	return func(c *gin.Context) {
+		rec := newRecorder(now)
+		c.Set(contextKey, rec)
+
+		var writer *timingWriter
+		if options.Expose == nil || options.Expose(c) {
+			writer = &timingWriter{ResponseWriter: c.Writer, rec: rec}
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		if writer != nil {
+			writer.addHeader() // 只设置了状态码、还没写出时，在 gin 写出响应头之前补上
+			c.Writer = writer.ResponseWriter
+		}
+
+		spans, total := rec.snapshot()
+		if options.SlowThreshold <= 0 || total < options.SlowThreshold {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latency_ms", float64(total.Microseconds())/1000),
+			slog.String("waterfall", Waterfall(spans, total)),
+		}
+		if id := c.GetString("requestID"); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if sc := tracing.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID))
+		}
+		logger.LogAttrs(context.WithoutCancel(c.Request.Context()), slog.LevelWarn, "slow request", attrs...)
+	}
+}
+
+// Wrap 记录中间件或处理函数的耗时，name 为 Server-Timing 中的名称
+//
+// 调用 c.Next() 的中间件耗时包含后续处理链，Server-Timing 中的 dur 是去掉
+// 后续被 Wrap 部分之后的自身耗时；不调用 c.Next() 的中间件只记录它自己。
+// 没有注册 Middleware 时直接调用 h，不影响原有行为。
+// 包装后 gin 调试输出和路由表（routes.Inspect）中的函数名显示为 timing.Wrap.func1
+func Wrap(name string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec := fromContext(c)
+		if rec == nil {
+			h(c)
+			return
+		}
+		done := rec.begin(name)
+		defer done() // h panic 时也结束计时，交给 Recovery 处理
+		h(c)
+	}
+}
+
+// timingWriter 在第一次写出响应头前加上 Server-Timing
+type timingWriter struct {
+	gin.ResponseWriter
+	rec  *recorder
+	sent bool
+}
+
+func (w *timingWriter) addHeader() {
+	if w.sent || w.ResponseWriter.Written() {
+		return
+	}
+	w.sent = true
+	spans, total := w.rec.snapshot()
+	// 用 Add 保留上游（如被代理的服务）已经设置的 Server-Timing
+	w.Header().Add(HeaderName, headerValue(spans, total))
+}
+
+func (w *timingWriter) WriteHeaderNow() {
+	w.addHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timingWriter) Write(data []byte) (int, error) {
+	w.addHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timingWriter) WriteString(s string) (int, error) {
+	w.addHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timingWriter) Flush() {
+	w.addHeader()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,148 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	cors "go-learning/gin/19_cors"
+)
+
+// waterfallWidth 瀑布图时间轴的宽度（字符）
+const waterfallWidth = 40
+
+// Waterfall 把 span 画成文本瀑布图，按开始顺序排列，嵌套的 span 缩进
+//
+//	span          start(ms)   dur(ms)  self(ms)
+//	logger            +0.01     73.90      1.11  |████████████████████████████████████████|
+//	  auth            +1.14     72.78      5.40  |████████████████████████████████████████|
+//	    handler       +6.53     67.38      0.09  |   █████████████████████████████████████|
+//	      db          +6.54     60.26     60.26  |   █████████████████████████████████    |
+//	      render     +66.81      7.02      7.02  |                                    ████|
+//
+// 未结束的 span（如响应写出后仍在运行的 goroutine）在名称后标记 *
+func Waterfall(spans []Span, total time.Duration) string {
+	nameWidth := len("span")
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = strings.Repeat("  ", span.Depth) + span.Name
+		if !span.Done {
+			names[i] += "*"
+		}
+		nameWidth = max(nameWidth, len(names[i]))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s  %9s  %8s  %8s", nameWidth, "span", "start(ms)", "dur(ms)", "self(ms)")
+	for i, span := range spans {
+		fmt.Fprintf(&b, "\n%-*s  %9s  %8s  %8s  |%s|", nameWidth, names[i],
+			"+"+millis(span.Start), millis(span.Duration), millis(span.Self), bar(span, total))
+	}
+	return b.String()
+}
+
+func bar(span Span, total time.Duration) string {
+	if total <= 0 {
+		return strings.Repeat(" ", waterfallWidth)
+	}
+	from := min(int(span.Start*waterfallWidth/total), waterfallWidth-1)
+	to := int(((span.Start+span.Duration)*waterfallWidth + total/2) / total) // 四舍五入，避免贯穿全程的 span 少一格
+	to = min(max(to, from+1), waterfallWidth)
+	return strings.Repeat(" ", from) + strings.Repeat("█", to-from) + strings.Repeat(" ", waterfallWidth-to)
+}
+
+// ServerTimingDemo 演示 Server-Timing 和慢请求瀑布图
+func ServerTimingDemo() {
+	fmt.Println("=== Server-Timing 与慢请求瀑布图示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	var logs bytes.Buffer
+	options := DefaultOptions()
+	options.SlowThreshold = 50 * time.Millisecond
+	options.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	// 只对内网返回 Server-Timing，避免对外暴露内部结构
+	options.Expose = func(c *gin.Context) bool {
+		ip := net.ParseIP(c.ClientIP())
+		return ip != nil && (ip.IsPrivate() || ip.IsLoopback())
+	}
+
+	logger := func(c *gin.Context) {
+		time.Sleep(time.Millisecond) // 模拟写日志
+		c.Next()
+	}
+	auth := func(c *gin.Context) {
+		time.Sleep(5 * time.Millisecond) // 模拟校验令牌、查询用户
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 1002, "message": "请先登录"})
+			return
+		}
+		c.Next()
+	}
+	corsOptions := cors.DefaultOptions()
+	corsOptions.AllowOrigins = []string{"https://app.example.com"}
+
+	router := gin.New()
+	router.Use(Middleware(options))
+	router.Use(Wrap("logger", logger), Wrap("cors", cors.Middleware(corsOptions)), Wrap("auth", auth))
+	router.GET("/api/profile", Wrap("handler", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"id": 1}})
+	}))
+	router.GET("/api/report", Wrap("handler", func(c *gin.Context) {
+		done := Track(c, "db")
+		time.Sleep(60 * time.Millisecond) // 模拟慢查询
+		done()
+		done = Track(c, "render")
+		time.Sleep(3 * time.Millisecond)
+		done()
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"rows": 1280}})
+	}))
+
+	send := func(title, target, remoteAddr, token string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  GET %s -> %d\n", title, target, w.Code)
+		if value := w.Header().Get(HeaderName); value != "" {
+			fmt.Printf("  %s: %s\n", HeaderName, value)
+		} else {
+			fmt.Printf("  （没有 %s 头）\n", HeaderName)
+		}
+		fmt.Println()
+	}
+
+	send("1. 内网请求", "/api/profile", "10.0.0.8:5000", "token")
+	send("2. 鉴权失败：auth 没有调用 c.Next()，后面没有 handler", "/api/profile", "10.0.0.8:5000", "")
+	send("3. 外网请求：Expose 返回 false", "/api/profile", "203.0.113.7:5000", "token")
+	send("4. 慢请求：Track 记录 db、render 的耗时", "/api/report", "10.0.0.8:5000", "token")
+
+	fmt.Println("慢请求日志中的瀑布图:")
+	for line := range strings.Lines(logs.String()) {
+		var record map[string]any
+		if json.Unmarshal([]byte(line), &record) != nil {
+			continue
+		}
+		fmt.Printf("  %s %s %v latency_ms=%v\n", record["msg"], record["route"], record["status"], record["latency_ms"])
+		waterfall, _ := record["waterfall"].(string)
+		fmt.Println("  " + strings.ReplaceAll(waterfall, "\n", "\n  "))
+	}
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - Middleware 放在最前面，需要计时的中间件和处理函数用 Wrap 包装，处理函数内部用 Track")
+	fmt.Println("  - 中间件通过 c.Next() 嵌套，dur 是去掉嵌套部分之后的自身耗时，total 是写出响应头时的总耗时")
+	fmt.Println("  - 响应头写出后的耗时只出现在慢请求日志中")
+	fmt.Println("  - 浏览器开发者工具 Network -> Timing 面板会展示 Server-Timing")
+}
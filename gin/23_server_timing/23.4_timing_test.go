@@ -0,0 +1,181 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock 手动推进的时钟，让耗时可以精确断言
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time           { return c.now }
+func (c *clock) advance(ms time.Duration) { c.now = c.now.Add(ms * time.Millisecond) }
+
+// TestRecorder 测试嵌套 span 的自身耗时
+func TestRecorder(t *testing.T) {
+	clk := &clock{now: time.Unix(0, 0)}
+	r := newRecorder(clk.Now)
+
+	endOuter := r.begin("outer")
+	clk.advance(1)
+	endInner := r.begin("inner")
+	clk.advance(2)
+	endLeaf := r.begin("leaf")
+	clk.advance(4)
+
+	spans, elapsed := r.snapshot()
+	assert.Equal(t, 7*time.Millisecond, elapsed)
+	assert.Equal(t, []time.Duration{7 * time.Millisecond, 6 * time.Millisecond, 4 * time.Millisecond},
+		[]time.Duration{spans[0].Duration, spans[1].Duration, spans[2].Duration}, "未结束的 span 按当前时间计算")
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond},
+		[]time.Duration{spans[0].Self, spans[1].Self, spans[2].Self})
+
+	endLeaf()
+	clk.advance(8)
+	endLeaf() // 重复调用无效
+	endInner()
+	endSibling := r.begin("sibling")
+	clk.advance(16)
+	endSibling()
+	endOuter()
+
+	spans, elapsed = r.snapshot()
+	require.Len(t, spans, 4)
+	assert.Equal(t, 31*time.Millisecond, elapsed)
+	want := []Span{
+		{Name: "outer", Depth: 0, Start: 0, Duration: 31 * time.Millisecond, Self: time.Millisecond, Done: true},
+		{Name: "inner", Depth: 1, Start: time.Millisecond, Duration: 14 * time.Millisecond, Self: 10 * time.Millisecond, Done: true},
+		{Name: "leaf", Depth: 2, Start: 3 * time.Millisecond, Duration: 4 * time.Millisecond, Self: 4 * time.Millisecond, Done: true},
+		{Name: "sibling", Depth: 1, Start: 15 * time.Millisecond, Duration: 16 * time.Millisecond, Self: 16 * time.Millisecond, Done: true},
+	}
+	for i := range want {
+		spans[i].children = 0
+		assert.Equal(t, want[i], spans[i], want[i].Name)
+	}
+}
+
+// TestMiddleware 测试 Server-Timing 头和慢请求日志
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := &clock{now: time.Unix(0, 0)}
+	var logs bytes.Buffer
+	options := DefaultOptions()
+	options.SlowThreshold = 50 * time.Millisecond
+	options.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	options.Expose = func(c *gin.Context) bool { return c.GetHeader("X-Public") == "" }
+
+	logger := func(c *gin.Context) {
+		clk.advance(1)
+		c.Next()
+		clk.advance(100) // 响应已写出，只出现在日志中
+	}
+	auth := func(c *gin.Context) {
+		clk.advance(2)
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+	router := gin.New()
+	router.Use(newMiddleware(options, clk.Now), Wrap("logger", logger), Wrap("auth", auth))
+	router.GET("/data", Wrap("handler", func(c *gin.Context) {
+		done := Track(c, "db query")
+		clk.advance(4)
+		done()
+		c.String(http.StatusOK, "ok")
+	}))
+	router.DELETE("/data", Wrap("handler", func(c *gin.Context) {
+		clk.advance(8)
+		c.Status(http.StatusNoContent) // 不写响应体，由 Middleware 在 gin 写出前补上
+	}))
+
+	send := func(method string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/data", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	token := map[string]string{"Authorization": "Bearer x"}
+
+	w := send(http.MethodGet, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "logger;dur=1.00, auth;dur=2.00, handler;dur=0.00, db_query;dur=4.00, total;dur=7.00", w.Header().Get(HeaderName))
+
+	w = send(http.MethodGet, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "logger;dur=1.00, auth;dur=2.00, total;dur=3.00", w.Header().Get(HeaderName))
+
+	w = send(http.MethodDelete, token)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	// 只设置状态码时，响应头在整条链结束后才写出，包含 logger 在 c.Next() 之后的耗时
+	assert.Equal(t, "logger;dur=101.00, auth;dur=2.00, handler;dur=8.00, total;dur=111.00", w.Header().Get(HeaderName))
+
+	w = send(http.MethodGet, map[string]string{"Authorization": "Bearer x", "X-Public": "1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderName), "Expose 返回 false")
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 4, "每个请求都因为 logger 的 100ms 成为慢请求")
+	var record struct {
+		Msg       string  `json:"msg"`
+		Route     string  `json:"route"`
+		Status    int     `json:"status"`
+		LatencyMS float64 `json:"latency_ms"`
+		Waterfall string  `json:"waterfall"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "slow request", record.Msg)
+	assert.Equal(t, "/data", record.Route)
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Equal(t, 107.0, record.LatencyMS)
+	assert.Contains(t, record.Waterfall, "\nlogger  ")
+	assert.Contains(t, record.Waterfall, "\n      db query  ")
+	assert.Contains(t, record.Waterfall, "101.00", "logger 在 c.Next() 之后的耗时")
+}
+
+// TestWithoutMiddleware 测试未注册 Middleware 时不影响原有行为
+func TestWithoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", Wrap("handler", func(c *gin.Context) {
+		Track(c, "db")()
+		assert.Nil(t, Spans(c))
+		c.String(http.StatusOK, "ok")
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderName))
+}
+
+// TestWaterfall 测试瀑布图和名称转换
+func TestWaterfall(t *testing.T) {
+	spans := []Span{
+		{Name: "logger", Duration: 10 * time.Millisecond, Self: time.Millisecond, Done: true},
+		{Name: "db", Depth: 1, Start: 5 * time.Millisecond, Duration: 5 * time.Millisecond, Self: 5 * time.Millisecond},
+	}
+	lines := strings.Split(Waterfall(spans, 10*time.Millisecond), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "span   "))
+	assert.True(t, strings.HasSuffix(lines[1], "|"+strings.Repeat("█", waterfallWidth)+"|"))
+	assert.True(t, strings.HasPrefix(lines[2], "  db*"), "未结束的 span")
+	assert.True(t, strings.HasSuffix(lines[2], "|"+strings.Repeat(" ", waterfallWidth/2)+strings.Repeat("█", waterfallWidth/2)+"|"))
+
+	assert.Equal(t, "db_query___", token("db query 用户"))
+	assert.Equal(t, "cache.hit", token("cache.hit"))
+	assert.Equal(t, "_", token(""))
+}
@@ -0,0 +1,136 @@
+package chain
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Predicate 判断当前请求是否满足条件，只应读取请求（路径、方法、请求头），不要修改上下文
+type Predicate func(c *gin.Context) bool
+
+// PathPrefix 路径以任一前缀开头时为 true，按路径段匹配：
+// "/public" 匹配 /public 和 /public/info，不匹配 /publication
+func PathPrefix(prefixes ...string) Predicate {
+	return func(c *gin.Context) bool {
+		path := c.Request.URL.Path
+		for _, prefix := range prefixes {
+			if hasPathPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// Method 请求方法为其中之一时为 true
+func Method(methods ...string) Predicate {
+	return func(c *gin.Context) bool {
+		return slices.Contains(methods, c.Request.Method)
+	}
+}
+
+// Header 请求头 name 不为空时为 true
+func Header(name string) Predicate {
+	return func(c *gin.Context) bool {
+		return c.GetHeader(name) != ""
+	}
+}
+
+// Not 取反
+func Not(p Predicate) Predicate {
+	return func(c *gin.Context) bool { return !p(c) }
+}
+
+// Any 任一条件满足时为 true
+func Any(predicates ...Predicate) Predicate {
+	return func(c *gin.Context) bool {
+		for _, p := range predicates {
+			if p(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// All 所有条件都满足时为 true
+func All(predicates ...Predicate) Predicate {
+	return func(c *gin.Context) bool {
+		for _, p := range predicates {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// decisionKey 在 gin.Context 中缓存一组 When 的判断结果，每次调用 When 生成一个新的键
+type decisionKey struct{ _ byte }
+
+// When 条件满足时才执行 handlers，否则跳过（直接交给后面的处理函数）
+//
+//	router.Use(chain.When(chain.Method(http.MethodPost, http.MethodPut), idempotency.Middleware(store))...)
+//
+// 返回的是处理链而不是单个函数：中间件内部调用 c.Next() 会推进 gin 的执行位置，
+// 无法把几个中间件合成一个函数，所以每个 handler 分别包装。
+// 条件在这组中第一个 handler 执行时判断一次，之后的 handler 沿用结果，
+// 即使前面的中间件改写了路径，同一组也不会只执行一半
+func When(p Predicate, handlers ...gin.HandlerFunc) gin.HandlersChain {
+	key := &decisionKey{}
+	wrapped := make(gin.HandlersChain, 0, len(handlers))
+	for _, h := range handlers {
+		if h == nil {
+			continue
+		}
+		wrapped = append(wrapped, func(c *gin.Context) {
+			ok, cached := c.Get(key)
+			if !cached {
+				ok = p(c)
+				c.Set(key, ok)
+			}
+			if ok.(bool) {
+				h(c)
+			}
+		})
+	}
+	return wrapped
+}
+
+// Unless 条件满足时跳过 handlers，常用于让公开接口跳过鉴权，不必为此单独建路由组
+//
+//	router.Use(chain.Unless(chain.PathPrefix("/public", "/health"), middleware.JWTAuth(), middleware.RequireRole("user"))...)
+func Unless(p Predicate, handlers ...gin.HandlerFunc) gin.HandlersChain {
+	return When(Not(p), handlers...)
+}
+
+// Chain 把多段处理链按顺序拼成一条新的处理链，忽略 nil
+//
+//	router.Use(chain.Chain(base, chain.Unless(public, auth...), gin.HandlersChain{audit})...)
+//
+// 总是返回新的切片：直接 append(base, ...) 在 base 容量有余时会改写共享的底层数组，
+// 两个分组互相覆盖对方的中间件
+func Chain(parts ...gin.HandlersChain) gin.HandlersChain {
+	n := 0
+	for _, part := range parts {
+		n += len(part)
+	}
+	chain := make(gin.HandlersChain, 0, n)
+	for _, part := range parts {
+		for _, h := range part {
+			if h != nil {
+				chain = append(chain, h)
+			}
+		}
+	}
+	return chain
+}
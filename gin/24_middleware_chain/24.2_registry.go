@@ -0,0 +1,207 @@
+package chain
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	tracing "go-learning/gin/10_tracing"
+	cors "go-learning/gin/19_cors"
+	timing "go-learning/gin/23_server_timing"
+	middleware "go-learning/gin/2_middleware"
+)
+
+// Priority 中间件的执行优先级，数值小的先执行
+type Priority int
+
+// 预定义的优先级，间隔 100，自定义中间件可以插在中间，如 PriorityAuth + 10
+const (
+	PriorityRecovery Priority = 100  // panic 恢复，最外层，能兜住其他中间件的 panic
+	PriorityTracing  Priority = 200  // 链路追踪、请求 ID，之后的日志都能带上
+	PriorityLogging  Priority = 300  // 访问日志、指标、Server-Timing
+	PriorityCORS     Priority = 400  // 在鉴权之前，预检请求不带 Authorization
+	PriorityAuth     Priority = 500  // 身份认证（JWT）
+	PriorityRBAC     Priority = 600  // 权限校验，依赖认证写入的角色
+	PriorityDefault  Priority = 1000 // 其他业务中间件
+)
+
+var priorityNames = map[Priority]string{
+	PriorityRecovery: "recovery",
+	PriorityTracing:  "tracing",
+	PriorityLogging:  "logging",
+	PriorityCORS:     "cors",
+	PriorityAuth:     "auth",
+	PriorityRBAC:     "rbac",
+	PriorityDefault:  "default",
+}
+
+// String 返回可读的优先级，如 auth、auth+10
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	base := p / 100 * 100
+	if name, ok := priorityNames[base]; ok && p > base {
+		return name + "+" + strconv.Itoa(int(p-base))
+	}
+	return strconv.Itoa(int(p))
+}
+
+// Entry 注册表中的一项
+type Entry struct {
+	Name     string
+	Priority Priority
+	Handlers gin.HandlersChain
+}
+
+// Registry 中间件注册表，各个包按名称和优先级提供中间件，最后统一排序挂到路由上
+//
+// 排序规则: 先按优先级，同一优先级按名称，与注册顺序（取决于包的初始化顺序）无关
+//
+//	registry := chain.NewRegistry()
+//	observability.Contribute(registry) // 各包提供 Contribute(*chain.Registry) 函数
+//	auth.Contribute(registry)
+//	registry.Use(router)
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	frozen  bool
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]Entry)}
+}
+
+// Add 注册中间件，handlers 可以是 When/Unless 返回的处理链
+// 名称重复、handlers 为空、已经调用过 Use 属于编程错误，直接 panic
+func (r *Registry) Add(name string, priority Priority, handlers ...gin.HandlerFunc) {
+	handlers = Chain(handlers)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.frozen:
+		panic("chain: 注册表已经挂到路由上，不能再添加 " + name)
+	case name == "" || len(handlers) == 0:
+		panic(fmt.Sprintf("chain: 中间件 %q 缺少名称或处理函数", name))
+	}
+	if _, ok := r.entries[name]; ok {
+		panic("chain: 中间件 " + name + " 重复注册")
+	}
+	r.entries[name] = Entry{Name: name, Priority: priority, Handlers: handlers}
+}
+
+// Entries 按执行顺序返回所有中间件
+func (r *Registry) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Priority != entries[j].Priority {
+			return entries[i].Priority < entries[j].Priority
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Handlers 按执行顺序返回拼接好的处理链
+func (r *Registry) Handlers() gin.HandlersChain {
+	return r.Wrapped(nil)
+}
+
+// Wrapped 与 Handlers 相同，但每个处理函数先经过 wrap，如用 timing.Wrap 统计每个中间件的耗时:
+//
+//	router.Use(registry.Wrapped(timing.Wrap)...)
+func (r *Registry) Wrapped(wrap func(name string, h gin.HandlerFunc) gin.HandlerFunc) gin.HandlersChain {
+	var chain gin.HandlersChain
+	for _, e := range r.Entries() {
+		for _, h := range e.Handlers {
+			if wrap != nil {
+				h = wrap(e.Name, h)
+			}
+			chain = append(chain, h)
+		}
+	}
+	return chain
+}
+
+// Use 把全部中间件挂到 router 上，之后不能再添加（之后添加的不会生效，容易误以为已经启用）
+func (r *Registry) Use(router gin.IRoutes) {
+	r.mu.Lock()
+	r.frozen = true
+	r.mu.Unlock()
+	router.Use(r.Handlers()...)
+}
+
+// MiddlewareChainDemo 演示条件中间件和按优先级排序的注册表
+func MiddlewareChainDemo() {
+	fmt.Println("=== 条件中间件与中间件注册表示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	public := PathPrefix("/public", "/health")
+
+	// 模拟各个包提供的中间件，注册顺序是打乱的
+	registry := NewRegistry()
+	// 鉴权模块
+	registry.Add("rbac", PriorityRBAC, Unless(public, middleware.RequireRole("user"))...)
+	registry.Add("jwt", PriorityAuth, Unless(public, middleware.JWTAuth())...)
+	// 基础设施模块
+	corsOptions := cors.DefaultOptions()
+	corsOptions.AllowOrigins = []string{"https://app.example.com"}
+	registry.Add("cors", PriorityCORS, cors.Middleware(corsOptions))
+	registry.Add("tracing", PriorityTracing, tracing.Middleware(tracing.DefaultOptions()))
+	registry.Add("recovery", PriorityRecovery, gin.Recovery())
+	// 审计模块：只记录写操作，插在鉴权之后
+	registry.Add("audit", PriorityRBAC+10, When(Method(http.MethodPost, http.MethodPut, http.MethodDelete), func(c *gin.Context) {
+		c.Next()
+		fmt.Printf("  [audit] %s %s user=%s status=%d\n", c.Request.Method, c.Request.URL.Path, c.GetString("userID"), c.Writer.Status())
+	})...)
+
+	fmt.Println("执行顺序（按优先级排序，与注册顺序无关）:")
+	for i, e := range registry.Entries() {
+		fmt.Printf("  %d. %-8s %-8s %d 个处理函数\n", i+1, e.Name, e.Priority, len(e.Handlers))
+	}
+	fmt.Println()
+
+	router := gin.New()
+	timingOptions := timing.DefaultOptions()
+	timingOptions.SlowThreshold = 0
+	router.Use(timing.Middleware(timingOptions))
+	router.Use(registry.Wrapped(timing.Wrap)...) // 统计每个中间件的耗时
+	router.GET("/public/info", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"message": "公开信息"}) })
+	router.GET("/api/profile", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"userID": c.GetString("userID")}) })
+	router.DELETE("/api/posts/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	token, _ := middleware.GenerateToken("user123", []string{"user"})
+	send := func(title, method, target, token string) {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		fmt.Println(title)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("  %s %s -> %d %s\n", method, target, w.Code, w.Body.String())
+		fmt.Printf("  %s: %s\n\n", timing.HeaderName, w.Header().Get(timing.HeaderName))
+	}
+	send("1. 公开接口：Unless 跳过 jwt、rbac，不需要单独的路由组", http.MethodGet, "/public/info", "")
+	send("2. 受保护接口未登录", http.MethodGet, "/api/profile", "")
+	send("3. 受保护接口已登录", http.MethodGet, "/api/profile", token)
+	send("4. 写操作：When 只对 POST/PUT/DELETE 执行审计", http.MethodDelete, "/api/posts/1", token)
+
+	fmt.Println("要点:")
+	fmt.Println("  - When/Unless 返回处理链，需要用 ... 展开；每个中间件分别包装，同一组只判断一次条件")
+	fmt.Println("  - PathPrefix 按路径段匹配，/public 不会匹配 /publication")
+	fmt.Println("  - 注册表按 优先级 -> 名称 排序，包的初始化顺序变化不会改变中间件顺序")
+	fmt.Println("  - 名称重复、Use 之后再 Add 会 panic，在启动时暴露配置错误")
+}
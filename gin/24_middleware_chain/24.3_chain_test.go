@@ -0,0 +1,157 @@
+package chain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trace 记录中间件执行顺序的测试中间件，调用 c.Next() 前后各记一次
+func trace(name string, log *[]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		*log = append(*log, name)
+		c.Next()
+		*log = append(*log, "/"+name)
+	}
+}
+
+func serve(router *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+// TestPredicate 测试条件
+func TestPredicate(t *testing.T) {
+	tests := []struct {
+		method, path string
+		predicate    Predicate
+		want         bool
+	}{
+		{"GET", "/public", PathPrefix("/public"), true},
+		{"GET", "/public/info", PathPrefix("/public"), true},
+		{"GET", "/publication", PathPrefix("/public"), false},
+		{"GET", "/static/app.js", PathPrefix("/public", "/static/"), true},
+		{"GET", "/anything", PathPrefix("/"), true},
+		{"POST", "/", Method(http.MethodPost, http.MethodPut), true},
+		{"GET", "/", Method(http.MethodPost, http.MethodPut), false},
+		{"GET", "/api", Not(PathPrefix("/api")), false},
+		{"POST", "/api", All(Method("POST"), PathPrefix("/api")), true},
+		{"POST", "/web", All(Method("POST"), PathPrefix("/api")), false},
+		{"GET", "/web", Any(Method("POST"), PathPrefix("/web")), true},
+		{"GET", "/", Header("X-Debug"), false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(tt.method, tt.path, nil)
+		assert.Equal(t, tt.want, tt.predicate(c), "%s %s", tt.method, tt.path)
+	}
+}
+
+// TestWhenUnless 测试条件中间件，被包装的中间件调用 c.Next() 时顺序不变
+func TestWhenUnless(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var log []string
+	evaluated := 0
+	rewrite := func(c *gin.Context) { c.Request.URL.Path = "/public/x" } // 改写路径也不会只执行半组
+	isPublic := func(c *gin.Context) bool {
+		evaluated++
+		return PathPrefix("/public")(c)
+	}
+
+	router := gin.New()
+	router.Use(trace("log", &log))
+	router.Use(Unless(isPublic, trace("jwt", &log), rewrite, trace("rbac", &log))...)
+	router.Use(When(Method(http.MethodDelete), trace("audit", &log))...)
+	handle := func(c *gin.Context) { log = append(log, "handler"); c.Status(http.StatusOK) }
+	router.GET("/public/info", handle)
+	router.GET("/api/users", handle)
+	router.DELETE("/api/users", handle)
+
+	serve(router, http.MethodGet, "/public/info")
+	assert.Equal(t, []string{"log", "handler", "/log"}, log)
+	assert.Equal(t, 1, evaluated)
+
+	log, evaluated = nil, 0
+	serve(router, http.MethodGet, "/api/users")
+	assert.Equal(t, []string{"log", "jwt", "rbac", "handler", "/rbac", "/jwt", "/log"}, log)
+	assert.Equal(t, 1, evaluated, "同一组只判断一次")
+
+	log = nil
+	serve(router, http.MethodDelete, "/api/users")
+	assert.Equal(t, []string{"log", "jwt", "rbac", "audit", "handler", "/audit", "/rbac", "/jwt", "/log"}, log)
+
+	// 跳过的中间件中止请求时不会影响后续处理
+	log = nil
+	deny := func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) }
+	router = gin.New()
+	router.Use(Unless(PathPrefix("/public"), deny)...)
+	router.GET("/public/info", handle)
+	router.GET("/api/users", handle)
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/public/info").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/api/users").Code)
+	assert.Equal(t, []string{"handler"}, log)
+}
+
+// TestChain 测试拼接处理链不共享底层数组
+func TestChain(t *testing.T) {
+	var log []string
+	base := make(gin.HandlersChain, 1, 4)
+	base[0] = trace("a", &log)
+	x := Chain(base, gin.HandlersChain{trace("x", &log), nil})
+	y := Chain(base, gin.HandlersChain{trace("y", &log)})
+	require.Len(t, x, 2)
+	require.Len(t, y, 2)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/x", append(x, func(c *gin.Context) {})...)
+	serve(router, http.MethodGet, "/x")
+	assert.Equal(t, []string{"a", "x", "/x", "/a"}, log, "y 没有覆盖 x")
+	assert.Empty(t, Chain())
+}
+
+// TestRegistry 测试按优先级排序
+func TestRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var log []string
+	r := NewRegistry()
+	r.Add("rbac", PriorityRBAC, trace("rbac", &log))
+	r.Add("tenant", PriorityAuth+10, trace("tenant", &log))
+	r.Add("session", PriorityAuth, trace("session", &log))
+	r.Add("jwt", PriorityAuth, trace("jwt", &log), nil)
+	r.Add("cors", PriorityCORS, trace("cors", &log))
+	r.Add("tracing", PriorityTracing, trace("tracing", &log))
+	r.Add("recovery", PriorityRecovery, Unless(PathPrefix("/health"), trace("recovery", &log))...)
+
+	var names, priorities []string
+	for _, e := range r.Entries() {
+		names = append(names, e.Name)
+		priorities = append(priorities, e.Priority.String())
+	}
+	assert.Equal(t, []string{"recovery", "tracing", "cors", "jwt", "session", "tenant", "rbac"}, names, "同一优先级按名称排序")
+	assert.Equal(t, []string{"recovery", "tracing", "cors", "auth", "auth", "auth+10", "rbac"}, priorities)
+	assert.Equal(t, "50", Priority(50).String())
+	assert.Panics(t, func() { r.Add("jwt", PriorityAuth, trace("jwt", &log)) }, "重复注册")
+	assert.Panics(t, func() { r.Add("empty", PriorityAuth, nil) })
+
+	var wrapped []string
+	chain := r.Wrapped(func(name string, h gin.HandlerFunc) gin.HandlerFunc {
+		wrapped = append(wrapped, name)
+		return h
+	})
+	assert.Len(t, chain, 7)
+	assert.Equal(t, names, wrapped)
+
+	router := gin.New()
+	r.Use(router)
+	assert.Panics(t, func() { r.Add("late", PriorityDefault, trace("late", &log)) }, "Use 之后不能再添加")
+	router.GET("/api", func(c *gin.Context) { log = append(log, "handler") })
+	serve(router, http.MethodGet, "/api")
+	assert.Equal(t, "recovery tracing cors jwt session tenant rbac handler", strings.Join(log[:8], " "))
+}
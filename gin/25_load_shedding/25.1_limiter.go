@@ -0,0 +1,278 @@
+package shedding
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Class 请求的优先级类别，过载时先丢弃低优先级的请求
+type Class int
+
+const (
+	ClassLow      Class = iota // 匿名、批量导出等可以稍后重试的流量
+	ClassNormal                // 普通登录用户
+	ClassCritical              // 管理员等关键流量，最后才会被丢弃
+
+	numClasses
+)
+
+// String 返回类别名称
+func (c Class) String() string {
+	switch c {
+	case ClassLow:
+		return "low"
+	case ClassNormal:
+		return "normal"
+	case ClassCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// 拒绝原因，用于日志和指标
+const (
+	ReasonQueueFull    = "queue_full"    // 并发已满且等待队列已满
+	ReasonQueueTimeout = "queue_timeout" // 排队超时
+	ReasonEvicted      = "evicted"       // 排队时被更高优先级的请求挤出队列
+	ReasonCanceled     = "canceled"      // 排队时客户端断开
+	ReasonLatency      = "latency"       // 自适应模式：延迟超过目标，主动丢弃
+)
+
+// 自适应模式下各类别开始丢弃时的延迟倍数（观测延迟 / TargetLatency），
+// 超过后丢弃概率在 shedRamp 的范围内从 0 线性增加到 1。
+// low 在 1 倍时开始丢弃、1.5 倍时全部丢弃；normal 从 1.5 倍开始；critical 到 3 倍才开始
+var shedStart = [numClasses]float64{1, 1.5, 3}
+
+const shedRamp = 0.5
+
+// Options 并发限制与自适应丢弃配置
+type Options struct {
+	MaxConcurrent int           // 同时处理的请求数上限，0 表示不限制（只使用自适应模式）
+	MaxQueue      int           // 并发已满时的等待队列长度，0 表示不排队直接拒绝
+	QueueTimeout  time.Duration // 排队的最长时间，0 表示只受请求 context 限制
+	TargetLatency time.Duration // 目标延迟，大于 0 时启用自适应模式
+	Window        time.Duration // 统计延迟的窗口，每个窗口结束时更新观测延迟
+	RetryAfter    time.Duration // 拒绝时返回的 Retry-After
+
+	// Classify 判断请求的类别，nil 时都按 ClassNormal 处理
+	Classify func(c *gin.Context) Class
+	// OnShed 请求被拒绝时调用，用于记录日志和指标
+	OnShed func(c *gin.Context, class Class, reason string)
+}
+
+// DefaultOptions 默认配置：最多 100 个并发，50 个排队，排队最多 1 秒，不启用自适应模式
+func DefaultOptions() Options {
+	return Options{
+		MaxConcurrent: 100,
+		MaxQueue:      50,
+		QueueTimeout:  time.Second,
+		Window:        time.Second,
+		RetryAfter:    2 * time.Second,
+	}
+}
+
+// Stats 当前状态
+type Stats struct {
+	InFlight int               `json:"inFlight"` // 正在处理的请求数
+	Queued   int               `json:"queued"`   // 排队中的请求数
+	Latency  time.Duration     `json:"latency"`  // 自适应模式观测到的延迟（上一个窗口的平均值）
+	Shed     map[string]uint64 `json:"shed"`     // 各类别被拒绝的请求数
+}
+
+// Limiter 舱壁（bulkhead）：限制一组路由的并发数，超出的请求在有界队列中排队，
+// 队列满时直接拒绝，避免一组慢接口占满所有 goroutine 和连接，拖垮其他接口。
+// 每个路由组使用各自的 Limiter，彼此隔离。
+//
+// 与按客户端限流不同，它保护的是服务本身：流量来自大量客户端时，每个客户端都没有超过限额，
+// 服务仍可能被压垮
+type Limiter struct {
+	options Options
+	now     func() time.Time
+	random  func() float64
+
+	mu       sync.Mutex
+	inFlight int
+	queued   int
+	queues   [numClasses][]*waiter // 每个类别一个先进先出队列
+	shed     [numClasses]uint64
+
+	// 自适应模式
+	windowStart time.Time
+	windowSum   time.Duration
+	windowCount int
+	latency     time.Duration
+}
+
+type waiter struct {
+	ready chan bool // true 表示分配到了并发名额，false 表示被挤出队列
+}
+
+// New 创建 Limiter
+func New(options Options) *Limiter {
+	return newLimiter(options, time.Now, rand.Float64)
+}
+
+func newLimiter(options Options, now func() time.Time, random func() float64) *Limiter {
+	if options.Window <= 0 {
+		options.Window = time.Second
+	}
+	if options.RetryAfter <= 0 {
+		options.RetryAfter = time.Second
+	}
+	return &Limiter{options: options, now: now, random: random, windowStart: now()}
+}
+
+// Stats 返回当前状态
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollLocked()
+	shed := make(map[string]uint64, numClasses)
+	for class := range numClasses {
+		shed[class.String()] = l.shed[class]
+	}
+	return Stats{InFlight: l.inFlight, Queued: l.queued, Latency: l.latency, Shed: shed}
+}
+
+// acquire 获取并发名额，成功返回空字符串，失败返回拒绝原因
+//
+// 名额满时进入对应类别的队列；队列也满时，挤掉一个类别更低的排队请求（最后入队的那个），
+// 没有更低类别的请求可挤时直接拒绝
+func (l *Limiter) acquire(ctx context.Context, class Class) string {
+	l.mu.Lock()
+	if l.options.MaxConcurrent <= 0 || l.inFlight < l.options.MaxConcurrent {
+		l.inFlight++
+		l.mu.Unlock()
+		return ""
+	}
+	if l.queued >= l.options.MaxQueue && !l.evictLocked(class) {
+		l.mu.Unlock()
+		return ReasonQueueFull
+	}
+	w := &waiter{ready: make(chan bool, 1)}
+	l.queues[class] = append(l.queues[class], w)
+	l.queued++
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.options.QueueTimeout > 0 {
+		timer := time.NewTimer(l.options.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var reason string
+	select {
+	case granted := <-w.ready:
+		if granted {
+			return ""
+		}
+		return ReasonEvicted
+	case <-timeout:
+		reason = ReasonQueueTimeout
+	case <-ctx.Done():
+		reason = ReasonCanceled
+	}
+
+	l.mu.Lock()
+	removed := l.removeLocked(class, w)
+	l.mu.Unlock()
+	if removed {
+		return reason
+	}
+	// 超时的同时被唤醒或挤出，以 ready 中的结果为准，拿到的名额不能丢
+	if <-w.ready {
+		return ""
+	}
+	return ReasonEvicted
+}
+
+// release 归还名额：有排队的请求时直接交给优先级最高、等待最久的那个
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for class := numClasses - 1; class >= 0; class-- {
+		if queue := l.queues[class]; len(queue) > 0 {
+			l.queues[class] = queue[1:]
+			l.queued--
+			queue[0].ready <- true
+			return
+		}
+	}
+	l.inFlight--
+}
+
+// evictLocked 挤出一个比 class 低的排队请求
+func (l *Limiter) evictLocked(class Class) bool {
+	for low := ClassLow; low < class; low++ {
+		if queue := l.queues[low]; len(queue) > 0 {
+			victim := queue[len(queue)-1]
+			l.queues[low] = queue[:len(queue)-1]
+			l.queued--
+			victim.ready <- false
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Limiter) removeLocked(class Class, w *waiter) bool {
+	queue := l.queues[class]
+	for i, v := range queue {
+		if v == w {
+			l.queues[class] = append(queue[:i], queue[i+1:]...)
+			l.queued--
+			return true
+		}
+	}
+	return false
+}
+
+// shouldShed 自适应模式：按观测延迟与目标延迟的比值决定是否丢弃
+func (l *Limiter) shouldShed(class Class) bool {
+	if l.options.TargetLatency <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	l.rollLocked()
+	ratio := float64(l.latency) / float64(l.options.TargetLatency)
+	l.mu.Unlock()
+
+	p := (ratio - shedStart[class]) / shedRamp
+	return p > 0 && l.random() < p
+}
+
+// observe 记录一个完成请求的延迟（包括排队时间，排队变长本身就是过载的信号）
+func (l *Limiter) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollLocked()
+	l.windowSum += d
+	l.windowCount++
+}
+
+// rollLocked 窗口结束时更新观测延迟
+//
+// 窗口内没有完成的请求（如大部分请求被丢弃）时观测延迟减半，
+// 否则丢弃会让延迟一直停留在过载时的值，服务恢复后也不会放行
+func (l *Limiter) rollLocked() {
+	now := l.now()
+	elapsed := now.Sub(l.windowStart)
+	if elapsed < l.options.Window {
+		return
+	}
+	if l.windowCount > 0 {
+		l.latency = l.windowSum / time.Duration(l.windowCount)
+	} else {
+		l.latency /= 2
+	}
+	// 只在有请求时才会调用到这里，中间经过的窗口都没有请求完成
+	for empty := elapsed/l.options.Window - 1; empty > 0 && l.latency > 0; empty-- {
+		l.latency /= 2
+	}
+	l.windowStart, l.windowSum, l.windowCount = now, 0, 0
+}
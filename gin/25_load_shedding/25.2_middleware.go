@@ -0,0 +1,207 @@
+package shedding
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// codeOverloaded 服务繁忙的业务错误码
+const codeOverloaded = 2006
+
+// Middleware 返回中间件，同一个 Limiter 的所有路由共享并发名额
+//
+//	reports := router.Group("/api/reports")
+//	reports.Use(shedding.New(reportOptions).Middleware()) // 报表接口慢，单独限制，不影响其他接口
+//
+// 被拒绝的请求返回 503 + Retry-After，业务码 2006。
+// 使用 Classify 按角色分类时要放在鉴权中间件之后
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		class := ClassNormal
+		if l.options.Classify != nil {
+			class = l.options.Classify(c)
+		}
+		if class < ClassLow || class >= numClasses {
+			class = ClassNormal
+		}
+
+		start := l.now()
+		// 先做自适应判断，已经过载时不再占用排队名额
+		if l.shouldShed(class) {
+			l.reject(c, class, ReasonLatency)
+			return
+		}
+		if reason := l.acquire(c.Request.Context(), class); reason != "" {
+			l.reject(c, class, reason)
+			return
+		}
+
+		func() {
+			defer l.release() // 处理函数 panic 时也要归还名额
+			c.Next()
+		}()
+		l.observe(l.now().Sub(start))
+	}
+}
+
+func (l *Limiter) reject(c *gin.Context, class Class, reason string) {
+	l.mu.Lock()
+	l.shed[class]++
+	l.mu.Unlock()
+	if l.options.OnShed != nil {
+		l.options.OnShed(c, class, reason)
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(l.options.RetryAfter.Seconds()))))
+	response.ErrorWithStatus(c, http.StatusServiceUnavailable, codeOverloaded, "服务繁忙，请稍后重试")
+	c.Abort()
+}
+
+// ClassifyByRole 按鉴权中间件写入的 userID、roles 分类：
+// 拥有 criticalRole 的为 ClassCritical，已登录的为 ClassNormal，匿名请求为 ClassLow
+func ClassifyByRole(criticalRole string) func(c *gin.Context) Class {
+	return func(c *gin.Context) Class {
+		if roles, ok := c.Get("roles"); ok {
+			if list, ok := roles.([]string); ok && slices.Contains(list, criticalRole) {
+				return ClassCritical
+			}
+		}
+		if c.GetString("userID") != "" {
+			return ClassNormal
+		}
+		return ClassLow
+	}
+}
+
+// LoadSheddingDemo 演示舱壁并发限制和自适应丢弃
+func LoadSheddingDemo() {
+	fmt.Println("=== 并发限制与自适应丢弃示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	// 模拟鉴权：X-Role 为 admin/user，没有时为匿名
+	auth := func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("userID", role+"-1")
+			c.Set("roles", []string{role})
+		}
+	}
+	send := func(router *gin.Engine, role string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/report", nil)
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Header().Get("Retry-After")
+	}
+
+	// ========== 1. 舱壁：2 个并发 + 2 个排队 ==========
+	fmt.Println("1. 舱壁：MaxConcurrent=2，MaxQueue=2，处理耗时 100ms，依次到达 6 个匿名请求")
+	options := DefaultOptions()
+	options.MaxConcurrent = 2
+	options.MaxQueue = 2
+	options.QueueTimeout = time.Second
+	options.Classify = ClassifyByRole("admin")
+	var mu sync.Mutex
+	var shedLog []string
+	options.OnShed = func(c *gin.Context, class Class, reason string) {
+		mu.Lock()
+		shedLog = append(shedLog, fmt.Sprintf("%s/%s", class, reason))
+		mu.Unlock()
+	}
+	limiter := New(options)
+	router := gin.New()
+	router.GET("/api/report", auth, limiter.Middleware(), func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"code": 0})
+	})
+
+	burst := func(roles ...string) []string {
+		results := make([]string, len(roles))
+		var wg sync.WaitGroup
+		for i, role := range roles {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				code, retryAfter := send(router, role)
+				results[i] = strconv.Itoa(code)
+				if retryAfter != "" {
+					results[i] += " (Retry-After: " + retryAfter + ")"
+				}
+			}()
+			time.Sleep(5 * time.Millisecond) // 保证到达顺序
+		}
+		time.Sleep(20 * time.Millisecond)
+		stats := limiter.Stats()
+		fmt.Printf("  处理中 %d，排队 %d\n", stats.InFlight, stats.Queued)
+		wg.Wait()
+		return results
+	}
+	for i, result := range burst("", "", "", "", "", "") {
+		fmt.Printf("  请求%d -> %s\n", i+1, result)
+	}
+	fmt.Printf("  拒绝记录: %v\n", shedLog)
+	fmt.Println()
+
+	// ========== 2. 优先级：管理员挤掉排队中的匿名请求 ==========
+	fmt.Println("2. 优先级：并发和队列都被匿名请求占满时，管理员请求挤掉最后排队的匿名请求")
+	shedLog = nil
+	for i, result := range burst("", "", "", "", "admin") {
+		role := "匿名"
+		if i == 4 {
+			role = "管理员"
+		}
+		fmt.Printf("  请求%d（%s） -> %s\n", i+1, role, result)
+	}
+	fmt.Printf("  拒绝记录: %v\n", shedLog)
+	fmt.Println()
+
+	// ========== 3. 自适应丢弃 ==========
+	fmt.Println("3. 自适应：目标延迟 20ms，实际 50ms（2.5 倍），匿名和普通用户被丢弃，管理员放行")
+	options = DefaultOptions()
+	options.MaxConcurrent = 0 // 只使用自适应模式
+	options.TargetLatency = 20 * time.Millisecond
+	options.Window = 100 * time.Millisecond
+	options.Classify = ClassifyByRole("admin")
+	limiter = New(options)
+	router = gin.New()
+	router.GET("/api/report", auth, limiter.Middleware(), func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"code": 0})
+	})
+	send(router, "user") // 第一个窗口内的请求，窗口结束后得到观测延迟
+	time.Sleep(options.Window)
+	fmt.Printf("  观测延迟: %v\n", limiter.Stats().Latency.Round(time.Millisecond))
+	for _, role := range []string{"", "user", "admin"} {
+		code, _ := send(router, role)
+		name := role
+		if name == "" {
+			name = "anonymous"
+		}
+		fmt.Printf("  %-9s -> %d\n", name, code)
+	}
+	fmt.Println("  之后 5 个窗口没有新请求（没有请求完成的窗口，观测延迟减半）:")
+	time.Sleep(5 * options.Window)
+	fmt.Printf("  观测延迟: %v\n", limiter.Stats().Latency.Round(time.Millisecond))
+	code, _ := send(router, "")
+	fmt.Printf("  anonymous -> %d\n", code)
+	fmt.Printf("  各类别拒绝数: %v\n", limiter.Stats().Shed)
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 按客户端限流挡不住大量客户端同时到来，舱壁限制的是服务自身的并发")
+	fmt.Println("  - 每个路由组一个 Limiter，慢接口占满名额也不影响其他接口")
+	fmt.Println("  - 队列有上限、排队有超时，过载时尽快返回 503，而不是让请求堆积到超时")
+	fmt.Println("  - 优先级: 队列满时挤掉低优先级请求，自适应模式下低优先级先被丢弃")
+	fmt.Println("  - 503 带 Retry-After，客户端（如 20_httpclient）据此退避重试")
+}
@@ -0,0 +1,222 @@
+package shedding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRouter 处理函数阻塞到 unblock 关闭，X-Role 模拟鉴权结果
+func blockingRouter(l *Limiter, unblock <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("userID", "u1")
+			c.Set("roles", []string{role})
+		}
+	}, l.Middleware(), func(c *gin.Context) {
+		<-unblock
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// requests 并发发送请求，每个请求等到 ready 返回 true 后再发下一个，保证到达顺序
+type requests struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	codes []int
+}
+
+func (r *requests) send(t *testing.T, router *gin.Engine, role string, ready func() bool) {
+	r.mu.Lock()
+	i := len(r.codes)
+	r.codes = append(r.codes, 0)
+	r.mu.Unlock()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		r.mu.Lock()
+		r.codes[i] = w.Code
+		r.mu.Unlock()
+	}()
+	require.Eventually(t, ready, time.Second, time.Millisecond)
+}
+
+func (r *requests) code(i int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.codes[i]
+}
+
+func (r *requests) wait() []int {
+	r.wg.Wait()
+	return r.codes
+}
+
+// TestBulkhead 测试并发上限和有界队列
+func TestBulkhead(t *testing.T) {
+	options := DefaultOptions()
+	options.MaxConcurrent = 2
+	options.MaxQueue = 1
+	options.RetryAfter = 1500 * time.Millisecond
+	l := New(options)
+	unblock := make(chan struct{})
+	router := blockingRouter(l, unblock)
+	state := func(inFlight, queued int) func() bool {
+		return func() bool { s := l.Stats(); return s.InFlight == inFlight && s.Queued == queued }
+	}
+
+	var r requests
+	r.send(t, router, "", state(1, 0))
+	r.send(t, router, "", state(2, 0))
+	r.send(t, router, "", state(2, 1))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "向上取整到秒")
+	assert.Contains(t, w.Body.String(), `"code":2006`)
+
+	close(unblock)
+	assert.Equal(t, []int{200, 200, 200}, r.wait(), "排队的请求在名额释放后执行")
+	s := l.Stats()
+	assert.Equal(t, 0, s.InFlight)
+	assert.Equal(t, uint64(1), s.Shed["normal"])
+}
+
+// TestPriority 测试队列满时高优先级请求挤掉低优先级请求，名额优先分给高优先级
+func TestPriority(t *testing.T) {
+	options := DefaultOptions()
+	options.MaxConcurrent = 1
+	options.MaxQueue = 2
+	options.Classify = ClassifyByRole("admin")
+	var reasons []string
+	options.OnShed = func(c *gin.Context, class Class, reason string) {
+		reasons = append(reasons, class.String()+"/"+reason)
+	}
+	l := New(options)
+	unblock := make(chan struct{})
+	router := blockingRouter(l, unblock)
+	state := func(inFlight, queued int) func() bool {
+		return func() bool { s := l.Stats(); return s.InFlight == inFlight && s.Queued == queued }
+	}
+
+	var r requests
+	r.send(t, router, "", state(1, 0))      // 匿名，占用名额
+	r.send(t, router, "", state(1, 1))      // 匿名，排队
+	r.send(t, router, "user", state(1, 2))  // 普通用户，排队
+	r.send(t, router, "admin", state(1, 2)) // 管理员：挤掉排队的匿名请求
+	require.Eventually(t, func() bool { return r.code(1) != 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, r.code(1))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Role", "user")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "队列中没有更低优先级的请求可挤")
+
+	close(unblock)
+	assert.Equal(t, []int{200, 503, 200, 200}, r.wait())
+	assert.Equal(t, []string{"low/evicted", "normal/queue_full"}, reasons)
+
+	// 名额释放时先给管理员
+	l = New(options)
+	release := make(chan struct{})
+	var order []string
+	var mu sync.Mutex
+	router = gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("userID", "u1")
+			c.Set("roles", []string{role})
+		}
+	}, l.Middleware(), func(c *gin.Context) {
+		mu.Lock()
+		order = append(order, c.GetHeader("X-Role"))
+		mu.Unlock()
+		<-release
+	})
+	r2 := &requests{}
+	r2.send(t, router, "user", state(1, 0))
+	r2.send(t, router, "user", state(1, 1))
+	r2.send(t, router, "admin", state(1, 2))
+	close(release)
+	r2.wait()
+	assert.Equal(t, []string{"user", "admin", "user"}, order)
+}
+
+// TestQueueTimeout 测试排队超时和客户端断开
+func TestQueueTimeout(t *testing.T) {
+	options := DefaultOptions()
+	options.MaxConcurrent = 1
+	options.QueueTimeout = 20 * time.Millisecond
+	l := New(options)
+	require.Empty(t, l.acquire(context.Background(), ClassNormal))
+
+	assert.Equal(t, ReasonQueueTimeout, l.acquire(context.Background(), ClassNormal))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, ReasonCanceled, l.acquire(ctx, ClassCritical))
+	assert.Equal(t, 0, l.Stats().Queued, "超时、断开的请求离开队列")
+
+	l.release()
+	assert.Equal(t, 0, l.Stats().InFlight)
+}
+
+// TestAdaptive 测试按延迟丢弃
+func TestAdaptive(t *testing.T) {
+	now := time.Unix(0, 0)
+	random := 0.5
+	options := DefaultOptions()
+	options.TargetLatency = 100 * time.Millisecond
+	options.Window = time.Second
+	l := newLimiter(options, func() time.Time { return now }, func() float64 { return random })
+
+	l.observe(250 * time.Millisecond)
+	l.observe(150 * time.Millisecond)
+	assert.False(t, l.shouldShed(ClassLow), "第一个窗口还没有观测值")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, 200*time.Millisecond, l.Stats().Latency)
+	assert.True(t, l.shouldShed(ClassLow))       // 2 倍：p=1
+	assert.True(t, l.shouldShed(ClassNormal))    // p=1
+	assert.False(t, l.shouldShed(ClassCritical)) // 3 倍才开始
+
+	l.observe(125 * time.Millisecond)
+	now = now.Add(time.Second)
+	random = 0.49
+	assert.True(t, l.shouldShed(ClassLow), "1.25 倍：p=0.5")
+	random = 0.5
+	assert.False(t, l.shouldShed(ClassLow))
+	assert.False(t, l.shouldShed(ClassNormal))
+
+	now = now.Add(3 * time.Second) // 3 个窗口没有请求完成
+	assert.Equal(t, 125*time.Millisecond/8, l.Stats().Latency)
+
+	// 中间件：被丢弃的请求返回 503，不执行处理函数
+	l = newLimiter(options, func() time.Time { return now }, func() float64 { return 0 })
+	l.observe(time.Second)
+	now = now.Add(time.Second)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", l.Middleware(), func(c *gin.Context) { t.Error("不应执行") })
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, uint64(1), l.Stats().Shed["normal"])
+}
@@ -0,0 +1,115 @@
+package views
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// LayoutName 布局模板的名称，layouts 中的文件用 {{ define "layout" }} 定义
+const LayoutName = "layout"
+
+// Renderer 支持布局和局部模板的 HTML 渲染器，实现 gin 的 render.HTMLRender
+//
+// 模板目录约定:
+//
+//	layouts/*.tmpl   布局，定义 "layout"，其中用 {{ template "content" . }} 嵌入页面
+//	partials/*.tmpl  局部模板，如表单字段、提示消息，所有页面共享
+//	pages/*.tmpl     页面，定义 "title"、"content"；名称为去掉扩展名的相对路径，如 pages/user/edit.tmpl -> user/edit
+//
+// 每个页面单独解析成一组模板（布局 + 局部模板 + 页面），不同页面的 "content" 不会互相覆盖；
+// 只用 LoadHTMLGlob 把所有文件解析到同一组时，后加载的 define 会覆盖先加载的
+//
+//	router.HTMLRender = views.MustRenderer(templateFS, nil)
+//	c.HTML(http.StatusOK, "register", data)
+type Renderer struct {
+	pages map[string]*template.Template
+}
+
+// NewRenderer 从 fsys 解析模板，funcs 为额外的模板函数（内置 field）
+func NewRenderer(fsys fs.FS, funcs template.FuncMap) (*Renderer, error) {
+	base := template.New("").Funcs(template.FuncMap{"field": field}).Funcs(funcs)
+	for _, dir := range []string{"layouts", "partials"} {
+		files, err := fs.Glob(fsys, dir+"/*.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		if base, err = base.ParseFS(fsys, files...); err != nil {
+			return nil, err
+		}
+	}
+	if base.Lookup(LayoutName) == nil {
+		return nil, fmt.Errorf("views: layouts 中没有定义 %q", LayoutName)
+	}
+
+	r := &Renderer{pages: make(map[string]*template.Template)}
+	err := fs.WalkDir(fsys, "pages", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(file) != ".tmpl" {
+			return err
+		}
+		page, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		page, err = page.ParseFS(fsys, file)
+		if err != nil {
+			return err
+		}
+		if page.Lookup("content") == nil {
+			return fmt.Errorf("views: %s 没有定义 \"content\"", file)
+		}
+		r.pages[strings.TrimSuffix(strings.TrimPrefix(file, "pages/"), ".tmpl")] = page
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MustRenderer 与 NewRenderer 相同，出错时 panic；模板在编译时嵌入，出错属于编程错误
+func MustRenderer(fsys fs.FS, funcs template.FuncMap) *Renderer {
+	r, err := NewRenderer(fsys, funcs)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Pages 返回所有页面名称
+func (r *Renderer) Pages() []string {
+	names := make([]string, 0, len(r.pages))
+	for name := range r.pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instance 实现 render.HTMLRender，页面不存在时响应 500，错误记录到 c.Errors
+func (r *Renderer) Instance(name string, data any) render.Render {
+	page, ok := r.pages[name]
+	if !ok {
+		return missingPage(name)
+	}
+	return render.HTML{Template: page, Name: LayoutName, Data: data}
+}
+
+type missingPage string
+
+func (p missingPage) Render(w http.ResponseWriter) error {
+	// gin 在响应体写出前才发送状态码，这里可以改成 500
+	w.WriteHeader(http.StatusInternalServerError)
+	return fmt.Errorf("views: 页面 %q 不存在", string(p))
+}
+
+func (p missingPage) WriteContentType(w http.ResponseWriter) {}
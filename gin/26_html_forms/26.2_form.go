@@ -0,0 +1,197 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	validation "go-learning/gin/3_validator"
+)
+
+// Form 表单的提交值和校验错误，校验失败重新渲染页面时用于回填
+type Form struct {
+	Values  url.Values        // 提交的值
+	Errors  map[string]string // 表单字段名（form 标签）-> 错误信息，每个字段只保留第一条
+	Message string            // 与具体字段无关的错误，如请求格式不正确
+}
+
+// NewForm 创建空表单，用于首次渲染
+func NewForm() *Form {
+	return &Form{Values: url.Values{}, Errors: map[string]string{}}
+}
+
+// Value 返回字段提交的值
+func (f *Form) Value(name string) string {
+	if f == nil {
+		return ""
+	}
+	return f.Values.Get(name)
+}
+
+// Error 返回字段的错误信息
+func (f *Form) Error(name string) string {
+	if f == nil {
+		return ""
+	}
+	return f.Errors[name]
+}
+
+// Valid 没有任何错误时为 true
+func (f *Form) Valid() bool {
+	return f == nil || len(f.Errors) == 0 && f.Message == ""
+}
+
+// AddError 添加业务校验错误，如“邮箱已注册”，字段已有错误时不覆盖
+func (f *Form) AddError(name, message string) {
+	if _, ok := f.Errors[name]; !ok {
+		f.Errors[name] = message
+	}
+}
+
+// Bind 绑定并校验表单，返回的 Form 带有提交的值和每个字段的中文错误信息
+//
+// 错误信息根据 binding 标签生成，字段名称取 label 标签:
+//
+//	type RegisterForm struct {
+//	    Email string `form:"email" label:"邮箱" binding:"required,email"` // -> 请填写邮箱、邮箱格式不正确
+//	}
+//
+// 只支持平铺的表单结构体（可以有嵌入字段），嵌套结构体中同名字段的错误无法区分
+func Bind(c *gin.Context, obj any) (*Form, bool) {
+	form := NewForm()
+	err := c.ShouldBind(obj)
+	if c.Request.PostForm != nil {
+		form.Values = cloneValues(c.Request.PostForm)
+	}
+	if err == nil {
+		return form, true
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		form.Message = "提交的数据格式不正确"
+		return form, false
+	}
+	fields := formFields(reflect.TypeOf(obj))
+	for _, e := range errs {
+		info, ok := fields[e.StructField()]
+		if !ok {
+			info = fieldInfo{name: e.Field(), label: e.Field()}
+		}
+		form.AddError(info.name, message(e, info.label, fields))
+	}
+	return form, false
+}
+
+func cloneValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, v := range values {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+type fieldInfo struct {
+	name  string // form 标签
+	label string // label 标签，没有时使用 name
+}
+
+// formFields 返回 Go 字段名 -> 表单字段信息，包括嵌入结构体的字段
+func formFields(t reflect.Type) map[string]fieldInfo {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields := make(map[string]fieldInfo)
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			for k, v := range formFields(sf.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			name = sf.Name
+		}
+		label := sf.Tag.Get("label")
+		if label == "" {
+			label = name
+		}
+		fields[sf.Name] = fieldInfo{name: name, label: label}
+	}
+	return fields
+}
+
+// message 根据校验标签生成面向用户的错误信息
+func message(e validator.FieldError, label string, fields map[string]fieldInfo) string {
+	param := e.Param()
+	length := e.Kind() == reflect.String || e.Kind() == reflect.Slice || e.Kind() == reflect.Map
+	switch e.Tag() {
+	case "required":
+		return "请填写" + label
+	case "email":
+		return label + "格式不正确"
+	case "min", "gte":
+		if length {
+			return fmt.Sprintf("%s至少 %s 个字符", label, param)
+		}
+		return fmt.Sprintf("%s不能小于 %s", label, param)
+	case "max", "lte":
+		if length {
+			return fmt.Sprintf("%s最多 %s 个字符", label, param)
+		}
+		return fmt.Sprintf("%s不能大于 %s", label, param)
+	case "len":
+		return fmt.Sprintf("%s必须是 %s 位", label, param)
+	case "oneof":
+		return fmt.Sprintf("%s只能是 %s 之一", label, strings.ReplaceAll(param, " ", "、"))
+	case "eqfield", validation.TagPasswordConfirm:
+		other := param
+		if info, ok := fields[param]; ok {
+			other = info.label
+		}
+		return fmt.Sprintf("%s与%s不一致", label, other)
+	}
+	// phone、idcard 等规则的文案由 validation 包提供，字段名（包括参数中引用的字段）显示为 label
+	labelOf := func(name string) string {
+		if name == e.StructField() {
+			return label
+		}
+		if info, ok := fields[name]; ok {
+			return info.label
+		}
+		return name
+	}
+	if msg, ok := validation.MessageFor(e, labelOf); ok {
+		return msg
+	}
+	return label + "格式不正确"
+}
+
+// Field 传给 "field" 局部模板的数据
+type Field struct {
+	Name  string
+	Label string
+	Type  string
+	Value string
+	Error string
+}
+
+// field 模板函数：{{ template "field" field .form "email" "邮箱" "email" }}
+// 密码框不回填提交的值，避免密码出现在页面源码和浏览器缓存中
+func field(form *Form, name, label, typ string) Field {
+	f := Field{Name: name, Label: label, Type: typ, Error: form.Error(name)}
+	if typ != "password" {
+		f.Value = form.Value(name)
+	}
+	return f
+}
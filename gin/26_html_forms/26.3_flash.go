@@ -0,0 +1,176 @@
+package views
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 提示消息的类别，对应页面上的样式
+const (
+	FlashSuccess = "success"
+	FlashInfo    = "info"
+	FlashWarning = "warning"
+	FlashError   = "error"
+)
+
+const (
+	flashKey    = "views.flashes"  // 本次请求读取到的消息
+	outgoingKey = "views.outgoing" // 本次请求添加、下次请求显示的消息
+	optionsKey  = "views.flashOptions"
+
+	maxFlashCookie = 3800 // 单个 Cookie 一般限制在 4KB 左右，留出属性的空间
+)
+
+// Flash 一次性提示消息，常用于 POST 成功后重定向到新页面再显示（Post/Redirect/Get）
+type Flash struct {
+	Kind    string `json:"k"`
+	Message string `json:"m"`
+}
+
+// FlashOptions 提示消息配置
+type FlashOptions struct {
+	CookieName string
+	Path       string
+	Secure     bool
+	// Secret 对 Cookie 签名，防止伪造提示内容（如冒充站点发出的“请联系客服转账”）；
+	// 为空时每个进程随机生成，多实例部署时所有实例需要配置同一个密钥
+	Secret []byte
+}
+
+// DefaultFlashOptions 默认配置
+func DefaultFlashOptions() FlashOptions {
+	return FlashOptions{CookieName: "_flash", Path: "/"}
+}
+
+// FlashMiddleware 读取上一个请求留下的提示消息，读取后删除 Cookie，消息只显示一次
+func FlashMiddleware(options FlashOptions) gin.HandlerFunc {
+	if len(options.Secret) == 0 {
+		options.Secret = make([]byte, 32)
+		_, _ = rand.Read(options.Secret)
+	}
+	return func(c *gin.Context) {
+		c.Set(optionsKey, &options)
+		value, err := c.Cookie(options.CookieName)
+		if err != nil || value == "" {
+			c.Next()
+			return
+		}
+		if flashes, ok := decodeFlashes(value, options.Secret); ok {
+			c.Set(flashKey, flashes)
+		}
+		setFlashCookie(c, &options, "", -1)
+		c.Next()
+	}
+}
+
+// AddFlash 添加提示消息，在下一个请求（通常是重定向后的页面）中通过 Flashes 读取
+//
+//	views.AddFlash(c, views.FlashSuccess, "注册成功")
+//	c.Redirect(http.StatusSeeOther, "/welcome")
+//
+// 必须在写出响应之前调用；没有注册 FlashMiddleware 时忽略
+func AddFlash(c *gin.Context, kind, message string) {
+	value, ok := c.Get(optionsKey)
+	if !ok {
+		return
+	}
+	options := value.(*FlashOptions)
+	var outgoing []Flash
+	if v, ok := c.Get(outgoingKey); ok {
+		outgoing = v.([]Flash)
+	}
+	outgoing = append(outgoing, Flash{Kind: kind, Message: message})
+	// 超出 Cookie 大小时丢弃最早的消息
+	encoded := encodeFlashes(outgoing, options.Secret)
+	for len(encoded) > maxFlashCookie && len(outgoing) > 1 {
+		outgoing = outgoing[1:]
+		encoded = encodeFlashes(outgoing, options.Secret)
+	}
+	c.Set(outgoingKey, outgoing)
+	setFlashCookie(c, options, encoded, 0)
+}
+
+// Flashes 返回上一个请求留下的提示消息
+func Flashes(c *gin.Context) []Flash {
+	if v, ok := c.Get(flashKey); ok {
+		return v.([]Flash)
+	}
+	return nil
+}
+
+// H 在模板数据中加入 flashes，没有 form 时加入空表单（首次渲染）
+//
+//	c.HTML(http.StatusOK, "register", views.H(c, gin.H{"form": form}))
+func H(c *gin.Context, data gin.H) gin.H {
+	if data == nil {
+		data = gin.H{}
+	}
+	data["flashes"] = Flashes(c)
+	if _, ok := data["form"]; !ok {
+		data["form"] = NewForm()
+	}
+	return data
+}
+
+// setFlashCookie 设置（maxAge 为 0）或删除（maxAge 为 -1）Cookie，替换本次响应中已有的同名 Set-Cookie
+func setFlashCookie(c *gin.Context, options *FlashOptions, value string, maxAge int) {
+	header := c.Writer.Header()
+	kept := header.Values("Set-Cookie")[:0:0]
+	for _, line := range header.Values("Set-Cookie") {
+		if !strings.HasPrefix(line, options.CookieName+"=") {
+			kept = append(kept, line)
+		}
+	}
+	header["Set-Cookie"] = kept
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     options.CookieName,
+		Value:    value,
+		Path:     options.Path,
+		MaxAge:   maxAge,
+		Secure:   options.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+var encoding = base64.RawURLEncoding
+
+// encodeFlashes Cookie 的值：base64(json).base64(hmac)
+func encodeFlashes(flashes []Flash, secret []byte) string {
+	data, _ := json.Marshal(flashes)
+	payload := encoding.EncodeToString(data)
+	return payload + "." + encoding.EncodeToString(sign(payload, secret))
+}
+
+func decodeFlashes(value string, secret []byte) ([]Flash, bool) {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	mac, err := encoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(payload, secret)) {
+		return nil, false
+	}
+	data, err := encoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	var flashes []Flash
+	if json.Unmarshal(data, &flashes) != nil {
+		return nil, false
+	}
+	return flashes, true
+}
+
+func sign(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
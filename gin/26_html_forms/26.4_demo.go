@@ -0,0 +1,167 @@
+package views
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	csrf "go-learning/gin/17_csrf"
+	validation "go-learning/gin/3_validator"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates 返回嵌入的示例模板（layouts、partials、pages 三个目录）
+func Templates() fs.FS {
+	sub, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// registerForm 注册表单，label 标签用于生成错误信息
+type registerForm struct {
+	Name            string `form:"name" label:"姓名" binding:"required,max=20"`
+	Email           string `form:"email" label:"邮箱" binding:"required,email"`
+	Phone           string `form:"phone" label:"手机号" binding:"omitempty,phone"`
+	Password        string `form:"password" label:"密码" binding:"required,min=8"`
+	ConfirmPassword string `form:"confirmPassword" label:"确认密码" binding:"required,eqfield=Password"`
+}
+
+// HTMLFormsDemo 演示服务端渲染表单：布局和局部模板、校验失败回填、重定向后的提示消息
+func HTMLFormsDemo() {
+	fmt.Println("=== HTML 表单渲染示例 ===")
+	fmt.Println()
+
+	if err := validation.Register(); err != nil { // phone 规则
+		fmt.Println("注册验证规则失败:", err)
+		return
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	renderer := MustRenderer(Templates(), nil)
+	router.HTMLRender = renderer
+	csrfOptions := csrf.DefaultOptions()
+	csrfOptions.Secret = []byte("demo-csrf-secret")
+	flashOptions := DefaultFlashOptions()
+	flashOptions.Secret = []byte("demo-flash-secret")
+	router.Use(csrf.Middleware(csrfOptions), FlashMiddleware(flashOptions))
+
+	registered := map[string]bool{"taken@example.com": true}
+	router.GET("/register", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "register", H(c, csrf.H(c, nil)))
+	})
+	router.POST("/register", func(c *gin.Context) {
+		var req registerForm
+		form, ok := Bind(c, &req)
+		if ok && registered[req.Email] {
+			form.AddError("email", "该邮箱已注册")
+		}
+		if !form.Valid() {
+			// 422 + 重新渲染，页面带着用户填写的值和每个字段的错误
+			c.HTML(http.StatusUnprocessableEntity, "register", H(c, csrf.H(c, gin.H{"form": form})))
+			return
+		}
+		registered[req.Email] = true
+		AddFlash(c, FlashSuccess, "注册成功，欢迎加入")
+		// 303：浏览器用 GET 请求新页面，刷新不会重复提交表单
+		c.Redirect(http.StatusSeeOther, "/welcome?name="+url.QueryEscape(req.Name))
+	})
+	router.GET("/welcome", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "welcome", H(c, gin.H{"name": c.Query("name")}))
+	})
+
+	// 浏览器：保存 Cookie，每次请求带上
+	cookies := map[string]string{}
+	send := func(method, target string, values url.Values) *httptest.ResponseRecorder {
+		var body io.Reader
+		if values != nil {
+			body = strings.NewReader(values.Encode())
+		}
+		req := httptest.NewRequest(method, target, body)
+		if values != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Origin", "http://example.com")
+		}
+		for name, value := range cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.MaxAge < 0 {
+				delete(cookies, cookie.Name)
+			} else {
+				cookies[cookie.Name] = cookie.Value
+			}
+		}
+		return w
+	}
+	// show 只打印页面标题、提示消息、字段值和错误
+	lines := regexp.MustCompile(`<title>[^<]*<|class="(flash[^"]*|error)"[^>]*>[^<]*<|<input id="[^"]+"[^>]*value="[^"]+"`)
+	show := func(w *httptest.ResponseRecorder) {
+		fmt.Printf("  -> %d", w.Code)
+		if location := w.Header().Get("Location"); location != "" {
+			fmt.Printf(" Location: %s", location)
+		}
+		fmt.Println()
+		for _, line := range lines.FindAllString(w.Body.String(), -1) {
+			fmt.Printf("     %s\n", strings.TrimSuffix(line, "<"))
+		}
+	}
+
+	fmt.Printf("已加载页面: %v\n\n", renderer.Pages())
+
+	fmt.Println("1. GET /register 渲染空表单（布局 + 字段局部模板 + CSRF 隐藏字段）")
+	w := send(http.MethodGet, "/register", nil)
+	show(w)
+	token := regexp.MustCompile(`name="_csrf" value="([^"]+)"`).FindStringSubmatch(w.Body.String())[1]
+	fmt.Println()
+
+	fmt.Println("2. 提交不合法的表单：422，回填已填写的值（密码除外），每个字段显示中文错误")
+	show(send(http.MethodPost, "/register", url.Values{
+		"_csrf": {token}, "name": {"张三"}, "email": {"zhangsan"}, "phone": {"123"},
+		"password": {"secret"}, "confirmPassword": {"secret1"},
+	}))
+	fmt.Println()
+
+	fmt.Println("3. 业务校验失败：邮箱已注册")
+	valid := url.Values{
+		"_csrf": {token}, "name": {"张三"}, "email": {"taken@example.com"}, "phone": {"13800138000"},
+		"password": {"password123"}, "confirmPassword": {"password123"},
+	}
+	show(send(http.MethodPost, "/register", valid))
+	fmt.Println()
+
+	fmt.Println("4. 提交成功：写入提示消息 Cookie，303 重定向")
+	valid.Set("email", "zhangsan@example.com")
+	w = send(http.MethodPost, "/register", valid)
+	show(w)
+	fmt.Printf("     Cookie %s=%s...\n", flashOptions.CookieName, cookies[flashOptions.CookieName][:20])
+	fmt.Println()
+
+	fmt.Println("5. 跟随重定向：显示提示消息，同时删除 Cookie")
+	show(send(http.MethodGet, w.Header().Get("Location"), nil))
+	fmt.Println()
+
+	fmt.Println("6. 刷新页面：提示消息只显示一次")
+	show(send(http.MethodGet, w.Header().Get("Location"), nil))
+	fmt.Println()
+
+	fmt.Println("要点:")
+	fmt.Println("  - 每个页面单独解析（布局 + 局部模板 + 页面），多个页面的 content 互不覆盖")
+	fmt.Println("  - 模板通过 embed 编译进二进制，不依赖运行时的工作目录")
+	fmt.Println("  - 校验失败返回 422 并重新渲染，错误信息由 binding 和 label 标签生成")
+	fmt.Println("  - 成功后 Post/Redirect/Get，提示消息存放在签名的 Cookie 中，读取一次后删除")
+}
@@ -0,0 +1,182 @@
+package views
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	validation "go-learning/gin/3_validator"
+)
+
+// TestRenderer 测试布局、局部模板和页面的组合
+func TestRenderer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.tmpl":   {Data: []byte(`{{ define "layout" }}[{{ template "content" . }}|{{ template "hello" . }}]{{ end }}`)},
+		"partials/hello.tmpl": {Data: []byte(`{{ define "hello" }}hi {{ .name }}{{ end }}`)},
+		"pages/a.tmpl":        {Data: []byte(`{{ define "content" }}A{{ end }}`)},
+		"pages/user/b.tmpl":   {Data: []byte(`{{ define "content" }}B{{ upper .name }}{{ end }}`)},
+	}
+	r, err := NewRenderer(fsys, map[string]any{"upper": strings.ToUpper})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "user/b"}, r.Pages())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.HTMLRender = r
+	router.GET("/:page", func(c *gin.Context) {
+		page := c.Param("page")
+		if page == "b" {
+			page = "user/b"
+		}
+		c.HTML(http.StatusOK, page, gin.H{"name": "<x>"})
+	})
+	for page, want := range map[string]string{"a": "[A|hi &lt;x&gt;]", "b": "[B&lt;X&gt;|hi &lt;x&gt;]"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+page, nil))
+		assert.Equal(t, want, w.Body.String(), "各页面的 content 互不覆盖，输出经过转义")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	delete(fsys, "layouts/base.tmpl")
+	_, err = NewRenderer(fsys, nil)
+	assert.Error(t, err, "没有布局")
+
+	_, err = NewRenderer(Templates(), nil)
+	assert.NoError(t, err, "嵌入的模板可以解析")
+}
+
+// TestBind 测试错误信息和回填
+func TestBind(t *testing.T) {
+	type Base struct {
+		Email string `form:"email" label:"邮箱" binding:"required,email"`
+	}
+	type signup struct {
+		Base
+		Name     string `form:"name" binding:"required"`
+		Age      int    `form:"age" label:"年龄" binding:"omitempty,min=18"`
+		Password string `form:"password" label:"密码" binding:"required,min=8"`
+		Confirm  string `form:"confirm" label:"确认密码" binding:"eqfield=Password"`
+		Role     string `form:"role" label:"角色" binding:"omitempty,oneof=user admin"`
+	}
+	gin.SetMode(gin.TestMode)
+	bind := func(values url.Values) (*Form, bool) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		var obj signup
+		return Bind(c, &obj)
+	}
+
+	form, ok := bind(url.Values{"email": {"bad"}, "age": {"16"}, "password": {"short"}, "confirm": {"other"}, "role": {"root"}})
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{
+		"email":    "邮箱格式不正确",
+		"name":     "请填写name",
+		"age":      "年龄不能小于 18",
+		"password": "密码至少 8 个字符",
+		"confirm":  "确认密码与密码不一致",
+		"role":     "角色只能是 user、admin 之一",
+	}, form.Errors)
+	assert.Equal(t, "bad", form.Value("email"), "提交的值用于回填")
+
+	form, ok = bind(url.Values{"email": {"a@example.com"}, "name": {"张三"}, "password": {"password1"}, "confirm": {"password1"}})
+	assert.True(t, ok)
+	assert.True(t, form.Valid())
+	form.AddError("email", "该邮箱已注册")
+	form.AddError("email", "第二条错误不覆盖")
+	assert.Equal(t, "该邮箱已注册", form.Error("email"))
+	assert.False(t, form.Valid())
+
+	type contact struct {
+		Phone string `form:"phone" label:"手机号" binding:"omitempty,phone"`
+		Email string `form:"email" label:"邮箱"`
+	}
+	require.NoError(t, validation.Register(validation.For(contact{}, validation.AtLeastOneOf("Phone", "Email"))))
+	for values, want := range map[string]string{"phone=123": "手机号必须是有效的手机号", "": "手机号、邮箱至少填写一个"} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		form, ok := Bind(c, &contact{})
+		assert.False(t, ok)
+		assert.Equal(t, map[string]string{"phone": want}, form.Errors, "validation 包的文案使用 label，不出现 Go 字段名")
+	}
+
+	form, ok = bind(url.Values{"age": {"abc"}})
+	assert.False(t, ok)
+	assert.Equal(t, "提交的数据格式不正确", form.Message)
+
+	// 密码框不回填
+	form = NewForm()
+	form.Values.Set("password", "secret")
+	form.Values.Set("email", "a@example.com")
+	assert.Empty(t, field(form, "password", "密码", "password").Value)
+	assert.Equal(t, "a@example.com", field(form, "email", "邮箱", "email").Value)
+	var nilForm *Form
+	assert.Empty(t, nilForm.Value("email"))
+}
+
+// TestFlash 测试提示消息跨重定向显示一次、签名校验
+func TestFlash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	options := DefaultFlashOptions()
+	options.Secret = []byte("test-secret")
+	router := gin.New()
+	router.Use(FlashMiddleware(options))
+	router.POST("/save", func(c *gin.Context) {
+		AddFlash(c, FlashSuccess, "保存成功")
+		AddFlash(c, FlashInfo, "已通知审核人")
+		c.Redirect(http.StatusSeeOther, "/")
+	})
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, H(c, nil)["flashes"])
+	})
+	get := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", nil))
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1, "多次 AddFlash 只保留一个 Set-Cookie")
+	flash := cookies[0]
+	assert.True(t, flash.HttpOnly)
+
+	w = get(flash)
+	assert.JSONEq(t, `[{"k":"success","m":"保存成功"},{"k":"info","m":"已通知审核人"}]`, w.Body.String())
+	cookies = w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, -1, cookies[0].MaxAge, "读取后删除")
+
+	assert.Equal(t, "null", get(nil).Body.String())
+
+	// 篡改内容后签名不匹配，忽略
+	payload, signature, _ := strings.Cut(flash.Value, ".")
+	forged := encodeFlashes([]Flash{{Kind: FlashError, Message: "请联系客服转账"}}, []byte("other"))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	assert.NotEqual(t, payload, forgedPayload)
+	w = get(&http.Cookie{Name: options.CookieName, Value: forgedPayload + "." + signature})
+	assert.Equal(t, "null", w.Body.String())
+
+	// 没有注册中间件时 AddFlash 不生效
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	AddFlash(c, FlashInfo, "x")
+	assert.Empty(t, c.Writer.Header().Values("Set-Cookie"))
+}
@@ -0,0 +1,118 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// 请求体的媒体类型
+const (
+	MergePatchType = "application/merge-patch+json" // RFC 7386
+	JSONPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// AcceptPatch 支持的补丁类型，415 响应和资源的 OPTIONS 响应中通过 Accept-Patch 告知客户端
+const AcceptPatch = MergePatchType + ", " + JSONPatchType
+
+var (
+	ErrInvalidPatch = errors.New("补丁格式不正确")
+	ErrPathNotFound = errors.New("路径不存在")
+	ErrTestFailed   = errors.New("test 操作的值不相等")
+)
+
+// MergePatch 按 RFC 7386 合并，返回合并后的文档
+//
+//	{"nickname": "小张", "age": null}   修改 nickname，删除 age（绑定到结构体后为零值）
+//
+// 对象递归合并，其他值（包括数组）整体替换；
+// 无法表达“把字段设为 null”和“修改数组中的一个元素”，这两种情况使用 JSON Patch
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("文档不是合法的 JSON: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+	return t
+}
+
+// decode 解析 JSON，数字保留为 json.Number，避免大整数（如 ID）经过 float64 丢失精度
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("JSON 之后有多余的内容")
+	}
+	return v, nil
+}
+
+// equal 比较两个 JSON 值，数字按数值比较（1 与 1.0 相等）
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, _, errX := big.ParseFloat(string(x), 10, 256, big.ToNearestEven)
+		fy, _, errY := big.ParseFloat(string(y), 10, 256, big.ToNearestEven)
+		if errX != nil || errY != nil {
+			return x == y
+		}
+		return fx.Cmp(fy) == 0
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
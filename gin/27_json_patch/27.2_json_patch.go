@@ -0,0 +1,293 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operation JSON Patch 的一个操作
+// path、from 用指针区分“没有提供”和空字符串（整个文档）；
+// value 没有提供时为 nil，"value": null 时为 null 字面量
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// OpError 第 Index 个操作（从 0 开始）执行失败
+type OpError struct {
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("第 %d 个操作 %s %q: %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *OpError) Unwrap() error { return e.Err }
+
+// ApplyJSONPatch 按 RFC 6902 依次执行操作，返回修改后的文档
+//
+//	[
+//	  {"op": "test", "path": "/email", "value": "old@example.com"},
+//	  {"op": "replace", "path": "/email", "value": "new@example.com"},
+//	  {"op": "add", "path": "/tags/-", "value": "vip"}
+//	]
+//
+// 支持 add、remove、replace、move、copy、test；任何一个操作失败时整个补丁都不生效。
+// test 可以实现乐观并发控制：只有当前值与客户端看到的一致时才修改
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: 必须是操作数组: %v", ErrInvalidPatch, err)
+	}
+	root, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("文档不是合法的 JSON: %w", err)
+	}
+	// root 是新解析的，操作直接修改它；出错时丢弃，不影响调用方
+	for i, op := range ops {
+		if root, err = op.apply(root); err != nil {
+			path := ""
+			if op.Path != nil {
+				path = *op.Path
+			}
+			return nil, &OpError{Index: i, Op: op.Op, Path: path, Err: err}
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op operation) apply(root any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: 缺少 path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: 缺少 value", ErrInvalidPatch)
+		}
+		if value, err = decode(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: 缺少 from", ErrInvalidPatch)
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: 不支持的操作 %q", ErrInvalidPatch, op.Op)
+	}
+
+	switch op.Op {
+	case "add":
+		return add(root, path, value)
+	case "remove":
+		if len(path) == 0 {
+			return nil, fmt.Errorf("%w: 不能删除整个文档", ErrInvalidPatch)
+		}
+		return update(root, path, removeAt)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		return update(root, path, func(node any, token string) (any, error) {
+			if _, err := lookup(node, token); err != nil {
+				return nil, err
+			}
+			return setAt(node, token, value)
+		})
+	case "test":
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	}
+
+	// move、copy
+	from, err := parsePointer(*op.From)
+	if err != nil {
+		return nil, err
+	}
+	value, err = get(root, from)
+	if err != nil {
+		return nil, err
+	}
+	if op.Op == "copy" {
+		// 复制一份，后续操作修改其中一处时不影响另一处
+		return add(root, path, clone(value))
+	}
+	if *op.From == *op.Path {
+		return root, nil
+	}
+	if strings.HasPrefix(*op.Path, *op.From+"/") {
+		return nil, fmt.Errorf("%w: 不能移动到自己的子节点中", ErrInvalidPatch)
+	}
+	if root, err = update(root, from, removeAt); err != nil {
+		return nil, err
+	}
+	return add(root, path, value)
+}
+
+// parsePointer 解析 RFC 6901 JSON Pointer："/a~1b/0" -> ["a/b", "0"]，"" 表示整个文档
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: 路径必须以 / 开头: %q", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: 路径中 ~ 只能用于 ~0、~1: %q", ErrInvalidPatch, pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(root any, path []string) (any, error) {
+	node := root
+	for _, token := range path {
+		var err error
+		if node, err = lookup(node, token); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(node any, token string) (any, error) {
+		if arr, ok := node.([]any); ok {
+			i := len(arr)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(arr)+1); err != nil {
+					return nil, err
+				}
+			}
+			out := make([]any, 0, len(arr)+1)
+			out = append(out, arr[:i]...)
+			out = append(out, value)
+			return append(out, arr[i:]...), nil
+		}
+		return setAt(node, token, value)
+	})
+}
+
+// update 对 path 的父节点执行 fn，返回新的文档；数组增删元素后需要写回上一级
+func update(node any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	child, err := lookup(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = update(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	return setAt(node, path[0], child)
+}
+
+func lookup(node any, token string) (any, error) {
+	switch n := node.(type) {
+	case map[string]any:
+		v, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: 没有字段 %q", ErrPathNotFound, token)
+		}
+		return v, nil
+	case []any:
+		i, err := index(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	default:
+		return nil, fmt.Errorf("%w: %q 的上一级不是对象或数组", ErrPathNotFound, token)
+	}
+}
+
+// setAt 设置已有元素或对象字段
+func setAt(node any, token string, value any) (any, error) {
+	switch n := node.(type) {
+	case map[string]any:
+		n[token] = value
+		return n, nil
+	case []any:
+		i, err := index(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		n[i] = value
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: %q 的上一级不是对象或数组", ErrPathNotFound, token)
+	}
+}
+
+func removeAt(node any, token string) (any, error) {
+	if _, err := lookup(node, token); err != nil {
+		return nil, err
+	}
+	if arr, ok := node.([]any); ok {
+		i, _ := index(token, len(arr))
+		return append(arr[:i:i], arr[i+1:]...), nil
+	}
+	m := node.(map[string]any)
+	delete(m, token)
+	return m, nil
+}
+
+// index 解析数组下标，必须在 [0, limit) 内，不允许前导 0 和 "-"
+func index(token string, limit int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || token[0] < '0' || token[0] > '9' || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: 数组下标 %q 不正确", ErrPathNotFound, token)
+	}
+	if i >= limit {
+		return 0, fmt.Errorf("%w: 数组下标 %d 越界", ErrPathNotFound, i)
+	}
+	return i, nil
+}
+
+func clone(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, item := range x {
+			out[k] = clone(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, item := range x {
+			out[i] = clone(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,186 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnsupportedType = errors.New("不支持的补丁类型")
+	ErrReadOnly        = errors.New("字段只读")
+	ErrUnknownField    = errors.New("字段不存在")
+	ErrInvalidValue    = errors.New("补丁后的值类型不正确")
+)
+
+// Options 补丁配置
+type Options struct {
+	// ReadOnly 不允许修改的字段（JSON 字段名）；补丁中写入原值（如 {"id": 1}）不算修改
+	ReadOnly []string
+	// MaxBodySize Bind 读取请求体的上限（字节），<= 0 时使用默认值；超过时返回 413
+	MaxBodySize int64
+}
+
+// DefaultOptions 默认配置：主键和时间戳只读，补丁最大 1MB
+func DefaultOptions() Options {
+	return Options{
+		ReadOnly:    []string{"id", "createdAt", "updatedAt"},
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// Apply 把补丁应用到 current（结构体指针），返回被修改的字段（Go 字段名，已排序）
+//
+// 步骤:
+//  1. 按 contentType 选择 Merge Patch 或 JSON Patch，应用到 current 的 JSON 表示上
+//  2. 比较补丁前后的顶层字段，得到修改了哪些字段；修改只读字段、
+//     出现结构体中没有的字段（包括 json:"-" 的字段，如密码哈希）时拒绝
+//  3. 把修改的字段绑定回结构体，用 binding 标签重新校验整个结果（与 ShouldBindJSON 一致）
+//
+// 任何一步失败时 current 保持不变；成功时 current 更新为补丁后的值，
+// 没有出现在 JSON 中的字段（json:"-"、未导出字段）保留原值
+func Apply(current any, contentType string, body []byte, options Options) ([]string, error) {
+	target := reflect.ValueOf(current)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("patch: Apply 需要结构体指针，实际为 %T", current))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case MergePatchType:
+		apply = MergePatch
+	case JSONPatchType:
+		apply = ApplyJSONPatch
+	default:
+		return nil, fmt.Errorf("%w: %q，支持 %s", ErrUnsupportedType, mediaType, AcceptPatch)
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := apply(original, body)
+	if err != nil {
+		return nil, err
+	}
+	before, _ := decode(original)
+	after, err := decode(patched)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, ok := after.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: 补丁后的文档必须是对象", ErrInvalidValue)
+	}
+	beforeFields, ok := before.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: 资源的 JSON 表示必须是对象", ErrInvalidValue)
+	}
+
+	fields := jsonFields(target.Type().Elem())
+	var changed []string
+	for _, name := range changedKeys(beforeFields, afterFields) {
+		if slices.Contains(options.ReadOnly, name) {
+			return nil, fmt.Errorf("%w: %s", ErrReadOnly, name)
+		}
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+		changed = append(changed, name)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	// 补丁后的文档绑定到新值，只把修改的字段复制到 current 的副本中
+	decoded := reflect.New(target.Type().Elem())
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	if err := dec.Decode(decoded.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	result := reflect.New(target.Type().Elem())
+	result.Elem().Set(target.Elem())
+	names := make([]string, 0, len(changed))
+	for _, name := range changed {
+		field := fields[name]
+		result.Elem().FieldByIndex(field.index).Set(decoded.Elem().FieldByIndex(field.index))
+		names = append(names, field.name)
+	}
+	if err := binding.Validator.ValidateStruct(result.Interface()); err != nil {
+		return nil, err
+	}
+
+	target.Elem().Set(result.Elem())
+	sort.Strings(names)
+	return names, nil
+}
+
+// Updates 只更新补丁修改了的列，没有修改时不执行 SQL
+//
+//	changed, err := patch.Apply(&user, contentType, body, patch.DefaultOptions())
+//	...
+//	err = patch.Updates(db, &user, changed) // UPDATE users SET email=?, updated_at=? WHERE id=?
+//
+// 通过 Select 指定列，零值（如把 age 改为 0、nickname 改为空）也会写入
+func Updates(db *gorm.DB, model any, changed []string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	return db.Model(model).Select(changed).Updates(model).Error
+}
+
+func changedKeys(before, after map[string]any) []string {
+	var keys []string
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !equal(old, v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type jsonField struct {
+	name  string // Go 字段名
+	index []int
+}
+
+// jsonFields 返回 JSON 字段名 -> Go 字段，包括嵌入结构体（如 gorm.Model）的字段
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" || !sf.IsExported() {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && name == "" {
+			for k, v := range jsonFields(sf.Type) {
+				if _, ok := fields[k]; !ok {
+					fields[k] = jsonField{name: v.name, index: append([]int{i}, v.index...)}
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields[name] = jsonField{name: sf.Name, index: []int{i}}
+	}
+	return fields
+}
@@ -0,0 +1,201 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	validation "go-learning/gin/3_validator"
+	response "go-learning/gin/8_content_negotiation"
+)
+
+// 业务错误码，与 1.5_best_practices 的约定一致
+const (
+	codeInvalidParam = 1001
+	codeConflict     = 3004
+	codeInternal     = 2004
+)
+
+// Bind 读取请求体并把补丁应用到 current，失败时写入错误响应并返回 false
+//
+//	router.PATCH("/users/:id", func(c *gin.Context) {
+//	    var user User
+//	    ... // 查询当前资源
+//	    changed, ok := patch.Bind(c, &user, patch.DefaultOptions())
+//	    if !ok {
+//	        return
+//	    }
+//	    if err := patch.Updates(db, &user, changed); err != nil { ... }
+//	    response.Success(c, user)
+//	})
+//
+// 错误响应:
+//   - 413 请求体超过 options.MaxBodySize
+//   - 415 不支持的 Content-Type，带 Accept-Patch 头
+//   - 400 补丁不是合法的 JSON 或操作格式不正确
+//   - 409 JSON Patch 的 test 操作不满足（资源已被他人修改）
+//   - 422 路径不存在、修改只读或不存在的字段、补丁后的结果校验失败
+func Bind(c *gin.Context, current any, options Options) ([]string, bool) {
+	limit := options.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultOptions().MaxBodySize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, codeInvalidParam,
+				fmt.Sprintf("请求体过大，上限 %d 字节", limit))
+			return nil, false
+		}
+		response.ErrorWithStatus(c, http.StatusBadRequest, codeInvalidParam, "读取请求体失败")
+		return nil, false
+	}
+	changed, err := Apply(current, c.ContentType(), body, options)
+	if err != nil {
+		_ = c.Error(err)
+		status, code, msg := errorResponse(err)
+		if status == http.StatusUnsupportedMediaType {
+			c.Header("Accept-Patch", AcceptPatch)
+		}
+		response.ErrorWithStatus(c, status, code, msg)
+		return nil, false
+	}
+	return changed, true
+}
+
+func errorResponse(err error) (status, code int, msg string) {
+	var verrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType, codeInvalidParam, err.Error()
+	case errors.Is(err, ErrInvalidPatch):
+		return http.StatusBadRequest, codeInvalidParam, err.Error()
+	case errors.Is(err, ErrTestFailed):
+		return http.StatusConflict, codeConflict, "资源已被修改，请获取最新数据后重试: " + err.Error()
+	case errors.Is(err, ErrPathNotFound), errors.Is(err, ErrReadOnly),
+		errors.Is(err, ErrUnknownField), errors.Is(err, ErrInvalidValue):
+		return http.StatusUnprocessableEntity, codeInvalidParam, err.Error()
+	case errors.As(err, &verrs):
+		details := make([]string, 0, len(verrs))
+		for _, e := range verrs {
+			if msg, ok := validation.Message(e); ok {
+				details = append(details, msg)
+			} else {
+				details = append(details, fmt.Sprintf("参数 %s 校验失败：%s", e.Field(), e.Tag()))
+			}
+		}
+		return http.StatusUnprocessableEntity, codeInvalidParam, "补丁后的数据校验失败: " + strings.Join(details, "；")
+	default:
+		return http.StatusInternalServerError, codeInternal, "服务器内部错误"
+	}
+}
+
+// user 示例资源
+type user struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" binding:"required,max=20"`
+	Email        string    `json:"email" binding:"required,email"`
+	Age          int       `json:"age" binding:"gte=0,lte=150"`
+	Nickname     string    `json:"nickname"`
+	Tags         []string  `json:"tags" gorm:"serializer:json"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// PatchDemo 演示 JSON Merge Patch 和 JSON Patch 部分更新
+func PatchDemo() {
+	fmt.Println("=== JSON Merge Patch / JSON Patch 示例 ===")
+	fmt.Println()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		fmt.Println("打开数据库失败:", err)
+		return
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		fmt.Println("建表失败:", err)
+		return
+	}
+	db.Create(&user{Name: "张三", Email: "zhangsan@example.com", Age: 28, Nickname: "三哥",
+		Tags: []string{"new"}, PasswordHash: "$2a$10$..."})
+	// 打印 UPDATE 语句，观察只更新了哪些列
+	_ = db.Callback().Update().After("gorm:update").Register("demo:print_sql", func(tx *gorm.DB) {
+		fmt.Printf("     SQL: %s\n", tx.Statement.SQL.String())
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.PATCH("/users/:id", func(c *gin.Context) {
+		var u user
+		if err := db.First(&u, c.Param("id")).Error; err != nil {
+			response.ErrorWithStatus(c, http.StatusNotFound, 1004, "用户不存在")
+			return
+		}
+		changed, ok := Bind(c, &u, DefaultOptions())
+		if !ok {
+			return
+		}
+		if err := Updates(db, &u, changed); err != nil {
+			response.ErrorWithStatus(c, http.StatusInternalServerError, 2001, "数据库错误")
+			return
+		}
+		fmt.Printf("     修改的字段: %v\n", changed)
+		response.Success(c, u)
+	})
+
+	send := func(title, contentType, body string) {
+		fmt.Println(title)
+		fmt.Printf("  PATCH /users/1 (%s)\n  %s\n", contentType, body)
+		req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		result := w.Body.String()
+		if w.Code == http.StatusOK {
+			var u user
+			db.First(&u, 1)
+			result = fmt.Sprintf("name=%s email=%s age=%d nickname=%q tags=%v passwordHash=%s",
+				u.Name, u.Email, u.Age, u.Nickname, u.Tags, u.PasswordHash)
+		}
+		fmt.Printf("  -> %d %s\n", w.Code, result)
+		if accept := w.Header().Get("Accept-Patch"); accept != "" {
+			fmt.Printf("     Accept-Patch: %s\n", accept)
+		}
+		fmt.Println()
+	}
+
+	send("1. Merge Patch：只修改提供的字段，null 表示清空", MergePatchType,
+		`{"nickname": null, "age": 29}`)
+	send("2. JSON Patch：test 确认邮箱未被他人修改，再替换邮箱、追加标签", JSONPatchType,
+		`[{"op":"test","path":"/email","value":"zhangsan@example.com"},`+
+			`{"op":"replace","path":"/email","value":"zs@example.com"},{"op":"add","path":"/tags/-","value":"vip"}]`)
+	send("3. 同一个补丁再提交一次：test 失败，409，整个补丁不生效", JSONPatchType,
+		`[{"op":"test","path":"/email","value":"zhangsan@example.com"},{"op":"replace","path":"/name","value":"李四"}]`)
+	send("4. 修改只读字段", MergePatchType, `{"id": 2, "name": "李四"}`)
+	send("5. 写入 JSON 中不存在的字段（json:\"-\" 的密码哈希不能通过补丁修改）", MergePatchType,
+		`{"passwordHash": "hacked"}`)
+	send("6. 补丁后的结果重新执行 binding 校验", JSONPatchType,
+		`[{"op":"replace","path":"/email","value":"not-an-email"},{"op":"remove","path":"/name"}]`)
+	send("7. 路径不存在", JSONPatchType, `[{"op":"remove","path":"/tags/5"}]`)
+	send("8. 补丁没有改变任何值：不执行 UPDATE", MergePatchType, `{"name": "张三"}`)
+	send("9. 普通 JSON 不是补丁格式", "application/json", `{"name": "李四"}`)
+
+	fmt.Println("要点:")
+	fmt.Println("  - Merge Patch 简单直观，适合表单式修改；JSON Patch 能操作数组元素、设置 null、用 test 做条件更新")
+	fmt.Println("  - 补丁应用在资源的 JSON 表示上，再绑定回结构体，binding 标签校验的是修改后的完整资源")
+	fmt.Println("  - 只读字段和 json:\"-\" 字段不能通过补丁修改；写入原值不算修改")
+	fmt.Println("  - GORM 使用 Select 只更新修改的列，零值也会写入，并发修改其他列不会被覆盖")
+	fmt.Println("  - 不支持的 Content-Type 返回 415 + Accept-Patch；test 失败返回 409")
+}
@@ -0,0 +1,220 @@
+package patch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMergePatch RFC 7386 附录 A 中的部分用例
+func TestMergePatch(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{"id":9007199254740993}`, `{}`, `{"id":9007199254740993}`},
+	}
+	for _, tc := range cases {
+		got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, tc.want, string(got), tc.patch)
+	}
+	assert.Contains(t, string(must(MergePatch([]byte(`{"id":9007199254740993}`), []byte(`{}`)))), "9007199254740993", "大整数不丢精度")
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// TestJSONPatch 测试各个操作和错误
+func TestJSONPatch(t *testing.T) {
+	doc := `{"name":"a","tags":["x","y"],"a/b":{"~c":1},"n":1.0}`
+	cases := []struct{ patch, want string }{
+		{`[{"op":"add","path":"/tags/1","value":"z"}]`, `{"name":"a","tags":["x","z","y"],"a/b":{"~c":1},"n":1.0}`},
+		{`[{"op":"add","path":"/tags/-","value":null}]`, `{"name":"a","tags":["x","y",null],"a/b":{"~c":1},"n":1.0}`},
+		{`[{"op":"remove","path":"/tags/0"}]`, `{"name":"a","tags":["y"],"a/b":{"~c":1},"n":1.0}`},
+		{`[{"op":"replace","path":"/a~1b/~0c","value":2}]`, `{"name":"a","tags":["x","y"],"a/b":{"~c":2},"n":1.0}`},
+		{`[{"op":"move","from":"/name","path":"/tags/0"}]`, `{"tags":["a","x","y"],"a/b":{"~c":1},"n":1.0}`},
+		{`[{"op":"copy","from":"/tags","path":"/copy"},{"op":"add","path":"/copy/-","value":"c"}]`,
+			`{"name":"a","tags":["x","y"],"copy":["x","y","c"],"a/b":{"~c":1},"n":1.0}`},
+		{`[{"op":"test","path":"/n","value":1},{"op":"test","path":"/tags","value":["x","y"]}]`, doc},
+		{`[{"op":"replace","path":"","value":{"b":1}}]`, `{"b":1}`},
+	}
+	for _, tc := range cases {
+		got, err := ApplyJSONPatch([]byte(doc), []byte(tc.patch))
+		require.NoError(t, err, tc.patch)
+		assert.JSONEq(t, tc.want, string(got), tc.patch)
+	}
+
+	errCases := []struct {
+		patch string
+		err   error
+	}{
+		{`{"op":"add"}`, ErrInvalidPatch},
+		{`[{"op":"add","path":"/x"}]`, ErrInvalidPatch},
+		{`[{"op":"unknown","path":"/x"}]`, ErrInvalidPatch},
+		{`[{"op":"remove","path":"x"}]`, ErrInvalidPatch},
+		{`[{"op":"remove","path":"/a~2"}]`, ErrInvalidPatch},
+		{`[{"op":"move","from":"/a~1b","path":"/a~1b/d"}]`, ErrInvalidPatch},
+		{`[{"op":"remove","path":"/missing"}]`, ErrPathNotFound},
+		{`[{"op":"replace","path":"/missing","value":1}]`, ErrPathNotFound},
+		{`[{"op":"add","path":"/tags/3","value":1}]`, ErrPathNotFound},
+		{`[{"op":"add","path":"/tags/01","value":1}]`, ErrPathNotFound},
+		{`[{"op":"add","path":"/name/x","value":1}]`, ErrPathNotFound},
+		{`[{"op":"test","path":"/name","value":"b"}]`, ErrTestFailed},
+	}
+	for _, tc := range errCases {
+		_, err := ApplyJSONPatch([]byte(doc), []byte(tc.patch))
+		assert.ErrorIs(t, err, tc.err, tc.patch)
+	}
+
+	_, err := ApplyJSONPatch([]byte(doc), []byte(`[{"op":"add","path":"/x","value":1},{"op":"test","path":"/x","value":2}]`))
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, 1, opErr.Index)
+}
+
+type account struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" binding:"required"`
+	Age       int       `json:"age" binding:"gte=0"`
+	Tags      []string  `json:"tags" gorm:"serializer:json"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TestApply 测试补丁应用到结构体
+func TestApply(t *testing.T) {
+	original := account{ID: 1, Name: "a", Age: 20, Tags: []string{"x"}, Secret: "s"}
+	options := DefaultOptions()
+
+	a := original
+	changed, err := Apply(&a, "application/merge-patch+json; charset=utf-8", []byte(`{"age":null,"tags":["y"],"id":1}`), options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Age", "Tags"}, changed, "写入原值的 id 不算修改")
+	assert.Equal(t, account{ID: 1, Name: "a", Age: 0, Tags: []string{"y"}, Secret: "s"}, a, "json:\"-\" 字段保留原值")
+	assert.Equal(t, []string{"x"}, original.Tags, "不修改原来的切片")
+
+	changed, err = Apply(&a, MergePatchType, []byte(`{"name":"a"}`), options)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	for body, want := range map[string]error{
+		`{"id":2}`:        ErrReadOnly,
+		`{"secret":"x"}`:  ErrUnknownField,
+		`{"age":"x"}`:     ErrInvalidValue,
+		`[1]`:             ErrInvalidValue,
+		`{"name":null}`:   validator.ValidationErrors{},
+		`{"age":-1}`:      validator.ValidationErrors{},
+		`{"createdAt":1}`: ErrReadOnly,
+	} {
+		before := a
+		_, err := Apply(&a, MergePatchType, []byte(body), options)
+		if verrs, ok := want.(validator.ValidationErrors); ok {
+			assert.ErrorAs(t, err, &verrs, body)
+		} else {
+			assert.ErrorIs(t, err, want, body)
+		}
+		assert.Equal(t, before, a, "失败时不修改: %s", body)
+	}
+	_, err = Apply(&a, "application/json", []byte(`{}`), options)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.Panics(t, func() { _, _ = Apply(a, MergePatchType, []byte(`{}`), options) })
+
+	_, err = Apply(&arrayResource{}, MergePatchType, []byte(`{"a":1}`), options)
+	assert.ErrorIs(t, err, ErrInvalidValue, "JSON 表示不是对象时返回错误而不是 panic")
+}
+
+// arrayResource 自定义 JSON 表示不是对象的资源
+type arrayResource struct{}
+
+func (arrayResource) MarshalJSON() ([]byte, error) { return []byte(`[]`), nil }
+
+// TestBindAndUpdates 测试 HTTP 状态码和只更新修改的列
+func TestBindAndUpdates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&account{}))
+	require.NoError(t, db.Create(&account{Name: "a", Age: 20, Secret: "s"}).Error)
+	var statements []string
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:sql", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/accounts/:id", func(c *gin.Context) {
+		var a account
+		require.NoError(t, db.First(&a, c.Param("id")).Error)
+		changed, ok := Bind(c, &a, DefaultOptions())
+		if !ok {
+			return
+		}
+		require.NoError(t, Updates(db, &a, changed))
+		c.JSON(http.StatusOK, a)
+	})
+	send := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/accounts/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(JSONPatchType, `[{"op":"replace","path":"/age","value":0}]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, statements, 1)
+	assert.Equal(t, "UPDATE `accounts` SET `age`=?,`updated_at`=? WHERE `id` = ?", statements[0], "零值也写入，只更新修改的列")
+	var saved account
+	require.NoError(t, db.First(&saved, 1).Error)
+	assert.Equal(t, 0, saved.Age)
+	assert.Equal(t, "s", saved.Secret)
+
+	assert.Equal(t, http.StatusOK, send(MergePatchType, `{"age":0}`).Code)
+	assert.Len(t, statements, 1, "没有修改时不执行 UPDATE")
+
+	for _, tc := range []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/json", `{}`, http.StatusUnsupportedMediaType},
+		{MergePatchType, `{`, http.StatusBadRequest},
+		{JSONPatchType, `[{"op":"test","path":"/age","value":1}]`, http.StatusConflict},
+		{JSONPatchType, `[{"op":"remove","path":"/tags/0"}]`, http.StatusUnprocessableEntity},
+		{MergePatchType, `{"id":5}`, http.StatusUnprocessableEntity},
+		{MergePatchType, `{"name":""}`, http.StatusUnprocessableEntity},
+		{MergePatchType, `{"name":"` + strings.Repeat("a", 1<<20) + `"}`, http.StatusRequestEntityTooLarge},
+	} {
+		w := send(tc.contentType, tc.body)
+		assert.Equal(t, tc.status, w.Code, "%s %.40s", tc.contentType, tc.body)
+		if tc.status == http.StatusUnsupportedMediaType {
+			assert.Equal(t, AcceptPatch, w.Header().Get("Accept-Patch"))
+		}
+	}
+
+	status, code, _ := errorResponse(errors.New("db down"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, codeInternal, code)
+}
@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
+
+	tracing "go-learning/gin/10_tracing"
+	accesslog "go-learning/gin/11_access_log"
+	cors "go-learning/gin/19_cors"
+	timing "go-learning/gin/23_server_timing"
 )
 
 // MiddlewareFlowDemo 演示中间件执行流程
@@ -54,10 +59,10 @@ func MiddlewareFlowDemo() {
 	//  [Logger中间件] ← 记录响应耗时（c.Next()后执行）
 
 	// Logger 中间件 - 记录请求和响应时间
+	// 请求ID由 tracing.Middleware 写入：沿用上游的 X-Request-ID，没有时才生成
 	loggerMiddleware := func(c *gin.Context) {
 		start := time.Now()
-		requestID := uuid.New().String()
-		c.Set("requestID", requestID)
+		requestID := c.GetString("requestID")
 
 		log.Printf("[%s] 请求开始: %s %s", requestID, c.Request.Method, c.Request.URL.Path)
 
@@ -68,14 +73,10 @@ func MiddlewareFlowDemo() {
 		log.Printf("[%s] 请求完成: 状态码=%d, 耗时=%v", requestID, c.Writer.Status(), latency)
 	}
 
-	// 模拟 CORS 中间件
-	corsMiddleware := func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		log.Println("CORS 中间件: 设置跨域头")
-		c.Next()
-	}
+	// CORS 中间件：允许携带 Authorization 时必须使用来源白名单，不能是 *
+	corsOptions := cors.DefaultOptions()
+	corsOptions.AllowOrigins = []string{"https://app.example.com", "http://localhost:*"}
+	corsMiddleware := cors.Middleware(corsOptions)
 
 	// 模拟 JWT 鉴权中间件（简化版）
 	jwtMiddleware := func(c *gin.Context) {
@@ -104,14 +105,17 @@ func MiddlewareFlowDemo() {
 		c.Next()
 	}
 
-	// 注册中间件（按顺序）
-	router.Use(loggerMiddleware)
-	router.Use(corsMiddleware)
-	router.Use(jwtMiddleware)
-	router.Use(rbacMiddleware)
+	// 注册中间件（按顺序），链路追踪放在最前面
+	// timing.Wrap 记录每个中间件的耗时，通过 Server-Timing 响应头返回
+	router.Use(tracing.Middleware(tracing.DefaultOptions()))
+	router.Use(timing.Middleware(timing.DefaultOptions()))
+	router.Use(timing.Wrap("logger", loggerMiddleware))
+	router.Use(timing.Wrap("cors", corsMiddleware)) // 在 JWT 之前，预检请求不带 Authorization，直接返回
+	router.Use(timing.Wrap("jwt", jwtMiddleware))
+	router.Use(timing.Wrap("rbac", rbacMiddleware))
 
 	// 业务处理函数
-	router.GET("/api/data", func(c *gin.Context) {
+	router.GET("/api/data", timing.Wrap("handler", func(c *gin.Context) {
 		requestID, _ := c.Get("requestID")
 		userID, _ := c.Get("userID")
 		roles, _ := c.Get("roles")
@@ -122,7 +126,16 @@ func MiddlewareFlowDemo() {
 			"userID":    userID,
 			"roles":     roles,
 		})
-	})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	req.Header.Set("Authorization", "Bearer token123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	fmt.Printf("GET /api/data -> %d\n", w.Code)
+	fmt.Printf("Server-Timing: %s\n", w.Header().Get(timing.HeaderName))
+	fmt.Println("  （dur 为各中间件自身的耗时，单位毫秒，不含后续中间件）")
+	fmt.Println()
 
 	fmt.Println("中间件执行流程说明:")
 	fmt.Println("  1. Logger 中间件: 记录请求开始时间")
@@ -406,13 +419,8 @@ func RequireRole(role string) gin.HandlerFunc {
 	}
 }
 
-// JWTAuthDemo 演示JWT鉴权完整实现
-func JWTAuthDemo() {
-	fmt.Println("=== JWT 鉴权完整实现示例 ===")
-	fmt.Println()
-
-	router := gin.Default()
-
+// registerAuthRoutes 注册登录、用户信息和管理员接口，JWTAuthDemo 与契约测试（testdata/jwt_auth.yaml）共用
+func registerAuthRoutes(router *gin.Engine) {
 	// 登录接口 - 生成 Token
 	router.POST("/api/login", func(c *gin.Context) {
 		var login struct {
@@ -484,6 +492,15 @@ func JWTAuthDemo() {
 			})
 		}
 	}
+}
+
+// JWTAuthDemo 演示JWT鉴权完整实现
+func JWTAuthDemo() {
+	fmt.Println("=== JWT 鉴权完整实现示例 ===")
+	fmt.Println()
+
+	router := gin.Default()
+	registerAuthRoutes(router)
 
 	fmt.Println("JWT 鉴权实现说明:")
 	fmt.Println()
@@ -518,48 +535,54 @@ func JWTAuthDemo() {
 }
 
 // CORSMiddlewareDemo 演示跨域中间件配置
-// 注意: 实际使用需要安装 github.com/gin-contrib/cors
+// 完整的预检、通配与凭证示例见 CORS 示例（go-learning/gin/19_cors）
 func CORSMiddlewareDemo() {
 	fmt.Println("=== CORS 跨域中间件配置示例 ===")
 	fmt.Println()
 
 	fmt.Println("CORS (Cross-Origin Resource Sharing) 跨域资源共享配置")
 	fmt.Println()
-	fmt.Println("安装依赖:")
-	fmt.Println("  go get github.com/gin-contrib/cors")
-	fmt.Println()
 	fmt.Println("代码示例:")
-	fmt.Println("  import \"github.com/gin-contrib/cors\"")
-	fmt.Println()
-	fmt.Println("  func CORSMiddleware() gin.HandlerFunc {")
-	fmt.Println("      return cors.New(cors.Config{")
-	fmt.Println("          AllowOrigins: []string{")
-	fmt.Println("              \"https://prod.com\",")
-	fmt.Println("              \"http://localhost:3000\",")
-	fmt.Println("          },")
-	fmt.Println("          AllowMethods: []string{\"GET\", \"POST\", \"PUT\", \"PATCH\", \"DELETE\"},")
-	fmt.Println("          AllowHeaders: []string{")
-	fmt.Println("              \"Origin\",")
-	fmt.Println("              \"Content-Type\",")
-	fmt.Println("              \"Authorization\",")
-	fmt.Println("          },")
-	fmt.Println("          ExposeHeaders:    []string{\"Content-Length\"},")
-	fmt.Println("          AllowCredentials: true,")
-	fmt.Println("          MaxAge:           12 * time.Hour,")
-	fmt.Println("      })")
+	fmt.Println("  import cors \"go-learning/gin/19_cors\"")
+	fmt.Println()
+	fmt.Println("  options := cors.DefaultOptions()")
+	fmt.Println("  options.AllowOrigins = []string{")
+	fmt.Println("      \"https://prod.com\",")
+	fmt.Println("      \"https://*.prod.com\",     // 任意子域，不含 prod.com 本身")
+	fmt.Println("      \"http://localhost:*\",     // 本地开发任意端口")
 	fmt.Println("  }")
+	fmt.Println("  options.AllowCredentials = true")
+	fmt.Println("  options.MaxAge = 2 * time.Hour")
+	fmt.Println("  router.Use(cors.Middleware(options))  // 放在鉴权中间件之前")
+	fmt.Println()
+
+	options := cors.DefaultOptions()
+	options.AllowOrigins = []string{"https://prod.com", "http://localhost:*"}
+	options.AllowCredentials = true
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(cors.Middleware(options))
+	router.GET("/api/data", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	for _, origin := range []string{"https://prod.com", "http://localhost:3000", "https://evil.com"} {
+		req := httptest.NewRequest(http.MethodOptions, "/api/data", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", "authorization")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("预检 Origin: %-24s -> %d  Allow-Origin: %q\n", origin, w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
 	fmt.Println()
+
 	fmt.Println("配置说明:")
-	fmt.Println("  AllowOrigins     - 允许的源地址列表（* 表示允许所有）")
+	fmt.Println("  AllowOrigins     - 允许的源地址列表，支持 *.域名 和 :* 通配（单独的 * 表示允许所有）")
 	fmt.Println("  AllowMethods     - 允许的HTTP方法")
 	fmt.Println("  AllowHeaders     - 允许的请求头")
 	fmt.Println("  ExposeHeaders    - 暴露给客户端的响应头")
-	fmt.Println("  AllowCredentials - 是否允许携带凭证（Cookie等）")
-	fmt.Println("  MaxAge           - 预检请求（OPTIONS）缓存时间")
+	fmt.Println("  AllowCredentials - 是否允许携带凭证（Cookie等），不能与 * 同时使用")
+	fmt.Println("  MaxAge           - 预检请求（OPTIONS）缓存时间，通过 Access-Control-Max-Age 返回")
 	fmt.Println()
-	fmt.Println("使用示例:")
-	fmt.Println("  router.Use(CORSMiddleware())  // 全局中间件")
-	fmt.Println("  router.GET(\"/api/data\", CORSMiddleware(), handler)  // 路由级中间件")
+	fmt.Println("注意: 响应会带上 Vary: Origin，避免缓存把一个来源的响应返回给另一个来源")
 }
 
 // MiddlewareDebugDemo 演示中间件调试技巧
@@ -570,22 +593,25 @@ func MiddlewareDebugDemo() {
 	router := gin.Default()
 
 	// 1. 上下文数据追踪
+	// 请求ID和 traceparent 由 tracing.Middleware 处理：
+	// 沿用上游传入的值并写回响应头，同一个请求在网关、各服务的日志里是同一个ID
 	requestIDMiddleware := func(c *gin.Context) {
-		// 生成唯一请求ID
-		requestID := uuid.New().String()
-		c.Set("requestID", requestID)
+		logger := tracing.Logger(c) // 已带 trace_id、request_id、route
 
 		// 记录请求信息
-		log.Printf("[%s] %s %s", requestID, c.Request.Method, c.Request.URL.Path)
-		log.Printf("[%s] 请求头: %v", requestID, c.Request.Header)
+		// 不要直接输出 c.Request.Header，其中的 Authorization 会把令牌写进日志；
+		// 需要请求头时使用 accesslog 中间件，它会按名单脱敏
+		logger.Info("请求开始", "method", c.Request.Method, "path", c.Request.URL.Path)
 
 		c.Next()
 
 		// 记录响应信息（c.Next()后执行）
-		log.Printf("[%s] 响应状态码: %d", requestID, c.Writer.Status())
+		logger.Info("请求完成", "status", c.Writer.Status())
 	}
 
 	// 2. 中间件执行顺序验证
+	router.Use(tracing.Middleware(tracing.DefaultOptions()))
+	router.Use(accesslog.Middleware(accesslog.DefaultOptions()))
 	router.Use(requestIDMiddleware)
 
 	router.GET("/api/debug", func(c *gin.Context) {
@@ -601,7 +627,8 @@ func MiddlewareDebugDemo() {
 	fmt.Println("1. 上下文数据追踪:")
 	fmt.Println("   - 使用 c.Set() 存储调试信息")
 	fmt.Println("   - 使用 c.Get() 获取调试信息")
-	fmt.Println("   - 使用 UUID 生成唯一请求ID")
+	fmt.Println("   - 沿用上游的 X-Request-ID / traceparent，没有时生成新的")
+	fmt.Println("   - 使用 tracing.Logger(c) 输出带 trace_id 的结构化日志")
 	fmt.Println()
 	fmt.Println("2. 中间件执行顺序验证:")
 	fmt.Println("   - 在每个中间件中添加日志输出")
@@ -613,6 +640,7 @@ func MiddlewareDebugDemo() {
 	fmt.Println()
 	fmt.Println("测试示例:")
 	fmt.Println("  curl http://localhost:8080/api/debug")
+	fmt.Println("  curl -H \"X-Request-ID: abc-123\" http://localhost:8080/api/debug  # 响应和日志中沿用 abc-123")
 	fmt.Println("  查看控制台日志输出，观察中间件执行顺序")
 }
 
@@ -634,23 +662,13 @@ func MiddlewareBestPracticesDemo() {
 
 	fmt.Println("========== 2. 敏感信息过滤 ==========")
 	fmt.Println()
-	fmt.Println("  // 在日志中间件中过滤敏感字段")
-	fmt.Println("  func SensitiveDataLogger() gin.HandlerFunc {")
-	fmt.Println("      return func(c *gin.Context) {")
-	fmt.Println("          // 过滤认证相关路径的请求体")
-	fmt.Println("          if strings.Contains(c.Request.URL.Path, \"/auth\") {")
-	fmt.Println("              // 不记录敏感信息")
-	fmt.Println("              c.Next()")
-	fmt.Println("              return")
-	fmt.Println("          }")
+	fmt.Println("  // 不要在日志中原样输出请求头和请求体，使用 accesslog 中间件按名单脱敏")
+	fmt.Println("  options := accesslog.DefaultOptions()")
+	fmt.Println("  options.LogBody = true                                  // 记录 JSON / 表单请求体")
+	fmt.Println("  options.Redaction.BodyFields = append(options.Redaction.BodyFields, \"bank_account\")")
+	fmt.Println("  router.Use(accesslog.Middleware(options))")
 	fmt.Println()
-	fmt.Println("          // 记录其他请求")
-	fmt.Println("          body, _ := ioutil.ReadAll(c.Request.Body)")
-	fmt.Println("          log.Printf(\"Request Body: %%s\", body)")
-	fmt.Println("          c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))")
-	fmt.Println("          c.Next()")
-	fmt.Println("      }")
-	fmt.Println("  }")
+	fmt.Println("  // Authorization、Cookie、?token=、password 等默认输出为 [REDACTED]")
 	fmt.Println()
 
 	fmt.Println("========== 3. 中间件性能优化 ==========")
@@ -659,6 +677,8 @@ func MiddlewareBestPracticesDemo() {
 	fmt.Println("  - 使用缓存减少重复计算")
 	fmt.Println("  - 合理使用 c.Abort() 提前终止不必要的处理")
 	fmt.Println("  - 使用连接池管理数据库连接")
+	fmt.Println("  - 用 timeout.Timeout 给接口设置处理时限，GORM 调用使用 db.WithContext(c.Request.Context()) 才能随之取消")
+	fmt.Println("  - 用 timeout.MaxBodySize 限制请求体大小，超过返回 413")
 	fmt.Println()
 
 	fmt.Println("========== 4. 错误处理 ==========")
@@ -778,6 +798,11 @@ func MiddlewareTestDemo() {
 	fmt.Println("  }")
 	fmt.Println()
 
+	fmt.Println("  接口较多时，可以把请求和断言写成 YAML 用例（见 Contract 示例）:")
+	fmt.Println("    contracttest.RunFS(t, router, os.DirFS(\"testdata\"), \"*.yaml\")")
+	fmt.Println("  本包的 TestJWTAuthContract 用 testdata/jwt_auth.yaml 覆盖了 JWTAuthDemo 的全部接口")
+	fmt.Println()
+
 	fmt.Println("========== 3. 测试覆盖率统计 ==========")
 	fmt.Println()
 	fmt.Println("  # 生成测试覆盖率报告")
@@ -8,6 +8,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contract "go-learning/gin/21_contract"
+	"go-learning/gin/21_contract/contracttest"
 )
 
 // TestJWTMiddleware 演示中间件单元测试
@@ -104,4 +108,17 @@ func TestRequireRole(t *testing.T) {
 	})
 }
 
-
+// TestJWTAuthContract 用声明式用例覆盖 JWTAuthDemo 的接口，新增用例只需要修改 testdata/jwt_auth.yaml
+func TestJWTAuthContract(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-key")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerAuthRoutes(router)
+
+	suite, err := contract.Load("testdata/jwt_auth.yaml")
+	require.NoError(t, err)
+	userToken, err := GenerateToken("user456", []string{"user"})
+	require.NoError(t, err)
+	suite.Vars = map[string]string{"userToken": userToken}
+	contracttest.Run(t, router, suite)
+}
@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ========== 验证标签常量 ==========
+// 结构体上使用 binding:"required,idcard" 等标签引用这些规则
+const (
+	TagPhone          = "phone"           // 中国大陆手机号
+	TagStrongPassword = "strong_password" // 强密码：至少8位，包含字母和数字
+	TagIDCard         = "idcard"          // 18位居民身份证号（含校验位）
+	TagUSCC           = "uscc"            // 统一社会信用代码（含校验位）
+	TagBankCard       = "bankcard"        // 银行卡号（Luhn 校验）
+	TagPostcode       = "postcode"        // 邮政编码
+	TagLicensePlate   = "license_plate"   // 机动车号牌（含新能源号牌）
+)
+
+// ========== 预编译正则 ==========
+// regexp.MustCompile 在包初始化时只编译一次
+// 相比在验证函数中每次调用 regexp.MatchString，避免了重复编译的开销
+var (
+	phoneRegexp    = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	letterRegexp   = regexp.MustCompile(`[a-zA-Z]`)
+	digitRegexp    = regexp.MustCompile(`[0-9]`)
+	idCardRegexp   = regexp.MustCompile(`^[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dX]$`)
+	usccRegexp     = regexp.MustCompile(`^[0-9A-HJ-NPQRTUWXY]{2}\d{6}[0-9A-HJ-NPQRTUWXY]{10}$`)
+	bankCardRegexp = regexp.MustCompile(`^\d{13,19}$`)
+	postcodeRegexp = regexp.MustCompile(`^[0-8]\d{5}$`)
+
+	// 普通号牌: 省份简称 + 发牌机关字母 + 5位（最后一位可以是 挂/学/警/港/澳）
+	// 新能源号牌: 省份简称 + 发牌机关字母 + 6位
+	//   小型车: D/F 等字母开头，如 京AD12345
+	//   大型车: D/F 等字母结尾，如 京A12345D
+	plateRegexp = regexp.MustCompile(`^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼使领][A-HJ-NP-Z]` +
+		`(?:[A-HJ-NP-Z0-9]{4}[A-HJ-NP-Z0-9挂学警港澳]|[DABCEFGHJK][A-HJ-NP-Z0-9]\d{4}|\d{5}[DABCEFGHJK])$`)
+)
+
+// 身份证校验位计算参数（GB 11643-1999）
+var (
+	idCardWeights    = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	idCardCheckCodes = "10X98765432"
+)
+
+// 统一社会信用代码校验参数（GB 32100-2015）
+// 字符集共 31 个字符，不使用 I、O、Z、S、V
+var (
+	usccCharset = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+	usccWeights = []int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+)
+
+// IsPhone 判断是否为中国大陆手机号
+func IsPhone(s string) bool {
+	return phoneRegexp.MatchString(s)
+}
+
+// IsStrongPassword 判断密码强度：至少8位，同时包含字母和数字
+func IsStrongPassword(s string) bool {
+	return len(s) >= 8 && letterRegexp.MatchString(s) && digitRegexp.MatchString(s)
+}
+
+// IsIDCard 判断是否为合法的18位居民身份证号
+// 校验步骤:
+//  1. 格式校验: 地区码 + 出生日期 + 顺序码 + 校验位
+//  2. 出生日期必须是真实存在且不晚于今天的日期（排除 19990230 这类日期）
+//  3. 校验位: 前17位加权求和后对 11 取模，查表得到校验码
+func IsIDCard(s string) bool {
+	s = strings.ToUpper(s)
+	if !idCardRegexp.MatchString(s) {
+		return false
+	}
+
+	birthday, err := time.Parse("20060102", s[6:14])
+	if err != nil || birthday.After(time.Now()) {
+		return false
+	}
+
+	sum := 0
+	for i, w := range idCardWeights {
+		sum += int(s[i]-'0') * w
+	}
+	return s[17] == idCardCheckCodes[sum%11]
+}
+
+// IsUSCC 判断是否为合法的统一社会信用代码
+// 结构: 登记管理部门码(1) + 机构类别码(1) + 登记管理机关行政区划码(6) + 主体标识码(9) + 校验码(1)
+// 校验码 = 31 - (前17位字符值加权和 % 31)，结果为 31 时取 0
+func IsUSCC(s string) bool {
+	s = strings.ToUpper(s)
+	if !usccRegexp.MatchString(s) {
+		return false
+	}
+
+	sum := 0
+	for i, w := range usccWeights {
+		sum += strings.IndexByte(usccCharset, s[i]) * w
+	}
+	check := 31 - sum%31
+	if check == 31 {
+		check = 0
+	}
+	return s[17] == usccCharset[check]
+}
+
+// IsBankCard 判断是否为合法的银行卡号（13-19位数字，通过 Luhn 校验）
+// Luhn 算法: 从右往左，偶数位乘以 2（大于 9 则减 9），所有位求和后能被 10 整除
+func IsBankCard(s string) bool {
+	if !bankCardRegexp.MatchString(s) {
+		return false
+	}
+	return luhnValid(s)
+}
+
+// luhnValid Luhn 校验（调用方保证 s 只包含数字）
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// IsPostcode 判断是否为中国邮政编码（6位数字，首位 0-8）
+func IsPostcode(s string) bool {
+	return postcodeRegexp.MatchString(s)
+}
+
+// IsLicensePlate 判断是否为机动车号牌（普通号牌和新能源号牌）
+func IsLicensePlate(s string) bool {
+	return plateRegexp.MatchString(s)
+}
+
+// fieldRules 标签 → 字符串判断函数
+// Register 时统一注册到 validator
+var fieldRules = map[string]func(string) bool{
+	TagPhone:          IsPhone,
+	TagStrongPassword: IsStrongPassword,
+	TagIDCard:         IsIDCard,
+	TagUSCC:           IsUSCC,
+	TagBankCard:       IsBankCard,
+	TagPostcode:       IsPostcode,
+	TagLicensePlate:   IsLicensePlate,
+}
+
+// stringRule 将字符串判断函数包装为 validator.Func
+// 非字符串字段直接视为校验失败，避免标签被误用在 int 等字段上
+func stringRule(fn func(string) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			return false
+		}
+		return fn(field.String())
+	}
+}
@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// 结构体级规则的错误标签
+// 出现在 validator.FieldError.Tag() 中，可用于生成友好的错误信息
+const (
+	TagPasswordConfirm = "password_confirm" // 两次输入的密码不一致
+	TagDateRange       = "date_range"       // 结束日期早于开始日期
+	TagAtLeastOneOf    = "at_least_one_of"  // 多个字段至少填写一个
+)
+
+// DateLayout 字符串日期字段的解析格式
+// 除此之外还会尝试 RFC3339 格式
+const DateLayout = "2006-01-02"
+
+// StructRule 结构体级验证规则
+//
+// 字段级标签（binding:"required"）只能看到单个字段，
+// 像"确认密码必须等于密码"、"结束日期不能早于开始日期"这类规则需要同时读取多个字段，
+// 因此需要注册为结构体级验证（validator.RegisterStructValidation）
+//
+// 规则中的字段名使用 Go 结构体字段名（如 "Password"），而不是 json 标签名
+type StructRule func(sl validator.StructLevel)
+
+// StructRuleSet 某个结构体类型需要执行的一组结构体级规则
+type StructRuleSet struct {
+	Type  interface{}  // 结构体零值，如 RegisterRequest{}
+	Rules []StructRule // 该类型上执行的规则
+}
+
+// For 为结构体类型声明结构体级规则
+//
+// 使用示例:
+//
+//	validation.For(RegisterRequest{},
+//	    validation.PasswordConfirm("Password", "ConfirmPassword"),
+//	)
+func For(target interface{}, rules ...StructRule) StructRuleSet {
+	return StructRuleSet{Type: target, Rules: rules}
+}
+
+// PasswordConfirm 确认密码规则：confirmField 的值必须与 field 完全相同
+// 校验失败时错误挂在 confirmField 上，便于前端在确认密码输入框下提示
+func PasswordConfirm(field, confirmField string) StructRule {
+	return func(sl validator.StructLevel) {
+		current := sl.Current()
+		password, ok1 := stringField(current, field)
+		confirm, ok2 := stringField(current, confirmField)
+		if !ok1 || !ok2 {
+			return
+		}
+		if password != confirm {
+			sl.ReportError(confirm, confirmField, confirmField, TagPasswordConfirm, field)
+		}
+	}
+}
+
+// DateRange 日期区间规则：endField 不能早于 startField
+// 支持 time.Time、*time.Time 以及 "2006-01-02" / RFC3339 格式的字符串字段
+// 任意一端为空时跳过（是否必填交给 required 标签处理）
+func DateRange(startField, endField string) StructRule {
+	return func(sl validator.StructLevel) {
+		current := sl.Current()
+		start, ok1 := timeField(current, startField)
+		end, ok2 := timeField(current, endField)
+		if !ok1 || !ok2 {
+			return
+		}
+		if end.Before(start) {
+			sl.ReportError(fieldValue(current, endField), endField, endField, TagDateRange, startField)
+		}
+	}
+}
+
+// AtLeastOneOf 至少填写一个规则：fields 中至少有一个字段为非零值
+// 典型场景: 联系方式中手机号和邮箱至少填一个
+// 校验失败时错误挂在第一个字段上，Param() 为空格分隔的全部字段名
+func AtLeastOneOf(fields ...string) StructRule {
+	return func(sl validator.StructLevel) {
+		current := sl.Current()
+		for _, name := range fields {
+			f := current.FieldByName(name)
+			if f.IsValid() && !f.IsZero() {
+				return
+			}
+		}
+		if len(fields) > 0 {
+			sl.ReportError(fieldValue(current, fields[0]), fields[0], fields[0], TagAtLeastOneOf, strings.Join(fields, " "))
+		}
+	}
+}
+
+// structLevelFunc 将一组规则合并为一个 validator.StructLevelFunc
+func (s StructRuleSet) structLevelFunc() validator.StructLevelFunc {
+	return func(sl validator.StructLevel) {
+		for _, rule := range s.Rules {
+			rule(sl)
+		}
+	}
+}
+
+// stringField 读取字符串字段（字段不存在或类型不符时返回 false）
+func stringField(v reflect.Value, name string) (string, bool) {
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), true
+}
+
+// fieldValue 读取字段的值用于 ReportError，字段不存在或未导出时返回 nil
+func fieldValue(v reflect.Value, name string) interface{} {
+	f := v.FieldByName(name)
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	return f.Interface()
+}
+
+// timeField 读取日期字段，零值、空字符串或未导出字段返回 false
+func timeField(v reflect.Value, name string) (time.Time, bool) {
+	f := v.FieldByName(name)
+	if !f.IsValid() || !f.CanInterface() {
+		return time.Time{}, false
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return time.Time{}, false
+		}
+		f = f.Elem()
+	}
+
+	switch value := f.Interface().(type) {
+	case time.Time:
+		return value, !value.IsZero()
+	case string:
+		if value == "" {
+			return time.Time{}, false
+		}
+		if t, err := time.Parse(DateLayout, value); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
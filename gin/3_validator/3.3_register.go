@@ -0,0 +1,190 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Register 在 gin 的 binding.Validator 上一次性注册全部规则
+//
+// 包括:
+//   - 字段级规则: phone、strong_password、idcard、uscc、bankcard、postcode、license_plate
+//   - 结构体级规则: 通过 For(...) 声明的密码确认、日期区间、至少填写一个等
+//
+// 使用示例:
+//
+//	if err := validation.Register(
+//	    validation.For(RegisterRequest{}, validation.PasswordConfirm("Password", "ConfirmPassword")),
+//	); err != nil {
+//	    log.Fatal(err)
+//	}
+func Register(sets ...StructRuleSet) error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("binding.Validator 的引擎不是 *validator.Validate")
+	}
+	return RegisterOn(v, sets...)
+}
+
+// RegisterOn 在指定的 validator 实例上注册全部规则
+// 单元测试中可以传入 validator.New() 创建的独立实例，避免影响全局验证器
+func RegisterOn(v *validator.Validate, sets ...StructRuleSet) error {
+	for tag, fn := range fieldRules {
+		if err := v.RegisterValidation(tag, stringRule(fn)); err != nil {
+			return fmt.Errorf("注册验证规则 %s 失败: %w", tag, err)
+		}
+	}
+	for _, set := range sets {
+		v.RegisterStructValidation(set.structLevelFunc(), set.Type)
+	}
+	return nil
+}
+
+// Message 返回本包规则对应的中文错误信息
+// 第二个返回值为 false 表示不是本包定义的标签，调用方应使用自己的默认文案
+func Message(e validator.FieldError) (string, bool) {
+	subject, predicate, ok := describe(e, e.Field(), func(field string) string { return field })
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("参数 %s %s", subject, predicate), true
+}
+
+// MessageFor 与 Message 相同，但字段通过 label 显示为展示名（如表单的 label 标签），不带 "参数" 前缀
+//
+// label 的参数是 Go 字段名，包括 PasswordConfirm、DateRange、AtLeastOneOf 中引用的其它字段
+//
+//	msg, ok := validation.MessageFor(e, func(field string) string { return labels[field] })
+//	// 至少填写一个: "手机号、邮箱至少填写一个"
+func MessageFor(e validator.FieldError, label func(field string) string) (string, bool) {
+	subject, predicate, ok := describe(e, label(e.StructField()), label)
+	if !ok {
+		return "", false
+	}
+	return subject + predicate, true
+}
+
+// describe 拆分错误信息的主语和谓语，field 为出错字段的展示名，label 转换参数中引用的字段
+func describe(e validator.FieldError, field string, label func(string) string) (subject, predicate string, ok bool) {
+	switch e.Tag() {
+	case TagPhone:
+		return field, "必须是有效的手机号", true
+	case TagStrongPassword:
+		return field, "至少8位，且必须同时包含字母和数字", true
+	case TagIDCard:
+		return field, "必须是有效的18位身份证号", true
+	case TagUSCC:
+		return field, "必须是有效的统一社会信用代码", true
+	case TagBankCard:
+		return field, "必须是有效的银行卡号", true
+	case TagPostcode:
+		return field, "必须是6位邮政编码", true
+	case TagLicensePlate:
+		return field, "必须是有效的车牌号", true
+	case TagPasswordConfirm:
+		return field, fmt.Sprintf("必须与 %s 一致", label(e.Param())), true
+	case TagDateRange:
+		return field, fmt.Sprintf("不能早于 %s", label(e.Param())), true
+	case TagAtLeastOneOf:
+		names := strings.Fields(e.Param())
+		for i, name := range names {
+			names[i] = label(name)
+		}
+		return strings.Join(names, "、"), "至少填写一个", true
+	default:
+		return "", "", false
+	}
+}
+
+// ValidatorPackDemo 演示中国业务数据验证规则包
+func ValidatorPackDemo() {
+	fmt.Println("=== 中国业务数据验证规则包示例 ===")
+	fmt.Println()
+
+	// 企业开户申请：同时用到字段级规则和结构体级规则
+	type AccountRequest struct {
+		CompanyName     string `json:"companyName" binding:"required"`
+		CreditCode      string `json:"creditCode" binding:"required,uscc"`
+		LegalIDCard     string `json:"legalIdCard" binding:"required,idcard"`
+		BankCard        string `json:"bankCard" binding:"required,bankcard"`
+		Postcode        string `json:"postcode" binding:"omitempty,postcode"`
+		Plate           string `json:"plate" binding:"omitempty,license_plate"`
+		Phone           string `json:"phone" binding:"omitempty,phone"`
+		Email           string `json:"email" binding:"omitempty,email"`
+		Password        string `json:"password" binding:"required,strong_password"`
+		ConfirmPassword string `json:"confirmPassword" binding:"required"`
+		ValidFrom       string `json:"validFrom" binding:"required,datetime=2006-01-02"`
+		ValidTo         string `json:"validTo" binding:"required,datetime=2006-01-02"`
+	}
+
+	// ========== 一次调用注册全部规则 ==========
+	err := Register(
+		For(AccountRequest{},
+			PasswordConfirm("Password", "ConfirmPassword"), // 确认密码
+			DateRange("ValidFrom", "ValidTo"),              // 有效期区间
+			AtLeastOneOf("Phone", "Email"),                 // 手机号、邮箱至少一个
+		),
+	)
+	if err != nil {
+		fmt.Printf("注册验证规则失败: %v\n", err)
+		return
+	}
+
+	router := gin.Default()
+
+	router.POST("/accounts", func(c *gin.Context) {
+		var req AccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var verrs validator.ValidationErrors
+			if !errors.As(err, &verrs) {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": "请求体格式错误"})
+				return
+			}
+			details := make([]string, 0, len(verrs))
+			for _, e := range verrs {
+				if msg, ok := Message(e); ok {
+					details = append(details, msg)
+				} else {
+					details = append(details, fmt.Sprintf("参数 %s 校验失败：%s", e.Field(), e.Tag()))
+				}
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": "参数校验失败", "details": details})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "开户申请已提交"})
+	})
+
+	fmt.Println("字段级规则（预编译正则 + 校验位算法）:")
+	fmt.Println("  phone           - 中国大陆手机号")
+	fmt.Println("  strong_password - 至少8位，包含字母和数字")
+	fmt.Println("  idcard          - 18位身份证号，校验出生日期和校验位（GB 11643）")
+	fmt.Println("  uscc            - 统一社会信用代码，校验位（GB 32100）")
+	fmt.Println("  bankcard        - 银行卡号，Luhn 校验")
+	fmt.Println("  postcode        - 6位邮政编码")
+	fmt.Println("  license_plate   - 车牌号（普通号牌 + 新能源号牌）")
+	fmt.Println()
+	fmt.Println("结构体级规则:")
+	fmt.Println("  PasswordConfirm(\"Password\", \"ConfirmPassword\") - 两次密码一致")
+	fmt.Println("  DateRange(\"ValidFrom\", \"ValidTo\")              - 结束日期不早于开始日期")
+	fmt.Println("  AtLeastOneOf(\"Phone\", \"Email\")                 - 至少填写一个")
+	fmt.Println()
+	fmt.Println("自检:")
+	fmt.Printf("  IsIDCard(\"11010519491231002X\")  = %v\n", IsIDCard("11010519491231002X"))
+	fmt.Printf("  IsUSCC(\"91350100M000100Y43\")    = %v\n", IsUSCC("91350100M000100Y43"))
+	fmt.Printf("  IsBankCard(\"6222021234567890128\") = %v\n", IsBankCard("6222021234567890128"))
+	fmt.Printf("  IsLicensePlate(\"粤BD12345\")       = %v\n", IsLicensePlate("粤BD12345"))
+	fmt.Println()
+	fmt.Println("测试示例:")
+	fmt.Println("  curl -X POST http://localhost:8080/accounts \\")
+	fmt.Println("    -H \"Content-Type: application/json\" \\")
+	fmt.Println("    -d '{\"companyName\":\"示例科技\",\"creditCode\":\"91350100M000100Y43\",")
+	fmt.Println("         \"legalIdCard\":\"11010519491231002X\",\"bankCard\":\"6222021234567890128\",")
+	fmt.Println("         \"phone\":\"13800138000\",\"password\":\"Passw0rd\",\"confirmPassword\":\"Passw0rd\",")
+	fmt.Println("         \"validFrom\":\"2024-01-01\",\"validTo\":\"2025-01-01\"}'")
+}
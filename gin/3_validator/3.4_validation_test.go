@@ -0,0 +1,176 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFieldRules 测试字段级规则（格式 + 校验位）
+func TestFieldRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		fn    func(string) bool
+		input string
+		want  bool
+	}{
+		{"身份证-合法", IsIDCard, "11010519491231002X", true},
+		{"身份证-小写x", IsIDCard, "11010519491231002x", true},
+		{"身份证-校验位错误", IsIDCard, "110105194912310021", false},
+		{"身份证-日期不存在", IsIDCard, "110105199902300014", false},
+		{"身份证-长度错误", IsIDCard, "11010519491231002", false},
+		{"信用代码-合法", IsUSCC, "91350100M000100Y43", true},
+		{"信用代码-校验位错误", IsUSCC, "91350100M000100Y44", false},
+		{"信用代码-非法字符", IsUSCC, "91350100M000100O43", false},
+		{"银行卡-合法", IsBankCard, "6222021234567890128", true},
+		{"银行卡-Luhn失败", IsBankCard, "6222021234567890129", false},
+		{"银行卡-太短", IsBankCard, "622202", false},
+		{"邮编-合法", IsPostcode, "010000", true},
+		{"邮编-首位9", IsPostcode, "910000", false},
+		{"车牌-普通", IsLicensePlate, "京A12345", true},
+		{"车牌-挂车", IsLicensePlate, "苏E1234挂", true},
+		{"车牌-新能源小型车", IsLicensePlate, "粤BD12345", true},
+		{"车牌-新能源大型车", IsLicensePlate, "沪A12345F", true},
+		{"车牌-字母I", IsLicensePlate, "京I12345", false},
+		{"手机号-合法", IsPhone, "13800138000", true},
+		{"手机号-号段错误", IsPhone, "12800138000", false},
+		{"密码-合法", IsStrongPassword, "Passw0rd", true},
+		{"密码-无数字", IsStrongPassword, "Password", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.fn(tt.input))
+		})
+	}
+}
+
+// TestStructRules 测试结构体级规则
+func TestStructRules(t *testing.T) {
+	type Form struct {
+		Password        string     `validate:"required"`
+		ConfirmPassword string     `validate:"required"`
+		StartDate       time.Time  `validate:"-"`
+		EndDate         *time.Time `validate:"-"`
+		Phone           string     `validate:"omitempty,phone"`
+		Email           string     `validate:"omitempty,email"`
+	}
+
+	v := validator.New()
+	err := RegisterOn(v, For(Form{},
+		PasswordConfirm("Password", "ConfirmPassword"),
+		DateRange("StartDate", "EndDate"),
+		AtLeastOneOf("Phone", "Email"),
+	))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	later := now.Add(24 * time.Hour)
+	earlier := now.Add(-24 * time.Hour)
+
+	// 返回所有失败的标签
+	failedTags := func(f Form) []string {
+		err := v.Struct(f)
+		if err == nil {
+			return nil
+		}
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tags := make([]string, 0, len(verrs))
+		for _, e := range verrs {
+			tags = append(tags, e.Tag())
+		}
+		return tags
+	}
+
+	t.Run("全部通过", func(t *testing.T) {
+		f := Form{Password: "a", ConfirmPassword: "a", StartDate: now, EndDate: &later, Phone: "13800138000"}
+		assert.Empty(t, failedTags(f))
+	})
+
+	t.Run("密码不一致", func(t *testing.T) {
+		f := Form{Password: "a", ConfirmPassword: "b", Email: "a@b.com"}
+		assert.Equal(t, []string{TagPasswordConfirm}, failedTags(f))
+	})
+
+	t.Run("结束日期早于开始日期", func(t *testing.T) {
+		f := Form{Password: "a", ConfirmPassword: "a", StartDate: now, EndDate: &earlier, Email: "a@b.com"}
+		assert.Equal(t, []string{TagDateRange}, failedTags(f))
+	})
+
+	t.Run("联系方式全部为空", func(t *testing.T) {
+		f := Form{Password: "a", ConfirmPassword: "a"}
+		assert.Equal(t, []string{TagAtLeastOneOf}, failedTags(f))
+	})
+
+	t.Run("字段名配置错误或字段未导出时不 panic", func(t *testing.T) {
+		type Misconfigured struct {
+			start time.Time
+			end   time.Time
+			phone string
+		}
+		v := validator.New()
+		assert.NoError(t, RegisterOn(v, For(Misconfigured{},
+			DateRange("start", "end"),
+			DateRange("Start", "Missing"),
+			AtLeastOneOf("phone", "Missing"),
+		)))
+		var err error
+		assert.NotPanics(t, func() {
+			err = v.Struct(Misconfigured{start: now, end: earlier})
+		})
+		var verrs validator.ValidationErrors
+		if assert.ErrorAs(t, err, &verrs) {
+			assert.Equal(t, TagAtLeastOneOf, verrs[0].Tag())
+			assert.Nil(t, verrs[0].Value())
+		}
+	})
+}
+
+// TestMessage 测试错误信息，MessageFor 把参数中引用的字段也换成展示名
+func TestMessage(t *testing.T) {
+	type Contact struct {
+		Phone string `validate:"omitempty,phone"`
+		Email string
+	}
+	v := validator.New()
+	assert.NoError(t, RegisterOn(v, For(Contact{}, AtLeastOneOf("Phone", "Email"))))
+	labels := map[string]string{"Phone": "手机号", "Email": "邮箱"}
+	label := func(field string) string { return labels[field] }
+
+	firstError := func(c Contact) validator.FieldError {
+		var verrs validator.ValidationErrors
+		if !errors.As(v.Struct(c), &verrs) {
+			t.Fatalf("expected validation errors for %+v", c)
+		}
+		return verrs[0]
+	}
+
+	e := firstError(Contact{Phone: "123"})
+	msg, ok := Message(e)
+	assert.True(t, ok)
+	assert.Equal(t, "参数 Phone 必须是有效的手机号", msg)
+	msg, ok = MessageFor(e, label)
+	assert.True(t, ok)
+	assert.Equal(t, "手机号必须是有效的手机号", msg)
+
+	e = firstError(Contact{})
+	msg, _ = Message(e)
+	assert.Equal(t, "参数 Phone、Email 至少填写一个", msg)
+	msg, _ = MessageFor(e, label)
+	assert.Equal(t, "手机号、邮箱至少填写一个", msg, "不泄露 Go 字段名")
+
+	type Other struct {
+		Name string `validate:"required"`
+	}
+	var verrs validator.ValidationErrors
+	if assert.ErrorAs(t, v.Struct(Other{}), &verrs) {
+		_, ok = MessageFor(verrs[0], label)
+		assert.False(t, ok, "不是本包的标签")
+	}
+}
@@ -0,0 +1,289 @@
+package querydsl
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operator 过滤操作符
+type Operator string
+
+const (
+	OpEq   Operator = "eq"   // 等于（filter[status]=active 省略操作符时默认 eq）
+	OpNe   Operator = "ne"   // 不等于
+	OpGt   Operator = "gt"   // 大于
+	OpGte  Operator = "gte"  // 大于等于
+	OpLt   Operator = "lt"   // 小于
+	OpLte  Operator = "lte"  // 小于等于
+	OpLike Operator = "like" // 模糊匹配（包含）
+	OpIn   Operator = "in"   // 在列表中，多个值用逗号分隔
+)
+
+// FieldType 字段值类型，决定查询字符串如何转换为 Go 值
+type FieldType int
+
+const (
+	TypeString FieldType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeTime // 支持 2006-01-02 和 RFC3339
+)
+
+// Field 白名单中的一个可查询字段
+type Field struct {
+	Column   string     // 数据库列名（只来自白名单，绝不来自用户输入）
+	Type     FieldType  // 值类型
+	Ops      []Operator // 允许的过滤操作符，为空表示不可过滤
+	Sortable bool       // 是否允许排序
+}
+
+// Schema 某个模型的查询白名单
+//
+// 对外暴露的字段名（如 total_price）与数据库列名解耦，
+// 请求中出现白名单以外的字段或操作符时直接返回错误，而不是静默忽略
+type Schema struct {
+	Fields      map[string]Field
+	DefaultSort string // 默认排序，格式同 sort 参数，如 "-created_at"
+	DefaultSize int    // 默认每页条数
+	MaxSize     int    // 每页最大条数，防止一次拉取过多数据
+}
+
+// Filter 解析后的过滤条件
+type Filter struct {
+	Field  string      // 对外字段名
+	Column string      // 数据库列名
+	Op     Operator    // 操作符
+	Value  interface{} // 已按 FieldType 转换后的值；OpIn 时为 []interface{}
+}
+
+// Sort 解析后的排序条件
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Spec 类型化的列表查询规格
+type Spec struct {
+	Filters []Filter
+	Sorts   []Sort
+	Page    int
+	Size    int
+}
+
+// Offset 当前页的偏移量，页码限制在 [1, maxPage] 内
+func (s *Spec) Offset() int {
+	page := min(max(s.Page, 1), maxPage)
+	return (page - 1) * s.Size
+}
+
+// QueryError 查询参数错误
+// Param 为出错的查询参数名，便于前端定位
+type QueryError struct {
+	Param   string
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("查询参数 %s 无效: %s", e.Param, e.Message)
+}
+
+// filterKeyRegexp 匹配 filter[field] 和 filter[field][op]
+var filterKeyRegexp = regexp.MustCompile(`^filter\[([A-Za-z0-9_]+)\](?:\[([a-z]+)\])?$`)
+
+// 分页默认值
+const (
+	defaultPageSize = 20
+	defaultMaxSize  = 100
+	// maxPage 页码上限：深分页的 OFFSET 本身很慢，过大的页码还会让 Offset 的乘法溢出成负数
+	maxPage = 100000
+)
+
+// Parse 将查询字符串解析为 Spec
+//
+// 支持的格式:
+//
+//	filter[status]=active               → status = 'active'
+//	filter[total_price][gte]=100        → total_price >= 100
+//	filter[status][in]=pending,paid     → status IN ('pending', 'paid')
+//	sort=-created_at,order_no           → ORDER BY created_at DESC, order_no ASC
+//	page=2&size=20                      → LIMIT 20 OFFSET 20
+func (s *Schema) Parse(values url.Values) (*Spec, error) {
+	spec := &Spec{}
+
+	// ========== 1. 过滤条件 ==========
+	// 按参数名排序遍历，保证生成的 SQL 条件顺序稳定
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		m := filterKeyRegexp.FindStringSubmatch(key)
+		if m == nil {
+			if strings.HasPrefix(key, "filter") {
+				return nil, &QueryError{Param: key, Message: "格式应为 filter[字段] 或 filter[字段][操作符]"}
+			}
+			continue
+		}
+
+		name, op := m[1], Operator(m[2])
+		if op == "" {
+			op = OpEq
+		}
+
+		field, ok := s.Fields[name]
+		if !ok || !field.allows(op) {
+			return nil, &QueryError{Param: key, Message: fmt.Sprintf("字段 %s 不支持操作符 %s", name, op)}
+		}
+
+		for _, raw := range values[key] {
+			value, err := field.convert(op, raw)
+			if err != nil {
+				return nil, &QueryError{Param: key, Message: err.Error()}
+			}
+			spec.Filters = append(spec.Filters, Filter{Field: name, Column: field.Column, Op: op, Value: value})
+		}
+	}
+
+	// ========== 2. 排序 ==========
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = s.DefaultSort
+	}
+	sorts, err := s.parseSort(sortParam)
+	if err != nil {
+		return nil, err
+	}
+	spec.Sorts = sorts
+
+	// ========== 3. 分页 ==========
+	spec.Page, err = positiveInt(values, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Page > maxPage {
+		return nil, &QueryError{Param: "page", Message: fmt.Sprintf("页码不能超过 %d", maxPage)}
+	}
+
+	defaultSize := s.DefaultSize
+	if defaultSize <= 0 {
+		defaultSize = defaultPageSize
+	}
+	spec.Size, err = positiveInt(values, "size", defaultSize)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := s.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if spec.Size > maxSize {
+		return nil, &QueryError{Param: "size", Message: fmt.Sprintf("每页最多 %d 条", maxSize)}
+	}
+
+	return spec, nil
+}
+
+// parseSort 解析 sort 参数，字段前加 - 表示降序
+func (s *Schema) parseSort(param string) ([]Sort, error) {
+	if param == "" {
+		return nil, nil
+	}
+
+	var sorts []Sort
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+
+		field, ok := s.Fields[name]
+		if !ok || !field.Sortable {
+			return nil, &QueryError{Param: "sort", Message: fmt.Sprintf("字段 %s 不支持排序", name)}
+		}
+		sorts = append(sorts, Sort{Field: name, Column: field.Column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// allows 判断字段是否允许该操作符
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// convert 将查询字符串转换为字段类型对应的值
+func (f Field) convert(op Operator, raw string) (interface{}, error) {
+	if op == OpIn {
+		parts := strings.Split(raw, ",")
+		values := make([]interface{}, 0, len(parts))
+		for _, p := range parts {
+			v, err := f.convertOne(strings.TrimSpace(p))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return f.convertOne(raw)
+}
+
+func (f Field) convertOne(raw string) (interface{}, error) {
+	switch f.Type {
+	case TypeInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是整数", raw)
+		}
+		return v, nil
+	case TypeFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是数字", raw)
+		}
+		return v, nil
+	case TypeBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是布尔值", raw)
+		}
+		return v, nil
+	case TypeTime:
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是有效的日期", raw)
+		}
+		return t, nil
+	default:
+		return raw, nil
+	}
+}
+
+// positiveInt 读取正整数查询参数，缺省时返回默认值
+func positiveInt(values url.Values, key string, def int) (int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		return 0, &QueryError{Param: key, Message: "必须是正整数"}
+	}
+	return v, nil
+}
@@ -0,0 +1,216 @@
+package querydsl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+
+	gormexamples "go-learning/gorm"
+)
+
+// ========== GORM Scopes ==========
+// Scope 是 func(*gorm.DB) *gorm.DB 形式的函数，通过 db.Scopes(...) 组合使用
+//
+// 为什么不会 SQL 注入:
+//   - 列名只来自 Schema 白名单，并通过 clause.Column 交给 GORM 加引号
+//   - 值全部通过 clause 表达式作为占位符参数传递，不拼接进 SQL 字符串
+
+// FilterScope 将过滤条件转换为 WHERE 子句
+func (s *Spec) FilterScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, f := range s.Filters {
+			db = db.Where(f.expression())
+		}
+		return db
+	}
+}
+
+// SortScope 将排序条件转换为 ORDER BY 子句
+func (s *Spec) SortScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, sort := range s.Sorts {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+		}
+		return db
+	}
+}
+
+// PaginateScope 将分页参数转换为 LIMIT / OFFSET
+func (s *Spec) PaginateScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Limit(s.Size).Offset(s.Offset())
+	}
+}
+
+// Scopes 返回全部 Scope（过滤 + 排序 + 分页）
+func (s *Spec) Scopes() []func(*gorm.DB) *gorm.DB {
+	return []func(*gorm.DB) *gorm.DB{s.FilterScope(), s.SortScope(), s.PaginateScope()}
+}
+
+// Find 按 Spec 查询一页数据，同时返回满足过滤条件的总数
+// 与 fuyelead 项目的分页模式一致：先 Count 再 Find
+func Find[T any](db *gorm.DB, spec *Spec, dest *[]T) (int64, error) {
+	var total int64
+	if err := db.Model(new(T)).Scopes(spec.FilterScope()).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Scopes(spec.Scopes()...).Find(dest).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// expression 将单个过滤条件转换为 GORM 的 clause 表达式
+func (f Filter) expression() clause.Expression {
+	column := clause.Column{Name: f.Column}
+	switch f.Op {
+	case OpNe:
+		return clause.Neq{Column: column, Value: f.Value}
+	case OpGt:
+		return clause.Gt{Column: column, Value: f.Value}
+	case OpGte:
+		return clause.Gte{Column: column, Value: f.Value}
+	case OpLt:
+		return clause.Lt{Column: column, Value: f.Value}
+	case OpLte:
+		return clause.Lte{Column: column, Value: f.Value}
+	case OpLike:
+		// 转义用户输入中的 % 和 _，避免被当作通配符
+		// 转义字符用 !：反斜杠在 MySQL 字符串字面量中本身就是转义符，'\' 会变成未结束的字符串
+		pattern := "%" + likeEscaper.Replace(fmt.Sprint(f.Value)) + "%"
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, pattern}}
+	case OpIn:
+		values, _ := f.Value.([]interface{})
+		return clause.IN{Column: column, Values: values}
+	default:
+		return clause.Eq{Column: column, Value: f.Value}
+	}
+}
+
+// likeEscaper LIKE 通配符转义，与 ESCAPE '!' 对应
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// OrderSchema 订单列表的查询白名单（对应 gorm.OrderWithRelations）
+var OrderSchema = &Schema{
+	Fields: map[string]Field{
+		"order_no":    {Column: "order_no", Type: TypeString, Ops: []Operator{OpEq, OpLike}, Sortable: true},
+		"status":      {Column: "status", Type: TypeString, Ops: []Operator{OpEq, OpNe, OpIn}},
+		"user_id":     {Column: "user_id", Type: TypeInt, Ops: []Operator{OpEq, OpIn}},
+		"total_price": {Column: "total_price", Type: TypeFloat, Ops: []Operator{OpEq, OpGt, OpGte, OpLt, OpLte}, Sortable: true},
+		"created_at":  {Column: "created_at", Type: TypeTime, Ops: []Operator{OpGte, OpLt}, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+	DefaultSize: 20,
+	MaxSize:     100,
+}
+
+// QueryDSLDemo 演示安全的列表查询 DSL（过滤、排序、分页 → GORM Scopes）
+func QueryDSLDemo() {
+	fmt.Println("=== 列表查询 DSL 示例（过滤 / 排序 / 分页）===")
+	fmt.Println()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		fmt.Printf("连接数据库失败: %v\n", err)
+		return
+	}
+	if err := db.AutoMigrate(&gormexamples.OrderWithRelations{}); err != nil {
+		fmt.Printf("迁移失败: %v\n", err)
+		return
+	}
+
+	statuses := []string{"pending", "paid", "shipped"}
+	for i := 1; i <= 9; i++ {
+		db.Create(&gormexamples.OrderWithRelations{
+			OrderNo:    fmt.Sprintf("ORD%03d", i),
+			UserID:     1,
+			OfferingID: 1,
+			TotalPrice: float64(i * 50),
+			Status:     statuses[i%len(statuses)],
+		})
+	}
+	fmt.Println("✓ 测试数据创建完成（9个订单）")
+	fmt.Println()
+
+	// ========== 在 Gin 中使用 ==========
+	router := gin.Default()
+	router.GET("/orders", func(c *gin.Context) {
+		spec, err := OrderSchema.Parse(c.Request.URL.Query())
+		if err != nil {
+			var qe *QueryError
+			if errors.As(err, &qe) {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": qe.Error(), "param": qe.Param})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": err.Error()})
+			return
+		}
+
+		var orders []gormexamples.OrderWithRelations
+		total, err := Find(db.WithContext(c.Request.Context()), spec, &orders)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 2001, "message": "数据库错误"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"data": gin.H{"list": orders, "total": total, "page": spec.Page, "size": spec.Size},
+		})
+	})
+
+	// ========== 直接解析示例 URL ==========
+	rawQuery := "filter[status][in]=pending,paid&filter[total_price][gte]=100&sort=-total_price&page=1&size=3"
+	fmt.Printf("请求: GET /orders?%s\n", rawQuery)
+	values, _ := url.ParseQuery(rawQuery)
+	spec, err := OrderSchema.Parse(values)
+	if err != nil {
+		fmt.Printf("解析失败: %v\n", err)
+		return
+	}
+
+	var orders []gormexamples.OrderWithRelations
+	total, err := Find(db, spec, &orders)
+	if err != nil {
+		fmt.Printf("查询失败: %v\n", err)
+		return
+	}
+	fmt.Printf("符合条件: %d 条，第 %d 页:\n", total, spec.Page)
+	for _, o := range orders {
+		fmt.Printf("  - %s  %-8s %.2f\n", o.OrderNo, o.Status, o.TotalPrice)
+	}
+	fmt.Println()
+
+	// ========== 非法输入示例 ==========
+	fmt.Println("非法输入会被拒绝（而不是拼进 SQL）:")
+	for _, bad := range []string{
+		"filter[password_hash]=x",           // 白名单外的字段
+		"filter[status][gt]=a",              // 字段不允许的操作符
+		"filter[total_price][gte]=1 OR 1=1", // 类型不匹配
+		"sort=id;DROP TABLE t_order",        // 排序字段不在白名单
+		"size=1000",                         // 超过最大分页
+	} {
+		values, _ := url.ParseQuery(bad)
+		_, err := OrderSchema.Parse(values)
+		fmt.Printf("  %-36s → %v\n", bad, err)
+	}
+	fmt.Println()
+
+	fmt.Println("支持的语法:")
+	fmt.Println("  filter[字段]=值              - 等于")
+	fmt.Println("  filter[字段][操作符]=值      - eq ne gt gte lt lte like in")
+	fmt.Println("  sort=-created_at,order_no    - 逗号分隔，- 表示降序")
+	fmt.Println("  page=2&size=20               - 分页（size 受 MaxSize 限制）")
+	fmt.Println()
+	fmt.Println("测试示例:")
+	fmt.Println("  curl \"http://localhost:8080/orders?filter[status]=paid&filter[total_price][gte]=100&sort=-created_at&page=1&size=20\"")
+}
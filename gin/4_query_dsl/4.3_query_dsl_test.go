@@ -0,0 +1,143 @@
+package querydsl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	gormexamples "go-learning/gorm"
+)
+
+// TestParse 测试查询字符串解析和白名单校验
+func TestParse(t *testing.T) {
+	t.Run("完整查询", func(t *testing.T) {
+		values, _ := url.ParseQuery("filter[status]=active&filter[total_price][gte]=100&sort=-created_at&page=2&size=20")
+		spec, err := OrderSchema.Parse(values)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []Filter{
+			{Field: "status", Column: "status", Op: OpEq, Value: "active"},
+			{Field: "total_price", Column: "total_price", Op: OpGte, Value: float64(100)},
+		}, spec.Filters)
+		assert.Equal(t, []Sort{{Field: "created_at", Column: "created_at", Desc: true}}, spec.Sorts)
+		assert.Equal(t, 2, spec.Page)
+		assert.Equal(t, 20, spec.Size)
+		assert.Equal(t, 20, spec.Offset())
+	})
+
+	t.Run("Offset 限制页码", func(t *testing.T) {
+		spec := &Spec{Page: math.MaxInt, Size: 100}
+		assert.Equal(t, (maxPage-1)*100, spec.Offset(), "手动构造的 Spec 也不会溢出成负数")
+		spec.Page = 0
+		assert.Equal(t, 0, spec.Offset())
+	})
+
+	t.Run("默认值", func(t *testing.T) {
+		spec, err := OrderSchema.Parse(url.Values{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, spec.Page)
+		assert.Equal(t, 20, spec.Size)
+		assert.Equal(t, []Sort{{Field: "created_at", Column: "created_at", Desc: true}}, spec.Sorts)
+	})
+
+	errorCases := []struct {
+		query string
+		param string
+	}{
+		{"filter[password_hash]=x", "filter[password_hash]"},
+		{"filter[status][gt]=a", "filter[status][gt]"},
+		{"filter[total_price][gte]=abc", "filter[total_price][gte]"},
+		{"filter[status]]=x", "filter[status]]"},
+		{"sort=id", "sort"},
+		{"page=0", "page"},
+		{"page=100001", "page"},
+		{"size=101", "size"},
+	}
+	for _, tc := range errorCases {
+		t.Run("拒绝 "+tc.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tc.query)
+			_, err := OrderSchema.Parse(values)
+
+			var qe *QueryError
+			assert.True(t, errors.As(err, &qe))
+			assert.Equal(t, tc.param, qe.Param)
+		})
+	}
+}
+
+// TestFind 测试 Spec 转换为 GORM Scopes 后的查询结果
+func TestFind(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库，测试中只用一个连接
+	assert.NoError(t, db.AutoMigrate(&gormexamples.OrderWithRelations{}))
+
+	statuses := []string{"pending", "paid", "shipped"}
+	for i := 1; i <= 9; i++ {
+		db.Create(&gormexamples.OrderWithRelations{
+			OrderNo:    fmt.Sprintf("ORD%03d", i),
+			UserID:     1,
+			OfferingID: 1,
+			TotalPrice: float64(i * 50),
+			Status:     statuses[i%len(statuses)],
+		})
+	}
+	db.Create(&gormexamples.OrderWithRelations{OrderNo: "ORD_100%", UserID: 1, OfferingID: 1, TotalPrice: 1, Status: "paid"})
+	db.Create(&gormexamples.OrderWithRelations{OrderNo: "ORD!_1", UserID: 1, OfferingID: 1, TotalPrice: 1, Status: "paid"})
+
+	find := func(query string) ([]string, int64) {
+		values, _ := url.ParseQuery(query)
+		spec, err := OrderSchema.Parse(values)
+		assert.NoError(t, err)
+
+		var orders []gormexamples.OrderWithRelations
+		total, err := Find(db, spec, &orders)
+		assert.NoError(t, err)
+
+		orderNos := make([]string, 0, len(orders))
+		for _, o := range orders {
+			orderNos = append(orderNos, o.OrderNo)
+		}
+		return orderNos, total
+	}
+
+	t.Run("过滤排序分页", func(t *testing.T) {
+		orderNos, total := find("filter[status][in]=pending,paid&filter[total_price][gte]=100&sort=-total_price&size=2&page=2")
+		// 满足条件: 3(pending,150) 4(paid,200) 6(pending,300) 7(paid,350) 9(pending,450)
+		assert.Equal(t, int64(5), total)
+		assert.Equal(t, []string{"ORD006", "ORD004"}, orderNos)
+	})
+
+	t.Run("like 转义通配符", func(t *testing.T) {
+		orderNos, total := find("filter[order_no][like]=100%25")
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, []string{"ORD_100%"}, orderNos)
+	})
+
+	t.Run("like 使用可移植的转义字符", func(t *testing.T) {
+		orderNos, _ := find("filter[order_no][like]=D!_")
+		assert.Equal(t, []string{"ORD!_1"}, orderNos, "! 本身也被转义")
+
+		values, _ := url.ParseQuery("filter[order_no][like]=a_b")
+		spec, err := OrderSchema.Parse(values)
+		assert.NoError(t, err)
+		stmt := db.Session(&gorm.Session{DryRun: true}).Model(&gormexamples.OrderWithRelations{}).
+			Scopes(spec.FilterScope()).Find(&[]gormexamples.OrderWithRelations{}).Statement
+		assert.Contains(t, stmt.SQL.String(), "LIKE ? ESCAPE '!'", "不使用 MySQL 中会转义引号的反斜杠")
+		assert.Equal(t, "%a!_b%", stmt.Vars[0])
+	})
+
+	t.Run("注入字符串只作为值", func(t *testing.T) {
+		orderNos, total := find("filter[order_no]=' OR '1'='1")
+		assert.Equal(t, int64(0), total)
+		assert.Empty(t, orderNos)
+	})
+}
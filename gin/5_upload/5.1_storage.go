@@ -0,0 +1,188 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound 存储中不存在指定的对象
+var ErrNotFound = errors.New("对象不存在")
+
+// ErrInvalidKey 对象 key 非法（如包含 ../ 试图越出存储根目录）
+var ErrInvalidKey = errors.New("非法的对象 key")
+
+// Storage 文件存储接口
+//
+// 上传逻辑只依赖这个接口，不关心文件最终写到哪里:
+//   - LocalStorage: 本地文件系统（开发环境、单机部署）
+//   - MemoryStorage: 内存（单元测试）
+//   - 生产环境可以实现 OSS / S3 / MinIO 版本，上传代码无需修改
+//
+// key 使用 / 分隔的相对路径，如 "files/2024/01/01/xxx.png"
+type Storage interface {
+	// Put 写入对象，返回写入的字节数；同名对象会被覆盖
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Get 读取对象，对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// ========== 本地文件系统实现 ==========
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	Root string // 存储根目录
+}
+
+// NewLocalStorage 创建本地存储，根目录不存在时自动创建
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return &LocalStorage{Root: root}, nil
+}
+
+// Put 先写入临时文件再重命名，保证读取方不会读到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后这里删除会失败，忽略即可
+
+	n, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Get 打开本地文件
+func (s *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除本地文件
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path 将 key 转换为本地路径，并确保结果仍在 Root 目录内
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	clean := filepath.Clean("/" + key) // 以 / 开头再 Clean，../ 无法越过根
+	if clean == "/" {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+// ========== 内存实现 ==========
+
+// MemoryStorage 内存存储，适合单元测试
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// NewMemoryStorage 创建内存存储
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string][]byte)}
+}
+
+// Put 读取全部内容后保存
+func (s *MemoryStorage) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.objects[key] = data
+	s.mu.Unlock()
+	return int64(len(data)), nil
+}
+
+// Get 返回对象内容的只读副本
+func (s *MemoryStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	data, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete 删除对象
+func (s *MemoryStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.objects, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Keys 返回当前所有对象的 key（测试中用于断言）
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// contextReader 在每次 Read 前检查 ctx，客户端断开后尽快停止写入
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,243 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Config 上传配置
+type Config struct {
+	MaxFileSize  int64    // 单个文件最大字节数
+	AllowedTypes []string // 允许的 MIME 类型（根据文件内容嗅探，而不是扩展名或 Content-Type 头）
+	FormField    string   // multipart 表单中的文件字段名
+	KeyPrefix    string   // 最终文件的 key 前缀
+	ChunkSize    int64    // 分片上传时每片的字节数（最后一片可以更小）
+
+	SessionTTL  time.Duration // 分片上传会话的有效期，从最后一次收到分片算起，过期的会话和分片会被清理
+	MaxSessions int           // 同时存在的分片上传会话上限，防止只创建不上传的请求耗尽内存
+}
+
+// DefaultConfig 默认上传配置
+func DefaultConfig() Config {
+	return Config{
+		MaxFileSize:  10 << 20, // 10MB
+		AllowedTypes: []string{"image/png", "image/jpeg", "image/gif", "application/pdf", "text/plain"},
+		FormField:    "file",
+		KeyPrefix:    "files",
+		ChunkSize:    1 << 20, // 1MB
+		SessionTTL:   24 * time.Hour,
+		MaxSessions:  1000,
+	}
+}
+
+// FileInfo 上传完成后的文件信息
+type FileInfo struct {
+	Key      string `json:"key"`      // 存储中的 key
+	Filename string `json:"filename"` // 客户端提交的原始文件名（仅用于展示）
+	Size     int64  `json:"size"`     // 字节数
+	MIME     string `json:"mime"`     // 嗅探得到的 MIME 类型
+	SHA256   string `json:"sha256"`   // 内容摘要（十六进制）
+}
+
+// Uploader 上传服务
+type Uploader struct {
+	storage  Storage
+	config   Config
+	sessions *sessionStore
+	now      func() time.Time
+}
+
+// NewUploader 创建上传服务，config 中为零值的大小、数量和有效期使用 DefaultConfig 的值
+func NewUploader(storage Storage, config Config) *Uploader {
+	defaults := DefaultConfig()
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaults.MaxFileSize
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaults.ChunkSize
+	}
+	if config.FormField == "" {
+		config.FormField = defaults.FormField
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = defaults.SessionTTL
+	}
+	if config.MaxSessions <= 0 {
+		config.MaxSessions = defaults.MaxSessions
+	}
+	return &Uploader{
+		storage:  storage,
+		config:   config,
+		sessions: newSessionStore(),
+		now:      time.Now,
+	}
+}
+
+// 错误码（与统一响应格式的业务错误码保持一致）
+const (
+	codeParamError = 1001 // 参数校验失败
+	codeNotFound   = 1004 // 资源不存在
+	codeStorage    = 2004 // 内部服务器错误
+	codeState      = 3002 // 当前状态不允许该操作
+	codeBusy       = 2006 // 服务繁忙
+)
+
+// 上传过程中的错误
+var (
+	errTooLarge    = errors.New("文件超过大小限制")
+	errTypeDenied  = errors.New("不允许的文件类型")
+	errChecksum    = errors.New("校验和不匹配")
+	errNoFileField = errors.New("未找到文件字段")
+)
+
+// Register 注册上传相关路由
+//
+//	POST /upload                         - 普通 multipart 上传
+//	POST /uploads                        - 创建分片上传会话
+//	GET  /uploads/:id                    - 查询已上传分片（断点续传）
+//	PUT  /uploads/:id/chunks/:index      - 上传单个分片
+//	POST /uploads/:id/complete           - 合并分片
+func (u *Uploader) Register(r gin.IRoutes) {
+	r.POST("/upload", u.HandleUpload)
+	r.POST("/uploads", u.HandleCreateSession)
+	r.GET("/uploads/:id", u.HandleSessionStatus)
+	r.PUT("/uploads/:id/chunks/:index", u.HandleChunk)
+	r.POST("/uploads/:id/complete", u.HandleComplete)
+}
+
+// HandleUpload 普通 multipart 上传
+//
+// 使用 MultipartReader 流式读取，而不是 c.FormFile:
+//   - c.FormFile 会先把整个文件解析到内存或临时文件，超大文件在校验前就已占用资源
+//   - 流式读取可以在超过大小限制的瞬间中止，并在写入前完成 MIME 嗅探
+func (u *Uploader) HandleUpload(c *gin.Context) {
+	// 请求体上限 = 文件上限 + 1MB（multipart 边界和其他字段的开销）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, u.config.MaxFileSize+1<<20)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "请使用 multipart/form-data 上传"})
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			u.fail(c, err)
+			return
+		}
+		if part.FormName() != u.config.FormField {
+			part.Close()
+			continue
+		}
+
+		info, err := u.save(c, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			u.fail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"code": 0, "data": info, "message": "上传成功"})
+		return
+	}
+
+	u.fail(c, errNoFileField)
+}
+
+// save 嗅探类型、限制大小并写入存储
+func (u *Uploader) save(c *gin.Context, filename string, r io.Reader) (*FileInfo, error) {
+	// ========== 1. MIME 嗅探 ==========
+	// 只读取文件头部（mimetype 默认检测前 3072 字节），再用 MultiReader 拼回完整内容
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	mt, err := u.checkType(head)
+	if err != nil {
+		return nil, err
+	}
+
+	// ========== 2. 大小限制 + 摘要 ==========
+	// 多读 1 个字节用来判断是否超限
+	hash := sha256.New()
+	limited := &io.LimitedReader{R: io.MultiReader(bytes.NewReader(head), r), N: u.config.MaxFileSize + 1}
+	key := u.newKey(mt)
+
+	size, err := u.storage.Put(c.Request.Context(), key, io.TeeReader(limited, hash))
+	if err != nil {
+		return nil, err
+	}
+	if size > u.config.MaxFileSize {
+		u.storage.Delete(c.Request.Context(), key)
+		return nil, errTooLarge
+	}
+
+	return &FileInfo{
+		Key:      key,
+		Filename: path.Base(filename),
+		Size:     size,
+		MIME:     mt.String(),
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// checkType 根据文件头嗅探 MIME，并与白名单比对
+// 会沿着父类型向上检查，例如允许 text/plain 时，text/csv 同样可以通过
+func (u *Uploader) checkType(head []byte) (*mimetype.MIME, error) {
+	detected := mimetype.Detect(head)
+	for mt := detected; mt != nil; mt = mt.Parent() {
+		for _, allowed := range u.config.AllowedTypes {
+			if mt.Is(allowed) {
+				return detected, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errTypeDenied, detected.String())
+}
+
+// newKey 生成最终文件的 key: 前缀/年/月/日/uuid.扩展名
+// 扩展名取自嗅探结果，不使用客户端提交的文件名，避免路径穿越和伪造扩展名
+func (u *Uploader) newKey(mt *mimetype.MIME) string {
+	return path.Join(u.config.KeyPrefix, u.now().Format("2006/01/02"), uuid.NewString()+mt.Extension())
+}
+
+// fail 将错误转换为统一的错误响应
+func (u *Uploader) fail(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTooLarge), errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"code":    codeParamError,
+			"message": fmt.Sprintf("文件不能超过 %d 字节", u.config.MaxFileSize),
+		})
+	case errors.Is(err, errTypeDenied):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"code": codeParamError, "message": err.Error()})
+	case errors.Is(err, errChecksum), errors.Is(err, errChunkSize), errors.Is(err, errNoFileField):
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": err.Error()})
+	case errors.Is(err, errSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": codeNotFound, "message": err.Error()})
+	case errors.Is(err, errIncomplete), errors.Is(err, errSessionBusy), errors.Is(err, errChunkInFlight):
+		c.JSON(http.StatusConflict, gin.H{"code": codeState, "message": err.Error()})
+	case errors.Is(err, errTooManySessions):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"code": codeBusy, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": codeStorage, "message": "文件存储失败"})
+	}
+}
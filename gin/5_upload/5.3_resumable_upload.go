@@ -0,0 +1,531 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 分片上传的错误
+var (
+	errSessionNotFound = errors.New("上传会话不存在或已过期")
+	errSessionBusy     = errors.New("上传会话正在合并")
+	errChunkInFlight   = errors.New("仍有分片正在上传，请稍后再合并")
+	errIncomplete      = errors.New("分片未全部上传")
+	errChunkSize       = errors.New("分片大小不正确")
+	errTooManySessions = errors.New("上传会话过多，请稍后重试")
+)
+
+// ========== 分片上传会话 ==========
+// 流程:
+//  1. POST /uploads                    声明文件名、总大小、整体 sha256，得到 uploadId 和分片数
+//  2. PUT  /uploads/:id/chunks/:index  逐片上传原始字节，请求头 X-Chunk-SHA256 携带该片摘要
+//  3. GET  /uploads/:id                网络中断后查询已收到的分片，只补传缺失部分
+//  4. POST /uploads/:id/complete       服务端按顺序合并分片，校验总大小和整体 sha256
+
+// session 一次分片上传
+type session struct {
+	ID         string
+	Filename   string
+	Size       int64
+	SHA256     string
+	ChunkSize  int64
+	Chunks     int
+	MIME       *mimetype.MIME // 第 0 片到达时嗅探
+	CreatedAt  time.Time
+	ActiveAt   time.Time      // 创建或最后一次收到分片的时间，超过 SessionTTL 视为放弃
+	received   map[int]string // 分片序号 → 分片 sha256
+	writing    int            // 正在写入存储的分片请求数，合并和清理需要等它们结束
+	completing bool
+}
+
+// chunkLength 第 index 片应有的字节数（最后一片可能不足 ChunkSize）
+func (s *session) chunkLength(index int) int64 {
+	if index == s.Chunks-1 {
+		return s.Size - int64(index)*s.ChunkSize
+	}
+	return s.ChunkSize
+}
+
+// chunkKey 分片在存储中的 key
+func (s *session) chunkKey(index int) string {
+	return path.Join("chunks", s.ID, strconv.Itoa(index))
+}
+
+// receivedList 已收到的分片序号（升序）
+func (s *session) receivedList() []int {
+	list := make([]int, 0, len(s.received))
+	for index := range s.received {
+		list = append(list, index)
+	}
+	sort.Ints(list)
+	return list
+}
+
+// sessionStore 内存中的会话表
+// 多实例部署时需要换成 Redis 等共享存储，分片本身已经在 Storage 中
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session)}
+}
+
+// session 查找未过期的会话，调用方需持有 sessions.mu
+// 过期的会话在下次创建会话时清理，这里只是不再返回
+func (u *Uploader) session(id string) (*session, bool) {
+	s, ok := u.sessions.sessions[id]
+	if !ok || u.expired(s) {
+		return nil, false
+	}
+	return s, true
+}
+
+// expired 会话超过 SessionTTL 没有活动；正在合并或写入分片的会话不过期
+func (u *Uploader) expired(s *session) bool {
+	return !s.completing && s.writing == 0 && u.now().Sub(s.ActiveAt) > u.config.SessionTTL
+}
+
+// CreateSessionRequest 创建分片上传会话的请求
+type CreateSessionRequest struct {
+	Filename string `json:"filename" binding:"required,max=255"`
+	Size     int64  `json:"size" binding:"required,gt=0"`
+	SHA256   string `json:"sha256" binding:"required,len=64,hexadecimal"`
+}
+
+// SessionStatus 会话状态（用于断点续传）
+type SessionStatus struct {
+	UploadID  string `json:"uploadId"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunkSize"`
+	Chunks    int    `json:"chunks"`
+	Received  []int  `json:"received"`
+}
+
+// HandleCreateSession 创建分片上传会话
+func (u *Uploader) HandleCreateSession(c *gin.Context) {
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": err.Error()})
+		return
+	}
+	if req.Size > u.config.MaxFileSize {
+		u.fail(c, errTooLarge)
+		return
+	}
+
+	s := &session{
+		ID:        uuid.NewString(),
+		Filename:  path.Base(req.Filename),
+		Size:      req.Size,
+		SHA256:    strings.ToLower(req.SHA256),
+		ChunkSize: u.config.ChunkSize,
+		Chunks:    int((req.Size + u.config.ChunkSize - 1) / u.config.ChunkSize),
+		CreatedAt: u.now(),
+		ActiveAt:  u.now(),
+		received:  make(map[int]string),
+	}
+
+	u.sweep(context.WithoutCancel(c.Request.Context()), u.expired)
+	u.sessions.mu.Lock()
+	full := len(u.sessions.sessions) >= u.config.MaxSessions
+	if !full {
+		u.sessions.sessions[s.ID] = s
+	}
+	u.sessions.mu.Unlock()
+	if full {
+		u.fail(c, errTooManySessions)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "data": u.status(s), "message": "会话已创建"})
+}
+
+// HandleSessionStatus 查询会话状态，客户端据此跳过已上传的分片
+func (u *Uploader) HandleSessionStatus(c *gin.Context) {
+	u.sessions.mu.Lock()
+	s, ok := u.session(c.Param("id"))
+	var status SessionStatus
+	if ok {
+		status = u.status(s)
+	}
+	u.sessions.mu.Unlock()
+
+	if !ok {
+		u.fail(c, errSessionNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "data": status})
+}
+
+// HandleChunk 上传单个分片
+//
+// 请求体为分片的原始字节（application/octet-stream），请求头:
+//
+//	X-Chunk-SHA256: 该分片的 sha256（十六进制），用于发现传输损坏
+//
+// 同一个分片可以重复上传（覆盖），因此客户端超时重试是安全的
+func (u *Uploader) HandleChunk(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "分片序号无效"})
+		return
+	}
+	checksum := strings.ToLower(c.GetHeader("X-Chunk-SHA256"))
+	if len(checksum) != sha256.Size*2 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "缺少 X-Chunk-SHA256 请求头"})
+		return
+	}
+
+	// 读取会话快照并登记写入，IO 过程中不持有锁；
+	// 登记后合并会返回 409、清理会跳过该会话，直到写入结束
+	u.sessions.mu.Lock()
+	s, ok := u.session(c.Param("id"))
+	var busy bool
+	var expected int64
+	var chunkKey string
+	if ok {
+		busy = s.completing
+		if index < s.Chunks {
+			expected = s.chunkLength(index)
+			chunkKey = s.chunkKey(index)
+		}
+		if !busy && chunkKey != "" {
+			s.writing++
+		}
+	}
+	u.sessions.mu.Unlock()
+
+	switch {
+	case !ok:
+		u.fail(c, errSessionNotFound)
+		return
+	case busy:
+		u.fail(c, errSessionBusy)
+		return
+	case chunkKey == "":
+		c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "分片序号超出范围"})
+		return
+	}
+	defer func() {
+		u.sessions.mu.Lock()
+		s.writing--
+		u.sessions.mu.Unlock()
+	}()
+
+	// 分片大小固定且不大，直接读入内存校验后再写入存储
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, expected)
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			u.fail(c, fmt.Errorf("%w: 应为 %d 字节", errChunkSize, expected))
+			return
+		}
+		u.fail(c, err)
+		return
+	}
+	if int64(len(data)) != expected {
+		u.fail(c, fmt.Errorf("%w: 应为 %d 字节，实际 %d 字节", errChunkSize, expected, len(data)))
+		return
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != checksum {
+		u.fail(c, fmt.Errorf("%w: 分片 %d", errChecksum, index))
+		return
+	}
+
+	// 第 0 片包含文件头，在这里嗅探类型，尽早拒绝不允许的文件
+	var mt *mimetype.MIME
+	if index == 0 {
+		if mt, err = u.checkType(data); err != nil {
+			u.fail(c, err)
+			return
+		}
+	}
+
+	if _, err := u.storage.Put(c.Request.Context(), chunkKey, bytes.NewReader(data)); err != nil {
+		u.fail(c, err)
+		return
+	}
+
+	// 写入期间会话可能已被删除（Cleanup 按创建时间清理），或已开始合并：
+	// 刚写入的分片不属于任何会话，删除后返回错误
+	u.sessions.mu.Lock()
+	current, ok := u.sessions.sessions[s.ID]
+	err = nil
+	switch {
+	case !ok || current != s:
+		err = errSessionNotFound
+	case s.completing:
+		err = errSessionBusy
+	default:
+		s.received[index] = checksum
+		s.ActiveAt = u.now()
+		if mt != nil {
+			s.MIME = mt
+		}
+	}
+	u.sessions.mu.Unlock()
+	if err != nil {
+		u.storage.Delete(context.WithoutCancel(c.Request.Context()), chunkKey)
+		u.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"index": index, "size": len(data)}, "message": "分片已接收"})
+}
+
+// HandleComplete 按顺序合并分片，校验总大小和整体 sha256 后写入最终 key
+func (u *Uploader) HandleComplete(c *gin.Context) {
+	u.sessions.mu.Lock()
+	s, ok := u.session(c.Param("id"))
+	var err error
+	switch {
+	case !ok:
+		err = errSessionNotFound
+	case s.completing:
+		err = errSessionBusy
+	case s.writing > 0:
+		err = errChunkInFlight // 合并时分片不能被覆盖
+	case len(s.received) != s.Chunks:
+		err = fmt.Errorf("%w: 已收到 %d/%d", errIncomplete, len(s.received), s.Chunks)
+	default:
+		s.completing = true // 合并期间拒绝新的分片写入
+	}
+	u.sessions.mu.Unlock()
+	if err != nil {
+		u.fail(c, err)
+		return
+	}
+
+	info, err := u.assemble(c.Request.Context(), s)
+	if err != nil {
+		u.sessions.mu.Lock()
+		s.completing = false
+		u.sessions.mu.Unlock()
+		u.fail(c, err)
+		return
+	}
+
+	u.discard(context.WithoutCancel(c.Request.Context()), s)
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "data": info, "message": "上传成功"})
+}
+
+// assemble 将分片依次拼接写入存储，边写边计算摘要
+func (u *Uploader) assemble(ctx context.Context, s *session) (*FileInfo, error) {
+	key := u.newKey(s.MIME)
+	hash := sha256.New()
+	chunks := &chunkReader{ctx: ctx, storage: u.storage, session: s}
+	defer chunks.Close()
+
+	size, err := u.storage.Put(ctx, key, io.TeeReader(chunks, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if size != s.Size || sum != s.SHA256 {
+		u.storage.Delete(ctx, key)
+		return nil, fmt.Errorf("%w: 合并后的文件与声明不一致", errChecksum)
+	}
+
+	return &FileInfo{Key: key, Filename: s.Filename, Size: size, MIME: s.MIME.String(), SHA256: sum}, nil
+}
+
+// discard 删除会话及其全部分片
+func (u *Uploader) discard(ctx context.Context, s *session) {
+	u.sessions.mu.Lock()
+	delete(u.sessions.sessions, s.ID)
+	u.sessions.mu.Unlock()
+
+	for index := 0; index < s.Chunks; index++ {
+		u.storage.Delete(ctx, s.chunkKey(index))
+	}
+}
+
+// Cleanup 清理创建时间超过 maxAge 仍未完成的会话，返回清理的数量
+// 超过 SessionTTL 没有活动的会话在创建新会话时自动清理；
+// Cleanup 一般由定时任务调用，在没有新上传时也能及时释放存储
+func (u *Uploader) Cleanup(ctx context.Context, maxAge time.Duration) int {
+	deadline := u.now().Add(-maxAge)
+	return u.sweep(ctx, func(s *session) bool {
+		return !s.completing && s.writing == 0 && s.CreatedAt.Before(deadline)
+	})
+}
+
+// sweep 删除满足 match 的会话及其分片，返回删除的数量
+func (u *Uploader) sweep(ctx context.Context, match func(*session) bool) int {
+	u.sessions.mu.Lock()
+	var expired []*session
+	for _, s := range u.sessions.sessions {
+		if match(s) {
+			expired = append(expired, s)
+		}
+	}
+	u.sessions.mu.Unlock()
+
+	for _, s := range expired {
+		u.discard(ctx, s)
+	}
+	return len(expired)
+}
+
+// status 生成会话状态，调用方需持有 sessions.mu
+func (u *Uploader) status(s *session) SessionStatus {
+	return SessionStatus{
+		UploadID:  s.ID,
+		Filename:  s.Filename,
+		Size:      s.Size,
+		ChunkSize: s.ChunkSize,
+		Chunks:    s.Chunks,
+		Received:  s.receivedList(),
+	}
+}
+
+// chunkReader 按序号依次读取分片，对外表现为一个连续的 io.Reader
+// 不会把所有分片一次性读入内存
+type chunkReader struct {
+	ctx     context.Context
+	storage Storage
+	session *session
+	index   int
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.index >= r.session.Chunks {
+				return 0, io.EOF
+			}
+			rc, err := r.storage.Get(r.ctx, r.session.chunkKey(r.index))
+			if err != nil {
+				return 0, fmt.Errorf("读取分片 %d 失败: %w", r.index, err)
+			}
+			r.current = rc
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			r.index++
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+// Close 关闭当前打开的分片
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
+
+// UploadDemo 演示文件上传（大小限制、MIME 嗅探、分片断点续传、可插拔存储）
+func UploadDemo() {
+	fmt.Println("=== 文件上传示例 ===")
+	fmt.Println()
+
+	root, err := os.MkdirTemp("", "gin-upload-*")
+	if err != nil {
+		fmt.Printf("创建临时目录失败: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(root)
+
+	storage, err := NewLocalStorage(root)
+	if err != nil {
+		fmt.Printf("初始化存储失败: %v\n", err)
+		return
+	}
+
+	config := DefaultConfig()
+	uploader := NewUploader(storage, config)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	uploader.Register(router.Group("/api"))
+
+	fmt.Printf("存储目录: %s\n", root)
+	fmt.Printf("单文件上限: %d 字节，分片大小: %d 字节\n", config.MaxFileSize, config.ChunkSize)
+	fmt.Printf("允许的类型: %s\n", strings.Join(config.AllowedTypes, ", "))
+	fmt.Println()
+
+	fmt.Println("路由:")
+	fmt.Println("  POST /api/upload                      - multipart 上传（字段名 file）")
+	fmt.Println("  POST /api/uploads                     - 创建分片上传会话")
+	fmt.Println("  GET  /api/uploads/:id                 - 查询已上传分片（断点续传）")
+	fmt.Println("  PUT  /api/uploads/:id/chunks/:index   - 上传分片（请求头 X-Chunk-SHA256）")
+	fmt.Println("  POST /api/uploads/:id/complete        - 合并分片")
+	fmt.Println()
+
+	fmt.Println("安全要点:")
+	fmt.Println("  - 类型根据文件内容嗅探，改扩展名或伪造 Content-Type 无效（415）")
+	fmt.Println("  - 超过大小限制立即中止读取（413），不会先把整个文件落盘")
+	fmt.Println("  - 存储 key 由服务端生成，不使用客户端文件名，避免路径穿越")
+	fmt.Println("  - 分片和整体文件都做 sha256 校验")
+	fmt.Println()
+
+	fmt.Println("测试示例:")
+	fmt.Println("  curl -F \"file=@avatar.png\" http://localhost:8080/api/upload")
+	fmt.Println("  curl -X POST http://localhost:8080/api/uploads \\")
+	fmt.Println("    -H \"Content-Type: application/json\" \\")
+	fmt.Println("    -d '{\"filename\":\"big.pdf\",\"size\":3145728,\"sha256\":\"<整体sha256>\"}'")
+	fmt.Println("  curl -X PUT http://localhost:8080/api/uploads/<uploadId>/chunks/0 \\")
+	fmt.Println("    -H \"X-Chunk-SHA256: <分片sha256>\" --data-binary @chunk0")
+	fmt.Println()
+
+	// ========== 本地模拟一次上传 ==========
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"avatar.png", png},
+		{"avatar.png.exe", []byte("MZ\x90\x00 伪装成图片的可执行文件")},
+	} {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", file.name)
+		part.Write(file.data)
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("上传 %-16s → %d %s\n", file.name, w.Code, w.Body.String())
+	}
+	fmt.Println()
+
+	fmt.Println("清理放弃的分片上传（一般放在定时任务中）:")
+	fmt.Println("  uploader.Cleanup(ctx, 24*time.Hour)")
+}
@@ -0,0 +1,368 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader PNG 文件签名，足以让 mimetype 识别为 image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newTestRouter(config Config) (*gin.Engine, *MemoryStorage) {
+	gin.SetMode(gin.TestMode)
+	storage := NewMemoryStorage()
+	router := gin.New()
+	NewUploader(storage, config).Register(router)
+	return router, storage
+}
+
+func multipartRequest(field, filename string, data []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("description", "测试文件")
+	part, _ := writer.CreateFormFile(field, filename)
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type response struct {
+	Code    int             `json:"code"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+func serve(router *gin.Engine, req *http.Request) (int, response) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+// TestHandleUpload 测试普通 multipart 上传
+func TestHandleUpload(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxFileSize = 1024
+	router, storage := newTestRouter(config)
+
+	t.Run("上传成功", func(t *testing.T) {
+		data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 100)...)
+		code, resp := serve(router, multipartRequest("file", "../../etc/avatar.jpg", data))
+		assert.Equal(t, http.StatusCreated, code)
+
+		var info FileInfo
+		assert.NoError(t, json.Unmarshal(resp.Data, &info))
+		assert.Equal(t, "image/png", info.MIME)
+		assert.Equal(t, int64(len(data)), info.Size)
+		assert.Equal(t, sha256Hex(data), info.SHA256)
+		assert.Equal(t, "avatar.jpg", info.Filename)
+		assert.True(t, strings.HasPrefix(info.Key, "files/"))
+		assert.True(t, strings.HasSuffix(info.Key, ".png"), "扩展名应来自嗅探结果")
+
+		rc, err := storage.Get(context.Background(), info.Key)
+		assert.NoError(t, err)
+		stored, _ := io.ReadAll(rc)
+		assert.Equal(t, data, stored)
+	})
+
+	t.Run("伪装扩展名被拒绝", func(t *testing.T) {
+		before := len(storage.Keys())
+		code, _ := serve(router, multipartRequest("file", "avatar.png", []byte("MZ\x90\x00\x03\x00\x00\x00")))
+		assert.Equal(t, http.StatusUnsupportedMediaType, code)
+		assert.Len(t, storage.Keys(), before)
+	})
+
+	t.Run("超过大小限制", func(t *testing.T) {
+		before := len(storage.Keys())
+		data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 2048)...)
+		code, _ := serve(router, multipartRequest("file", "big.png", data))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+		assert.Len(t, storage.Keys(), before, "超限文件不应残留在存储中")
+	})
+
+	t.Run("缺少文件字段", func(t *testing.T) {
+		code, _ := serve(router, multipartRequest("avatar", "a.png", pngHeader))
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+// TestResumableUpload 测试分片上传和断点续传
+func TestResumableUpload(t *testing.T) {
+	config := DefaultConfig()
+	config.ChunkSize = 16
+	router, storage := newTestRouter(config)
+
+	data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte("0123456789"), 4)...) // 48 字节 → 3 片
+	chunk := func(i int) []byte {
+		end := min((i+1)*16, len(data))
+		return data[i*16 : end]
+	}
+
+	jsonRequest := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	putChunk := func(id string, index int, body []byte, checksum string) int {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/uploads/%s/chunks/%d", id, index), bytes.NewReader(body))
+		req.Header.Set("X-Chunk-SHA256", checksum)
+		code, _ := serve(router, req)
+		return code
+	}
+	status := func(id string) SessionStatus {
+		code, resp := serve(router, httptest.NewRequest(http.MethodGet, "/uploads/"+id, nil))
+		assert.Equal(t, http.StatusOK, code)
+		var s SessionStatus
+		json.Unmarshal(resp.Data, &s)
+		return s
+	}
+
+	// 创建会话
+	code, resp := serve(router, jsonRequest(http.MethodPost, "/uploads",
+		fmt.Sprintf(`{"filename":"a.png","size":%d,"sha256":"%s"}`, len(data), sha256Hex(data))))
+	assert.Equal(t, http.StatusCreated, code)
+	var created SessionStatus
+	assert.NoError(t, json.Unmarshal(resp.Data, &created))
+	assert.Equal(t, 3, created.Chunks)
+	id := created.UploadID
+
+	// 上传第 0、2 片，第 1 片校验和错误（模拟传输损坏）
+	assert.Equal(t, http.StatusOK, putChunk(id, 0, chunk(0), sha256Hex(chunk(0))))
+	assert.Equal(t, http.StatusOK, putChunk(id, 2, chunk(2), sha256Hex(chunk(2))))
+	assert.Equal(t, http.StatusBadRequest, putChunk(id, 1, chunk(1), sha256Hex(chunk(0))))
+	assert.Equal(t, http.StatusBadRequest, putChunk(id, 1, chunk(1)[:8], sha256Hex(chunk(1)[:8])), "分片大小必须精确")
+	assert.Equal(t, http.StatusBadRequest, putChunk(id, 3, chunk(2), sha256Hex(chunk(2))), "序号超出范围")
+
+	// 未传完不能合并
+	code, _ = serve(router, httptest.NewRequest(http.MethodPost, "/uploads/"+id+"/complete", nil))
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 断点续传: 查询后只补传缺失的分片
+	assert.Equal(t, []int{0, 2}, status(id).Received)
+	assert.Equal(t, http.StatusOK, putChunk(id, 1, chunk(1), sha256Hex(chunk(1))))
+
+	code, resp = serve(router, httptest.NewRequest(http.MethodPost, "/uploads/"+id+"/complete", nil))
+	assert.Equal(t, http.StatusCreated, code)
+	var info FileInfo
+	assert.NoError(t, json.Unmarshal(resp.Data, &info))
+	assert.Equal(t, sha256Hex(data), info.SHA256)
+	assert.Equal(t, "image/png", info.MIME)
+
+	// 合并后分片和会话都被清理，只剩最终文件
+	assert.Equal(t, []string{info.Key}, storage.Keys())
+	code, _ = serve(router, httptest.NewRequest(http.MethodGet, "/uploads/"+id, nil))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	t.Run("整体校验和不一致", func(t *testing.T) {
+		code, resp := serve(router, jsonRequest(http.MethodPost, "/uploads",
+			fmt.Sprintf(`{"filename":"b.png","size":%d,"sha256":"%s"}`, len(pngHeader), strings.Repeat("0", 64))))
+		assert.Equal(t, http.StatusCreated, code)
+		var s SessionStatus
+		json.Unmarshal(resp.Data, &s)
+
+		assert.Equal(t, http.StatusOK, putChunk(s.UploadID, 0, pngHeader, sha256Hex(pngHeader)))
+		code, _ = serve(router, httptest.NewRequest(http.MethodPost, "/uploads/"+s.UploadID+"/complete", nil))
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("清理过期会话", func(t *testing.T) {
+		uploader := NewUploader(NewMemoryStorage(), config)
+		r := gin.New()
+		uploader.Register(r)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, jsonRequest(http.MethodPost, "/uploads", `{"filename":"c.png","size":8,"sha256":"`+sha256Hex(pngHeader)+`"}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		assert.Equal(t, 0, uploader.Cleanup(context.Background(), time.Hour))
+		assert.Equal(t, 1, uploader.Cleanup(context.Background(), 0))
+	})
+
+	t.Run("零值配置使用默认值", func(t *testing.T) {
+		r := gin.New()
+		NewUploader(NewMemoryStorage(), Config{AllowedTypes: config.AllowedTypes}).Register(r)
+		code, resp := serve(r, jsonRequest(http.MethodPost, "/uploads", `{"filename":"d.png","size":8,"sha256":"`+sha256Hex(pngHeader)+`"}`))
+		assert.Equal(t, http.StatusCreated, code)
+		var s SessionStatus
+		json.Unmarshal(resp.Data, &s)
+		assert.Equal(t, DefaultConfig().ChunkSize, s.ChunkSize, "ChunkSize 为 0 时不能除零")
+	})
+
+	t.Run("会话过期和数量上限", func(t *testing.T) {
+		limited := config
+		limited.SessionTTL = time.Hour
+		limited.MaxSessions = 2
+		storage := NewMemoryStorage()
+		uploader := NewUploader(storage, limited)
+		now := time.Now()
+		uploader.now = func() time.Time { return now }
+		r := gin.New()
+		uploader.Register(r)
+		create := func() (int, string) {
+			code, resp := serve(r, jsonRequest(http.MethodPost, "/uploads",
+				fmt.Sprintf(`{"filename":"e.png","size":%d,"sha256":"%s"}`, len(data), sha256Hex(data))))
+			var s SessionStatus
+			json.Unmarshal(resp.Data, &s)
+			return code, s.UploadID
+		}
+
+		_, first := create()
+		_, second := create()
+		code, _ := create()
+		assert.Equal(t, http.StatusServiceUnavailable, code, "超过 MaxSessions")
+
+		// 第二个会话持续收到分片，不会过期
+		now = now.Add(40 * time.Minute)
+		req := httptest.NewRequest(http.MethodPut, "/uploads/"+second+"/chunks/0", bytes.NewReader(chunk(0)))
+		req.Header.Set("X-Chunk-SHA256", sha256Hex(chunk(0)))
+		code, _ = serve(r, req)
+		assert.Equal(t, http.StatusOK, code)
+
+		now = now.Add(30 * time.Minute)
+		code, _ = serve(r, httptest.NewRequest(http.MethodGet, "/uploads/"+first, nil))
+		assert.Equal(t, http.StatusNotFound, code, "超过 SessionTTL 没有活动")
+		code, _ = serve(r, httptest.NewRequest(http.MethodGet, "/uploads/"+second, nil))
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = create()
+		assert.Equal(t, http.StatusCreated, code, "过期会话被清理，腾出名额")
+		assert.Len(t, uploader.sessions.sessions, 2)
+		now = now.Add(2 * time.Hour)
+		create()
+		assert.Len(t, uploader.sessions.sessions, 1)
+		for key := range storage.objects {
+			assert.NotContains(t, key, second, "过期会话的分片被删除")
+		}
+	})
+}
+
+// blockingStorage key 满足 block 时 Put 阻塞，直到 release 被关闭，用于构造并发交错
+type blockingStorage struct {
+	*MemoryStorage
+	block   func(key string) bool
+	entered chan string
+	release chan struct{}
+}
+
+func (s *blockingStorage) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if s.block != nil && s.block(key) {
+		s.entered <- key
+		<-s.release
+	}
+	return s.MemoryStorage.Put(ctx, key, r)
+}
+
+// TestCompleteWithConcurrentChunk 测试合并与分片上传交错时分片不会被覆盖或遗留
+func TestCompleteWithConcurrentChunk(t *testing.T) {
+	config := DefaultConfig()
+	config.ChunkSize = 8
+	data := append(append([]byte{}, pngHeader...), []byte("abcdefgh")...) // 16 字节 → 2 片
+
+	setup := func(block func(key string) bool) (*gin.Engine, *blockingStorage, string) {
+		storage := &blockingStorage{MemoryStorage: NewMemoryStorage(), entered: make(chan string, 1), release: make(chan struct{})}
+		r := gin.New()
+		NewUploader(storage, config).Register(r)
+		req := httptest.NewRequest(http.MethodPost, "/uploads",
+			strings.NewReader(fmt.Sprintf(`{"filename":"a.png","size":%d,"sha256":"%s"}`, len(data), sha256Hex(data))))
+		req.Header.Set("Content-Type", "application/json")
+		_, resp := serve(r, req)
+		var s SessionStatus
+		json.Unmarshal(resp.Data, &s)
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/uploads/%s/chunks/%d", s.UploadID, i), bytes.NewReader(data[i*8:(i+1)*8]))
+			req.Header.Set("X-Chunk-SHA256", sha256Hex(data[i*8:(i+1)*8]))
+			code, _ := serve(r, req)
+			assert.Equal(t, http.StatusOK, code)
+		}
+		storage.block = block // 分片都已上传，之后的写入才阻塞
+		return r, storage, s.UploadID
+	}
+	putChunk := func(r *gin.Engine, id string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPut, "/uploads/"+id+"/chunks/1", bytes.NewReader(body))
+		req.Header.Set("X-Chunk-SHA256", sha256Hex(body))
+		code, _ := serve(r, req)
+		return code
+	}
+	complete := func(r *gin.Engine, id string) int {
+		code, _ := serve(r, httptest.NewRequest(http.MethodPost, "/uploads/"+id+"/complete", nil))
+		return code
+	}
+
+	t.Run("分片写入期间不能合并", func(t *testing.T) {
+		r, storage, id := setup(func(key string) bool { return strings.HasPrefix(key, "chunks/") })
+		done := make(chan int)
+		go func() { done <- putChunk(r, id, []byte("abcdefgh")) }()
+		<-storage.entered
+
+		assert.Equal(t, http.StatusConflict, complete(r, id), "分片正在写入")
+		close(storage.release)
+		assert.Equal(t, http.StatusOK, <-done)
+		assert.Equal(t, http.StatusCreated, complete(r, id))
+	})
+
+	t.Run("合并期间不能写入分片", func(t *testing.T) {
+		r, storage, id := setup(func(key string) bool { return !strings.HasPrefix(key, "chunks/") })
+		done := make(chan int)
+		go func() { done <- complete(r, id) }()
+		<-storage.entered
+
+		assert.Equal(t, http.StatusConflict, putChunk(r, id, []byte("zzzzzzzz")), "会话正在合并")
+		close(storage.release)
+		assert.Equal(t, http.StatusCreated, <-done)
+		keys := storage.Keys()
+		assert.Len(t, keys, 1, "分片全部清理，只剩最终文件")
+		rc, err := storage.Get(context.Background(), keys[0])
+		assert.NoError(t, err)
+		merged, _ := io.ReadAll(rc)
+		assert.Equal(t, data, merged)
+	})
+}
+
+// TestLocalStoragePath 测试本地存储不会越出根目录
+func TestLocalStoragePath(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	p, err := storage.path("../../etc/passwd")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(p, storage.Root))
+
+	_, err = storage.path("..")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	ctx := context.Background()
+	_, err = storage.Put(ctx, "a/b.txt", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	rc, err := storage.Get(ctx, "a/b.txt")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "hello", string(content))
+
+	assert.NoError(t, storage.Delete(ctx, "a/b.txt"))
+	_, err = storage.Get(ctx, "a/b.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
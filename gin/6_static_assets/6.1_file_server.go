@@ -0,0 +1,238 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CachePolicy 某一路径前缀的 Cache-Control 策略
+type CachePolicy struct {
+	Prefix       string // 路径前缀，如 "/assets/"；也可以是完整路径，如 "/index.html"
+	CacheControl string // Cache-Control 响应头
+}
+
+// Options 静态资源服务配置
+type Options struct {
+	FS            fs.FS         // 资源文件系统（通常是 embed.FS 的子目录）
+	Index         string        // 目录默认文件，同时也是 SPA 回退页面
+	SPAFallback   bool          // 未知路径是否回退到 Index
+	APIPrefixes   []string      // 这些前缀下的未知路径返回 JSON 404，而不是回退到 Index
+	CachePolicies []CachePolicy // 按顺序匹配，第一个匹配的生效
+	DefaultCache  string        // 没有匹配任何策略时的 Cache-Control
+}
+
+// 按优先级排列的预压缩编码及对应的文件后缀
+var encodings = []struct {
+	name   string
+	suffix string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// asset 一个文件的内容（原始或预压缩版本）
+type asset struct {
+	data []byte
+	etag string
+}
+
+// file 一个对外可见的文件及其预压缩版本
+type file struct {
+	name     string            // 原始文件名（用于推断 Content-Type）
+	identity *asset            // 未压缩版本
+	encoded  map[string]*asset // 编码名 → 预压缩版本
+}
+
+// FileServer 基于 fs.FS 的静态资源服务
+//
+// 与 router.Static 相比:
+//   - 资源在创建时一次性读入并计算强 ETag（内容的 sha256），运行时不再访问磁盘
+//   - 客户端支持时直接返回构建阶段生成的 .br / .gz 文件，不在请求中实时压缩
+//   - 不同路径使用不同的缓存策略（带 hash 的资源长期缓存，index.html 每次协商）
+//   - 支持 SPA 路由回退
+type FileServer struct {
+	options Options
+	files   map[string]*file // 请求路径（以 / 开头）→ 文件
+}
+
+// NewFileServer 读取文件系统中的全部资源，创建静态资源服务
+func NewFileServer(options Options) (*FileServer, error) {
+	if options.Index == "" {
+		options.Index = "index.html"
+	}
+
+	s := &FileServer{options: options, files: make(map[string]*file)}
+	err := fs.WalkDir(options.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(options.FS, name)
+		if err != nil {
+			return err
+		}
+
+		// 预压缩文件挂到原始文件下，不单独对外暴露
+		for _, enc := range encodings {
+			if original, ok := strings.CutSuffix(name, enc.suffix); ok {
+				f := s.file(original)
+				f.encoded[enc.name] = newAsset(data)
+				return nil
+			}
+		}
+		s.file(name).identity = newAsset(data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("加载静态资源失败: %w", err)
+	}
+
+	// 只有 .gz / .br 而没有原始文件的条目无法服务 Range 和不支持压缩的客户端，直接丢弃
+	for key, f := range s.files {
+		if f.identity == nil {
+			delete(s.files, key)
+		}
+	}
+	return s, nil
+}
+
+func (s *FileServer) file(name string) *file {
+	key := "/" + name
+	f, ok := s.files[key]
+	if !ok {
+		f = &file{name: path.Base(name), encoded: make(map[string]*asset)}
+		s.files[key] = f
+	}
+	return f
+}
+
+// newAsset 计算强 ETag：内容相同则 ETag 相同，与部署时间无关，多实例之间也保持一致
+func newAsset(data []byte) *asset {
+	sum := sha256.Sum256(data)
+	return &asset{data: data, etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// Handler 返回 Gin 处理函数
+//
+// 一般注册为 router.NoRoute(server.Handler())，
+// 这样 API 路由优先匹配，其余路径才交给静态资源服务
+func (s *FileServer) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Header("Allow", "GET, HEAD")
+			c.AbortWithStatus(http.StatusMethodNotAllowed)
+			return
+		}
+		s.serve(c, c.Request.URL.Path)
+	}
+}
+
+// serve 查找文件并输出，找不到时按规则回退
+func (s *FileServer) serve(c *gin.Context, urlPath string) {
+	urlPath = path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += s.options.Index
+	}
+
+	if f, ok := s.files[urlPath]; ok {
+		s.write(c, urlPath, f)
+		return
+	}
+	if f, ok := s.files[path.Join(urlPath, s.options.Index)]; ok {
+		s.write(c, urlPath, f)
+		return
+	}
+
+	switch {
+	case s.isAPI(urlPath):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"code": 1004, "message": "接口不存在"})
+	case !s.options.SPAFallback || path.Ext(urlPath) != "":
+		// 带扩展名的路径是在请求具体资源（如过期的 app.xxx.js），
+		// 返回 index.html 会让浏览器把 HTML 当脚本解析，应当如实返回 404
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		index, ok := s.files["/"+s.options.Index]
+		if !ok {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		s.write(c, "/"+s.options.Index, index)
+	}
+}
+
+// write 选择合适的编码版本并输出
+// 条件请求（If-None-Match）和 Range 请求都交给 http.ServeContent 处理
+func (s *FileServer) write(c *gin.Context, urlPath string, f *file) {
+	header := c.Writer.Header()
+	header.Set("Cache-Control", s.cacheControl(urlPath))
+
+	chosen := f.identity
+	if len(f.encoded) > 0 {
+		header.Add("Vary", "Accept-Encoding")
+
+		// Range 针对的是具体表示的字节偏移，压缩版本的偏移对客户端没有意义，
+		// 因此带 Range 的请求始终返回原始内容
+		if c.GetHeader("Range") == "" {
+			for _, enc := range encodings {
+				if a, ok := f.encoded[enc.name]; ok && acceptsEncoding(c.GetHeader("Accept-Encoding"), enc.name) {
+					chosen = a
+					header.Set("Content-Encoding", enc.name)
+					break
+				}
+			}
+		}
+	}
+
+	// 不同编码是不同的表示，强 ETag 必须不同（已按各自内容计算）
+	header.Set("ETag", chosen.etag)
+	http.ServeContent(c.Writer, c.Request, f.name, time.Time{}, bytes.NewReader(chosen.data))
+}
+
+// cacheControl 按顺序匹配缓存策略
+func (s *FileServer) cacheControl(urlPath string) string {
+	for _, policy := range s.options.CachePolicies {
+		if strings.HasPrefix(urlPath, policy.Prefix) {
+			return policy.CacheControl
+		}
+	}
+	if s.options.DefaultCache != "" {
+		return s.options.DefaultCache
+	}
+	return "no-cache"
+}
+
+func (s *FileServer) isAPI(urlPath string) bool {
+	for _, prefix := range s.options.APIPrefixes {
+		if urlPath == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(urlPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding 判断 Accept-Encoding 是否接受指定编码（q=0 表示明确拒绝）
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
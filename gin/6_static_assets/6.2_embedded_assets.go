@@ -0,0 +1,116 @@
+package assets
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========== 嵌入的资源 ==========
+// go:embed 在编译时把文件打包进二进制，运行时与工作目录无关
+//
+// web/assets 下的文件名带内容 hash（app.3f9a1c.js），内容变化时文件名随之变化，
+// 因此可以放心设置一年的 immutable 缓存；.gz / .br 为构建阶段生成的预压缩版本:
+//
+//	gzip -k -9 web/assets/*.js web/assets/*.css
+//	brotli -k -q 11 web/assets/*.js web/assets/*.css
+
+//go:embed web
+var webFS embed.FS
+
+//go:embed templates
+var templateFS embed.FS
+
+// Web 返回嵌入的前端资源（以 web 目录为根）
+func Web() fs.FS {
+	sub, err := fs.Sub(webFS, "web")
+	if err != nil {
+		panic(err) // 目录在编译期已确定，不会发生
+	}
+	return sub
+}
+
+// Templates 返回解析好的嵌入模板，用于 router.SetHTMLTemplate
+// 代替依赖工作目录的 router.LoadHTMLGlob("templates/*")
+func Templates() *template.Template {
+	return template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+}
+
+// DefaultOptions 默认的资源服务配置
+func DefaultOptions() Options {
+	return Options{
+		FS:          Web(),
+		Index:       "index.html",
+		SPAFallback: true,
+		APIPrefixes: []string{"/api/"},
+		CachePolicies: []CachePolicy{
+			{Prefix: "/assets/", CacheControl: "public, max-age=31536000, immutable"}, // 文件名带 hash
+			{Prefix: "/index.html", CacheControl: "no-cache"},                         // 每次协商，保证发布后立即生效
+		},
+		DefaultCache: "public, max-age=3600",
+	}
+}
+
+// StaticAssetsDemo 演示嵌入式静态资源服务（ETag、预压缩、Range、SPA 回退）
+func StaticAssetsDemo() {
+	fmt.Println("=== 嵌入式静态资源服务示例 ===")
+	fmt.Println()
+
+	server, err := NewFileServer(DefaultOptions())
+	if err != nil {
+		fmt.Printf("创建资源服务失败: %v\n", err)
+		return
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/api/orders", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": []gin.H{{"orderNo": "ORD001", "status": "paid"}}})
+	})
+	router.NoRoute(server.Handler())
+
+	fmt.Println("嵌入的文件:")
+	fs.WalkDir(Web(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			fmt.Printf("  /%s\n", name)
+		}
+		return nil
+	})
+	fmt.Println()
+
+	request := func(desc, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  GET %s → %d", desc, target, w.Code)
+		for _, h := range []string{"Content-Type", "Content-Encoding", "Content-Range", "Cache-Control", "ETag"} {
+			if v := w.Header().Get(h); v != "" {
+				fmt.Printf("\n    %s: %s", h, v)
+			}
+		}
+		fmt.Printf("\n    响应体: %d 字节\n", w.Body.Len())
+		return w
+	}
+
+	w := request("1. 带 hash 的资源，长期缓存", "/assets/app.3f9a1c.js", nil)
+	request("2. 客户端支持 br，返回预压缩版本", "/assets/app.3f9a1c.js", map[string]string{"Accept-Encoding": "gzip, br"})
+	request("3. 协商缓存命中", "/assets/app.3f9a1c.js", map[string]string{"If-None-Match": w.Header().Get("ETag")})
+	request("4. Range 请求（断点续传 / 音视频拖动）", "/assets/app.3f9a1c.js", map[string]string{"Range": "bytes=0-99"})
+	request("5. 前端路由，回退到 index.html", "/orders/123", nil)
+	request("6. 不存在的资源文件，不回退", "/assets/app.000000.js", nil)
+	request("7. 不存在的接口，返回 JSON 404", "/api/unknown", nil)
+	fmt.Println()
+
+	fmt.Println("注册方式:")
+	fmt.Println("  server, _ := assets.NewFileServer(assets.DefaultOptions())")
+	fmt.Println("  router.NoRoute(server.Handler())   // API 路由优先，其余交给静态资源")
+	fmt.Println("  router.SetHTMLTemplate(assets.Templates())")
+}
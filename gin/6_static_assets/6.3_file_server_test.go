@@ -0,0 +1,130 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T, options Options) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server, err := NewFileServer(options)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/api/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	router.NoRoute(server.Handler())
+	return router
+}
+
+func get(router *gin.Engine, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// TestFileServer 测试 ETag、缓存策略、预压缩、Range 和 SPA 回退
+func TestFileServer(t *testing.T) {
+	js := bytes.Repeat([]byte("console.log('hello');\n"), 20)
+	options := DefaultOptions()
+	options.FS = fstest.MapFS{
+		"index.html":          {Data: []byte("<!DOCTYPE html><title>app</title>")},
+		"robots.txt":          {Data: []byte("User-agent: *\n")},
+		"assets/app.js":       {Data: js},
+		"assets/app.js.gz":    {Data: gzipBytes(js)},
+		"assets/orphan.js.br": {Data: []byte("no identity")},
+	}
+	router := newTestRouter(t, options)
+
+	t.Run("强 ETag 与缓存策略", func(t *testing.T) {
+		w := get(router, "/assets/app.js", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, js, w.Body.Bytes())
+		assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Regexp(t, `^"[0-9a-f]{32}"$`, w.Header().Get("ETag"))
+
+		w = get(router, "/robots.txt", nil)
+		assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+		assert.Empty(t, w.Header().Get("Vary"), "没有预压缩版本时不需要 Vary")
+	})
+
+	t.Run("If-None-Match 返回 304", func(t *testing.T) {
+		etag := get(router, "/assets/app.js", nil).Header().Get("ETag")
+		w := get(router, "/assets/app.js", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Zero(t, w.Body.Len())
+	})
+
+	t.Run("返回预压缩版本", func(t *testing.T) {
+		identity := get(router, "/assets/app.js", nil)
+		w := get(router, "/assets/app.js", map[string]string{"Accept-Encoding": "br;q=0, gzip"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+		assert.NotEqual(t, identity.Header().Get("ETag"), w.Header().Get("ETag"), "不同编码的 ETag 必须不同")
+
+		r, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		decoded, _ := io.ReadAll(r)
+		assert.Equal(t, js, decoded)
+
+		w = get(router, "/assets/app.js", map[string]string{"Accept-Encoding": "gzip;q=0"})
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("Range 请求返回原始内容片段", func(t *testing.T) {
+		w := get(router, "/assets/app.js", map[string]string{"Range": "bytes=0-6", "Accept-Encoding": "gzip"})
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "console", w.Body.String())
+		assert.Equal(t, "bytes 0-6/440", w.Header().Get("Content-Range"))
+	})
+
+	t.Run("SPA 回退", func(t *testing.T) {
+		w := get(router, "/orders/123", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "<title>app</title>")
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+		assert.Equal(t, http.StatusOK, get(router, "/", nil).Code)
+		assert.Equal(t, http.StatusNotFound, get(router, "/assets/missing.js", nil).Code, "带扩展名的路径不回退")
+		assert.Equal(t, http.StatusNotFound, get(router, "/assets/orphan.js", nil).Code, "缺少原始文件的预压缩版本不对外暴露")
+		assert.Equal(t, http.StatusNotFound, get(router, "/assets/app.js.gz", nil).Code)
+
+		w = get(router, "/api/unknown", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":1004`)
+		assert.Equal(t, "pong", get(router, "/api/ping", nil).Body.String(), "已注册的路由优先")
+	})
+}
+
+// TestEmbeddedAssets 测试嵌入的资源可以正常加载
+func TestEmbeddedAssets(t *testing.T) {
+	router := newTestRouter(t, DefaultOptions())
+
+	w := get(router, "/assets/app.3f9a1c.js", map[string]string{"Accept-Encoding": "gzip, br"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+
+	assert.NotNil(t, Templates().Lookup("index.tmpl"))
+}
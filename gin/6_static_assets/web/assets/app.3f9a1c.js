@@ -0,0 +1,73 @@
+// 前端路由示例：所有页面都由 index.html 承载，刷新 /orders 等地址时由服务端回退到 index.html
+(function () {
+  'use strict';
+
+  var routes = {
+    '/': function () {
+      return '<h1>首页</h1><p>静态资源通过 embed.FS 打包进二进制文件，部署时无需携带 static 目录。</p>';
+    },
+    '/orders': function () {
+      return '<h1>订单列表</h1><p>数据来自 <code>GET /api/orders</code>。</p><ul id="orders"></ul>';
+    },
+    '/users/:id': function (params) {
+      return '<h1>用户 ' + params.id + '</h1><p>数据来自 <code>GET /api/users/' + params.id + '</code>。</p>';
+    }
+  };
+
+  function match(path) {
+    var keys = Object.keys(routes);
+    for (var i = 0; i < keys.length; i++) {
+      var pattern = keys[i].split('/');
+      var parts = path.split('/');
+      if (pattern.length !== parts.length) {
+        continue;
+      }
+      var params = {};
+      var ok = true;
+      for (var j = 0; j < pattern.length; j++) {
+        if (pattern[j].charAt(0) === ':') {
+          params[pattern[j].slice(1)] = decodeURIComponent(parts[j]);
+        } else if (pattern[j] !== parts[j]) {
+          ok = false;
+          break;
+        }
+      }
+      if (ok) {
+        return { render: routes[keys[i]], params: params };
+      }
+    }
+    return null;
+  }
+
+  function render() {
+    var app = document.getElementById('app');
+    var route = match(window.location.pathname);
+    app.innerHTML = route ? route.render(route.params) : '<h1>404</h1><p>页面不存在</p>';
+
+    if (window.location.pathname === '/orders') {
+      fetch('/api/orders')
+        .then(function (res) { return res.json(); })
+        .then(function (body) {
+          var list = document.getElementById('orders');
+          (body.data || []).forEach(function (order) {
+            var item = document.createElement('li');
+            item.textContent = order.orderNo + ' - ' + order.status;
+            list.appendChild(item);
+          });
+        });
+    }
+  }
+
+  document.addEventListener('click', function (event) {
+    var link = event.target.closest('a[data-link]');
+    if (!link) {
+      return;
+    }
+    event.preventDefault();
+    window.history.pushState(null, '', link.getAttribute('href'));
+    render();
+  });
+
+  window.addEventListener('popstate', render);
+  render();
+})();
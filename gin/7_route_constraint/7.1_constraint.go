@@ -0,0 +1,211 @@
+package constraint
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Constraint 路径参数约束：校验原始字符串并转换为类型化的值
+// 返回 error 表示该参数不满足约束
+type Constraint func(raw string) (interface{}, error)
+
+// 内置约束
+var (
+	// Int 64 位有符号整数，值类型为 int64
+	Int Constraint = func(raw string) (interface{}, error) {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("应为整数")
+		}
+		return v, nil
+	}
+
+	// UUID 标准 36 位 UUID，值类型为 uuid.UUID
+	UUID Constraint = func(raw string) (interface{}, error) {
+		if len(raw) != 36 {
+			return nil, errors.New("应为 UUID")
+		}
+		v, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("应为 UUID")
+		}
+		return v, nil
+	}
+
+	// Slug 小写字母、数字和连字符（不能以连字符开头或结尾），值类型为 string
+	Slug = Regex(`[a-z0-9]+(?:-[a-z0-9]+)*`)
+)
+
+// Regex 自定义正则约束，值类型为 string
+// 表达式会自动加上 ^ 和 $，必须匹配整个路径段
+func Regex(expr string) Constraint {
+	re := regexp.MustCompile(`^(?:` + expr + `)$`)
+	return func(raw string) (interface{}, error) {
+		if !re.MatchString(raw) {
+			return nil, fmt.Errorf("应匹配 %s", expr)
+		}
+		return raw, nil
+	}
+}
+
+// 约束类型注册表，路由中通过 {name:type} 引用
+var (
+	typesMu sync.RWMutex
+	types   = map[string]Constraint{
+		"int":  Int,
+		"uuid": UUID,
+		"slug": Slug,
+	}
+)
+
+// RegisterType 注册自定义约束类型，之后可以在路由中使用 {name:typeName}
+//
+//	constraint.RegisterType("date", func(raw string) (interface{}, error) {
+//		return time.Parse("2006-01-02", raw)
+//	})
+func RegisterType(name string, c Constraint) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	types[name] = c
+}
+
+func lookupType(name string) (Constraint, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	c, ok := types[name]
+	return c, ok
+}
+
+// param 路由中声明的一个参数
+type param struct {
+	name       string
+	position   int // 在 Gin 路由中的参数序号
+	constraint Constraint
+}
+
+// parsePattern 将带约束的路由转换为 Gin 路由
+//
+//	/users/{id:int}/orders/{no}      → /users/:arg0/orders/:arg1
+//	/codes/{code:regex([A-Z]{3})}    → /codes/:arg0
+//
+// Gin 中同一位置的参数名必须一致（/users/:id 和 /users/:uuid 会冲突），
+// 因此统一使用按位置编号的参数名，再由分发器还原成声明的名字
+func parsePattern(pattern string) (string, []param, error) {
+	segments := strings.Split(pattern, "/")
+	var params []param
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "{") {
+			if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+				return "", nil, fmt.Errorf("路由 %s: 请使用 {name:type} 声明参数，而不是 %s", pattern, seg)
+			}
+			continue
+		}
+		if !strings.HasSuffix(seg, "}") {
+			return "", nil, fmt.Errorf("路由 %s: 参数 %s 缺少右括号", pattern, seg)
+		}
+
+		name, typeName, hasType := strings.Cut(seg[1:len(seg)-1], ":")
+		if name == "" {
+			return "", nil, fmt.Errorf("路由 %s: 参数名不能为空", pattern)
+		}
+		p := param{name: name, position: len(params)}
+
+		switch {
+		case !hasType:
+			// 不做约束
+		case strings.HasPrefix(typeName, "regex(") && strings.HasSuffix(typeName, ")"):
+			expr := typeName[len("regex(") : len(typeName)-1]
+			if _, err := regexp.Compile(expr); err != nil {
+				return "", nil, fmt.Errorf("路由 %s: 参数 %s 的正则无效: %w", pattern, name, err)
+			}
+			p.constraint = Regex(expr)
+		default:
+			c, ok := lookupType(typeName)
+			if !ok {
+				return "", nil, fmt.Errorf("路由 %s: 未知的参数类型 %s", pattern, typeName)
+			}
+			p.constraint = c
+		}
+
+		for _, existing := range params {
+			if existing.name == name {
+				return "", nil, fmt.Errorf("路由 %s: 参数 %s 重复", pattern, name)
+			}
+		}
+		params = append(params, p)
+		segments[i] = ":" + positionalName(p.position)
+	}
+	return strings.Join(segments, "/"), params, nil
+}
+
+func positionalName(position int) string {
+	return "arg" + strconv.Itoa(position)
+}
+
+// ========== 在处理函数中读取参数 ==========
+
+// 解析结果在 gin.Context 中的 key
+const (
+	valuesKey  = "route_constraint_values"
+	patternKey = "route_constraint_pattern"
+)
+
+// Pattern 返回命中的路由声明，如 /users/{id:int}
+// c.FullPath() 返回的是按位置编号的 Gin 路由（/users/:arg0），日志和监控中应使用这里的值
+func Pattern(c *gin.Context) string {
+	return c.GetString(patternKey)
+}
+
+// Route 返回用于日志、监控、缓存的路由模板：
+// 约束路由返回声明的 /users/{id:int}，普通路由返回 c.FullPath()，未匹配时为空字符串
+//
+// 约束路由在处理函数执行时才确定，中间件应在 c.Next() 之后读取
+func Route(c *gin.Context) string {
+	if pattern := Pattern(c); pattern != "" {
+		return pattern
+	}
+	return c.FullPath()
+}
+
+// Values 返回当前路由解析后的全部参数值
+func Values(c *gin.Context) map[string]interface{} {
+	if v, ok := c.Get(valuesKey); ok {
+		return v.(map[string]interface{})
+	}
+	return nil
+}
+
+// ParamInt 读取 int 类型的路径参数
+// 参数未声明为 int 属于编程错误，与 c.MustGet 一样直接 panic
+func ParamInt(c *gin.Context, name string) int64 {
+	return mustParam[int64](c, name)
+}
+
+// ParamUUID 读取 uuid 类型的路径参数
+func ParamUUID(c *gin.Context, name string) uuid.UUID {
+	return mustParam[uuid.UUID](c, name)
+}
+
+// ParamString 读取 slug、正则或无约束的路径参数
+func ParamString(c *gin.Context, name string) string {
+	return mustParam[string](c, name)
+}
+
+func mustParam[T any](c *gin.Context, name string) T {
+	raw, ok := Values(c)[name]
+	if !ok {
+		panic(fmt.Sprintf("路径参数 %q 未在路由 %s 中声明", name, c.FullPath()))
+	}
+	v, ok := raw.(T)
+	if !ok {
+		panic(fmt.Sprintf("路径参数 %q 的类型为 %T，而不是 %T", name, raw, v))
+	}
+	return v
+}
@@ -0,0 +1,263 @@
+package constraint
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FailureMode 参数不满足约束时的处理方式
+type FailureMode int
+
+const (
+	// NotFound 返回 404：约束是路由匹配的一部分，/users/abc 就是不存在的资源（默认）
+	NotFound FailureMode = iota
+	// BadRequest 返回 400 并指出哪个参数不合法，便于前端调试
+	BadRequest
+)
+
+// Options 约束路由配置
+type Options struct {
+	OnFailure FailureMode
+}
+
+// route 一条声明了约束的路由
+type route struct {
+	pattern string // 声明时的完整路由，如 /api/users/{id:int}
+	params  []param
+	handler gin.HandlerFunc
+}
+
+// dispatcher 同一个 Gin 路由（方法 + 路径）下的全部候选路由
+// 候选路由共用注册 Gin 路由时所在分组的中间件链，因此必须来自同一个分组
+type dispatcher struct {
+	mu     sync.RWMutex
+	group  *gin.RouterGroup
+	routes []*route
+}
+
+// Router 带参数约束的路由注册器
+//
+//	r := constraint.New(router, constraint.Options{})
+//	r.GET("/users/{id:int}", getUserByID)
+//	r.GET("/users/{uuid:uuid}", getUserByUUID)
+//	r.GET("/posts/{slug:slug}", getPost)
+//
+// 同一位置可以声明多个不同约束的路由，按注册顺序依次尝试，
+// 第一个全部参数都满足约束的路由处理请求；都不满足时按 OnFailure 返回 404 或 400
+type Router struct {
+	group       *gin.RouterGroup
+	options     Options
+	dispatchers map[string]*dispatcher // 方法 + Gin 路由 → 分发器，同一个 Engine 下的分组共享
+	mu          *sync.Mutex
+}
+
+// New 创建约束路由注册器
+func New(engine *gin.Engine, options Options) *Router {
+	return &Router{
+		group:       &engine.RouterGroup,
+		options:     options,
+		dispatchers: make(map[string]*dispatcher),
+		mu:          &sync.Mutex{},
+	}
+}
+
+// Group 创建路由分组
+// 路由级中间件请通过分组注册：同一路径的多个候选路由共用一个 Gin 处理链，
+// 因此同一个 Gin 路径下的候选路由必须在同一个分组中声明，否则 Handle 会 panic
+// （如 pub 和带鉴权的 admin 分组都声明 /api/users/{...}，admin 的路由会跑在 pub 的处理链上）
+func (r *Router) Group(relativePath string, handlers ...gin.HandlerFunc) *Router {
+	return &Router{
+		group:       r.group.Group(relativePath, handlers...),
+		options:     r.options,
+		dispatchers: r.dispatchers,
+		mu:          r.mu,
+	}
+}
+
+// Use 为当前分组添加中间件
+func (r *Router) Use(handlers ...gin.HandlerFunc) *Router {
+	r.group.Use(handlers...)
+	return r
+}
+
+// GET 注册 GET 路由
+func (r *Router) GET(pattern string, handler gin.HandlerFunc) {
+	r.Handle(http.MethodGet, pattern, handler)
+}
+
+// POST 注册 POST 路由
+func (r *Router) POST(pattern string, handler gin.HandlerFunc) {
+	r.Handle(http.MethodPost, pattern, handler)
+}
+
+// PUT 注册 PUT 路由
+func (r *Router) PUT(pattern string, handler gin.HandlerFunc) {
+	r.Handle(http.MethodPut, pattern, handler)
+}
+
+// PATCH 注册 PATCH 路由
+func (r *Router) PATCH(pattern string, handler gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, pattern, handler)
+}
+
+// DELETE 注册 DELETE 路由
+func (r *Router) DELETE(pattern string, handler gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, pattern, handler)
+}
+
+// Handle 注册路由，路由声明有误时 panic（与 Gin 注册冲突路由时的行为一致）
+func (r *Router) Handle(method, pattern string, handler gin.HandlerFunc) {
+	ginPath, params, err := parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	rt := &route{pattern: joinPaths(r.group.BasePath(), pattern), params: params, handler: handler}
+	key := method + " " + joinPaths(r.group.BasePath(), ginPath)
+
+	r.mu.Lock()
+	d, ok := r.dispatchers[key]
+	if !ok {
+		d = &dispatcher{group: r.group}
+		r.dispatchers[key] = d
+	}
+	r.mu.Unlock()
+
+	if d.group != r.group {
+		panic(fmt.Sprintf("constraint: %s %s 与已注册的 %s 对应同一个 Gin 路由 %s，但位于不同的分组，"+
+			"中间件不同的候选路由不能共用一个处理链，请放在同一个分组中或使用不同的路径",
+			method, rt.pattern, d.routes[0].pattern, key))
+	}
+
+	d.mu.Lock()
+	d.routes = append(d.routes, rt)
+	d.mu.Unlock()
+
+	if !ok {
+		r.group.Handle(method, ginPath, d.handle(r.options))
+	}
+}
+
+// handle 依次尝试候选路由
+func (d *dispatcher) handle(options Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d.mu.RLock()
+		routes := d.routes
+		d.mu.RUnlock()
+
+		var firstErr error
+		for _, rt := range routes {
+			values, err := rt.match(c)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			// 还原声明的参数名，c.Param("id") 依然可用
+			for _, p := range rt.params {
+				c.Params = append(c.Params, gin.Param{Key: p.name, Value: c.Param(positionalName(p.position))})
+			}
+			c.Set(valuesKey, values)
+			c.Set(patternKey, rt.pattern)
+			rt.handler(c)
+			return
+		}
+
+		if options.OnFailure == BadRequest {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"code": 1001, "message": firstErr.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"code": 1004, "message": "资源不存在"})
+	}
+}
+
+// match 校验全部参数，返回解析后的值
+func (rt *route) match(c *gin.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(rt.params))
+	for _, p := range rt.params {
+		raw := c.Param(positionalName(p.position))
+		if p.constraint == nil {
+			values[p.name] = raw
+			continue
+		}
+		v, err := p.constraint(raw)
+		if err != nil {
+			return nil, fmt.Errorf("路径参数 %s 无效: %v", p.name, err)
+		}
+		values[p.name] = v
+	}
+	return values, nil
+}
+
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	if base == "/" || base == "" {
+		return relative
+	}
+	return base + relative
+}
+
+// RouteConstraintDemo 演示类型化的路径参数约束
+func RouteConstraintDemo() {
+	fmt.Println("=== Gin 路径参数约束示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	r := New(engine, Options{OnFailure: NotFound})
+
+	r.GET("/users/{id:int}", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"route": "按数字ID查询", "id": ParamInt(c, "id")})
+	})
+	r.GET("/users/{uuid:uuid}", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"route": "按UUID查询", "uuid": ParamUUID(c, "uuid")})
+	})
+	r.GET("/posts/{slug:slug}", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"route": "按slug查询", "slug": ParamString(c, "slug")})
+	})
+
+	// 需要明确告诉调用方哪个参数错误的接口，可以使用 400
+	strict := New(engine, Options{OnFailure: BadRequest}).Group("/api")
+	strict.GET("/currencies/{code:regex([A-Z]{3})}/rates/{days:int}", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"currency": ParamString(c, "code"), "days": ParamInt(c, "days")})
+	})
+
+	fmt.Println("路由声明:")
+	fmt.Println("  GET /users/{id:int}")
+	fmt.Println("  GET /users/{uuid:uuid}")
+	fmt.Println("  GET /posts/{slug:slug}")
+	fmt.Println("  GET /api/currencies/{code:regex([A-Z]{3})}/rates/{days:int}   （失败返回 400）")
+	fmt.Println()
+
+	fmt.Println("请求结果:")
+	for _, target := range []string{
+		"/users/123",
+		"/users/550e8400-e29b-41d4-a716-446655440000",
+		"/users/abc",
+		"/posts/my-post-123",
+		"/posts/My_Post",
+		"/api/currencies/USD/rates/30",
+		"/api/currencies/usd/rates/30",
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		fmt.Printf("  %-50s → %d %s\n", target, w.Code, w.Body.String())
+	}
+	fmt.Println()
+
+	fmt.Println("支持的类型:")
+	fmt.Println("  {id:int}                 - 64位整数，ParamInt 读取")
+	fmt.Println("  {id:uuid}                - UUID，ParamUUID 读取")
+	fmt.Println("  {slug:slug}              - 小写字母、数字和连字符，ParamString 读取")
+	fmt.Println("  {code:regex([A-Z]{3})}   - 自定义正则（自动匹配整段），ParamString 读取")
+	fmt.Println("  {name}                   - 不做约束")
+	fmt.Println("  RegisterType(name, fn)   - 注册自定义类型")
+}
@@ -0,0 +1,157 @@
+package constraint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(engine *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+// TestParsePattern 测试路由声明解析
+func TestParsePattern(t *testing.T) {
+	ginPath, params, err := parsePattern("/users/{id:int}/posts/{slug}/codes/{code:regex([A-Z]{3})}")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/:arg0/posts/:arg1/codes/:arg2", ginPath)
+	assert.Len(t, params, 3)
+	assert.Nil(t, params[1].constraint)
+
+	for _, bad := range []string{
+		"/users/:id",
+		"/users/{id:int",
+		"/users/{:int}",
+		"/users/{id:float}",
+		"/users/{id:regex([a-z)}",
+		"/users/{id}/orders/{id}",
+	} {
+		_, _, err := parsePattern(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestRouter 测试约束路由的分发和失败处理
+func TestRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	r := New(engine, Options{})
+
+	r.GET("/users/{id:int}", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": ParamInt(c, "id"), "raw": c.Param("id"), "pattern": Pattern(c)})
+	})
+	r.GET("/users/{uuid:uuid}", func(c *gin.Context) {
+		c.String(http.StatusOK, ParamUUID(c, "uuid").String())
+	})
+	r.GET("/users/me", func(c *gin.Context) {
+		c.String(http.StatusOK, "me")
+	})
+	r.GET("/users/{id:int}/orders/{no:regex(ORD[0-9]{3})}", func(c *gin.Context) {
+		c.String(http.StatusOK, "%d-%s", ParamInt(c, "id"), ParamString(c, "no"))
+	})
+
+	api := New(engine, Options{OnFailure: BadRequest}).Group("/api")
+	api.GET("/posts/{slug:slug}", func(c *gin.Context) {
+		c.String(http.StatusOK, ParamString(c, "slug"))
+	})
+
+	t.Run("int", func(t *testing.T) {
+		w := serve(engine, http.MethodGet, "/users/42")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"id":42,"raw":"42","pattern":"/users/{id:int}"}`, w.Body.String())
+	})
+
+	t.Run("同一位置按约束分发", func(t *testing.T) {
+		w := serve(engine, http.MethodGet, "/users/550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", w.Body.String())
+
+		assert.Equal(t, "me", serve(engine, http.MethodGet, "/users/me").Body.String())
+		assert.Equal(t, "7-ORD001", serve(engine, http.MethodGet, "/users/7/orders/ORD001").Body.String())
+	})
+
+	t.Run("不满足约束返回 404", func(t *testing.T) {
+		for _, target := range []string{
+			"/users/abc",
+			"/users/99999999999999999999",
+			"/users/550e8400e29b41d4a716446655440000",
+			"/users/7/orders/ORD1",
+			"/users/7/orders/xORD001",
+		} {
+			assert.Equal(t, http.StatusNotFound, serve(engine, http.MethodGet, target).Code, target)
+		}
+	})
+
+	t.Run("配置为 400", func(t *testing.T) {
+		assert.Equal(t, "hello-world", serve(engine, http.MethodGet, "/api/posts/hello-world").Body.String())
+
+		w := serve(engine, http.MethodGet, "/api/posts/Hello_World")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "slug")
+	})
+
+	t.Run("参数类型不匹配时 panic", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(valuesKey, map[string]interface{}{"id": int64(1)})
+		assert.Panics(t, func() { ParamUUID(c, "id") })
+		assert.Panics(t, func() { ParamInt(c, "missing") })
+	})
+}
+
+// TestGroupMiddleware 测试分组中间件：不同分组不能共用同一个 Gin 路由
+func TestRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var routes []string
+	record := func(c *gin.Context) {
+		c.Next()
+		routes = append(routes, Route(c))
+	}
+	engine.Use(record)
+	engine.GET("/plain/:id", func(c *gin.Context) {})
+	New(engine, Options{}).Group("/api").GET("/users/{id:int}", func(c *gin.Context) {})
+
+	for _, target := range []string{"/plain/1", "/api/users/1", "/api/users/x", "/missing"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	assert.Equal(t, []string{"/plain/:id", "/api/users/{id:int}", "/api/users/:arg0", ""}, routes,
+		"普通路由用 FullPath；约束不满足时没有命中的声明，退回 Gin 路由")
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	r := New(engine, Options{})
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	}
+
+	pub := r.Group("/api")
+	admin := r.Group("/api", auth)
+	pub.GET("/users/{id:int}", func(c *gin.Context) { c.String(http.StatusOK, "public") })
+	assert.PanicsWithValue(t,
+		"constraint: GET /api/users/{name} 与已注册的 /api/users/{id:int} 对应同一个 Gin 路由 GET /api/users/:arg0，"+
+			"但位于不同的分组，中间件不同的候选路由不能共用一个处理链，请放在同一个分组中或使用不同的路径",
+		func() {
+			admin.GET("/users/{name}", func(c *gin.Context) { c.String(http.StatusOK, "admin") })
+		}, "否则 admin 的路由会跑在 pub 的处理链上，鉴权被跳过")
+
+	// 不同路径各自注册 Gin 路由，中间件生效
+	admin.GET("/admins/{name}", func(c *gin.Context) { c.String(http.StatusOK, "admin") })
+	assert.Equal(t, http.StatusUnauthorized, serve(engine, http.MethodGet, "/api/admins/root").Code)
+	assert.Equal(t, "public", serve(engine, http.MethodGet, "/api/users/1").Body.String())
+
+	// 同一个分组（包括 Use 返回的）可以在同一位置声明多个候选路由
+	admin.Use(func(c *gin.Context) { c.Header("X-Admin", "1") })
+	admin.GET("/admins/{id:int}/roles", func(c *gin.Context) { c.String(http.StatusOK, "roles") })
+	assert.NotPanics(t, func() {
+		admin.GET("/admins/{name}/roles", func(c *gin.Context) { c.String(http.StatusOK, "roles") })
+	})
+}
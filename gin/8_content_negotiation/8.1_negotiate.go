@@ -0,0 +1,142 @@
+package response
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Format 响应编码格式
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatXML      Format = "xml"
+	FormatYAML     Format = "yaml"
+	FormatMsgPack  Format = "msgpack"
+	FormatProtobuf Format = "protobuf"
+)
+
+// formatMIME 各格式可接受的 MIME 类型，第一个为响应使用的 Content-Type
+var formatMIME = []struct {
+	format Format
+	types  []string
+}{
+	{FormatJSON, []string{"application/json"}},
+	{FormatXML, []string{"application/xml", "text/xml"}},
+	{FormatYAML, []string{"application/yaml", "application/x-yaml", "text/yaml"}},
+	{FormatMsgPack, []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}},
+	{FormatProtobuf, []string{"application/x-protobuf", "application/protobuf"}},
+}
+
+// ErrNotAcceptable 客户端要求的格式都不支持
+var ErrNotAcceptable = errors.New("不支持请求的响应格式")
+
+// FormatQuery 用于覆盖 Accept 的查询参数名，如 ?format=yaml
+// 方便在浏览器或 curl 中直接查看不同格式
+const FormatQuery = "format"
+
+// Negotiate 根据 ?format= 和 Accept 请求头选择响应格式
+//
+// 规则:
+//   - ?format= 优先；值不支持时返回 ErrNotAcceptable，而不是静默退回 JSON
+//   - Accept 按 q 值从高到低匹配，q 相同时保持客户端给出的顺序
+//   - 没有 Accept，或包含 */* 时使用 JSON
+func Negotiate(c *gin.Context) (Format, error) {
+	if f := c.Query(FormatQuery); f != "" {
+		for _, item := range formatMIME {
+			if strings.EqualFold(f, string(item.format)) {
+				return item.format, nil
+			}
+		}
+		return "", ErrNotAcceptable
+	}
+
+	accept := c.GetHeader("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+	for _, mediaRange := range parseAccept(accept) {
+		if f, ok := matchFormat(mediaRange); ok {
+			return f, nil
+		}
+	}
+	return "", ErrNotAcceptable
+}
+
+// ContentType 格式对应的 Content-Type
+func (f Format) ContentType() string {
+	for _, item := range formatMIME {
+		if item.format == f {
+			return item.types[0]
+		}
+	}
+	return "application/json"
+}
+
+// SupportedTypes 支持的全部 MIME 类型（用于 406 响应的提示）
+func SupportedTypes() []string {
+	var types []string
+	for _, item := range formatMIME {
+		types = append(types, item.types...)
+	}
+	return types
+}
+
+// acceptItem Accept 中的一项
+type acceptItem struct {
+	mediaRange string
+	q          float64
+}
+
+// parseAccept 解析 Accept 请求头，按 q 值降序返回，q=0 的项被排除
+func parseAccept(header string) []string {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaRange == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, acceptItem{mediaRange: mediaRange, q: q})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	ranges := make([]string, len(items))
+	for i, item := range items {
+		ranges[i] = item.mediaRange
+	}
+	return ranges
+}
+
+// matchFormat 将一个媒体范围（可以是 type/* 或 */*）匹配到支持的格式
+func matchFormat(mediaRange string) (Format, bool) {
+	if mediaRange == "*/*" {
+		return FormatJSON, true
+	}
+	for _, item := range formatMIME {
+		for _, t := range item.types {
+			if t == mediaRange {
+				return item.format, true
+			}
+			if prefix, ok := strings.CutSuffix(mediaRange, "/*"); ok && strings.HasPrefix(t, prefix+"/") {
+				return item.format, true
+			}
+		}
+	}
+	return "", false
+}
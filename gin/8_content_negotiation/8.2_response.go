@@ -0,0 +1,302 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go-learning/gin/internal/httpheader"
+)
+
+// Response 统一响应结构，所有格式使用同一个信封
+//   - code: 业务状态码，0 表示成功，非0表示失败
+//   - data: 成功时返回的数据
+//   - message: 错误时的提示信息
+type Response struct {
+	Code    int         `json:"code" yaml:"code" codec:"code"`
+	Data    interface{} `json:"data" yaml:"data" codec:"data"`
+	Message string      `json:"message" yaml:"message" codec:"message"`
+}
+
+// Success 成功响应，格式由 Accept / ?format= 决定
+func Success(c *gin.Context, data interface{}) {
+	Render(c, http.StatusOK, Response{Code: 0, Data: data})
+}
+
+// Error 错误响应（HTTP 状态码为 200，与 UnifiedResponseDemo 的约定一致）
+func Error(c *gin.Context, code int, msg string) {
+	Render(c, http.StatusOK, Response{Code: code, Message: msg})
+}
+
+// ErrorWithStatus 需要同时设置 HTTP 状态码的错误响应
+func ErrorWithStatus(c *gin.Context, status, code int, msg string) {
+	Render(c, status, Response{Code: code, Message: msg})
+}
+
+// Render 按协商出的格式输出响应
+//
+// 各格式的编码方式:
+//
+//	json      c.JSON
+//	xml       <response><code/><data/><message/></response>，map 键作为元素名，数组元素为 <item>
+//	yaml      gin 内置的 render.YAML
+//	msgpack   gin 内置的 render.MsgPack
+//	protobuf  google.protobuf.Struct，字段与 JSON 一致（数字统一为 double）
+//
+// 除 JSON 外，data 会先按 JSON 规则（遵循 json tag）转换为通用结构，
+// 保证所有格式的字段名和嵌套结构完全一致
+func Render(c *gin.Context, status int, resp Response) {
+	// 追加而不是覆盖，保留 CORS（Origin）、CSRF（Cookie）等中间件设置的 Vary
+	httpheader.AddVary(c.Writer.Header(), "Accept")
+
+	format, err := Negotiate(c)
+	if err != nil {
+		// 无法使用客户端要求的格式，406 响应本身使用 JSON
+		c.AbortWithStatusJSON(http.StatusNotAcceptable, Response{
+			Code:    1001,
+			Message: fmt.Sprintf("%v，支持: %v", err, SupportedTypes()),
+		})
+		return
+	}
+
+	if format == FormatJSON {
+		c.JSON(status, resp)
+		return
+	}
+
+	data, err := normalize(resp.Data)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Response{Code: 2004, Message: "响应序列化失败"})
+		return
+	}
+	envelope := Response{Code: resp.Code, Data: data, Message: resp.Message}
+
+	switch format {
+	case FormatXML:
+		body, err := marshalXML(envelope)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Response{Code: 2004, Message: "响应序列化失败"})
+			return
+		}
+		c.Data(status, "application/xml; charset=utf-8", body)
+	case FormatYAML:
+		c.Render(status, render.YAML{Data: envelope})
+	case FormatMsgPack:
+		c.Render(status, render.MsgPack{Data: envelope})
+	case FormatProtobuf:
+		msg, err := structpb.NewStruct(map[string]interface{}{
+			"code":    envelope.Code,
+			"data":    envelope.Data,
+			"message": envelope.Message,
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Response{Code: 2004, Message: "响应序列化失败"})
+			return
+		}
+		c.Render(status, render.ProtoBuf{Data: msg})
+	}
+}
+
+// normalize 按 JSON 规则将任意数据转换为 map / slice / 基本类型组成的通用结构
+// 整数保持为 int64，避免大整数（如订单ID）经 float64 转换后丢失精度
+func normalize(data interface{}) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = convertNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertNumbers(item)
+		}
+		return val
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	default:
+		return val
+	}
+}
+
+// ========== XML 编码 ==========
+
+// xmlNameRegexp 可以直接作为 XML 元素名的 map 键
+var xmlNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// marshalXML 将信封编码为 XML
+func marshalXML(resp Response) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+
+	root := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := enc.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "code"}}, int64(resp.Code)); err != nil {
+		return nil, err
+	}
+	if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "data"}}, resp.Data); err != nil {
+		return nil, err
+	}
+	if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "message"}}, resp.Message); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXMLValue 编码 normalize 之后的通用结构
+//   - map: 键作为子元素名（按键排序）；键不是合法元素名时使用 <entry key="...">
+//   - slice: 每个元素为 <item>
+//   - nil: 空元素
+func encodeXMLValue(enc *xml.Encoder, start xml.StartElement, v interface{}) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if !xmlNameRegexp.MatchString(k) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}},
+				}
+			}
+			if err := encodeXMLValue(enc, child, val[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "item"}}, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		if err := enc.EncodeToken(xml.CharData(val)); err != nil {
+			return err
+		}
+	case int64:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatInt(val, 10))); err != nil {
+			return err
+		}
+	case float64:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatFloat(val, 'f', -1, 64))); err != nil {
+			return err
+		}
+	case bool:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatBool(val))); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的 XML 值类型 %T", v)
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// ContentNegotiationDemo 演示统一响应的内容协商（JSON / XML / YAML / MessagePack / Protobuf）
+func ContentNegotiationDemo() {
+	fmt.Println("=== 统一响应内容协商示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/users/:id", func(c *gin.Context) {
+		Success(c, gin.H{
+			"id":    c.Param("id"),
+			"name":  "John Doe",
+			"roles": []string{"admin", "user"},
+		})
+	})
+	router.GET("/orders/:id", func(c *gin.Context) {
+		Error(c, 1004, "订单不存在")
+	})
+
+	request := func(target, accept string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		fmt.Printf("GET %s", target)
+		if accept != "" {
+			fmt.Printf("  (Accept: %s)", accept)
+		}
+		fmt.Printf("\n  → %d %s\n", w.Code, w.Header().Get("Content-Type"))
+		switch ct := w.Header().Get("Content-Type"); {
+		case strings.Contains(ct, "msgpack"), strings.Contains(ct, "protobuf"):
+			fmt.Printf("  %d 字节二进制: % x ...\n", w.Body.Len(), w.Body.Bytes()[:min(16, w.Body.Len())])
+		default:
+			for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		fmt.Println()
+	}
+
+	request("/users/1", "")
+	request("/users/1", "application/xml")
+	request("/users/1", "text/html;q=0.9, application/yaml")
+	request("/users/1?format=msgpack", "application/json")
+	request("/users/1", "application/x-protobuf")
+	request("/orders/404", "application/yaml")
+	request("/users/1", "text/html")
+
+	fmt.Println("使用方式:")
+	fmt.Println("  response.Success(c, data)          // 替代 c.JSON(200, Response{...})")
+	fmt.Println("  response.Error(c, 1001, \"参数错误\")")
+	fmt.Println()
+	fmt.Println("格式选择:")
+	fmt.Println("  ?format=json|xml|yaml|msgpack|protobuf  优先于 Accept")
+	fmt.Println("  Accept 按 q 值选择，都不支持时返回 406")
+	fmt.Println("  protobuf 使用 google.protobuf.Struct 作为信封，客户端无需额外的 .proto 文件")
+}
@@ -0,0 +1,157 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type order struct {
+	ID      int64    `json:"id"`
+	OrderNo string   `json:"orderNo"`
+	Amount  float64  `json:"amount"`
+	Tags    []string `json:"tags"`
+	Remark  *string  `json:"remark"`
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/orders/1", func(c *gin.Context) {
+		Success(c, order{ID: 9007199254740993, OrderNo: "ORD001", Amount: 99.5, Tags: []string{"vip"}})
+	})
+	router.GET("/orders/2", func(c *gin.Context) {
+		Error(c, 1004, "订单不存在")
+	})
+	return router
+}
+
+func request(router *gin.Engine, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestNegotiate 测试 Accept 和 ?format= 的格式选择
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		target string
+		accept string
+		want   Format
+	}{
+		{"/", "", FormatJSON},
+		{"/", "*/*", FormatJSON},
+		{"/", "text/xml", FormatXML},
+		{"/", "application/json;q=0.5, application/yaml", FormatYAML},
+		{"/", "text/html, application/x-msgpack;q=0.8, application/xml;q=0.9", FormatXML},
+		{"/", "application/*", FormatJSON},
+		{"/", "application/protobuf", FormatProtobuf},
+		{"/?format=YAML", "application/json", FormatYAML},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, tc.target, nil)
+		c.Request.Header.Set("Accept", tc.accept)
+		f, err := Negotiate(c)
+		assert.NoError(t, err, tc.accept)
+		assert.Equal(t, tc.want, f, tc.accept)
+	}
+
+	for _, tc := range []struct{ target, accept string }{
+		{"/", "text/html"},
+		{"/", "application/json;q=0"},
+		{"/?format=csv", ""},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, tc.target, nil)
+		c.Request.Header.Set("Accept", tc.accept)
+		_, err := Negotiate(c)
+		assert.ErrorIs(t, err, ErrNotAcceptable)
+	}
+}
+
+// TestRender 测试各格式输出同一个信封
+func TestRender(t *testing.T) {
+	router := newTestRouter()
+
+	want := map[string]interface{}{
+		"code": float64(0),
+		"data": map[string]interface{}{
+			"id":      float64(9007199254740993),
+			"orderNo": "ORD001",
+			"amount":  99.5,
+			"tags":    []interface{}{"vip"},
+			"remark":  nil,
+		},
+		"message": "",
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := request(router, "/orders/1", "")
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		assert.Contains(t, w.Body.String(), `"id":9007199254740993`)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		w := request(router, "/orders/1", "application/xml")
+		assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(),
+			`<response><code>0</code><data><amount>99.5</amount><id>9007199254740993</id><orderNo>ORD001</orderNo><remark></remark><tags><item>vip</item></tags></data><message></message></response>`)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		w := request(router, "/orders/1", "application/x-yaml")
+		var got map[string]interface{}
+		assert.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &got))
+		data := got["data"].(map[string]interface{})
+		assert.EqualValues(t, 9007199254740993, data["id"], "大整数不应丢失精度")
+		assert.Equal(t, "ORD001", data["orderNo"])
+		assert.EqualValues(t, 0, got["code"])
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		w := request(router, "/orders/1?format=msgpack", "")
+		var got map[string]interface{}
+		var mh codec.MsgpackHandle
+		mh.RawToString = true
+		assert.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), &mh).Decode(&got))
+		data := got["data"].(map[interface{}]interface{})
+		assert.EqualValues(t, 9007199254740993, data["id"])
+		assert.Equal(t, "ORD001", data["orderNo"])
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		w := request(router, "/orders/1", "application/x-protobuf")
+		assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+		var msg structpb.Struct
+		assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), &msg))
+		assert.Equal(t, want, msg.AsMap())
+	})
+
+	t.Run("错误响应同样协商", func(t *testing.T) {
+		w := request(router, "/orders/2", "application/yaml")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "code: 1004\ndata: null\nmessage: 订单不存在\n", w.Body.String())
+	})
+
+	t.Run("406", func(t *testing.T) {
+		w := request(router, "/orders/1", "text/html")
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		var resp Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1001, resp.Code)
+	})
+}
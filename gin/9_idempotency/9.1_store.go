@@ -0,0 +1,269 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status 幂等记录的状态
+type Status string
+
+const (
+	StatusInFlight  Status = "in_flight" // 首个请求仍在处理
+	StatusCompleted Status = "completed" // 已完成，响应已保存
+)
+
+// Record 一个幂等键对应的记录
+type Record struct {
+	Key         string      // 存储键（已包含方法、路径和调用方，见 Middleware）
+	Fingerprint string      // 请求指纹（请求体的 sha256），用于发现同一个键被用于不同请求
+	Owner       string      // 持有者令牌，由 Begin 生成，Complete / Release 时核对
+	Status      Status      // 处理状态
+	StatusCode  int         // 保存的 HTTP 状态码
+	Header      http.Header // 保存的响应头
+	Body        []byte      // 保存的响应体
+	ExpiresAt   time.Time   // 过期时间，过期后同一个键可以重新使用
+}
+
+// ErrNotOwner Complete / Release 的调用方不是该键的持有者（记录已过期被他人占用）
+var ErrNotOwner = errors.New("幂等键不属于当前请求")
+
+// Store 幂等记录存储
+//
+// Begin 必须是原子的：并发的两个相同键的请求，只能有一个成为持有者，
+// 否则两个请求都会执行业务逻辑，幂等就失去了意义。
+//
+// Complete / Release 必须同时按 key 和 owner 匹配：请求 A 处理超过 TTL 后，
+// 记录可能已被请求 B 重新占用，A 迟到的 Complete / Release 不能修改 B 的记录
+type Store interface {
+	// Begin 尝试占用 key
+	//   - 占用成功返回持有者令牌 owner，调用方继续执行业务逻辑
+	//   - key 已存在且未过期，返回已有记录（处理中或已完成），owner 为空
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (owner string, existing *Record, err error)
+	// Complete 保存响应并将记录标记为已完成，不是持有者时返回 ErrNotOwner
+	Complete(ctx context.Context, key, owner string, statusCode int, header http.Header, body []byte) error
+	// Release 放弃占用（业务失败、panic），之后相同的键可以重试；不是持有者时返回 ErrNotOwner
+	Release(ctx context.Context, key, owner string) error
+}
+
+// newOwner 生成随机的持有者令牌
+func newOwner() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sweepInterval MemoryStore 清理过期记录的最短间隔
+const sweepInterval = time.Minute
+
+// ========== 内存实现 ==========
+
+// MemoryStore 内存存储，适合单实例部署和测试
+// 多实例部署时重试可能落到其他实例上，需要使用 GormStore 等共享存储
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*Record
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record), now: time.Now}
+}
+
+// Begin 占用 key
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (string, *Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if existing, ok := s.records[key]; ok && now.Before(existing.ExpiresAt) {
+		copied := *existing
+		copied.Owner = "" // 令牌只交给持有者
+		return "", &copied, nil
+	}
+
+	// 每隔 sweepInterval 顺便清理过期记录，避免 map 无限增长，也不必每次都遍历
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.lastSweep = now
+		for k, r := range s.records {
+			if !now.Before(r.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	owner := newOwner()
+	s.records[key] = &Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       owner,
+		Status:      StatusInFlight,
+		ExpiresAt:   now.Add(ttl),
+	}
+	return owner, nil, nil
+}
+
+// owned 返回 owner 持有的处理中记录，调用方持有 mu
+func (s *MemoryStore) owned(key, owner string) (*Record, bool) {
+	r, ok := s.records[key]
+	if !ok || r.Owner != owner || r.Status != StatusInFlight {
+		return nil, false
+	}
+	return r, true
+}
+
+// Complete 保存响应
+func (s *MemoryStore) Complete(_ context.Context, key, owner string, statusCode int, header http.Header, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.owned(key, owner)
+	if !ok {
+		return ErrNotOwner
+	}
+	r.Status = StatusCompleted
+	r.StatusCode = statusCode
+	r.Header = header.Clone()
+	r.Body = append([]byte(nil), body...)
+	return nil
+}
+
+// Release 删除处理中的记录
+func (s *MemoryStore) Release(_ context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.owned(key, owner); !ok {
+		return ErrNotOwner
+	}
+	delete(s.records, key)
+	return nil
+}
+
+// ========== GORM 实现 ==========
+
+// IdempotencyKey 幂等记录表（t_idempotency_key）
+// 主键即幂等键，依靠数据库主键约束保证 Begin 的原子性
+type IdempotencyKey struct {
+	Key         string    `gorm:"column:idem_key;primaryKey;size:64"`
+	Fingerprint string    `gorm:"column:fingerprint;size:64;not null"`
+	Owner       string    `gorm:"column:owner;size:32;not null;default:''"`
+	Status      string    `gorm:"column:status;size:20;not null"`
+	StatusCode  int       `gorm:"column:status_code"`
+	Header      string    `gorm:"column:header;type:text"` // JSON 编码的响应头
+	Body        []byte    `gorm:"column:body"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "t_idempotency_key"
+}
+
+// GormStore 基于数据库的存储，多实例共享
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储，并自动迁移表结构
+func NewGormStore(db *gorm.DB) (*GormStore, error) {
+	if err := db.AutoMigrate(&IdempotencyKey{}); err != nil {
+		return nil, err
+	}
+	return &GormStore{db: db}, nil
+}
+
+// Begin 使用 INSERT ... ON CONFLICT DO NOTHING 占用 key
+// 影响行数为 1 表示占用成功；为 0 表示已存在，读取已有记录
+func (s *GormStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (string, *Record, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	// 过期的记录先删除，让出 key
+	if err := db.Where("idem_key = ? AND expires_at <= ?", key, now).Delete(&IdempotencyKey{}).Error; err != nil {
+		return "", nil, err
+	}
+
+	row := IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       newOwner(),
+		Status:      string(StatusInFlight),
+		ExpiresAt:   now.Add(ttl),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+	if result.Error != nil {
+		return "", nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return row.Owner, nil, nil
+	}
+
+	var existing IdempotencyKey
+	if err := db.Where("idem_key = ?", key).Take(&existing).Error; err != nil {
+		return "", nil, err
+	}
+	record := &Record{
+		Key:         existing.Key,
+		Fingerprint: existing.Fingerprint,
+		Status:      Status(existing.Status),
+		StatusCode:  existing.StatusCode,
+		Body:        existing.Body,
+		ExpiresAt:   existing.ExpiresAt,
+	}
+	if existing.Header != "" {
+		if err := json.Unmarshal([]byte(existing.Header), &record.Header); err != nil {
+			return "", nil, err
+		}
+	}
+	return "", record, nil
+}
+
+// Complete 保存响应，只更新 owner 持有且仍处于处理中的记录
+func (s *GormStore) Complete(ctx context.Context, key, owner string, statusCode int, header http.Header, body []byte) error {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Model(&IdempotencyKey{}).
+		Where("idem_key = ? AND owner = ? AND status = ?", key, owner, string(StatusInFlight)).
+		Updates(map[string]interface{}{
+			"status":      string(StatusCompleted),
+			"status_code": statusCode,
+			"header":      string(headerJSON),
+			"body":        body,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// Release 删除 owner 持有且仍处于处理中的记录
+func (s *GormStore) Release(ctx context.Context, key, owner string) error {
+	result := s.db.WithContext(ctx).
+		Where("idem_key = ? AND owner = ? AND status = ?", key, owner, string(StatusInFlight)).
+		Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotOwner
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderKey 客户端传递幂等键的请求头
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed 重放的响应会带上这个响应头，便于客户端和排查问题时区分
+const HeaderReplayed = "Idempotent-Replayed"
+
+// Options 幂等中间件配置
+type Options struct {
+	TTL      time.Duration               // 记录保留时间，超过后同一个键可以重新使用
+	Required bool                        // 是否要求必须携带幂等键（缺少时返回 400）
+	MaxBody  int64                       // 参与指纹计算的请求体上限，超过返回 413
+	Scope    func(c *gin.Context) string // 幂等键的作用域（如当前用户ID），避免不同用户的键互相冲突
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		TTL:     24 * time.Hour,
+		MaxBody: 1 << 20, // 1MB
+		Scope: func(c *gin.Context) string {
+			// 与 JWTAuth 中间件配合时按用户隔离
+			return c.GetString("userID")
+		},
+	}
+}
+
+// 错误码
+const (
+	codeParamError = 1001 // 参数错误
+	codeInternal   = 2004 // 内部服务器错误
+	codeConflict   = 3004 // 资源冲突
+)
+
+// Middleware 幂等中间件
+//
+// 处理流程:
+//  1. 没有 Idempotency-Key 时直接放行（Required 为 true 时返回 400）
+//  2. 以 方法 + 路径 + 作用域 + 幂等键 作为存储键，以请求体 sha256 作为指纹
+//  3. 首次请求：占用存储键 → 执行业务逻辑 → 保存完整响应
+//  4. 重试请求：
+//     - 首次请求仍在处理 → 409，客户端稍后重试
+//     - 请求体与首次不同 → 422，同一个键不能用于不同的请求
+//     - 首次请求已完成 → 原样重放保存的响应，不再执行业务逻辑
+//
+// 业务返回 5xx 或 panic 时不保存响应并释放键，客户端可以用同一个键重试
+func Middleware(store Store, options Options) gin.HandlerFunc {
+	if options.TTL <= 0 {
+		options.TTL = 24 * time.Hour
+	}
+	if options.MaxBody <= 0 {
+		options.MaxBody = 1 << 20
+	}
+
+	return func(c *gin.Context) {
+		idemKey := strings.TrimSpace(c.GetHeader(HeaderKey))
+		if idemKey == "" {
+			if options.Required {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "缺少 " + HeaderKey + " 请求头"})
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(idemKey) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": HeaderKey + " 过长"})
+			return
+		}
+
+		// ========== 1. 读取请求体计算指纹，再放回去供业务使用 ==========
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, options.MaxBody+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": "读取请求体失败"})
+			return
+		}
+		if int64(len(body)) > options.MaxBody {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"code": codeParamError, "message": "请求体过大"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := ""
+		if options.Scope != nil {
+			scope = options.Scope(c)
+		}
+		key := storageKey(c.Request.Method, c.FullPath(), scope, idemKey)
+		fingerprint := digest(body)
+
+		// ========== 2. 尝试占用 ==========
+		ctx := c.Request.Context()
+		owner, existing, err := store.Begin(ctx, key, fingerprint, options.TTL)
+		if err != nil {
+			log.Printf("[idempotency] 占用幂等键失败: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"code": codeInternal, "message": "内部服务器错误"})
+			return
+		}
+		if existing != nil {
+			replayOrReject(c, existing, fingerprint)
+			return
+		}
+
+		// ========== 3. 首次请求：执行业务逻辑并记录响应 ==========
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// 无论业务成功、失败还是 panic，都要结束占用状态；
+		// 使用独立的 context，客户端断开不影响记录的保存
+		storeCtx := context.WithoutCancel(ctx)
+		settled := false
+		defer func() {
+			if !settled {
+				if err := store.Release(storeCtx, key, owner); err != nil {
+					log.Printf("[idempotency] 释放幂等键失败: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return // 服务端错误不缓存，允许重试
+		}
+		if err := store.Complete(storeCtx, key, owner, status, replayableHeader(recorder.Header()), recorder.body.Bytes()); err != nil {
+			log.Printf("[idempotency] 保存响应失败: %v", err)
+			// 记录已被他人占用时不能再释放
+			settled = errors.Is(err, ErrNotOwner)
+			return
+		}
+		settled = true
+	}
+}
+
+// replayOrReject 处理重复请求
+func replayOrReject(c *gin.Context, existing *Record, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"code":    codeParamError,
+			"message": HeaderKey + " 已用于另一个不同内容的请求",
+		})
+		return
+	}
+
+	if existing.Status != StatusCompleted {
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"code":    codeConflict,
+			"message": "相同 " + HeaderKey + " 的请求正在处理中，请稍后重试",
+		})
+		return
+	}
+
+	for name, values := range existing.Header {
+		for _, v := range values {
+			c.Writer.Header().Add(name, v)
+		}
+	}
+	c.Header(HeaderReplayed, "true")
+	c.Data(existing.StatusCode, existing.Header.Get("Content-Type"), existing.Body)
+	c.Abort()
+}
+
+// storageKey 生成存储键
+// 同一个幂等键用在不同接口或不同用户下是不同的请求，因此一起参与计算
+func storageKey(method, route, scope, idemKey string) string {
+	return digest([]byte(method + "\n" + route + "\n" + scope + "\n" + idemKey))
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// replayableHeader 需要随响应一起保存的响应头
+// Set-Cookie 等与会话相关的头不应重放给重试请求
+func replayableHeader(header http.Header) http.Header {
+	saved := http.Header{}
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Set-Cookie", "Date", "Content-Length":
+			continue
+		}
+		saved[name] = append([]string(nil), values...)
+	}
+	return saved
+}
+
+// responseRecorder 在写出响应的同时保留一份副本
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyDemo 演示 Idempotency-Key 幂等中间件
+func IdempotencyDemo() {
+	fmt.Println("=== Idempotency-Key 幂等请求示例 ===")
+	fmt.Println()
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+
+	var nextID atomic.Int64
+	router.POST("/orders", Middleware(NewMemoryStore(), DefaultOptions()), func(c *gin.Context) {
+		var req struct {
+			OfferingID int `json:"offeringId" binding:"required"`
+			Quantity   int `json:"quantity" binding:"required,min=1"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": codeParamError, "message": err.Error()})
+			return
+		}
+		id := nextID.Add(1)
+		c.Header("Location", fmt.Sprintf("/orders/%d", id))
+		c.JSON(http.StatusCreated, gin.H{"code": 0, "data": gin.H{"id": id, "offeringId": req.OfferingID, "quantity": req.Quantity}})
+	})
+
+	send := func(desc, key, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(HeaderKey, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		fmt.Printf("%s\n  → %d %s", desc, w.Code, strings.TrimSpace(w.Body.String()))
+		if w.Header().Get(HeaderReplayed) != "" {
+			fmt.Printf("  [%s: true]", HeaderReplayed)
+		}
+		fmt.Println()
+	}
+
+	send("1. 首次请求", "a1b2c3", `{"offeringId":1,"quantity":2}`)
+	send("2. 网络超时后重试（同一个键、同样的内容）", "a1b2c3", `{"offeringId":1,"quantity":2}`)
+	send("3. 同一个键、不同的内容", "a1b2c3", `{"offeringId":1,"quantity":5}`)
+	send("4. 新的键", "d4e5f6", `{"offeringId":1,"quantity":2}`)
+	send("5. 不带幂等键（每次都会创建）", "", `{"offeringId":1,"quantity":2}`)
+	fmt.Println()
+
+	fmt.Println("状态码约定:")
+	fmt.Println("  201/200  首次处理或重放的响应（重放带 Idempotent-Replayed: true）")
+	fmt.Println("  409      相同键的首个请求仍在处理中（Retry-After: 1）")
+	fmt.Println("  422      相同键但请求体不同")
+	fmt.Println("  5xx      不保存，允许使用同一个键重试")
+	fmt.Println()
+	fmt.Println("存储:")
+	fmt.Println("  NewMemoryStore()      - 单实例 / 测试")
+	fmt.Println("  NewGormStore(db)      - 多实例共享，表 t_idempotency_key，依靠主键约束保证原子性")
+	fmt.Println()
+	fmt.Println("客户端示例:")
+	fmt.Println("  curl -X POST http://localhost:8080/orders \\")
+	fmt.Println("    -H \"Idempotency-Key: $(uuidgen)\" \\")
+	fmt.Println("    -H \"Content-Type: application/json\" \\")
+	fmt.Println("    -d '{\"offeringId\":1,\"quantity\":2}'")
+}
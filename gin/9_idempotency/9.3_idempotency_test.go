@@ -0,0 +1,216 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer 计数的创建接口，release 为 nil 时立即返回
+type testServer struct {
+	router  *gin.Engine
+	created atomic.Int64
+	entered chan struct{}
+	release chan struct{}
+	status  int
+}
+
+func newTestServer(store Store, options Options) *testServer {
+	gin.SetMode(gin.TestMode)
+	s := &testServer{router: gin.New(), status: http.StatusCreated}
+	s.router.POST("/users", Middleware(store, options), func(c *gin.Context) {
+		if s.entered != nil {
+			s.entered <- struct{}{}
+			<-s.release
+		}
+		var req struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 1001, "message": err.Error()})
+			return
+		}
+		id := s.created.Add(1)
+		c.Header("Location", fmt.Sprintf("/users/%d", id))
+		c.SetCookie("session", "abc", 60, "/", "", false, true)
+		c.JSON(s.status, gin.H{"code": 0, "data": gin.H{"id": id, "name": req.Name}})
+	})
+	return s
+}
+
+func (s *testServer) post(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func newGormStore(t *testing.T) Store {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的库，测试中只用一个连接
+	store, err := NewGormStore(db)
+	assert.NoError(t, err)
+	return store
+}
+
+// TestMiddleware 使用两种存储分别测试幂等中间件
+func TestMiddleware(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"gorm":   newGormStore,
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("重放响应", func(t *testing.T) {
+				s := newTestServer(newStore(t), DefaultOptions())
+				first := s.post("k1", `{"name":"john"}`)
+				assert.Equal(t, http.StatusCreated, first.Code)
+
+				retry := s.post("k1", `{"name":"john"}`)
+				assert.Equal(t, http.StatusCreated, retry.Code)
+				assert.Equal(t, first.Body.String(), retry.Body.String())
+				assert.Equal(t, "/users/1", retry.Header().Get("Location"))
+				assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+				assert.Equal(t, "true", retry.Header().Get(HeaderReplayed))
+				assert.Empty(t, retry.Header().Get("Set-Cookie"), "Set-Cookie 不应重放")
+				assert.Equal(t, int64(1), s.created.Load(), "业务逻辑只执行一次")
+
+				assert.Equal(t, http.StatusCreated, s.post("k2", `{"name":"john"}`).Code)
+				assert.Equal(t, http.StatusCreated, s.post("", `{"name":"john"}`).Code)
+				assert.Equal(t, int64(3), s.created.Load())
+			})
+
+			t.Run("请求体不同返回 422", func(t *testing.T) {
+				s := newTestServer(newStore(t), DefaultOptions())
+				s.post("k1", `{"name":"john"}`)
+				w := s.post("k1", `{"name":"jane"}`)
+				assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+				assert.Equal(t, int64(1), s.created.Load())
+			})
+
+			t.Run("处理中返回 409", func(t *testing.T) {
+				s := newTestServer(newStore(t), DefaultOptions())
+				s.entered = make(chan struct{})
+				s.release = make(chan struct{})
+
+				done := make(chan *httptest.ResponseRecorder)
+				go func() { done <- s.post("k1", `{"name":"john"}`) }()
+				<-s.entered
+
+				w := s.post("k1", `{"name":"john"}`)
+				assert.Equal(t, http.StatusConflict, w.Code)
+				assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+				close(s.release)
+				assert.Equal(t, http.StatusCreated, (<-done).Code)
+
+				s.entered = nil
+				assert.Equal(t, "true", s.post("k1", `{"name":"john"}`).Header().Get(HeaderReplayed))
+			})
+
+			t.Run("5xx 不保存，允许重试", func(t *testing.T) {
+				s := newTestServer(newStore(t), DefaultOptions())
+				s.status = http.StatusServiceUnavailable
+				assert.Equal(t, http.StatusServiceUnavailable, s.post("k1", `{"name":"john"}`).Code)
+
+				s.status = http.StatusCreated
+				w := s.post("k1", `{"name":"john"}`)
+				assert.Equal(t, http.StatusCreated, w.Code)
+				assert.Empty(t, w.Header().Get(HeaderReplayed))
+				assert.Equal(t, int64(2), s.created.Load())
+			})
+		})
+	}
+}
+
+// TestMiddlewareOptions 测试必填和作用域
+func TestMiddlewareOptions(t *testing.T) {
+	options := DefaultOptions()
+	options.Required = true
+	s := newTestServer(NewMemoryStore(), options)
+	assert.Equal(t, http.StatusBadRequest, s.post("", `{"name":"john"}`).Code)
+
+	// 不同作用域（用户）下的相同键互不影响
+	user := "1"
+	options.Scope = func(*gin.Context) string { return user }
+	s = newTestServer(NewMemoryStore(), options)
+	s.post("k1", `{"name":"john"}`)
+	user = "2"
+	assert.Empty(t, s.post("k1", `{"name":"john"}`).Header().Get(HeaderReplayed))
+	assert.Equal(t, int64(2), s.created.Load())
+}
+
+// TestMemoryStoreExpiry 测试过期后键可以重新使用
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	owner, existing, err := store.Begin(ctx, "k", "f1", time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+	assert.NoError(t, store.Complete(ctx, "k", owner, http.StatusOK, http.Header{}, []byte("ok")))
+
+	_, existing, _ = store.Begin(ctx, "k", "f1", time.Minute)
+	assert.Equal(t, StatusCompleted, existing.Status)
+	assert.Empty(t, existing.Owner, "令牌不返回给其他请求")
+
+	now = now.Add(2 * time.Minute)
+	_, existing, _ = store.Begin(ctx, "k", "f2", time.Minute)
+	assert.Nil(t, existing)
+
+	// 过期记录按间隔清理
+	store.Begin(ctx, "other", "f", time.Second)
+	now = now.Add(2 * time.Second)
+	store.Begin(ctx, "k2", "f", time.Minute)
+	assert.Contains(t, store.records, "other", "距上次清理不足 sweepInterval")
+	now = now.Add(sweepInterval)
+	store.Begin(ctx, "k3", "f", time.Minute)
+	assert.NotContains(t, store.records, "other")
+}
+
+// TestStoreOwner 测试过期被他人占用后，原持有者迟到的 Complete / Release 不影响新记录
+func TestStoreOwner(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]Store{"memory": NewMemoryStore(), "gorm": newGormStore(t)} {
+		t.Run(name, func(t *testing.T) {
+			ownerA, _, err := store.Begin(ctx, "k", "f", -time.Second) // 立即过期
+			require.NoError(t, err)
+			ownerB, existing, err := store.Begin(ctx, "k", "f", time.Minute)
+			require.NoError(t, err)
+			require.Nil(t, existing, "过期的键被 B 重新占用")
+			assert.NotEqual(t, ownerA, ownerB)
+
+			assert.ErrorIs(t, store.Complete(ctx, "k", ownerA, http.StatusOK, http.Header{}, []byte("A")), ErrNotOwner)
+			assert.ErrorIs(t, store.Release(ctx, "k", ownerA), ErrNotOwner)
+			_, existing, _ = store.Begin(ctx, "k", "f", time.Minute)
+			require.NotNil(t, existing, "B 的记录没有被 A 删除")
+			assert.Equal(t, StatusInFlight, existing.Status, "A 的响应没有写到 B 的记录上")
+
+			assert.NoError(t, store.Complete(ctx, "k", ownerB, http.StatusOK, http.Header{}, []byte("B")))
+			_, existing, _ = store.Begin(ctx, "k", "f", time.Minute)
+			assert.Equal(t, []byte("B"), existing.Body)
+			assert.ErrorIs(t, store.Release(ctx, "k", ownerB), ErrNotOwner, "已完成的记录不能释放")
+		})
+	}
+}
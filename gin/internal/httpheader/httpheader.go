@@ -0,0 +1,28 @@
+// Package httpheader 各章节中间件共用的 HTTP 头部小工具
+package httpheader
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Contains 逗号分隔的头部（Vary、Connection 等）中是否包含 token（不区分大小写）
+func Contains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddVary 在 Vary 中追加 token（已存在时不重复）
+//
+// 内容协商、CORS、CSRF、HTTP 缓存等中间件都会设置 Vary，应追加而不是覆盖
+func AddVary(header http.Header, token string) {
+	if !Contains(header, "Vary", token) {
+		header.Add("Vary", token)
+	}
+}
@@ -0,0 +1,23 @@
+package httpheader
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestContains 测试逗号分隔头部的匹配
+func TestContains(t *testing.T) {
+	header := http.Header{}
+	header.Add("Vary", "Origin, accept-encoding")
+	header.Add("Vary", "Cookie")
+	assert.True(t, Contains(header, "Vary", "Accept-Encoding"), "不区分大小写，忽略空白")
+	assert.True(t, Contains(header, "Vary", "Cookie"), "多个同名头部")
+	assert.False(t, Contains(header, "Vary", "Accept"), "按整个 token 匹配")
+	assert.False(t, Contains(header, "Connection", "upgrade"))
+
+	AddVary(header, "cookie")
+	AddVary(header, "Accept")
+	assert.Equal(t, []string{"Origin, accept-encoding", "Cookie", "Accept"}, header.Values("Vary"))
+}
@@ -24,6 +24,15 @@ type Database struct {
 	*gorm.DB
 }
 
+// Close 关闭底层连接池，服务优雅关闭时在 HTTP 请求处理完之后调用
+func (d *Database) Close() error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	return sqlDB.Close()
+}
+
 // GormDatabaseConfigDemo 展示 GORM 数据库配置（基于 fuyelead 项目）
 func GormDatabaseConfigDemo() {
 	fmt.Println("=== GORM 数据库配置示例（基于 fuyelead 项目）===")
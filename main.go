@@ -8,6 +8,10 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	routes "go-learning/gin/22_routes"
 )
 
 // callDemoByReflection 通过反射调用示例函数
@@ -124,6 +128,15 @@ func main() {
 		return
 	}
 
+	// 子命令: 打印示例路由的路由表和中间件链
+	// 不叫 routes，go run . routes 仍然运行 RoutesDemo（小写自动转换）
+	if args[0] == "routes:list" {
+		if err := printRoutes(args[1:]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+		}
+		return
+	}
+
 	// 通过反射调用示例
 	arg := args[0]
 	if err := callDemoByReflection(arg); err != nil {
@@ -133,6 +146,35 @@ func main() {
 	}
 }
 
+// printRoutes 打印示例路由的路由表，只构造路由，不启动服务
+// 例如: go run main.go routes:list MiddlewareRoute
+func printRoutes(args []string) error {
+	names := make([]string, 0, len(routerRegistry))
+	for name := range routerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) == 0 {
+		fmt.Println("用法: go run main.go routes:list <示例名>")
+		fmt.Println()
+		fmt.Println("可以查看路由表的示例:")
+		for _, name := range names {
+			fmt.Printf("    %s\n", name)
+		}
+		return nil
+	}
+
+	build, exists := routerRegistry[args[0]]
+	if !exists {
+		build, exists = routerRegistry[toPascalCase(args[0])]
+	}
+	if !exists {
+		return fmt.Errorf("没有可以查看路由表的示例: %s（可选: %s）", args[0], strings.Join(names, ", "))
+	}
+	gin.SetMode(gin.ReleaseMode) // 不输出 gin 的调试信息
+	return routes.WriteTable(os.Stdout, routes.Inspect(build()))
+}
+
 // printHelp 打印帮助信息
 func printHelp() {
 	fmt.Println("=== Go 语言学习示例运行器（智能反射调用版）===")
@@ -163,6 +205,7 @@ func printHelp() {
 	fmt.Println("  go run main.go BasicRoutes        # Gin基础路由")
 	fmt.Println("  go run main.go Constants          # 常量示例")
 	fmt.Println("  go run main.go arrayAccess         # 支持小写开头（自动转换）")
+	fmt.Println("  go run main.go routes:list RouteGroup  # 打印示例的路由表和中间件链（不启动服务）")
 	fmt.Println()
 	fmt.Printf("当前注册了 %d 个示例\n", len(demoRegistry))
 	fmt.Println("\n🚀 智能匹配: 支持大小写自动转换和下划线格式")
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"github.com/gin-gonic/gin"
+
 	functions "go-learning/basics/1.10_method"
 	variablescope "go-learning/basics/1.11_variable_scope"
 	array "go-learning/basics/1.12_array"
@@ -22,6 +24,31 @@ import (
 	loopcontrol "go-learning/basics/1.9_loop_control"
 	ginroutes "go-learning/gin/1_router_parameter"
 	ginmiddleware "go-learning/gin/2_middleware"
+	ginvalidator "go-learning/gin/3_validator"
+	ginquerydsl "go-learning/gin/4_query_dsl"
+	ginupload "go-learning/gin/5_upload"
+	ginassets "go-learning/gin/6_static_assets"
+	ginconstraint "go-learning/gin/7_route_constraint"
+	ginresponse "go-learning/gin/8_content_negotiation"
+	ginidempotency "go-learning/gin/9_idempotency"
+	gintracing "go-learning/gin/10_tracing"
+	ginaccesslog "go-learning/gin/11_access_log"
+	ginserver "go-learning/gin/12_server"
+	ginmetrics "go-learning/gin/13_metrics"
+	ginhttpcache "go-learning/gin/14_http_cache"
+	gintimeout "go-learning/gin/15_timeout"
+	ginrealtime "go-learning/gin/16_realtime"
+	gincsrf "go-learning/gin/17_csrf"
+	ginsecure "go-learning/gin/18_secure_headers"
+	gincors "go-learning/gin/19_cors"
+	ginhttpclient "go-learning/gin/20_httpclient"
+	gincontract "go-learning/gin/21_contract"
+	ginroutedebug "go-learning/gin/22_routes"
+	gintiming "go-learning/gin/23_server_timing"
+	ginchain "go-learning/gin/24_middleware_chain"
+	ginshedding "go-learning/gin/25_load_shedding"
+	ginviews "go-learning/gin/26_html_forms"
+	ginpatch "go-learning/gin/27_json_patch"
 	gormexamples "go-learning/gorm"
 )
 
@@ -129,6 +156,56 @@ var demoRegistry = map[string]interface{}{
 	"MiddlewareBestPractices": ginmiddleware.MiddlewareBestPracticesDemo,
 	"MiddlewareTest":          ginmiddleware.MiddlewareTestDemo,
 	"GinRouter":               ginmiddleware.GinRouterDemo,
+	// Gin验证规则包示例
+	"ValidatorPack": ginvalidator.ValidatorPackDemo,
+	// Gin列表查询DSL示例
+	"QueryDSL": ginquerydsl.QueryDSLDemo,
+	// Gin文件上传示例
+	"Upload": ginupload.UploadDemo,
+	// Gin嵌入式静态资源示例
+	"StaticAssets": ginassets.StaticAssetsDemo,
+	// Gin路径参数约束示例
+	"RouteConstraint": ginconstraint.RouteConstraintDemo,
+	// Gin统一响应内容协商示例
+	"ContentNegotiation": ginresponse.ContentNegotiationDemo,
+	// Gin幂等请求示例
+	"Idempotency": ginidempotency.IdempotencyDemo,
+	// Gin链路追踪示例
+	"Tracing": gintracing.TracingDemo,
+	// Gin访问日志示例
+	"AccessLog": ginaccesslog.AccessLogDemo,
+	// Gin服务启动与优雅关闭示例
+	"Server": ginserver.ServerDemo,
+	// Gin Prometheus 指标示例
+	"Metrics": ginmetrics.MetricsDemo,
+	// Gin响应缓存与ETag示例
+	"HTTPCache": ginhttpcache.HTTPCacheDemo,
+	// Gin请求超时与请求体大小限制示例
+	"Timeout": gintimeout.TimeoutDemo,
+	// Gin实时推送（SSE/WebSocket）示例
+	"Realtime": ginrealtime.RealtimeDemo,
+	// Gin CSRF防护示例
+	"CSRF": gincsrf.CSRFDemo,
+	// Gin安全响应头示例
+	"SecureHeaders": ginsecure.SecureHeadersDemo,
+	// Gin CORS跨域示例
+	"CORS": gincors.CORSDemo,
+	// Gin第三方调用（重试/熔断）示例
+	"HTTPClient": ginhttpclient.HTTPClientDemo,
+	// Gin接口契约测试示例
+	"Contract": gincontract.ContractDemo,
+	// Gin路由与中间件链查看示例
+	"Routes": ginroutedebug.RoutesDemo,
+	// Gin Server-Timing耗时分析示例
+	"ServerTiming": gintiming.ServerTimingDemo,
+	// Gin条件中间件与中间件注册表示例
+	"MiddlewareChain": ginchain.MiddlewareChainDemo,
+	// Gin并发限制与过载保护示例
+	"LoadShedding": ginshedding.LoadSheddingDemo,
+	// Gin HTML表单渲染示例
+	"HTMLForms": ginviews.HTMLFormsDemo,
+	// Gin JSON Merge Patch与JSON Patch部分更新示例
+	"Patch": ginpatch.PatchDemo,
 	// GORM 示例（基于 fuyelead 项目）
 	"GormBasics":              gormexamples.GormBasicsDemo,
 	"GormRelationships":       gormexamples.GormRelationshipsDemo,
@@ -136,3 +213,9 @@ var demoRegistry = map[string]interface{}{
 	"GormDatabaseConfig":      gormexamples.GormDatabaseConfigDemo,
 	"GormPreloadExplanation":  gormexamples.GormPreloadExplanationDemo,
 }
+
+// routerRegistry 可以通过 routes:list 子命令查看路由表的示例路由，不启动服务
+var routerRegistry = map[string]func() *gin.Engine{
+	"RouteGroup":      ginroutes.RouteGroupRouter,
+	"MiddlewareRoute": ginroutes.MiddlewareRouteRouter,
+}